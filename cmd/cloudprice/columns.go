@@ -0,0 +1,78 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/client"
+)
+
+// priceColumn is a sortable column of a price table
+type priceColumn struct {
+	title   string
+	key     string
+	width   int
+	value   func(p client.Price) string
+	compare func(a, b client.Price) int
+}
+
+var priceColumns = []priceColumn{
+	{"PROVIDER", "provider", 8, func(p client.Price) string { return p.Provider }, func(a, b client.Price) int { return cmp.Compare(a.Provider, b.Provider) }},
+	{"REGION", "region", 24, func(p client.Price) string { return p.Region }, func(a, b client.Price) int { return cmp.Compare(a.Region, b.Region) }},
+	{"INSTANCE TYPE", "instance-type", 26, displayInstanceType, func(a, b client.Price) int { return cmp.Compare(displayInstanceType(a), displayInstanceType(b)) }},
+	{"VCPUS", "vcpus", 6, func(p client.Price) string { return fmt.Sprint(p.VCPUs) }, func(a, b client.Price) int { return cmp.Compare(a.VCPUs, b.VCPUs) }},
+	{"MEMORY", "memory", 11, func(p client.Price) string { return fmt.Sprintf("%.1f %s", p.MemoryGB, memoryUnit(p)) }, func(a, b client.Price) int { return cmp.Compare(a.MemoryGB, b.MemoryGB) }},
+	{"$/HR", "cost", 10, func(p client.Price) string { return fmt.Sprintf("%.4f", p.TotalCost) }, func(a, b client.Price) int { return cmp.Compare(a.TotalCost, b.TotalCost) }},
+	{"$/VCPU", "cost-per-vcpu", 10, func(p client.Price) string { return fmt.Sprintf("%.5f", p.CostPerVCPU) }, func(a, b client.Price) int { return cmp.Compare(a.CostPerVCPU, b.CostPerVCPU) }},
+	{"$/MEM", "cost-per-gb", 10, func(p client.Price) string { return fmt.Sprintf("%.5f", p.CostPerGB) }, func(a, b client.Price) int { return cmp.Compare(a.CostPerGB, b.CostPerGB) }},
+}
+
+// memoryUnit returns the unit of a price's memory, which monitors predating memory units
+// published in GB
+func memoryUnit(p client.Price) string {
+	if p.MemoryUnit == "" {
+		return "GB"
+	}
+	return p.MemoryUnit
+}
+
+// defaultSortColumn is the $/vCPU column
+const defaultSortColumn = 6
+
+// priceColumnKeys lists the keys prices can be sorted by
+func priceColumnKeys() []string {
+	keys := make([]string, len(priceColumns))
+	for i, col := range priceColumns {
+		keys[i] = col.key
+	}
+	return keys
+}
+
+// findPriceColumn returns the index of the column with a key
+func findPriceColumn(key string) (int, error) {
+	i := slices.IndexFunc(priceColumns, func(col priceColumn) bool { return col.key == key })
+	if i < 0 {
+		return 0, fmt.Errorf("unknown sort column %q (expected one of %s)", key, strings.Join(priceColumnKeys(), ", "))
+	}
+	return i, nil
+}
+
+// sortPrices sorts prices by a column, breaking ties in the order given
+func sortPrices(prices []client.Price, column int, descending bool) {
+	compare := priceColumns[column].compare
+	slices.SortStableFunc(prices, func(a, b client.Price) int {
+		if descending {
+			return compare(b, a)
+		}
+		return compare(a, b)
+	})
+}
+
+func displayInstanceType(p client.Price) string {
+	if p.Confidential {
+		return p.InstanceType + " (conf)"
+	}
+	return p.InstanceType
+}
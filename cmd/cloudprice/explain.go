@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/client"
+	cli "github.com/urfave/cli/v2"
+)
+
+var explainCommand = &cli.Command{
+	Name:      "explain",
+	Usage:     "Show how the price of an instance type adds up from the provider's catalog rates",
+	ArgsUsage: "provider/region/instance_type",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print the explanation as JSON instead of a table",
+		},
+	},
+	Action: runExplain,
+}
+
+func runExplain(cctx *cli.Context) error {
+	parts := strings.Split(cctx.Args().First(), "/")
+	if cctx.NArg() != 1 || len(parts) != 3 {
+		return fmt.Errorf("expected one provider/region/instance_type, such as gcp/europe-west1/n2-standard-8")
+	}
+
+	explanation, err := client.New(cctx.String("api-url")).Explain(cctx.Context, parts[0], parts[1], parts[2])
+	if err != nil {
+		return err
+	}
+
+	if cctx.Bool("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(explanation)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDESCRIPTION\tRATE\tQUANTITY\tCOST")
+	for _, c := range explanation.Components {
+		id := c.ID
+		if id == "" {
+			id = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s %s\t%s\n", id, c.Description, c.Rate, c.Quantity, c.Unit, c.Cost)
+	}
+	fmt.Fprintf(w, "\tlist cost\t\t\t%s\n", explanation.ListCost)
+	for _, a := range explanation.Adjustments {
+		fmt.Fprintf(w, "\t%s\t\t\t%s\n", a.Description, a.Amount)
+	}
+	fmt.Fprintf(w, "\ttotal cost\t\t\t%s\n", explanation.TotalCost)
+	if explanation.PublishedCost != "" {
+		fmt.Fprintf(w, "\tpublished cost (fetched %s)\t\t\t%s\n", explanation.PublishedAt.Local().Format(time.DateTime), explanation.PublishedCost)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/client"
+	cli "github.com/urfave/cli/v2"
+)
+
+var version = "dev"
+
+func main() {
+	app := &cli.App{
+		Name:    "cloudprice",
+		Usage:   "Query the prices of a running cloud pricing monitor",
+		Version: version,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "api-url",
+				Usage:   "URL of the running monitor's HTTP API",
+				EnvVars: []string{"CLOUD_PRICING_API_URL"},
+				Value:   client.DefaultURL,
+			},
+		},
+		Commands: []*cli.Command{
+			pricesCommand,
+			simulateCommand,
+			tuiCommand,
+			reportCommand,
+			queryCommand,
+			providersCommand,
+			explainCommand,
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
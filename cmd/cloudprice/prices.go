@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/client"
+	cli "github.com/urfave/cli/v2"
+)
+
+var pricesCommand = &cli.Command{
+	Name:  "prices",
+	Usage: "List the prices published by a running monitor",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "provider",
+			Usage: "Only list prices of this provider (aws, gcp, or azure)",
+		},
+		&cli.StringFlag{
+			Name:  "region",
+			Usage: "Only list prices in this region",
+		},
+		&cli.StringFlag{
+			Name:  "instance-type",
+			Usage: "Only list prices of this instance type",
+		},
+		&cli.BoolFlag{
+			Name:  "fresh",
+			Usage: "Fetch the price from the provider through the monitor instead of listing the published price (requires --provider, --region, and --instance-type)",
+		},
+		&cli.DurationFlag{
+			Name:  "max-age",
+			Usage: "Have the monitor fetch again the listed prices fetched longer ago than this (e.g., 10m) before listing them",
+		},
+		&cli.StringFlag{
+			Name:  "sort",
+			Usage: "Column to sort by: " + strings.Join(priceColumnKeys(), ", "),
+			Value: priceColumns[defaultSortColumn].key,
+		},
+		&cli.BoolFlag{
+			Name:  "reverse",
+			Usage: "Sort in descending order",
+		},
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "Only list the first prices after sorting (0 lists all)",
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print the prices as JSON instead of a table",
+		},
+	},
+	Action: listPrices,
+}
+
+func listPrices(cctx *cli.Context) error {
+	column, err := findPriceColumn(cctx.String("sort"))
+	if err != nil {
+		return err
+	}
+
+	prices, err := client.New(cctx.String("api-url")).Prices(cctx.Context, client.PriceFilter{
+		Provider:     cctx.String("provider"),
+		Region:       cctx.String("region"),
+		InstanceType: cctx.String("instance-type"),
+		Fresh:        cctx.Bool("fresh"),
+		MaxAge:       cctx.Duration("max-age"),
+	})
+	if err != nil {
+		return err
+	}
+
+	sortPrices(prices, column, cctx.Bool("reverse"))
+	if limit := cctx.Int("limit"); limit > 0 {
+		prices = prices[:min(limit, len(prices))]
+	}
+
+	if cctx.Bool("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(prices)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, col := range priceColumns {
+		if i > 0 {
+			fmt.Fprint(w, "\t")
+		}
+		fmt.Fprint(w, col.title)
+	}
+	fmt.Fprintln(w)
+
+	for _, p := range prices {
+		for i, col := range priceColumns {
+			if i > 0 {
+				fmt.Fprint(w, "\t")
+			}
+			fmt.Fprint(w, col.value(p))
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
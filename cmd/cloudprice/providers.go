@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/client"
+	cli "github.com/urfave/cli/v2"
+)
+
+var providersCommand = &cli.Command{
+	Name:  "providers",
+	Usage: "Show which providers and capabilities a running monitor has enabled, and their health",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print the providers as JSON instead of a table",
+		},
+	},
+	Action: runProviders,
+}
+
+func runProviders(cctx *cli.Context) error {
+	providers, err := client.New(cctx.String("api-url")).Providers(cctx.Context)
+	if err != nil {
+		return err
+	}
+
+	if cctx.Bool("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(providers)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tHEALTH\tSERIES\tFAILING\tLAST SUCCESS\tCAPABILITIES")
+	for _, p := range providers {
+		var enabled []string
+		for capability, state := range p.Capabilities {
+			if state == client.CapabilityEnabled {
+				enabled = append(enabled, capability)
+			}
+		}
+		slices.Sort(enabled)
+
+		lastSuccess := "-"
+		if p.Health.LastSuccessAt != nil {
+			lastSuccess = p.Health.LastSuccessAt.Local().Format(time.DateTime)
+		}
+		capabilities := "-"
+		if len(enabled) > 0 {
+			capabilities = strings.Join(enabled, ",")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\n", p.Name, p.Health.Status, p.Health.Series, p.Health.Failing, lastSuccess, capabilities)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/client"
+	cli "github.com/urfave/cli/v2"
+)
+
+var queryCommand = &cli.Command{
+	Name:      "query",
+	Usage:     "Run a read-only SQL query over a running monitor's prices and price history",
+	ArgsUsage: "SQL",
+	Description: "Queries read the prices table of published prices and the history table of price changes " +
+		"recorded to the monitor's --history-file, in SQLite's dialect.",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print the result as JSON instead of a table",
+		},
+	},
+	Action: runQuery,
+}
+
+func runQuery(cctx *cli.Context) error {
+	if cctx.NArg() != 1 {
+		return fmt.Errorf("expected a single SQL query argument")
+	}
+
+	result, err := client.New(cctx.String("api-url")).Query(cctx.Context, cctx.Args().First())
+	if err != nil {
+		return err
+	}
+
+	if cctx.Bool("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.ToUpper(strings.Join(result.Columns, "\t")))
+	for _, row := range result.Rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			if v == nil {
+				cells[i] = "-"
+			} else {
+				cells[i] = fmt.Sprint(v)
+			}
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if result.Truncated {
+		fmt.Fprintf(os.Stderr, "only the first %d rows were returned\n", len(result.Rows))
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/client"
+	"github.com/shopspring/decimal"
+	cli "github.com/urfave/cli/v2"
+	"github.com/xuri/excelize/v2"
+)
+
+// hoursPerMonth is the average number of hours in a month, as used by the monitor
+const hoursPerMonth = 730
+
+var reportCommand = &cli.Command{
+	Name:  "report",
+	Usage: "Write a running monitor's prices to an XLSX rate card, or their recent changes to a Markdown report",
+	Description: "The rate card has a summary sheet and a sheet per provider, with its instance types grouped by " +
+		"region. Commitment options are discounts off the on-demand price, such as negotiated savings plan or " +
+		"committed use discount rates, given as --commitment name=percent. With --format markdown, the report " +
+		"instead summarizes the notable changes of the last --window from the monitor's price history: the " +
+		"biggest increases and decreases, new instance types, and stale series.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "Path to write the report to, required for XLSX rate cards (Markdown reports are printed without it)",
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "Format of the report: xlsx for a rate card, or markdown for a summary of recent price changes",
+			Value: "xlsx",
+		},
+		&cli.StringFlag{
+			Name:  "window",
+			Usage: "How far back a Markdown report looks for changes, in days such as 7d or as a duration such as 36h",
+			Value: "7d",
+		},
+		&cli.IntFlag{
+			Name:  "top",
+			Usage: "Most increases and decreases listed by a Markdown report",
+			Value: 10,
+		},
+		&cli.StringFlag{
+			Name:  "provider",
+			Usage: "Only include prices of this provider (aws, gcp, or azure)",
+		},
+		&cli.StringFlag{
+			Name:  "region",
+			Usage: "Only include prices in this region",
+		},
+		&cli.StringSliceFlag{
+			Name:  "commitment",
+			Usage: "Commitment option as name=discount percent off on demand, such as 1-year=28 (repeatable)",
+		},
+	},
+	Action: writeReport,
+}
+
+// commitment is a commitment option priced at a discount off on demand
+type commitment struct {
+	name     string
+	discount float64
+}
+
+// parseCommitment parses a name=percent commitment option
+func parseCommitment(s string) (commitment, error) {
+	name, percent, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return commitment{}, fmt.Errorf("invalid commitment %q (expected name=percent)", s)
+	}
+	discount, err := strconv.ParseFloat(percent, 64)
+	if err != nil || discount < 0 || discount >= 100 {
+		return commitment{}, fmt.Errorf("invalid discount of commitment %q (expected a percentage from 0 to less than 100)", s)
+	}
+	return commitment{name: name, discount: discount}, nil
+}
+
+func writeReport(cctx *cli.Context) error {
+	switch cctx.String("format") {
+	case "xlsx":
+		if cctx.String("output") == "" {
+			return fmt.Errorf("an XLSX rate card requires --output")
+		}
+	case "markdown":
+		return writeTrendReport(cctx)
+	default:
+		return fmt.Errorf("unknown report format %q (expected xlsx or markdown)", cctx.String("format"))
+	}
+
+	var commitments []commitment
+	for _, s := range cctx.StringSlice("commitment") {
+		c, err := parseCommitment(s)
+		if err != nil {
+			return err
+		}
+		commitments = append(commitments, c)
+	}
+
+	prices, err := client.New(cctx.String("api-url")).Prices(cctx.Context, client.PriceFilter{
+		Provider: cctx.String("provider"),
+		Region:   cctx.String("region"),
+	})
+	if err != nil {
+		return err
+	}
+	if len(prices) == 0 {
+		return fmt.Errorf("the monitor published no matching prices")
+	}
+
+	slices.SortFunc(prices, func(a, b client.Price) int {
+		return cmp.Or(
+			cmp.Compare(a.Provider, b.Provider),
+			cmp.Compare(a.Region, b.Region),
+			cmp.Compare(a.VCPUs, b.VCPUs),
+			cmp.Compare(a.MemoryGB, b.MemoryGB),
+			cmp.Compare(displayInstanceType(a), displayInstanceType(b)),
+		)
+	})
+
+	card, err := newRateCard(cctx.String("api-url"), commitments)
+	if err != nil {
+		return err
+	}
+	defer card.file.Close()
+
+	if err := card.writeSummary(prices, time.Now()); err != nil {
+		return err
+	}
+	for _, provider := range providersOf(prices) {
+		if err := card.writeProvider(provider, prices); err != nil {
+			return err
+		}
+	}
+
+	if err := card.file.SaveAs(cctx.String("output")); err != nil {
+		return fmt.Errorf("failed to write rate card: %w", err)
+	}
+	fmt.Printf("Wrote %d prices to %s\n", len(prices), cctx.String("output"))
+	return nil
+}
+
+// providersOf returns the providers of sorted prices in order
+func providersOf(prices []client.Price) []string {
+	var providers []string
+	for _, p := range prices {
+		if len(providers) == 0 || providers[len(providers)-1] != p.Provider {
+			providers = append(providers, p.Provider)
+		}
+	}
+	return providers
+}
+
+// rateCard is an XLSX rate card being written
+type rateCard struct {
+	file        *excelize.File
+	source      string
+	commitments []commitment
+
+	titleStyle  int
+	headerStyle int
+	regionStyle int
+	costStyle   int
+	memoryStyle int
+}
+
+func newRateCard(source string, commitments []commitment) (*rateCard, error) {
+	card := &rateCard{
+		file:        excelize.NewFile(),
+		source:      source,
+		commitments: commitments,
+	}
+
+	costFormat := `"$"#,##0.0000`
+	memoryFormat := "0.0"
+	styles := []struct {
+		id    *int
+		style *excelize.Style
+	}{
+		{&card.titleStyle, &excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}}},
+		{&card.headerStyle, &excelize.Style{
+			Font:      &excelize.Font{Bold: true, Color: "#FFFFFF"},
+			Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#1F4E78"}},
+			Alignment: &excelize.Alignment{WrapText: true, Vertical: "center"},
+		}},
+		{&card.regionStyle, &excelize.Style{
+			Font: &excelize.Font{Bold: true},
+			Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#DDEBF7"}},
+		}},
+		{&card.costStyle, &excelize.Style{CustomNumFmt: &costFormat}},
+		{&card.memoryStyle, &excelize.Style{CustomNumFmt: &memoryFormat}},
+	}
+	for _, s := range styles {
+		id, err := card.file.NewStyle(s.style)
+		if err != nil {
+			card.file.Close()
+			return nil, fmt.Errorf("failed to create rate card style: %w", err)
+		}
+		*s.id = id
+	}
+	return card, nil
+}
+
+// writeSummary fills the first sheet with what the rate card covers and how it was priced
+func (c *rateCard) writeSummary(prices []client.Price, now time.Time) error {
+	const sheet = "Summary"
+	if err := c.file.SetSheetName("Sheet1", sheet); err != nil {
+		return fmt.Errorf("failed to create summary sheet: %w", err)
+	}
+
+	rows := [][]any{
+		{"Cloud VM rate card"},
+		{},
+		{"Generated", now.UTC().Format(time.RFC1123)},
+		{"Source", c.source},
+		{"Prices", len(prices)},
+		{"Monthly costs", fmt.Sprintf("Hourly cost × %d hours", hoursPerMonth)},
+		{"On demand", "Published on-demand price"},
+	}
+	for _, commitment := range c.commitments {
+		rows = append(rows, []any{commitment.name, fmt.Sprintf("%g%% off the on-demand price", commitment.discount)})
+	}
+
+	for i, row := range rows {
+		if err := c.file.SetSheetRow(sheet, cell(1, i+1), &row); err != nil {
+			return fmt.Errorf("failed to write summary sheet: %w", err)
+		}
+	}
+	if err := c.file.SetCellStyle(sheet, "A1", "A1", c.titleStyle); err != nil {
+		return fmt.Errorf("failed to write summary sheet: %w", err)
+	}
+	return c.file.SetColWidth(sheet, "A", "A", 18)
+}
+
+// writeProvider adds a sheet of a provider's sorted prices, with a heading row before each region
+func (c *rateCard) writeProvider(provider string, prices []client.Price) error {
+	sheet := strings.ToUpper(provider)
+	if _, err := c.file.NewSheet(sheet); err != nil {
+		return fmt.Errorf("failed to create %s sheet: %w", provider, err)
+	}
+
+	var providerPrices []client.Price
+	for _, p := range prices {
+		if p.Provider == provider {
+			providerPrices = append(providerPrices, p)
+		}
+	}
+
+	header := []any{"Instance type", "vCPUs", fmt.Sprintf("Memory (%s)", memoryUnit(providerPrices[0])), "On demand $/hr", "On demand $/month"}
+	for _, commitment := range c.commitments {
+		header = append(header, commitment.name+" $/hr", commitment.name+" $/month")
+	}
+	lastColumn := len(header)
+	if err := c.file.SetSheetRow(sheet, "A1", &header); err != nil {
+		return fmt.Errorf("failed to write %s sheet: %w", provider, err)
+	}
+	if err := c.file.SetCellStyle(sheet, "A1", cell(lastColumn, 1), c.headerStyle); err != nil {
+		return fmt.Errorf("failed to write %s sheet: %w", provider, err)
+	}
+
+	row := 2
+	region := ""
+	for _, p := range providerPrices {
+		if p.Region != region {
+			region = p.Region
+			if err := c.file.SetCellValue(sheet, cell(1, row), region); err != nil {
+				return fmt.Errorf("failed to write %s sheet: %w", provider, err)
+			}
+			if err := c.file.SetCellStyle(sheet, cell(1, row), cell(lastColumn, row), c.regionStyle); err != nil {
+				return fmt.Errorf("failed to write %s sheet: %w", provider, err)
+			}
+			row++
+		}
+
+		onDemand := decimal.NewFromFloat(p.TotalCost)
+		values := []any{displayInstanceType(p), p.VCPUs, p.MemoryGB}
+		values = append(values, costs(onDemand)...)
+		for _, commitment := range c.commitments {
+			discount := decimal.NewFromFloat(commitment.discount).Shift(-2)
+			values = append(values, costs(onDemand.Mul(decimal.NewFromInt(1).Sub(discount)))...)
+		}
+		if err := c.file.SetSheetRow(sheet, cell(1, row), &values); err != nil {
+			return fmt.Errorf("failed to write %s sheet: %w", provider, err)
+		}
+		if err := c.file.SetCellStyle(sheet, cell(3, row), cell(3, row), c.memoryStyle); err != nil {
+			return fmt.Errorf("failed to write %s sheet: %w", provider, err)
+		}
+		if err := c.file.SetCellStyle(sheet, cell(4, row), cell(lastColumn, row), c.costStyle); err != nil {
+			return fmt.Errorf("failed to write %s sheet: %w", provider, err)
+		}
+		row++
+	}
+
+	if err := c.file.SetColWidth(sheet, "A", "A", 28); err != nil {
+		return fmt.Errorf("failed to write %s sheet: %w", provider, err)
+	}
+	lastColumnName, _ := excelize.ColumnNumberToName(lastColumn)
+	if err := c.file.SetColWidth(sheet, "B", lastColumnName, 14); err != nil {
+		return fmt.Errorf("failed to write %s sheet: %w", provider, err)
+	}
+	return c.file.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+}
+
+// costs returns the hourly and monthly cells of an hourly cost
+func costs(hourly decimal.Decimal) []any {
+	return []any{hourly.InexactFloat64(), hourly.Mul(decimal.NewFromInt(hoursPerMonth)).InexactFloat64()}
+}
+
+// cell returns the name of the cell at a 1-based column and row
+func cell(column, row int) string {
+	name, _ := excelize.CoordinatesToCellName(column, row)
+	return name
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/client"
+	cli "github.com/urfave/cli/v2"
+)
+
+var simulateCommand = &cli.Command{
+	Name:      "simulate",
+	Usage:     "Estimate the cost of a hypothetical fleet at a running monitor's prices",
+	ArgsUsage: "[provider/region/instance_type=count[:pricing_model] ...]",
+	Description: "Items are given as arguments, such as aws/us-east-1/m5.large=10 or " +
+		"gcp/us-central1/n2d-standard-4=4:confidential, or as a simulation request in a JSON file.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "file",
+			Usage: "Read the simulation request from this JSON file (- for stdin) in the format of POST /api/v1/simulate",
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print the result as JSON instead of a table",
+		},
+	},
+	Action: simulate,
+}
+
+func simulate(cctx *cli.Context) error {
+	var req client.SimulationRequest
+	if path := cctx.String("file"); path != "" {
+		if err := readSimulationRequest(path, &req); err != nil {
+			return err
+		}
+	}
+
+	for _, arg := range cctx.Args().Slice() {
+		item, err := parseSimulationItem(arg)
+		if err != nil {
+			return err
+		}
+		req.Items = append(req.Items, item)
+	}
+
+	if len(req.Items) == 0 {
+		return fmt.Errorf("no items to simulate; pass items as arguments or --file")
+	}
+
+	result, err := client.New(cctx.String("api-url")).Simulate(cctx.Context, req)
+	if err != nil {
+		return err
+	}
+
+	if cctx.Bool("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+
+	return printSimulation(result)
+}
+
+func readSimulationRequest(path string, req *client.SimulationRequest) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open simulation request: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if err := json.NewDecoder(r).Decode(req); err != nil {
+		return fmt.Errorf("failed to decode simulation request: %w", err)
+	}
+	return nil
+}
+
+// parseSimulationItem parses an item given as provider/region/instance_type=count with an
+// optional :pricing_model suffix
+func parseSimulationItem(arg string) (client.SimulationItem, error) {
+	series, quantity, ok := strings.Cut(arg, "=")
+	parts := strings.Split(series, "/")
+	if !ok || len(parts) != 3 {
+		return client.SimulationItem{}, fmt.Errorf("invalid item %q (expected provider/region/instance_type=count)", arg)
+	}
+
+	quantity, model, _ := strings.Cut(quantity, ":")
+	count, err := strconv.ParseFloat(quantity, 64)
+	if err != nil {
+		return client.SimulationItem{}, fmt.Errorf("invalid count in item %q: %w", arg, err)
+	}
+
+	return client.SimulationItem{
+		Provider:     parts[0],
+		Region:       parts[1],
+		InstanceType: parts[2],
+		Count:        count,
+		PricingModel: model,
+	}, nil
+}
+
+func printSimulation(result *client.SimulationResult) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tREGION\tINSTANCE TYPE\tPRICING\tCOUNT\t$/HR EACH\t$/HR\t$/MONTH")
+	for _, line := range result.Items {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%g\t%.4f\t%.4f\t%.2f\n",
+			line.Provider, line.Region, line.InstanceType, line.PricingModel, line.Count,
+			line.UnitCostPerHour, line.HourlyCost, line.MonthlyCost)
+	}
+	fmt.Fprintln(w)
+
+	regions := make([]string, 0, len(result.Regions))
+	for region := range result.Regions {
+		regions = append(regions, region)
+	}
+	slices.Sort(regions)
+	for _, region := range regions {
+		cost := result.Regions[region]
+		fmt.Fprintf(w, "%s\t\t\t\t\t\t%.4f\t%.2f\n", region, cost.HourlyCost, cost.MonthlyCost)
+	}
+	fmt.Fprintf(w, "TOTAL\t\t\t\t\t\t%.4f\t%.2f\n", result.HourlyCost, result.MonthlyCost)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(result.Unpriced) > 0 {
+		fmt.Fprintf(os.Stderr, "\n%d items could not be priced and are left out of the totals:\n", len(result.Unpriced))
+		for _, item := range result.Unpriced {
+			fmt.Fprintf(os.Stderr, "  %s/%s/%s (%s): %s\n", item.Provider, item.Region, item.InstanceType, item.PricingModel, item.Error)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/client"
+	cli "github.com/urfave/cli/v2"
+)
+
+// parseWindow parses how far back a report looks, a number of days such as 7d or a duration
+// such as 36h
+func parseWindow(text string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(text, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	} else if d, err := time.ParseDuration(text); err == nil && d > 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid window %q (expected days such as 7d or a duration such as 36h)", text)
+}
+
+// seriesKey identifies a series in the price history
+type seriesKey struct {
+	provider     string
+	region       string
+	instanceType string
+	confidential bool
+}
+
+// priceChange is the net change of a series' price over the report window
+type priceChange struct {
+	previous client.PriceRecord
+	current  client.PriceRecord
+}
+
+func (c priceChange) percent() float64 {
+	return (c.current.TotalCost - c.previous.TotalCost) / c.previous.TotalCost * 100
+}
+
+// trendReport is what changed in the price history of a monitor since a time
+type trendReport struct {
+	source    string
+	window    string
+	since     time.Time
+	generated time.Time
+
+	// increased and decreased count every changed series, and increases and decreases keep
+	// the biggest changes of them
+	increased, decreased int
+	increases, decreases []priceChange
+
+	newTypes []client.PriceRecord
+	stale    []client.SeriesStatus
+}
+
+func writeTrendReport(cctx *cli.Context) error {
+	window, err := parseWindow(cctx.String("window"))
+	if err != nil {
+		return err
+	}
+	if cctx.Int("top") < 1 {
+		return fmt.Errorf("top must be at least 1")
+	}
+
+	c := client.New(cctx.String("api-url"))
+	filter := client.PriceFilter{
+		Provider: cctx.String("provider"),
+		Region:   cctx.String("region"),
+	}
+	// The whole history is read, since the price a series had when the window opened may have
+	// been recorded long before
+	records, err := c.History(cctx.Context, filter, time.Time{})
+	if err != nil {
+		return err
+	}
+	statuses, err := c.Pricing(cctx.Context, filter)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	report := newTrendReport(records, statuses, now.Add(-window), cctx.Int("top"))
+	report.source = cctx.String("api-url")
+	report.window = cctx.String("window")
+	report.generated = now
+
+	markdown := report.markdown()
+	path := cctx.String("output")
+	if path == "" {
+		_, err := os.Stdout.WriteString(markdown)
+		return err
+	}
+	if err := os.WriteFile(path, []byte(markdown), 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	fmt.Printf("Wrote the price changes of the last %s to %s\n", report.window, path)
+	return nil
+}
+
+// newTrendReport compares the price of every series in the history when the window opened with
+// its latest price, keeping the top biggest increases and decreases. Series first recorded in
+// the window are new, and series whose latest fetch failed are stale.
+func newTrendReport(records []client.PriceRecord, statuses []client.SeriesStatus, since time.Time, top int) *trendReport {
+	// Backfills append records older than the live ones
+	slices.SortStableFunc(records, func(a, b client.PriceRecord) int {
+		return a.Time.Compare(b.Time)
+	})
+
+	type series struct {
+		first, before, latest client.PriceRecord
+		seenBefore            bool
+	}
+	var keys []seriesKey
+	history := make(map[seriesKey]*series)
+	for _, r := range records {
+		key := seriesKey{r.Provider, r.Region, r.InstanceType, r.Confidential}
+		s, ok := history[key]
+		if !ok {
+			s = &series{first: r}
+			history[key] = s
+			keys = append(keys, key)
+		}
+		if r.Time.Before(since) {
+			s.before, s.seenBefore = r, true
+		}
+		s.latest = r
+	}
+
+	report := &trendReport{since: since}
+	for _, key := range keys {
+		s := history[key]
+		switch {
+		case !s.seenBefore:
+			report.newTypes = append(report.newTypes, s.first)
+		case s.latest.TotalCost > s.before.TotalCost && s.before.TotalCost > 0:
+			report.increases = append(report.increases, priceChange{s.before, s.latest})
+		case s.latest.TotalCost < s.before.TotalCost && s.before.TotalCost > 0:
+			report.decreases = append(report.decreases, priceChange{s.before, s.latest})
+		}
+	}
+
+	slices.SortFunc(report.increases, func(a, b priceChange) int { return cmp.Compare(b.percent(), a.percent()) })
+	slices.SortFunc(report.decreases, func(a, b priceChange) int { return cmp.Compare(a.percent(), b.percent()) })
+	slices.SortFunc(report.newTypes, func(a, b client.PriceRecord) int {
+		return cmp.Or(
+			cmp.Compare(a.Provider, b.Provider),
+			cmp.Compare(a.Region, b.Region),
+			cmp.Compare(a.InstanceType, b.InstanceType),
+		)
+	})
+
+	for _, status := range statuses {
+		if status.Status == client.SeriesError && status.Price != nil {
+			report.stale = append(report.stale, status)
+		}
+	}
+	slices.SortFunc(report.stale, func(a, b client.SeriesStatus) int {
+		return cmp.Compare(b.Price.AgeSeconds, a.Price.AgeSeconds)
+	})
+
+	report.increased, report.decreased = len(report.increases), len(report.decreases)
+	report.increases = report.increases[:min(len(report.increases), top)]
+	report.decreases = report.decreases[:min(len(report.decreases), top)]
+	return report
+}
+
+// markdown renders the report as GitHub flavored Markdown, ready to post to a wiki or pull request
+func (r *trendReport) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Cloud VM price changes\n\n")
+	fmt.Fprintf(&b, "Changes in the last %s, from %s to %s, in the price history of %s.\n\n",
+		r.window, r.since.UTC().Format(time.DateTime), r.generated.UTC().Format(time.DateTime+" MST"), r.source)
+	fmt.Fprintf(&b, "- %s: %d up and %d down\n", countOf(r.increased+r.decreased, "price change"), r.increased, r.decreased)
+	fmt.Fprintf(&b, "- %s\n", countOf(len(r.newTypes), "new instance type"))
+	fmt.Fprintf(&b, "- %s\n", countOf(len(r.stale), "stale series"))
+
+	for _, section := range []struct {
+		title   string
+		changes []priceChange
+	}{
+		{"Biggest increases", r.increases},
+		{"Biggest decreases", r.decreases},
+	} {
+		fmt.Fprintf(&b, "\n## %s\n\n", section.title)
+		if len(section.changes) == 0 {
+			b.WriteString("None.\n")
+			continue
+		}
+		b.WriteString("| Provider | Region | Instance type | Before $/hr | Now $/hr | Change | Changed |\n")
+		b.WriteString("| --- | --- | --- | ---: | ---: | ---: | --- |\n")
+		for _, c := range section.changes {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %+.1f%% | %s |\n",
+				c.current.Provider, c.current.Region, recordInstanceType(c.current),
+				formatCost(c.previous.TotalCost), formatCost(c.current.TotalCost), c.percent(),
+				c.current.Time.UTC().Format(time.DateOnly))
+		}
+	}
+
+	b.WriteString("\n## New instance types\n\n")
+	if len(r.newTypes) == 0 {
+		b.WriteString("None.\n")
+	} else {
+		b.WriteString("| Provider | Region | Instance type | vCPUs | Memory (GB) | $/hr | First seen |\n")
+		b.WriteString("| --- | --- | --- | ---: | ---: | ---: | --- |\n")
+		for _, rec := range r.newTypes {
+			fmt.Fprintf(&b, "| %s | %s | %s | %d | %.1f | %s | %s |\n",
+				rec.Provider, rec.Region, recordInstanceType(rec), rec.VCPUs, rec.MemoryGB,
+				formatCost(rec.TotalCost), rec.Time.UTC().Format(time.DateOnly))
+		}
+	}
+
+	b.WriteString("\n## Stale series\n\n")
+	if len(r.stale) == 0 {
+		b.WriteString("None.\n")
+	} else {
+		b.WriteString("The latest fetch of these series failed, so the monitor still serves the last price it fetched.\n\n")
+		b.WriteString("| Provider | Region | Instance type | $/hr | Fetched | Failures | Reason |\n")
+		b.WriteString("| --- | --- | --- | ---: | --- | ---: | --- |\n")
+		for _, s := range r.stale {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %d | %s |\n",
+				s.Provider, s.Region, displayInstanceType(*s.Price), formatCost(s.Price.TotalCost),
+				s.Price.UpdatedAt.UTC().Format(time.DateTime), s.ConsecutiveFailures, cmp.Or(s.ErrorReason, "-"))
+		}
+	}
+	return b.String()
+}
+
+// recordInstanceType returns the instance type of a history record as the tables show it
+func recordInstanceType(r client.PriceRecord) string {
+	return displayInstanceType(client.Price{InstanceType: r.InstanceType, Confidential: r.Confidential})
+}
+
+// formatCost formats an hourly cost with as many decimal places as it has
+func formatCost(cost float64) string {
+	return strconv.FormatFloat(cost, 'f', -1, 64)
+}
+
+// countOf returns a count of a noun, pluralized with an s unless it already ends in one
+func countOf(n int, noun string) string {
+	if n != 1 && !strings.HasSuffix(noun, "s") {
+		noun += "s"
+	}
+	return fmt.Sprintf("%d %s", n, noun)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/client"
+	cli "github.com/urfave/cli/v2"
+	"golang.org/x/term"
+)
+
+var tuiCommand = &cli.Command{
+	Name:  "tui",
+	Usage: "Explore the live prices of a running monitor in an interactive terminal UI",
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "refresh",
+			Usage: "How often to reload prices from the monitor",
+			Value: 10 * time.Second,
+		},
+	},
+	Action: runTUI,
+}
+
+// tuiState is everything the price table is drawn from
+type tuiState struct {
+	client *client.Client
+
+	prices    []client.Price
+	fetchErr  error
+	fetchedAt time.Time
+
+	filter     string
+	sortColumn int
+	descending bool
+	offset     int
+}
+
+type tuiFetchResult struct {
+	prices []client.Price
+	err    error
+}
+
+func runTUI(cctx *cli.Context) error {
+	ctx, cancel := context.WithCancel(cctx.Context)
+	defer cancel()
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return fmt.Errorf("tui requires an interactive terminal")
+	}
+
+	refresh := cctx.Duration("refresh")
+	if refresh <= 0 {
+		return fmt.Errorf("refresh must be positive")
+	}
+
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to put terminal in raw mode: %w", err)
+	}
+	defer term.Restore(fd, oldState)
+
+	// Draw on the alternate screen so the shell's scrollback is left as it was
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	keys := make(chan string)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- string(buf[:n])
+		}
+	}()
+
+	state := &tuiState{
+		client:     client.New(cctx.String("api-url")),
+		sortColumn: defaultSortColumn,
+	}
+
+	results := make(chan tuiFetchResult, 1)
+	fetch := func() {
+		prices, err := state.client.Prices(ctx, client.PriceFilter{})
+		results <- tuiFetchResult{prices: prices, err: err}
+	}
+	go fetch()
+
+	reload := time.NewTicker(refresh)
+	defer reload.Stop()
+
+	// Redraw every second to pick up terminal resizes and keep the age of the data current
+	redraw := time.NewTicker(time.Second)
+	defer redraw.Stop()
+
+	for {
+		state.draw(fd)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case chunk, ok := <-keys:
+			if !ok || !state.handleKeys(chunk) {
+				return nil
+			}
+		case result := <-results:
+			state.fetchErr = result.err
+			if result.err == nil {
+				state.prices = result.prices
+				state.fetchedAt = time.Now()
+			}
+		case <-reload.C:
+			go fetch()
+		case <-redraw.C:
+		}
+	}
+}
+
+// handleKeys applies a chunk of terminal input and reports whether to keep running. Typing
+// filters, Tab and Shift-Tab change the sort column, Ctrl-R reverses it, the arrow and page
+// keys scroll, Esc clears the filter, and Ctrl-C or Ctrl-Q quits.
+func (s *tuiState) handleKeys(chunk string) bool {
+	for len(chunk) > 0 {
+		var seq string
+		switch {
+		case strings.HasPrefix(chunk, "\x1b[5~"), strings.HasPrefix(chunk, "\x1b[6~"):
+			seq = chunk[:4]
+		case strings.HasPrefix(chunk, "\x1b[") && len(chunk) >= 3:
+			seq = chunk[:3]
+		default:
+			seq = chunk[:1]
+		}
+		chunk = chunk[len(seq):]
+
+		switch seq {
+		case "\x03", "\x11":
+			return false
+		case "\x1b":
+			s.filter = ""
+			s.offset = 0
+		case "\x7f", "\x08":
+			if s.filter != "" {
+				s.filter = s.filter[:len(s.filter)-1]
+				s.offset = 0
+			}
+		case "\t":
+			s.sortColumn = (s.sortColumn + 1) % len(priceColumns)
+		case "\x1b[Z":
+			s.sortColumn = (s.sortColumn + len(priceColumns) - 1) % len(priceColumns)
+		case "\x12":
+			s.descending = !s.descending
+		case "\x1b[A":
+			s.offset--
+		case "\x1b[B":
+			s.offset++
+		case "\x1b[5~":
+			s.offset -= 10
+		case "\x1b[6~":
+			s.offset += 10
+		default:
+			if len(seq) == 1 && seq[0] >= ' ' && seq[0] <= '~' {
+				s.filter += seq
+				s.offset = 0
+			}
+		}
+	}
+	return true
+}
+
+// visible returns the prices matching every word of the filter, sorted
+func (s *tuiState) visible() []client.Price {
+	words := strings.Fields(strings.ToLower(s.filter))
+
+	var rows []client.Price
+	for _, p := range s.prices {
+		text := strings.ToLower(p.Provider + " " + p.Region + " " + displayInstanceType(p))
+		if !slices.ContainsFunc(words, func(w string) bool { return !strings.Contains(text, w) }) {
+			rows = append(rows, p)
+		}
+	}
+
+	sortPrices(rows, s.sortColumn, s.descending)
+	return rows
+}
+
+func (s *tuiState) draw(fd int) {
+	width, height, err := term.GetSize(fd)
+	if err != nil {
+		width, height = 80, 24
+	}
+
+	rows := s.visible()
+
+	// Header, column titles, and footer take three lines
+	pageSize := max(height-3, 1)
+	s.offset = max(min(s.offset, len(rows)-pageSize), 0)
+
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+
+	status := fmt.Sprintf("%d of %d prices", len(rows), len(s.prices))
+	if !s.fetchedAt.IsZero() {
+		status += fmt.Sprintf(", updated %s ago", time.Since(s.fetchedAt).Truncate(time.Second))
+	}
+	if s.fetchErr != nil {
+		status += " | error: " + s.fetchErr.Error()
+	}
+	writeTUILine(&b, fmt.Sprintf("filter: %s_  %s", s.filter, status), width)
+
+	var header strings.Builder
+	for i, col := range priceColumns {
+		title := col.title
+		if i == s.sortColumn && s.descending {
+			title += " v"
+		} else if i == s.sortColumn {
+			title += " ^"
+		}
+		fmt.Fprintf(&header, "%-*s ", col.width, title)
+	}
+	writeTUILine(&b, "\x1b[7m"+padTUI(header.String(), width)+"\x1b[0m", 0)
+
+	end := min(s.offset+pageSize, len(rows))
+	for _, p := range rows[s.offset:end] {
+		var line strings.Builder
+		for _, col := range priceColumns {
+			fmt.Fprintf(&line, "%-*s ", col.width, col.value(p))
+		}
+		writeTUILine(&b, line.String(), width)
+	}
+
+	for i := end - s.offset; i < pageSize; i++ {
+		b.WriteString("\r\n")
+	}
+	b.WriteString(padTUI("type to filter  tab/shift-tab: sort  ctrl-r: reverse  up/down/pgup/pgdn: scroll  esc: clear  ctrl-c: quit", width))
+
+	fmt.Print(b.String())
+}
+
+// writeTUILine writes a line truncated to the terminal width, or as is when width is zero
+func writeTUILine(b *strings.Builder, line string, width int) {
+	if width > 0 && len(line) > width {
+		line = line[:width]
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func padTUI(s string, width int) string {
+	if len(s) >= width {
+		return s[:width]
+	}
+	return s + strings.Repeat(" ", width-len(s))
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/monitor"
+	cli "github.com/urfave/cli/v2"
+)
+
+var version = "dev"
+
+func main() {
+	app := &cli.App{
+		Name:    "monitord",
+		Usage:   "Monitor and export cloud VM pricing as Prometheus metrics",
+		Version: version,
+		Flags:   monitor.Flags,
+		Before:  monitor.ApplyConfigFile,
+		Commands: []*cli.Command{
+			monitor.BackfillCommand,
+			monitor.CompareCommand,
+			monitor.ExportBundleCommand,
+			monitor.GetCommand,
+			monitor.IAMPolicyCommand,
+		},
+		Action: monitor.Run,
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,189 @@
+//go:build integration
+
+// Package integration runs the monitor against mock pricing servers serving recorded responses,
+// scraped by Prometheus, and asserts on the series Prometheus scraped. It needs Docker Compose
+// and is only built with the integration tag:
+//
+//	go test -tags integration -count=1 ./integration/...
+//
+// With INTEGRATION_PROMETHEUS_URL set, the tests query that Prometheus instead, leaving starting
+// and stopping the stack to the caller.
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// composeProject isolates the stack from other compose projects on the host
+const composeProject = "cloud-pricing-monitor-integration"
+
+// scrapeTimeout bounds how long a series may take to be fetched and scraped
+const scrapeTimeout = 2 * time.Minute
+
+var prometheusURL = "http://localhost:19090"
+
+func TestMain(m *testing.M) {
+	if u := os.Getenv("INTEGRATION_PROMETHEUS_URL"); u != "" {
+		prometheusURL = u
+		os.Exit(m.Run())
+	}
+	if port := os.Getenv("PROMETHEUS_PORT"); port != "" {
+		prometheusURL = "http://localhost:" + port
+	}
+
+	if err := compose("up", "--detach", "--build", "--wait"); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start the integration stack: %v\n", err)
+		compose("down", "--volumes")
+		os.Exit(1)
+	}
+	code := m.Run()
+	if code != 0 {
+		compose("logs", "monitor", "mock-pricing")
+	}
+	if err := compose("down", "--volumes"); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to stop the integration stack: %v\n", err)
+	}
+	os.Exit(code)
+}
+
+func compose(args ...string) error {
+	cmd := exec.Command("docker", append([]string{"compose", "--project-name", composeProject, "--file", "docker-compose.yml"}, args...)...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// sample is a series of an instant query result
+type sample struct {
+	labels map[string]string
+	value  float64
+}
+
+// query runs an instant query against Prometheus
+func query(ctx context.Context, promql string) ([]sample, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, prometheusURL+"/api/v1/query?"+url.Values{"query": {promql}}.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			Result []struct {
+				Metric map[string]string `json:"metric"`
+				Value  [2]any            `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode query response: %w", err)
+	}
+	if body.Status != "success" {
+		return nil, fmt.Errorf("query %s failed: %s", promql, body.Error)
+	}
+
+	samples := make([]sample, 0, len(body.Data.Result))
+	for _, result := range body.Data.Result {
+		text, _ := result.Value[1].(string)
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sample value %q: %w", text, err)
+		}
+		samples = append(samples, sample{result.Metric, value})
+	}
+	return samples, nil
+}
+
+// waitForValue waits until a query returns a single series of the expected value, to within
+// float rounding
+func waitForValue(t *testing.T, promql string, want float64) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+
+	var last []sample
+	var lastErr error
+	for {
+		last, lastErr = query(ctx, promql)
+		if lastErr == nil && len(last) == 1 && math.Abs(last[0].value-want) < 1e-9 {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				t.Fatalf("%s: %v", promql, lastErr)
+			}
+			t.Fatalf("%s: want a single series of %v, got %v", promql, want, last)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func TestMonitorIsScraped(t *testing.T) {
+	waitForValue(t, `up{job="cloud-pricing-monitor"}`, 1)
+}
+
+func TestScrapedPrices(t *testing.T) {
+	// Each recording also holds products the monitor must skip, such as Windows, bring your own
+	// license, and spot prices, which would publish a different cost if they were picked
+	tests := []struct {
+		provider, region, instanceType string
+		cost                           float64
+	}{
+		{"aws", "us-east-1", "m5.large", 0.096},
+		{"aws", "eu-west-1", "m5.large", 0.107},
+		{"aws", "us-east-1", "c5.xlarge", 0.17},
+		{"aws", "eu-west-1", "c5.xlarge", 0.192},
+		{"azure", "eastus", "Standard_D2s_v3", 0.096},
+		{"azure", "westeurope", "Standard_D2s_v3", 0.11},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider+"/"+tt.region+"/"+tt.instanceType, func(t *testing.T) {
+			t.Parallel()
+			selector := fmt.Sprintf(`{provider=%q,region=%q,instance_type=%q}`, tt.provider, tt.region, tt.instanceType)
+			waitForValue(t, `cloud_vm_total_cost_per_hour`+selector, tt.cost)
+			waitForValue(t, `cloud_vm_pricing_resolution_failed`+selector, 0)
+		})
+	}
+}
+
+func TestScrapedVMShape(t *testing.T) {
+	// m5.large has 2 vCPUs and 8 GiB, which is 8.59 GB, the default memory unit
+	selector := `{provider="aws",region="us-east-1",instance_type="m5.large"}`
+	waitForValue(t, `cloud_vm_cost_per_vcpu_hour`+selector, 0.048)
+	waitForValue(t, `cloud_vm_cost_per_gb_hour`+selector, 0.0111758709)
+}
+
+func TestUnrecordedInstanceType(t *testing.T) {
+	// m7i.large has no recording, so the catalog has no price for it in either region
+	for _, region := range []string{"us-east-1", "eu-west-1"} {
+		selector := fmt.Sprintf(`{provider="aws",region=%q,instance_type="m7i.large"}`, region)
+		waitForValue(t, `cloud_vm_pricing_resolution_failed`+selector, 1)
+
+		samples, err := query(context.Background(), `cloud_vm_total_cost_per_hour`+selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(samples) != 0 {
+			t.Errorf("unrecorded instance type in %s has a price: %v", region, samples)
+		}
+	}
+}
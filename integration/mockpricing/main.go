@@ -0,0 +1,180 @@
+// Command mockpricing serves recorded AWS Price List and Azure Retail Prices responses, so the
+// monitor can be run end to end without cloud credentials. Each recording holds every region's
+// products of one instance type, and requests are answered with the products their filters
+// match, as the real APIs would.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "Address to listen on")
+	dir := flag.String("recordings", "integration/testdata", "Directory of the recorded responses")
+	flag.Parse()
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /aws", awsHandler(filepath.Join(*dir, "aws")))
+	mux.Handle("GET /azure/api/retail/prices", azureHandler(filepath.Join(*dir, "azure")))
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {})
+
+	slog.Info("serving recorded pricing", "addr", *addr, "recordings", *dir)
+	if err := http.ListenAndServe(*addr, logRequests(mux)); err != nil {
+		slog.Error("mock pricing server failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slog.Info("request", "method", r.Method, "path", r.URL.Path, "target", r.Header.Get("X-Amz-Target"))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// awsFilter is a TERM_MATCH filter of a GetProducts request
+type awsFilter struct {
+	Field string `json:"Field"`
+	Value string `json:"Value"`
+}
+
+// awsHandler answers the Price List API's JSON protocol. GetProducts is answered from
+// aws/products/<instance type>.json and GetAttributeValues from
+// aws/attributes/<attribute name>.json.
+func awsHandler(dir string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Filters       []awsFilter `json:"Filters"`
+			AttributeName string      `json:"AttributeName"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			awsError(w, "SerializationException", err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		switch target := r.Header.Get("X-Amz-Target"); target {
+		case "AWSPriceListService.GetProducts":
+			products, err := awsProducts(dir, req.Filters)
+			if err != nil {
+				awsError(w, "InternalErrorException", err.Error())
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"FormatVersion": "aws_v1", "PriceList": products})
+		case "AWSPriceListService.GetAttributeValues":
+			data, err := os.ReadFile(filepath.Join(dir, "attributes", req.AttributeName+".json"))
+			if err != nil {
+				data = []byte(`{"AttributeValues":[]}`)
+			}
+			w.Write(data)
+		default:
+			awsError(w, "UnknownOperationException", fmt.Sprintf("operation %s is not recorded", target))
+		}
+	})
+}
+
+// awsProducts returns the recorded products of the filtered instance type whose attributes
+// match every filter
+func awsProducts(dir string, filters []awsFilter) ([]string, error) {
+	var instanceType string
+	for _, f := range filters {
+		if f.Field == "instanceType" {
+			instanceType = f.Value
+		}
+	}
+
+	var recording struct {
+		PriceList []string `json:"PriceList"`
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "products", instanceType+".json"))
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &recording); err != nil {
+		return nil, fmt.Errorf("invalid recording of %s: %w", instanceType, err)
+	}
+
+	products := []string{}
+	for _, item := range recording.PriceList {
+		var product struct {
+			Product struct {
+				Attributes map[string]string `json:"attributes"`
+			} `json:"product"`
+		}
+		if err := json.Unmarshal([]byte(item), &product); err != nil {
+			return nil, fmt.Errorf("invalid product in recording of %s: %w", instanceType, err)
+		}
+
+		matches := true
+		for _, f := range filters {
+			if f.Field != "ServiceCode" && product.Product.Attributes[f.Field] != f.Value {
+				matches = false
+			}
+		}
+		if matches {
+			products = append(products, item)
+		}
+	}
+	return products, nil
+}
+
+func awsError(w http.ResponseWriter, code, message string) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"__type": code, "message": message})
+}
+
+var (
+	azureSkuFilter    = regexp.MustCompile(`armSkuName eq '([^']+)'`)
+	azureRegionFilter = regexp.MustCompile(`armRegionName eq '([^']+)'`)
+)
+
+// azureHandler answers the Retail Prices API from azure/<VM size>.json, narrowed to the
+// filtered region
+func azureHandler(dir string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filter := r.URL.Query().Get("$filter")
+		sku := azureSkuFilter.FindStringSubmatch(filter)
+		if sku == nil {
+			http.Error(w, "only requests filtered by armSkuName are recorded", http.StatusBadRequest)
+			return
+		}
+
+		var page struct {
+			Items []map[string]any `json:"Items"`
+		}
+		data, err := os.ReadFile(filepath.Join(dir, sku[1]+".json"))
+		if err != nil && !os.IsNotExist(err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &page); err != nil {
+				http.Error(w, fmt.Sprintf("invalid recording of %s: %v", sku[1], err), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		items := []map[string]any{}
+		region := azureRegionFilter.FindStringSubmatch(filter)
+		for _, item := range page.Items {
+			if region == nil || strings.EqualFold(fmt.Sprint(item["armRegionName"]), region[1]) {
+				items = append(items, item)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"Items": items, "NextPageLink": nil})
+	})
+}
@@ -0,0 +1,177 @@
+// Package client queries the JSON API of a running cloud pricing monitor
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultURL is where the monitor serves its API when run with the default listen address
+const DefaultURL = "http://localhost:6009"
+
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// New creates a client for the monitor serving its API at baseURL
+func New(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    http.DefaultClient,
+	}
+}
+
+// Prices returns the published prices matching a filter, in version 1 of the price listing
+func (c *Client) Prices(ctx context.Context, filter PriceFilter) ([]Price, error) {
+	var body struct {
+		Prices []Price `json:"prices"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/prices?"+filter.query().Encode(), MediaTypePricesV1, nil, &body); err != nil {
+		return nil, fmt.Errorf("failed to list prices: %w", err)
+	}
+	return body.Prices, nil
+}
+
+// PricesV2 returns the published prices matching a filter, in version 2 of the price listing
+func (c *Client) PricesV2(ctx context.Context, filter PriceFilter) (*PriceListV2, error) {
+	var list PriceListV2
+	if err := c.do(ctx, http.MethodGet, "/api/v1/prices?"+filter.query().Encode(), MediaTypePricesV2, nil, &list); err != nil {
+		return nil, fmt.Errorf("failed to list prices: %w", err)
+	}
+	return &list, nil
+}
+
+// Pricing returns the status of the series matching a filter: their published prices and
+// whether the latest fetch of each succeeded. Fresh and MaxAge don't apply to it.
+func (c *Client) Pricing(ctx context.Context, filter PriceFilter) ([]SeriesStatus, error) {
+	var body struct {
+		Series []SeriesStatus `json:"series"`
+	}
+	filter.Fresh, filter.MaxAge = false, 0
+	if err := c.do(ctx, http.MethodGet, "/api/v1/pricing?"+filter.query().Encode(), "application/json", nil, &body); err != nil {
+		return nil, fmt.Errorf("failed to get pricing status: %w", err)
+	}
+	return body.Series, nil
+}
+
+// Providers describes each provider of the monitor: whether it is configured, which of its
+// capabilities are enabled, and its health
+func (c *Client) Providers(ctx context.Context) ([]ProviderStatus, error) {
+	var body struct {
+		Providers []ProviderStatus `json:"providers"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/providers", "application/json", nil, &body); err != nil {
+		return nil, fmt.Errorf("failed to list providers: %w", err)
+	}
+	return body.Providers, nil
+}
+
+func (f PriceFilter) query() url.Values {
+	query := url.Values{}
+	for name, value := range map[string]string{
+		"provider":      f.Provider,
+		"region":        f.Region,
+		"instance_type": f.InstanceType,
+	} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	if f.Fresh {
+		query.Set("fresh", "true")
+	}
+	if f.MaxAge > 0 {
+		query.Set("max_age", strconv.FormatInt(int64(f.MaxAge.Seconds()), 10))
+	}
+	return query
+}
+
+// History returns the price history of the series matching a filter, recorded since a time or
+// in full when since is zero, in the order it was recorded. Fresh and MaxAge don't apply to it.
+func (c *Client) History(ctx context.Context, filter PriceFilter, since time.Time) ([]PriceRecord, error) {
+	var body struct {
+		Records []PriceRecord `json:"records"`
+	}
+	filter.Fresh, filter.MaxAge = false, 0
+	query := filter.query()
+	if !since.IsZero() {
+		query.Set("since", since.UTC().Format(time.RFC3339))
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/history?"+query.Encode(), "application/json", nil, &body); err != nil {
+		return nil, fmt.Errorf("failed to get price history: %w", err)
+	}
+	return body.Records, nil
+}
+
+// Explain fetches the price of a series from its provider and returns how it adds up
+func (c *Client) Explain(ctx context.Context, provider, region, instanceType string) (*Explanation, error) {
+	var explanation Explanation
+	query := url.Values{"provider": {provider}, "region": {region}, "instance_type": {instanceType}}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/explain?"+query.Encode(), "application/json", nil, &explanation); err != nil {
+		return nil, fmt.Errorf("failed to explain price: %w", err)
+	}
+	return &explanation, nil
+}
+
+// Simulate prices a hypothetical fleet at the monitor's published prices
+func (c *Client) Simulate(ctx context.Context, req SimulationRequest) (*SimulationResult, error) {
+	var result SimulationResult
+	if err := c.do(ctx, http.MethodPost, "/api/v1/simulate", "application/json", req, &result); err != nil {
+		return nil, fmt.Errorf("failed to simulate costs: %w", err)
+	}
+	return &result, nil
+}
+
+// Query runs a read-only SQL query over the monitor's published prices and price history
+func (c *Client) Query(ctx context.Context, sql string) (*QueryResult, error) {
+	var result QueryResult
+	query := url.Values{"sql": {sql}}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/query?"+query.Encode(), "application/json", nil, &result); err != nil {
+		return nil, fmt.Errorf("failed to run query: %w", err)
+	}
+	return &result, nil
+}
+
+// do sends a request with an optional JSON body, accepting a response of the media type, and
+// decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, path, accept string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", accept)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
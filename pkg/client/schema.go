@@ -0,0 +1,37 @@
+package client
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+)
+
+// schemas are the JSON Schemas of the monitor's API responses and exported files, named
+// <format>.v<version>.json. A published version never changes; fields are only added to it, and
+// anything else gets a new version.
+//
+//go:embed schemas/*.json
+var schemas embed.FS
+
+// SchemaNames returns the names of every published schema
+func SchemaNames() []string {
+	entries, _ := fs.ReadDir(schemas, "schemas")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+// Schema returns a published JSON Schema by name, such as prices.v2.json
+func Schema(name string) ([]byte, error) {
+	if path.Base(name) != name {
+		return nil, fmt.Errorf("unknown schema %q", name)
+	}
+	data, err := schemas.ReadFile("schemas/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown schema %q", name)
+	}
+	return data, nil
+}
@@ -0,0 +1,258 @@
+package client
+
+import "time"
+
+// Price is a published price as served by the monitor's JSON API
+type Price struct {
+	Provider     string    `json:"provider"`
+	Region       string    `json:"region"`
+	InstanceType string    `json:"instance_type"`
+	Confidential bool      `json:"confidential"`
+	TotalCost    float64   `json:"total_cost"`
+	CostPerVCPU  float64   `json:"cost_per_vcpu,omitempty"`
+	VCPUs        int       `json:"vcpus"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	// MemoryGB and CostPerGB are in MemoryUnit, GB or GiB as configured on the monitor
+	MemoryGB   float64 `json:"memory_gb"`
+	CostPerGB  float64 `json:"cost_per_gb,omitempty"`
+	MemoryUnit string  `json:"memory_unit"`
+
+	PreviousCost float64    `json:"previous_cost,omitempty"`
+	ChangedAt    *time.Time `json:"changed_at,omitempty"`
+
+	// AgeSeconds is how long ago the price was fetched, and TTLSeconds how long until it is
+	// next due to be fetched, zero when overdue and nil when the monitor doesn't refresh it
+	AgeSeconds int64  `json:"age_seconds"`
+	TTLSeconds *int64 `json:"ttl_seconds,omitempty"`
+}
+
+// Media types of the versions of the price listing, requested with the Accept header. A plain
+// application/json request gets version 1.
+const (
+	MediaTypePricesV1 = "application/vnd.cloud-pricing-monitor.prices.v1+json"
+	MediaTypePricesV2 = "application/vnd.cloud-pricing-monitor.prices.v2+json"
+)
+
+// PriceListV2 is version 2 of the price listing. Costs are decimal strings, so they survive
+// decoding exactly, and memory fields are named independently of the unit they are in.
+type PriceListV2 struct {
+	SchemaVersion int `json:"schema_version"`
+	// MemoryUnit is the unit of every price's Memory and CostPerMemory, GB or GiB
+	MemoryUnit string    `json:"memory_unit"`
+	Prices     []PriceV2 `json:"prices"`
+}
+
+// PriceV2 is a published price in version 2 of the price listing
+type PriceV2 struct {
+	Provider      string     `json:"provider"`
+	Region        string     `json:"region"`
+	InstanceType  string     `json:"instance_type"`
+	Confidential  bool       `json:"confidential"`
+	VCPUs         int        `json:"vcpus"`
+	Memory        float64    `json:"memory"`
+	TotalCost     string     `json:"total_cost"`
+	CostPerVCPU   string     `json:"cost_per_vcpu,omitempty"`
+	CostPerMemory string     `json:"cost_per_memory,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	PreviousCost  string     `json:"previous_cost,omitempty"`
+	ChangedAt     *time.Time `json:"changed_at,omitempty"`
+	AgeSeconds    int64      `json:"age_seconds"`
+	TTLSeconds    *int64     `json:"ttl_seconds,omitempty"`
+}
+
+// PriceFilter narrows a price listing to exact matches of its non-empty fields
+type PriceFilter struct {
+	Provider     string
+	Region       string
+	InstanceType string
+
+	// Fresh fetches the price from the provider instead of returning the published price, and
+	// requires every other field
+	Fresh bool
+
+	// MaxAge has the monitor fetch and publish again the matching prices fetched longer ago,
+	// when positive
+	MaxAge time.Duration
+}
+
+// States of a series in the pricing status, the outcome of its latest fetch
+const (
+	SeriesOK    = "ok"
+	SeriesError = "error"
+	// SeriesHeld is a fetched price that changed and awaits confirmation before it is published
+	SeriesHeld = "held"
+)
+
+// SeriesStatus is the published price of a series and the outcome of its latest fetch. A
+// failing series keeps its last published price, if any.
+type SeriesStatus struct {
+	Provider      string     `json:"provider"`
+	Region        string     `json:"region"`
+	InstanceType  string     `json:"instance_type"`
+	Confidential  bool       `json:"confidential"`
+	Status        string     `json:"status"`
+	Price         *Price     `json:"price,omitempty"`
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	// ErrorReason is the class of Error: throttled, not_found, auth, parse, or other
+	ErrorReason         string `json:"error_reason,omitempty"`
+	ConsecutiveFailures int    `json:"consecutive_failures,omitempty"`
+}
+
+// States of a provider's capability
+const (
+	CapabilityEnabled     = "enabled"
+	CapabilityDisabled    = "disabled"
+	CapabilityUnsupported = "unsupported"
+)
+
+// Health states of a provider, from the outcome of the latest fetch of each of its series
+const (
+	ProviderHealthy = "healthy"
+	// ProviderDegraded has some series failing, and ProviderFailing all of them
+	ProviderDegraded = "degraded"
+	ProviderFailing  = "failing"
+	// ProviderPending hasn't fetched any series yet
+	ProviderPending = "pending"
+	// ProviderOffline serves prices from an offline bundle and never fetches
+	ProviderOffline      = "offline"
+	ProviderUnconfigured = "unconfigured"
+)
+
+// ProviderStatus describes what a provider of the monitor prices and how its fetches fare
+type ProviderStatus struct {
+	Name string `json:"name"`
+	// Configured is set when the monitor prices anything with the provider
+	Configured bool `json:"configured"`
+	// Capabilities maps each capability, such as spot or storage, to its state
+	Capabilities map[string]string `json:"capabilities"`
+	Health       ProviderHealth    `json:"health"`
+}
+
+// ProviderHealth summarizes the latest fetch of each of a provider's series
+type ProviderHealth struct {
+	Status  string `json:"status"`
+	Series  int    `json:"series"`
+	Failing int    `json:"failing"`
+	Held    int    `json:"held"`
+	// LastSuccessAt is when a series was last fetched successfully, and LastError why the
+	// latest failing fetch failed
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	// FailingReasons counts the failing series by the class of their error
+	FailingReasons map[string]int `json:"failing_reasons,omitempty"`
+}
+
+// Pricing models a simulated instance can be priced at
+const (
+	PricingOnDemand     = "on_demand"
+	PricingConfidential = "confidential"
+)
+
+// SimulationItem is a group of identical instances in a hypothetical fleet
+type SimulationItem struct {
+	Provider     string  `json:"provider"`
+	Region       string  `json:"region"`
+	InstanceType string  `json:"instance_type"`
+	Count        float64 `json:"count"`
+	PricingModel string  `json:"pricing_model,omitempty"`
+}
+
+type SimulationRequest struct {
+	Items []SimulationItem `json:"items"`
+}
+
+// SimulationLine is the cost of one item of a simulation
+type SimulationLine struct {
+	SimulationItem
+	UnitCostPerHour float64 `json:"unit_cost_per_hour"`
+	HourlyCost      float64 `json:"hourly_cost"`
+	MonthlyCost     float64 `json:"monthly_cost"`
+}
+
+// SimulationCost is a subtotal of a simulation
+type SimulationCost struct {
+	HourlyCost  float64 `json:"hourly_cost"`
+	MonthlyCost float64 `json:"monthly_cost"`
+}
+
+// UnpricedItem is an item with no published price to simulate it at
+type UnpricedItem struct {
+	SimulationItem
+	Error string `json:"error"`
+}
+
+type SimulationResult struct {
+	HourlyCost  float64                    `json:"hourly_cost"`
+	MonthlyCost float64                    `json:"monthly_cost"`
+	Items       []SimulationLine           `json:"items"`
+	Providers   map[string]*SimulationCost `json:"providers"`
+	Regions     map[string]*SimulationCost `json:"regions"`
+	Unpriced    []UnpricedItem             `json:"unpriced,omitempty"`
+}
+
+// PriceRecord is a point in the price history of a series: the first price seen for it or a
+// change of its price. Memory is always in GB.
+type PriceRecord struct {
+	Time         time.Time `json:"time"`
+	Provider     string    `json:"provider"`
+	Region       string    `json:"region"`
+	InstanceType string    `json:"instance_type"`
+	Confidential bool      `json:"confidential,omitempty"`
+	TotalCost    float64   `json:"total_cost"`
+	MemoryGB     float64   `json:"memory_gb"`
+	VCPUs        int       `json:"vcpus"`
+	GPUType      string    `json:"gpu_type,omitempty"`
+	GPUCount     int       `json:"gpu_count,omitempty"`
+	// Source is "live", or the AWS price list version the record was backfilled from
+	Source string `json:"source"`
+}
+
+// QueryResult is the result of a SQL query over the published prices and their history
+type QueryResult struct {
+	Columns []string `json:"columns"`
+	Rows    [][]any  `json:"rows"`
+	// Truncated is set when the query returned more rows than the monitor sends
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Explanation is how the price of a series adds up, from the catalog rates it is billed at to
+// the price the monitor publishes. Costs are decimal strings in USD per hour.
+type Explanation struct {
+	Provider     string           `json:"provider"`
+	Region       string           `json:"region"`
+	InstanceType string           `json:"instance_type"`
+	Components   []PriceComponent `json:"components"`
+	// ListCost is the sum of the components' costs
+	ListCost    string            `json:"list_cost"`
+	Adjustments []PriceAdjustment `json:"adjustments"`
+	// TotalCost is the list cost after the adjustments, as the monitor publishes it
+	TotalCost string `json:"total_cost"`
+	// PublishedCost is the price the monitor published for the series, before this one was
+	// fetched to be explained, and PublishedAt when it was fetched
+	PublishedCost string     `json:"published_cost,omitempty"`
+	PublishedAt   *time.Time `json:"published_at,omitempty"`
+}
+
+// PriceComponent is a part of an explained price: the rate of a catalog entry times the
+// quantity of it an instance is billed for
+type PriceComponent struct {
+	// ID identifies the catalog entry, such as a GCP SKU ID, an AWS usage type, or an Azure
+	// meter ID. Prices from AWS price list files have none.
+	ID          string `json:"id,omitempty"`
+	Description string `json:"description"`
+	Rate        string `json:"rate"`
+	Quantity    string `json:"quantity"`
+	// Unit is what the quantity counts: vCPU, GiB, GPU, or instance
+	Unit string `json:"unit"`
+	Cost string `json:"cost"`
+}
+
+// PriceAdjustment is a change the monitor made to the list cost, such as rounding
+type PriceAdjustment struct {
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+	// Amount is added to the list cost, so a reduction is negative
+	Amount string `json:"amount"`
+}
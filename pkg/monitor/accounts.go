@@ -0,0 +1,80 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/shopspring/decimal"
+)
+
+// awsAccountID matches the 12 digit ID of an AWS account
+var awsAccountID = regexp.MustCompile(`^[0-9]{12}$`)
+
+// AWSAccount is an AWS account priced at its negotiated discount off the list price
+type AWSAccount struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+
+	// DiscountPercent is the account's negotiated discount off the list price, such as the
+	// discount of an Enterprise Discount Program
+	DiscountPercent float64 `json:"discount_percent,omitempty"`
+}
+
+// LoadAWSAccounts reads a JSON list of AWS accounts. Accounts are priced at their discount
+// rather than through an assumed role, so a role_arn, like any field it doesn't know, is an
+// error instead of being ignored.
+func LoadAWSAccounts(file string) ([]AWSAccount, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read AWS accounts file: %w", err)
+	}
+	defer f.Close()
+
+	var accounts []AWSAccount
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&accounts); err != nil {
+		return nil, fmt.Errorf("failed to parse AWS accounts file: %w", err)
+	}
+
+	ids := make(map[string]bool)
+	for i := range accounts {
+		a := &accounts[i]
+		if !awsAccountID.MatchString(a.ID) {
+			return nil, fmt.Errorf("AWS account %d must have a 12 digit ID, got %q", i, a.ID)
+		}
+		if ids[a.ID] {
+			return nil, fmt.Errorf("AWS account %s is defined more than once", a.ID)
+		}
+		ids[a.ID] = true
+
+		if a.DiscountPercent < 0 || a.DiscountPercent >= 100 {
+			return nil, fmt.Errorf("AWS account %s discount must be a percentage below 100", a.ID)
+		}
+		if a.Name == "" {
+			a.Name = a.ID
+		}
+	}
+
+	return accounts, nil
+}
+
+// Price returns the account's price of a list price, after its discount
+func (a AWSAccount) Price(listPrice decimal.Decimal) decimal.Decimal {
+	discount := decimal.NewFromFloat(a.DiscountPercent).Div(decimal.NewFromInt(100))
+	return listPrice.Mul(decimal.NewFromInt(1).Sub(discount))
+}
+
+// recordAccountCosts exports what a published AWS price costs every account. The Price List
+// returns the same list prices to every caller, so each account's price is the published price
+// less its discount.
+func (m *Monitor) recordAccountCosts(p VMPricing) {
+	if p.Provider != "aws" || p.Confidential {
+		return
+	}
+	for _, account := range m.awsAccounts {
+		m.metrics.RecordAccountCost(p, account, account.Price(p.TotalCost))
+	}
+}
@@ -0,0 +1,139 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// adaptiveStabilityRatio is how many times longer than its poll interval a series must have
+// kept its price for the interval to grow. A series unchanged for three weeks is polled daily.
+const adaptiveStabilityRatio = 24
+
+// The kinds of series an adaptive schedule spaces out
+const (
+	seriesOnDemand = "on_demand"
+	seriesSpot     = "spot"
+)
+
+// scheduledSeries identifies a series an adaptive schedule polls
+type scheduledSeries struct {
+	kind         string
+	provider     string
+	region       string
+	instanceType string
+}
+
+// seriesPoll is the last value of a series, since when it has had it, and when the series is
+// next due
+type seriesPoll struct {
+	value       string
+	stableSince time.Time
+	next        time.Time
+}
+
+// AdaptiveSchedule polls each series at an interval that follows how often its price changes:
+// series whose price hasn't changed in weeks are polled less often and volatile ones, such as
+// spot prices, more often, within the configured bounds
+type AdaptiveSchedule struct {
+	minInterval  time.Duration
+	baseInterval time.Duration
+	maxInterval  time.Duration
+
+	mu     sync.Mutex
+	series map[scheduledSeries]*seriesPoll
+}
+
+// NewAdaptiveSchedule returns a schedule that starts new series at the base interval and keeps
+// every series between min and max
+func NewAdaptiveSchedule(minInterval, baseInterval, maxInterval time.Duration) *AdaptiveSchedule {
+	return &AdaptiveSchedule{
+		minInterval:  minInterval,
+		baseInterval: baseInterval,
+		maxInterval:  maxInterval,
+		series:       make(map[scheduledSeries]*seriesPoll),
+	}
+}
+
+// Due reports whether a series should be fetched. Series that have never been fetched are due.
+// Ticks don't line up exactly with due times, so a series due within half a tick is fetched
+// early rather than a whole tick late.
+func (s *AdaptiveSchedule) Due(series scheduledSeries, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	poll, ok := s.series[series]
+	return !ok || !now.Before(poll.next.Add(-s.minInterval/2))
+}
+
+// Observe records the value of a series after a fetch and schedules its next fetch. A new
+// series is treated as having been stable long enough to be polled at the base interval.
+func (s *AdaptiveSchedule) Observe(series scheduledSeries, value string, now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	poll, ok := s.series[series]
+	switch {
+	case !ok:
+		poll = &seriesPoll{value: value, stableSince: now.Add(-s.baseInterval * adaptiveStabilityRatio)}
+		s.series[series] = poll
+	case poll.value != value:
+		poll.value = value
+		poll.stableSince = now
+	}
+
+	interval := min(max(now.Sub(poll.stableSince)/adaptiveStabilityRatio, s.minInterval), s.maxInterval)
+	poll.next = now.Add(interval)
+	return interval
+}
+
+// Next returns when a series is next due, if it has been fetched
+func (s *AdaptiveSchedule) Next(series scheduledSeries) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	poll, ok := s.series[series]
+	if !ok {
+		return time.Time{}, false
+	}
+	return poll.next, true
+}
+
+// Forget drops the schedule of a series that is no longer monitored, so that it is due as soon
+// as it is monitored again
+func (s *AdaptiveSchedule) Forget(series scheduledSeries) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.series, series)
+}
+
+// due reports whether a series should be fetched, which it always is without an adaptive
+// schedule
+func (m *Monitor) due(series scheduledSeries) bool {
+	return m.schedule == nil || m.schedule.Due(series, time.Now())
+}
+
+// observe schedules the next fetch of a series from its value after a fetch
+func (m *Monitor) observe(series scheduledSeries, value string) {
+	if m.schedule == nil {
+		return
+	}
+	interval := m.schedule.Observe(series, value, time.Now())
+	slog.Debug("scheduled next fetch",
+		"kind", series.kind,
+		"provider", series.provider,
+		"region", series.region,
+		"instance_type", series.instanceType,
+		"interval", interval,
+	)
+}
+
+// fetchDuePricing fetches the on-demand and spot prices of the series that are due between
+// full polls. Everything derived from the prices waits for the next full poll.
+func (m *Monitor) fetchDuePricing(ctx context.Context) {
+	m.resetCatalogs()
+	m.runVMFetches(ctx, m.vmFetches())
+	m.recordSpotPrices(ctx)
+}
@@ -0,0 +1,341 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shopspring/decimal"
+)
+
+// alertVars are the values an alert rule's expression can use: the fields of a price like a
+// derived metric, the price before its last change, the price at the previous poll, and the
+// current AWS spot price of the instance type
+var alertVars = append(slices.Clone(derivedVars), "PreviousCost", "LastPollCost", "SpotCost")
+
+// AlertRule is a condition over the prices of each series, such as
+// SpotCost > 0.8 * TotalCost, that fires once it has held for a number of consecutive polls
+type AlertRule struct {
+	Name        string `json:"name"`
+	Expression  string `json:"expression"`
+	Polls       int    `json:"polls,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	expr *Expr
+}
+
+// LoadAlertRules reads alert rules from a JSON file holding a list of them, and parses their
+// expressions
+func LoadAlertRules(file string) ([]AlertRule, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alert rules file: %w", err)
+	}
+
+	var rules []AlertRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse alert rules file: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for i := range rules {
+		r := &rules[i]
+		if r.Name == "" {
+			return nil, fmt.Errorf("alert rule %d has no name", i)
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("alert rule %s is defined more than once", r.Name)
+		}
+		names[r.Name] = true
+
+		if r.Polls == 0 {
+			r.Polls = 1
+		}
+		if r.Polls < 1 {
+			return nil, fmt.Errorf("alert rule %s must hold for at least 1 poll", r.Name)
+		}
+
+		r.expr, err = ParseExpr(r.Expression, alertVars)
+		if err != nil {
+			return nil, fmt.Errorf("alert rule %s: %w", r.Name, err)
+		}
+	}
+
+	return rules, nil
+}
+
+// alertRulesUseSpot reports whether any alert rule in a file needs spot prices. A file that
+// can't be loaded is left to fail when the monitor starts.
+func alertRulesUseSpot(file string) bool {
+	if file == "" {
+		return false
+	}
+	rules, err := LoadAlertRules(file)
+	if err != nil {
+		return false
+	}
+	return NewAlertEvaluator(rules).UsesSpot()
+}
+
+// Alert is a rule holding for a series
+type Alert struct {
+	Rule    AlertRule
+	Pricing VMPricing
+	// Values are the variables the rule was last evaluated with
+	Values map[string]decimal.Decimal
+}
+
+// Title returns the summary line of the alert's issue
+func (a Alert) Title() string {
+	p := a.Pricing
+	variant := ""
+	if p.Confidential {
+		variant = " (confidential)"
+	}
+	return fmt.Sprintf("%s: %s %s%s in %s", a.Rule.Name, strings.ToUpper(p.Provider), p.InstanceType, variant, p.Region)
+}
+
+// Body returns a plain-text description of the alert and the values it fired on
+func (a Alert) Body() string {
+	p := a.Pricing
+
+	var b strings.Builder
+	if a.Rule.Description != "" {
+		b.WriteString(a.Rule.Description + "\n\n")
+	}
+	if a.Rule.Polls > 1 {
+		fmt.Fprintf(&b, "The alert rule %s has held for %d consecutive polls.\n\n", a.Rule.Name, a.Rule.Polls)
+	} else {
+		fmt.Fprintf(&b, "The alert rule %s holds as of the latest poll.\n\n", a.Rule.Name)
+	}
+	fmt.Fprintf(&b, "Expression: %s\n", a.Rule.Expression)
+	fmt.Fprintf(&b, "Series: provider=%s region=%s instance_type=%s confidential=%t\n", p.Provider, p.Region, p.InstanceType, p.Confidential)
+
+	b.WriteString("\nValues:\n")
+	for _, name := range slices.Sorted(maps.Keys(a.Values)) {
+		if a.Rule.expr.Uses(name) {
+			fmt.Fprintf(&b, "- %s: %s\n", name, a.Values[name].String())
+		}
+	}
+	return b.String()
+}
+
+// alertSeries identifies the state of a rule for one series
+type alertSeries struct {
+	rule string
+	key  PriceKey
+}
+
+// AlertEvaluator evaluates alert rules over the published prices after every poll. A rule
+// fires for a series once its expression has been true for the rule's number of consecutive
+// polls, and resolves the first time it is false again. A rule that can't be evaluated for a
+// series, such as one using a spot price that isn't known, doesn't hold.
+type AlertEvaluator struct {
+	rules []AlertRule
+
+	mu       sync.Mutex
+	streak   map[alertSeries]int
+	firing   map[alertSeries]Alert
+	lastPoll map[PriceKey]decimal.Decimal
+}
+
+func NewAlertEvaluator(rules []AlertRule) *AlertEvaluator {
+	return &AlertEvaluator{
+		rules:    rules,
+		streak:   make(map[alertSeries]int),
+		firing:   make(map[alertSeries]Alert),
+		lastPoll: make(map[PriceKey]decimal.Decimal),
+	}
+}
+
+// UsesSpot reports whether any rule needs spot prices, which take an API call per series
+func (e *AlertEvaluator) UsesSpot() bool {
+	return slices.ContainsFunc(e.rules, func(r AlertRule) bool {
+		return r.expr.Uses("SpotCost")
+	})
+}
+
+// AlertResults are the alerts of a poll
+type AlertResults struct {
+	// Firing are every alert currently firing, including the ones that just started
+	Firing []Alert
+	// Started are the alerts that started firing this poll, which should be notified
+	Started []Alert
+	// Resolved are the alerts that stopped firing this poll
+	Resolved []Alert
+}
+
+// Evaluate evaluates every rule for each published price, given the spot prices known for
+// this poll. Series that are no longer published resolve.
+func (e *AlertEvaluator) Evaluate(entries []PriceEntry, spot map[PriceKey]decimal.Decimal, memoryUnit MemoryUnit) AlertResults {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var results AlertResults
+	published := make(map[PriceKey]bool, len(entries))
+	for _, entry := range entries {
+		p := entry.Pricing
+		key := p.Key()
+		published[key] = true
+
+		vars := derivedValues(p, memoryUnit)
+		if !entry.ChangedAt.IsZero() {
+			vars["PreviousCost"] = entry.PreviousCost
+		}
+		if cost, ok := e.lastPoll[key]; ok {
+			vars["LastPollCost"] = cost
+		}
+		if cost, ok := spot[key]; ok {
+			vars["SpotCost"] = cost
+		}
+		e.lastPoll[key] = p.TotalCost
+
+		for _, rule := range e.rules {
+			series := alertSeries{rule.Name, key}
+			v, err := rule.expr.Eval(vars)
+			if err != nil || v.IsZero() {
+				e.streak[series] = 0
+				if alert, ok := e.firing[series]; ok {
+					delete(e.firing, series)
+					results.Resolved = append(results.Resolved, alert)
+				}
+				continue
+			}
+
+			e.streak[series]++
+			alert := Alert{Rule: rule, Pricing: p, Values: vars}
+			if _, ok := e.firing[series]; !ok {
+				if e.streak[series] < rule.Polls {
+					continue
+				}
+				results.Started = append(results.Started, alert)
+			}
+			e.firing[series] = alert
+			results.Firing = append(results.Firing, alert)
+		}
+	}
+
+	for series, alert := range e.firing {
+		if !published[series.key] {
+			delete(e.firing, series)
+			results.Resolved = append(results.Resolved, alert)
+		}
+	}
+	for series := range e.streak {
+		if !published[series.key] {
+			delete(e.streak, series)
+		}
+	}
+	for key := range e.lastPoll {
+		if !published[key] {
+			delete(e.lastPoll, key)
+		}
+	}
+
+	return results
+}
+
+// evaluateAlerts evaluates the alert rules after a poll, and opens an issue in every configured
+// tracker for each alert that starts firing
+func (m *Monitor) evaluateAlerts(ctx context.Context) {
+	entries := m.snapshot.Entries()
+
+	var spot map[PriceKey]decimal.Decimal
+	if m.alerts.UsesSpot() && m.awsFetcher != nil {
+		spot = m.fetchSpotPrices(ctx, entries)
+	}
+
+	results := m.alerts.Evaluate(entries, spot, m.metrics.memoryUnit)
+	m.metrics.RecordAlerts(results)
+
+	for _, alert := range results.Resolved {
+		p := alert.Pricing
+		slog.Info("alert resolved",
+			"alert", alert.Rule.Name,
+			"provider", p.Provider,
+			"region", p.Region,
+			"instance_type", p.InstanceType,
+			"confidential", p.Confidential,
+		)
+	}
+
+	for _, alert := range results.Started {
+		p := alert.Pricing
+		slog.Warn("alert firing",
+			"alert", alert.Rule.Name,
+			"provider", p.Provider,
+			"region", p.Region,
+			"instance_type", p.InstanceType,
+			"confidential", p.Confidential,
+			"cost_per_hour", p.TotalCost,
+		)
+
+		for _, tracker := range m.issueTrackers {
+			result := "opened"
+			err := tracker.CreateIssue(ctx, alert.Title(), alert.Body())
+			if err != nil {
+				result = "failed"
+			}
+			m.metrics.AlertIssues.With(prometheus.Labels{
+				"alert":   alert.Rule.Name,
+				"tracker": tracker.Name(),
+				"result":  result,
+			}).Inc()
+			if err != nil {
+				slog.Error("failed to open alert issue",
+					"tracker", tracker.Name(),
+					"alert", alert.Rule.Name,
+					"provider", p.Provider,
+					"region", p.Region,
+					"instance_type", p.InstanceType,
+					"error", err,
+				)
+			}
+		}
+	}
+}
+
+// fetchSpotPrices fetches the current spot price of every published AWS instance type, which
+// confidential variants share with their standard type
+func (m *Monitor) fetchSpotPrices(ctx context.Context, entries []PriceEntry) map[PriceKey]decimal.Decimal {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	spot := make(map[PriceKey]decimal.Decimal)
+	for _, entry := range entries {
+		p := entry.Pricing
+		if p.Provider != "aws" || p.Confidential {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			price, err := m.awsFetcher.SpotPrice(ctx, p.Region, p.InstanceType)
+			if err != nil {
+				slog.Warn("failed to fetch spot price",
+					"region", p.Region,
+					"instance_type", p.InstanceType,
+					"reason", errorReason(err),
+					"error", err,
+				)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			key := p.Key()
+			spot[key] = price
+			key.Confidential = true
+			spot[key] = price
+		}()
+	}
+	wg.Wait()
+	return spot
+}
@@ -0,0 +1,140 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	compute "google.golang.org/api/compute/v1"
+)
+
+// allRegions is the region list keyword for every region a provider offers
+const allRegions = "all"
+
+// splitAllRegions reports whether a configured region list asks for every region, returning the
+// regions it names otherwise. The keyword must be the list's only value.
+func splitAllRegions(provider string, regions []string) ([]string, bool, error) {
+	if !slices.Contains(regions, allRegions) {
+		return regions, false, nil
+	}
+	flag := watchListFlags[provider].regions
+	switch {
+	case len(regions) > 1:
+		return nil, false, fmt.Errorf("%s %s can't be combined with other regions", flag, allRegions)
+	case provider == "azure":
+		return nil, false, fmt.Errorf("%s does not support %s", flag, allRegions)
+	}
+	return nil, true, nil
+}
+
+// takeAllRegions replaces the all keyword in the configured region lists, to be resolved into
+// the provider's regions once its fetcher is initialized
+func (m *Monitor) takeAllRegions() error {
+	for provider, watched := range m.watching {
+		regions, all, err := splitAllRegions(provider, watched.regions)
+		if err != nil {
+			return err
+		}
+		if !all {
+			continue
+		}
+		if provider == "gcp" && m.gcpProject == "" {
+			return fmt.Errorf("gcp-regions %s requires gcp-project to list regions", allRegions)
+		}
+
+		if m.allRegions == nil {
+			m.allRegions = make(map[string]bool)
+		}
+		watched.regions = regions
+		m.allRegions[provider] = true
+	}
+	return nil
+}
+
+// ListEnabledRegions returns the regions enabled for the account. Opt-in regions are left out
+// until they are enabled, since nothing can run in them before.
+func (f *AWSPricingFetcher) ListEnabledRegions(ctx context.Context) ([]string, error) {
+	out, err := ec2.NewFromConfig(f.cfg).DescribeRegions(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe AWS regions: %w", err)
+	}
+
+	regions := make([]string, 0, len(out.Regions))
+	for _, region := range out.Regions {
+		regions = append(regions, aws.ToString(region.RegionName))
+	}
+	slices.Sort(regions)
+	return regions, nil
+}
+
+// ListProjectRegions returns the regions of Compute Engine that are up, as a project sees them
+func (f *GCPPricingFetcher) ListProjectRegions(ctx context.Context, project string) ([]string, error) {
+	var regions []string
+	err := f.compute.Regions.List(project).Pages(ctx, func(page *compute.RegionList) error {
+		for _, region := range page.Items {
+			if region.Status == "UP" {
+				regions = append(regions, region.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list GCP regions: %w", err)
+	}
+	slices.Sort(regions)
+	return regions, nil
+}
+
+// listRegions lists every region of a provider, as the all keyword asks for
+func (m *Monitor) listRegions(ctx context.Context, provider string) ([]string, error) {
+	switch provider {
+	case "aws":
+		return m.awsFetcher.ListEnabledRegions(ctx)
+	case "gcp":
+		return m.gcpFetcher.ListProjectRegions(ctx, m.gcpProject)
+	}
+	return nil, fmt.Errorf("%s does not support %s regions", provider, allRegions)
+}
+
+// listAllRegions swaps the regions listed on the previous poll for every region each provider
+// monitored in all of them offers now, so a newly launched region is priced without a restart.
+// A shard keeps only the regions it owns, and a provider whose regions can't be listed keeps
+// those listed before.
+func (m *Monitor) listAllRegions(ctx context.Context) {
+	if m.listedRegions == nil {
+		m.listedRegions = make(map[string][]string)
+	}
+
+	for provider, all := range m.allRegions {
+		var listed []string
+		if all {
+			// A provider whose fetcher failed to initialize is listed once it does
+			if m.provider(provider) == nil {
+				continue
+			}
+			var err error
+			listed, err = m.listRegions(ctx, provider)
+			if err != nil {
+				slog.Error("failed to list regions", "provider", provider, "error", err)
+				continue
+			}
+			listed = m.ownedRegions(provider, listed)
+		}
+
+		// A region also configured by name stays when the provider no longer lists it
+		configured := func(region string) bool {
+			return slices.Contains(m.watched[provider].regions, region)
+		}
+		listed = slices.DeleteFunc(listed, configured)
+		previous := slices.DeleteFunc(slices.Clone(m.listedRegions[provider]), configured)
+		if !slices.Equal(listed, previous) {
+			slog.Info("listed regions", "provider", provider, "regions", listed)
+		}
+		watched := m.watch(provider)
+		watched.regions = replaceWatched(watched.regions, previous, listed)
+		m.listedRegions[provider] = listed
+	}
+}
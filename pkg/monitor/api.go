@@ -0,0 +1,331 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/client"
+)
+
+// maxAgeRefreshLimit is the most prices a single request with ?max_age= may fetch again, so that
+// one request can't spend the providers' API quota on the whole listing
+const maxAgeRefreshLimit = 100
+
+// freshness returns how many whole seconds ago a price was fetched and, if it is refreshed,
+// how many until it is next due to be fetched
+func freshness(entry PriceEntry, expiresAt time.Time) (age int64, ttl *int64) {
+	now := time.Now()
+	age = int64(max(now.Sub(entry.UpdatedAt), 0) / time.Second)
+	if !expiresAt.IsZero() {
+		remaining := int64(max(expiresAt.Sub(now), 0) / time.Second)
+		ttl = &remaining
+	}
+	return age, ttl
+}
+
+// newAPIPrice converts a published price to its API representation at the rounding's precision,
+// with memory in the memory unit. expiresAt is when the price is next due to be fetched, zero
+// if it isn't refreshed.
+func newAPIPrice(entry PriceEntry, expiresAt time.Time, rounding PriceRounding, memoryUnit MemoryUnit) client.Price {
+	p := entry.Pricing
+	price := client.Price{
+		Provider:     p.Provider,
+		Region:       p.Region,
+		InstanceType: p.InstanceType,
+		Confidential: p.Confidential,
+		TotalCost:    rounding.Float(p.TotalCost),
+		VCPUs:        p.VCPUs,
+		MemoryGB:     memoryUnit.FromGB(p.MemoryGB).InexactFloat64(),
+		MemoryUnit:   string(memoryUnit),
+		UpdatedAt:    entry.UpdatedAt,
+		PreviousCost: rounding.Float(entry.PreviousCost),
+	}
+	if cost, ok := p.CostPerVCPU(); ok {
+		price.CostPerVCPU = rounding.Float(cost)
+	}
+	if cost, ok := p.CostPerMemory(memoryUnit); ok {
+		price.CostPerGB = rounding.Float(cost)
+	}
+	if !entry.ChangedAt.IsZero() {
+		price.ChangedAt = &entry.ChangedAt
+	}
+	price.AgeSeconds, price.TTLSeconds = freshness(entry, expiresAt)
+	return price
+}
+
+// newAPIPriceV2 converts a published price to its representation in version 2 of the price
+// listing, with costs as decimal strings at the rounding's precision
+func newAPIPriceV2(entry PriceEntry, expiresAt time.Time, rounding PriceRounding, memoryUnit MemoryUnit) client.PriceV2 {
+	p := entry.Pricing
+	price := client.PriceV2{
+		Provider:     p.Provider,
+		Region:       p.Region,
+		InstanceType: p.InstanceType,
+		Confidential: p.Confidential,
+		VCPUs:        p.VCPUs,
+		Memory:       memoryUnit.FromGB(p.MemoryGB).InexactFloat64(),
+		TotalCost:    rounding.Round(p.TotalCost).String(),
+		UpdatedAt:    entry.UpdatedAt,
+	}
+	if cost, ok := p.CostPerVCPU(); ok {
+		price.CostPerVCPU = rounding.Round(cost).String()
+	}
+	if cost, ok := p.CostPerMemory(memoryUnit); ok {
+		price.CostPerMemory = rounding.Round(cost).String()
+	}
+	if !entry.ChangedAt.IsZero() {
+		price.PreviousCost = rounding.Round(entry.PreviousCost).String()
+		price.ChangedAt = &entry.ChangedAt
+	}
+	price.AgeSeconds, price.TTLSeconds = freshness(entry, expiresAt)
+	return price
+}
+
+// pricesMediaTypes maps the media types a price listing can be requested as to its version
+var pricesMediaTypes = map[string]int{
+	client.MediaTypePricesV1: 1,
+	client.MediaTypePricesV2: 2,
+	"application/json":       1,
+	"application/*":          1,
+	"*/*":                    1,
+}
+
+// negotiatePricesVersion picks the version of the price listing to serve for an Accept header:
+// the supported media type with the highest quality, the first listed on a tie, and version 1
+// without a header. It reports false when the header accepts no version.
+func negotiatePricesVersion(accept string) (version int, mediaType string, ok bool) {
+	if strings.TrimSpace(accept) == "" {
+		return 1, "application/json", true
+	}
+
+	best := 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		v, supported := pricesMediaTypes[mt]
+		if !supported {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality > best {
+			best, version, mediaType = quality, v, mt
+		}
+	}
+	if version == 0 {
+		return 0, "", false
+	}
+	if !strings.Contains(mediaType, "vnd.") {
+		mediaType = "application/json"
+	}
+	return version, mediaType, true
+}
+
+// parseMaxAge parses the ?max_age= of a price listing, a number of seconds or a duration such
+// as 10m
+func parseMaxAge(text string) (time.Duration, error) {
+	if seconds, err := strconv.ParseInt(text, 10, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	if d, err := time.ParseDuration(text); err == nil && d >= 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid max_age %q (expected seconds or a duration such as 10m)", text)
+}
+
+// pricesHandler serves every published price, optionally narrowed with ?provider=, ?region=,
+// and ?instance_type=. With ?fresh=true, the one series named by all three is fetched from its
+// provider instead, without being published. With ?max_age=, the listed prices fetched longer
+// ago are fetched and published again with refresh before they are served. expiry returns when
+// a published price is next due to be fetched.
+func pricesHandler(snapshot *PriceSnapshot, fetch func(ctx context.Context, provider, region, instanceType string) (*VMPricing, error), refresh func(ctx context.Context, entries []PriceEntry), expiry func(PriceEntry) time.Time, rounding PriceRounding, memoryUnit MemoryUnit) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version, mediaType, ok := negotiatePricesVersion(r.Header.Get("Accept"))
+		if !ok {
+			http.Error(w, fmt.Sprintf("unsupported Accept header (expected %s or %s)", client.MediaTypePricesV1, client.MediaTypePricesV2), http.StatusNotAcceptable)
+			return
+		}
+		w.Header().Set("Vary", "Accept")
+
+		query := r.URL.Query()
+		filter := map[string]string{
+			"provider":      query.Get("provider"),
+			"region":        query.Get("region"),
+			"instance_type": query.Get("instance_type"),
+		}
+
+		if query.Get("fresh") == "true" {
+			if filter["provider"] == "" || filter["region"] == "" || filter["instance_type"] == "" {
+				http.Error(w, "fresh prices require provider, region, and instance_type", http.StatusBadRequest)
+				return
+			}
+
+			p, err := fetch(r.Context(), filter["provider"], filter["region"], filter["instance_type"])
+			switch {
+			case errors.Is(err, errProviderNotMonitored):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			case err != nil:
+				http.Error(w, fmt.Sprintf("failed to fetch price: %v", err), http.StatusBadGateway)
+				return
+			}
+			writePrices(w, version, mediaType, []PriceEntry{{Pricing: *p, UpdatedAt: time.Now()}}, nil, rounding, memoryUnit)
+			return
+		}
+
+		published := func() []PriceEntry {
+			entries := []PriceEntry{}
+			for _, entry := range snapshot.Entries() {
+				p := entry.Pricing
+				if (filter["provider"] != "" && p.Provider != filter["provider"]) ||
+					(filter["region"] != "" && p.Region != filter["region"]) ||
+					(filter["instance_type"] != "" && p.InstanceType != filter["instance_type"]) {
+					continue
+				}
+				entries = append(entries, entry)
+			}
+			return entries
+		}
+		entries := published()
+
+		if text := query.Get("max_age"); text != "" {
+			maxAge, err := parseMaxAge(text)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			var stale []PriceEntry
+			for _, entry := range entries {
+				if time.Since(entry.UpdatedAt) > maxAge {
+					stale = append(stale, entry)
+				}
+			}
+			if len(stale) > maxAgeRefreshLimit {
+				http.Error(w, fmt.Sprintf("max_age would fetch %d prices again, more than the limit of %d; narrow the listing with provider, region, or instance_type", len(stale), maxAgeRefreshLimit), http.StatusBadRequest)
+				return
+			}
+			if len(stale) > 0 {
+				refresh(r.Context(), stale)
+				entries = published()
+			}
+		}
+		writePrices(w, version, mediaType, entries, expiry, rounding, memoryUnit)
+	})
+}
+
+// writePrices writes a price listing in a version of its format. Prices have no TTL without an
+// expiry.
+func writePrices(w http.ResponseWriter, version int, mediaType string, entries []PriceEntry, expiry func(PriceEntry) time.Time, rounding PriceRounding, memoryUnit MemoryUnit) {
+	expiresAt := func(entry PriceEntry) time.Time {
+		if expiry == nil {
+			return time.Time{}
+		}
+		return expiry(entry)
+	}
+
+	var body any
+	switch version {
+	case 2:
+		list := client.PriceListV2{
+			SchemaVersion: 2,
+			MemoryUnit:    string(memoryUnit),
+			Prices:        make([]client.PriceV2, 0, len(entries)),
+		}
+		for _, entry := range entries {
+			list.Prices = append(list.Prices, newAPIPriceV2(entry, expiresAt(entry), rounding, memoryUnit))
+		}
+		body = list
+	default:
+		prices := make([]client.Price, 0, len(entries))
+		for _, entry := range entries {
+			prices = append(prices, newAPIPrice(entry, expiresAt(entry), rounding, memoryUnit))
+		}
+		body = map[string]any{"prices": prices}
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// historyHandler serves the price history recorded to --history-file, optionally narrowed with
+// ?provider=, ?region=, and ?instance_type=, and to the records since the RFC 3339 time of
+// ?since=. A monitor that doesn't record history answers 404 Not Found.
+func historyHandler(history HistoryStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if history == nil {
+			http.Error(w, "the monitor doesn't record price history (--history-file)", http.StatusNotFound)
+			return
+		}
+
+		query := r.URL.Query()
+		var since time.Time
+		if text := query.Get("since"); text != "" {
+			var err error
+			if since, err = time.Parse(time.RFC3339, text); err != nil {
+				http.Error(w, fmt.Sprintf("invalid since %q (expected an RFC 3339 time)", text), http.StatusBadRequest)
+				return
+			}
+		}
+
+		records, err := history.Records()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		served := []client.PriceRecord{}
+		for _, rec := range records {
+			if (query.Get("provider") != "" && rec.Provider != query.Get("provider")) ||
+				(query.Get("region") != "" && rec.Region != query.Get("region")) ||
+				(query.Get("instance_type") != "" && rec.InstanceType != query.Get("instance_type")) ||
+				rec.Time.Before(since) {
+				continue
+			}
+			served = append(served, client.PriceRecord(rec))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{"records": served}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// schemasHandler serves the published JSON Schemas, listing their names at /api/v1/schemas
+func schemasHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if name == "" {
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(map[string]any{"schemas": client.SchemaNames()}); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		schema, err := client.Schema(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/schema+json")
+		w.Write(schema)
+	})
+}
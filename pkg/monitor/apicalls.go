@@ -0,0 +1,67 @@
+package monitor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/shopspring/decimal"
+)
+
+// apiCallPrices are the prices in USD per request of the billable cloud APIs, by provider and
+// service ID. The APIs the monitor reads prices, instance types, quotas, and clusters from are
+// free: the AWS Price List Query API, the EC2, ECS, Auto Scaling, and Service Quotas reads, and
+// the GCP Cloud Billing Catalog, Compute Engine, and Resource Manager APIs. Requests to any of
+// them are counted at no cost.
+var apiCallPrices = map[string]map[string]decimal.Decimal{
+	"aws": {
+		"Cost Explorer": decimal.RequireFromString("0.01"),
+	},
+}
+
+// apiCallPrice returns the price of a request to a cloud API, zero for free APIs
+func apiCallPrice(provider, service string) decimal.Decimal {
+	return apiCallPrices[provider][service]
+}
+
+type apiCallMetricsKey struct{}
+
+// withAPICallMetrics returns a context that clients configured with count their API requests
+// in metrics. Clients are configured when fetchers are created, so the context has to be the
+// one fetchers are created with.
+func withAPICallMetrics(ctx context.Context, metrics *Metrics) context.Context {
+	return context.WithValue(ctx, apiCallMetricsKey{}, metrics)
+}
+
+func apiCallMetrics(ctx context.Context) *Metrics {
+	metrics, _ := ctx.Value(apiCallMetricsKey{}).(*Metrics)
+	return metrics
+}
+
+// countAWSAPICalls counts every attempt of an AWS operation, after retries so that each retry
+// is counted like the request it repeats
+func countAWSAPICalls(metrics *Metrics) func(stack *middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Finalize.Add(middleware.FinalizeMiddlewareFunc("CountAPICalls", func(
+			ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler,
+		) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			metrics.RecordAPICall("aws", awsmiddleware.GetServiceID(ctx))
+			return next.HandleFinalize(ctx, in)
+		}), middleware.After)
+	}
+}
+
+// gcpAPICallTransport counts every request to a GCP API, by the service its host names, such
+// as cloudbilling for cloudbilling.googleapis.com
+type gcpAPICallTransport struct {
+	base    http.RoundTripper
+	metrics *Metrics
+}
+
+func (t *gcpAPICallTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	service, _, _ := strings.Cut(req.URL.Hostname(), ".")
+	t.metrics.RecordAPICall("gcp", service)
+	return t.base.RoundTrip(req)
+}
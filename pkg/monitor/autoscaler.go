@@ -0,0 +1,327 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"slices"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The cluster-autoscaler gRPC expander plugin (expander/grpcplugin/protos/expander.proto) is
+// served without generated code: requests are decoded with protowire, reading only the fields
+// needed to price each option, and the chosen options are echoed back byte for byte. This
+// avoids depending on the autoscaler's and Kubernetes' protobuf packages.
+const (
+	expanderService = "grpcplugin.Expander"
+
+	// BestOptionsRequest
+	expanderRequestOptionsField = 1
+	expanderRequestNodeMapField = 2
+	// Option
+	expanderOptionNodeGroupField = 1
+	expanderOptionNodeCountField = 2
+	// Node, ObjectMeta, and NodeSpec from k8s.io/api/core/v1
+	kubeNodeMetadataField   = 1
+	kubeNodeSpecField       = 2
+	kubeMetaLabelsField     = 11
+	kubeSpecProviderIDField = 3
+	// Map entries
+	protoMapKeyField   = 1
+	protoMapValueField = 2
+	// BestOptionsResponse
+	expanderResponseOptionsField = 1
+)
+
+// PricingExpander implements the cluster-autoscaler gRPC expander, choosing the node group
+// options that are cheapest to scale up according to the published prices
+type PricingExpander struct {
+	snapshot *PriceSnapshot
+}
+
+func NewPricingExpander(snapshot *PriceSnapshot) *PricingExpander {
+	return &PricingExpander{snapshot: snapshot}
+}
+
+// expanderServer is the handler type of the expander service
+type expanderServer interface {
+	BestOptions(ctx context.Context, req []byte) ([]byte, error)
+}
+
+var expanderServiceDesc = grpc.ServiceDesc{
+	ServiceName: expanderService,
+	HandlerType: (*expanderServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "BestOptions",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				var req []byte
+				if err := dec(&req); err != nil {
+					return nil, err
+				}
+				return srv.(expanderServer).BestOptions(ctx, req)
+			},
+		},
+	},
+	Metadata: "expander.proto",
+}
+
+// rawCodec passes protobuf messages through as bytes
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	*b = append([]byte(nil), data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+// Serve runs the expander gRPC server until the context is canceled. The cluster-autoscaler
+// only connects to expanders over TLS.
+func (e *PricingExpander) Serve(ctx context.Context, addr, certFile, keyFile string) error {
+	creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load expander TLS certificate: %w", err)
+	}
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for expander: %w", err)
+	}
+
+	server := grpc.NewServer(grpc.Creds(creds), grpc.ForceServerCodec(rawCodec{}))
+	server.RegisterService(&expanderServiceDesc, e)
+
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+
+	slog.Info("starting cluster-autoscaler expander", "address", addr)
+	go func() {
+		if err := server.Serve(lis); err != nil {
+			slog.Error("cluster-autoscaler expander failed", "error", err)
+		}
+	}()
+	return nil
+}
+
+// expanderOption is a node group scale-up option from the autoscaler
+type expanderOption struct {
+	nodeGroupID string
+	nodeCount   int
+	raw         []byte
+}
+
+// expanderNode is the template node of a node group
+type expanderNode struct {
+	labels     map[string]string
+	providerID string
+}
+
+// BestOptions returns the options with the lowest hourly cost for their node count. Options
+// whose instance type has no published price are left out, unless none can be priced, in
+// which case every option is returned so the autoscaler falls back to its own choice.
+func (e *PricingExpander) BestOptions(ctx context.Context, req []byte) ([]byte, error) {
+	options, nodes, err := parseBestOptionsRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var best decimal.NullDecimal
+	costs := make([]decimal.NullDecimal, len(options))
+	for i, opt := range options {
+		price, ok := e.nodePrice(nodes[opt.nodeGroupID])
+		if !ok {
+			slog.Debug("no price for node group option", "node_group", opt.nodeGroupID)
+			continue
+		}
+
+		costs[i] = decimal.NewNullDecimal(price.Mul(decimal.NewFromInt(int64(opt.nodeCount))))
+		if !best.Valid || costs[i].Decimal.LessThan(best.Decimal) {
+			best = costs[i]
+		}
+	}
+
+	var resp []byte
+	for i, opt := range options {
+		if !best.Valid || costs[i].Valid && costs[i].Decimal.Equal(best.Decimal) {
+			resp = protowire.AppendTag(resp, expanderResponseOptionsField, protowire.BytesType)
+			resp = protowire.AppendBytes(resp, opt.raw)
+			slog.Debug("chose node group option", "node_group", opt.nodeGroupID, "cost_per_hour", costs[i].Decimal)
+		}
+	}
+	return resp, nil
+}
+
+// nodePrice looks up the price of a template node. The provider comes from the provider ID
+// when the template has one, otherwise the instance type is looked up on every provider.
+func (e *PricingExpander) nodePrice(node expanderNode) (decimal.Decimal, bool) {
+	instanceType := node.labels["node.kubernetes.io/instance-type"]
+	region := node.labels["topology.kubernetes.io/region"]
+	if instanceType == "" || region == "" {
+		return decimal.Zero, false
+	}
+
+	providers := slices.Sorted(maps.Values(kubeProviderIDSchemes))
+	if provider, ok := kubeProvider(node.providerID); ok {
+		providers = []string{provider}
+	}
+
+	for _, provider := range providers {
+		entry, ok := e.snapshot.Get(PriceKey{Provider: provider, Region: region, InstanceType: instanceType})
+		if ok {
+			return entry.Pricing.TotalCost, true
+		}
+	}
+	return decimal.Zero, false
+}
+
+func parseBestOptionsRequest(b []byte) ([]expanderOption, map[string]expanderNode, error) {
+	var options []expanderOption
+	nodes := make(map[string]expanderNode)
+
+	err := walkProto(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		switch {
+		case num == expanderRequestOptionsField && typ == protowire.BytesType:
+			opt, err := parseExpanderOption(v)
+			if err != nil {
+				return err
+			}
+			options = append(options, opt)
+
+		case num == expanderRequestNodeMapField && typ == protowire.BytesType:
+			var id string
+			var node expanderNode
+			err := walkProto(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+				switch {
+				case num == protoMapKeyField && typ == protowire.BytesType:
+					id = string(v)
+				case num == protoMapValueField && typ == protowire.BytesType:
+					var err error
+					node, err = parseKubeNode(v)
+					return err
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			nodes[id] = node
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse expander request: %w", err)
+	}
+
+	return options, nodes, nil
+}
+
+func parseExpanderOption(b []byte) (expanderOption, error) {
+	opt := expanderOption{raw: b}
+	err := walkProto(b, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error {
+		switch {
+		case num == expanderOptionNodeGroupField && typ == protowire.BytesType:
+			opt.nodeGroupID = string(v)
+		case num == expanderOptionNodeCountField && typ == protowire.VarintType:
+			opt.nodeCount = int(int32(n))
+		}
+		return nil
+	})
+	return opt, err
+}
+
+func parseKubeNode(b []byte) (expanderNode, error) {
+	node := expanderNode{labels: make(map[string]string)}
+	err := walkProto(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+
+		switch num {
+		case kubeNodeMetadataField:
+			return walkProto(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+				if num != kubeMetaLabelsField || typ != protowire.BytesType {
+					return nil
+				}
+
+				var key, value string
+				err := walkProto(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+					switch num {
+					case protoMapKeyField:
+						key = string(v)
+					case protoMapValueField:
+						value = string(v)
+					}
+					return nil
+				})
+				node.labels[key] = value
+				return err
+			})
+
+		case kubeNodeSpecField:
+			return walkProto(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+				if num == kubeSpecProviderIDField && typ == protowire.BytesType {
+					node.providerID = string(v)
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	return node, err
+}
+
+// walkProto calls fn for every field of a protobuf message, with the contents of
+// length-delimited fields in v and the value of varint fields in n
+func walkProto(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error) error {
+	for len(b) > 0 {
+		num, typ, tagLen := protowire.ConsumeTag(b)
+		if tagLen < 0 {
+			return protowire.ParseError(tagLen)
+		}
+		b = b[tagLen:]
+
+		var v []byte
+		var n uint64
+		var valueLen int
+		switch typ {
+		case protowire.BytesType:
+			v, valueLen = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			n, valueLen = protowire.ConsumeVarint(b)
+		default:
+			valueLen = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if valueLen < 0 {
+			return protowire.ParseError(valueLen)
+		}
+		b = b[valueLen:]
+
+		if err := fn(num, typ, v, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
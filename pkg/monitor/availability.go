@@ -0,0 +1,238 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/jazware/cloud-pricing-monitor/pkg/client"
+	compute "google.golang.org/api/compute/v1"
+)
+
+// Kinds of services whose regional availability is exported
+const (
+	serviceInstanceFamily = "instance_family"
+	serviceGPUType        = "gpu_type"
+	serviceDiskType       = "disk_type"
+)
+
+// azureSizePattern splits Azure VM sizes like Standard_D2s_v3 into the series (D), the vCPU
+// count (2), and the rest (s_v3)
+var azureSizePattern = regexp.MustCompile(`^(?:Standard_|Basic_)?([A-Za-z]+)\d+(.*)$`)
+
+// instanceFamily returns the family of an instance type: m5 for m5.large on AWS, n2 for
+// n2-standard-4 on GCP, and Ds_v3 for Standard_D2s_v3 on Azure. A type that can't be broken
+// down is its own family.
+func instanceFamily(provider, instanceType string) string {
+	switch provider {
+	case "aws":
+		family, _, _ := strings.Cut(instanceType, ".")
+		return family
+	case "gcp":
+		family, _, _ := strings.Cut(instanceType, "-")
+		return family
+	case "azure":
+		if m := azureSizePattern.FindStringSubmatch(instanceType); m != nil {
+			return m[1] + m[2]
+		}
+	}
+	return instanceType
+}
+
+// ZoneOfferings records which instance types each zone of a region offers. Every zone of the
+// region is present, including zones that offer none of the requested types.
+type ZoneOfferings map[string]map[string]bool
+
+// InstanceTypeOfferings returns the availability zones of a region that offer each instance
+// type. Local and Wavelength Zones are left out.
+func (f *AWSPricingFetcher) InstanceTypeOfferings(ctx context.Context, region string, instanceTypes []string) (ZoneOfferings, error) {
+	client := ec2.NewFromConfig(f.cfg, func(o *ec2.Options) {
+		o.Region = region
+	})
+
+	zones, err := client.DescribeAvailabilityZones(ctx, &ec2.DescribeAvailabilityZonesInput{
+		Filters: []ec2types.Filter{
+			{Name: aws.String("zone-type"), Values: []string{"availability-zone"}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe AWS availability zones: %w", err)
+	}
+
+	offerings := make(ZoneOfferings)
+	for _, zone := range zones.AvailabilityZones {
+		offerings[aws.ToString(zone.ZoneName)] = make(map[string]bool)
+	}
+
+	paginator := ec2.NewDescribeInstanceTypeOfferingsPaginator(client, &ec2.DescribeInstanceTypeOfferingsInput{
+		LocationType: ec2types.LocationTypeAvailabilityZone,
+		Filters: []ec2types.Filter{
+			{Name: aws.String("instance-type"), Values: instanceTypes},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe AWS instance type offerings: %w", err)
+		}
+
+		for _, offering := range page.InstanceTypeOfferings {
+			if offered, ok := offerings[aws.ToString(offering.Location)]; ok {
+				offered[string(offering.InstanceType)] = true
+			}
+		}
+	}
+
+	return offerings, nil
+}
+
+// MachineTypeOfferings returns the zones of each region that offer each machine type. Zones
+// are listed through a project, which only sees the zones and machine types available to it.
+// Custom machine types are never listed by the API, so they are ignored.
+func (f *GCPPricingFetcher) MachineTypeOfferings(ctx context.Context, project string, regions, machineTypes []string) (map[string]ZoneOfferings, error) {
+	offerings := make(map[string]ZoneOfferings)
+	for _, region := range regions {
+		offerings[region] = make(ZoneOfferings)
+	}
+
+	// zoneRegions maps each zone of a monitored region back to its region
+	zoneRegions := make(map[string]string)
+	err := f.compute.Zones.List(project).Pages(ctx, func(page *compute.ZoneList) error {
+		for _, zone := range page.Items {
+			region := path.Base(zone.Region)
+			if _, ok := offerings[region]; ok {
+				offerings[region][zone.Name] = make(map[string]bool)
+				zoneRegions[zone.Name] = region
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list GCP zones: %w", err)
+	}
+
+	predefined := slices.DeleteFunc(slices.Clone(machineTypes), isCustomMachineType)
+	if len(predefined) == 0 {
+		return offerings, nil
+	}
+
+	// eq matches a regular expression against the whole name
+	call := f.compute.MachineTypes.AggregatedList(project)
+	call.Filter(fmt.Sprintf("name eq '%s'", strings.Join(predefined, "|")))
+	err = call.Pages(ctx, func(page *compute.MachineTypeAggregatedList) error {
+		for _, scoped := range page.Items {
+			for _, machineType := range scoped.MachineTypes {
+				region, ok := zoneRegions[machineType.Zone]
+				if !ok {
+					continue
+				}
+				offerings[region][machineType.Zone][machineType.Name] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list GCP machine types: %w", err)
+	}
+
+	return offerings, nil
+}
+
+// recordAvailability exports which zones offer each monitored instance type
+func (m *Monitor) recordAvailability(ctx context.Context) {
+	if m.awsFetcher != nil {
+		for _, region := range m.watch("aws").regions {
+			offerings, err := m.awsFetcher.InstanceTypeOfferings(ctx, region, m.watch("aws").instanceTypes)
+			if err != nil {
+				slog.Error("failed to check AWS instance type availability", "region", region, "error", err)
+				continue
+			}
+			m.publishAvailability("aws", region, m.watch("aws").instanceTypes, offerings)
+		}
+	}
+
+	// GCP regions can be added by discovery without a project configured
+	if m.gcpFetcher != nil && m.gcpProject != "" {
+		// GPUs are attached to instances, so machine types with attached GPUs aren't listed
+		machineTypes := slices.DeleteFunc(slices.Clone(m.watch("gcp").instanceTypes), func(t string) bool {
+			return isCustomMachineType(t) || hasAttachedGPUs(t)
+		})
+		offerings, err := m.gcpFetcher.MachineTypeOfferings(ctx, m.gcpProject, m.watch("gcp").regions, machineTypes)
+		if err != nil {
+			slog.Error("failed to check GCP machine type availability", "error", err)
+			return
+		}
+		for region, zones := range offerings {
+			m.publishAvailability("gcp", region, machineTypes, zones)
+		}
+	}
+}
+
+func (m *Monitor) publishAvailability(provider, region string, instanceTypes []string, offerings ZoneOfferings) {
+	for _, instanceType := range instanceTypes {
+		offered := false
+		for _, types := range offerings {
+			offered = offered || types[instanceType]
+		}
+		if !offered {
+			slog.Warn("instance type not offered in any zone of region",
+				"provider", provider,
+				"region", region,
+				"instance_type", instanceType,
+			)
+		}
+	}
+
+	m.metrics.RecordAvailability(provider, region, instanceTypes, offerings)
+}
+
+// recordFamilyAvailability exports which regions offer the families of the monitored instance
+// types, from the catalog lookups of the poll. A region offers a family when any of its
+// monitored types has a price there, held over from an earlier poll included, and doesn't when
+// the catalog has none of them. A family whose types only failed for other reasons is left out
+// until a lookup tells.
+func (m *Monitor) recordFamilyAvailability() {
+	type location struct{ provider, region string }
+	offered := make(map[location]map[string]bool)
+	set := func(provider, region, instanceType string, available bool) {
+		loc := location{provider, region}
+		if offered[loc] == nil {
+			offered[loc] = make(map[string]bool)
+		}
+		family := instanceFamily(provider, instanceType)
+		offered[loc][family] = offered[loc][family] || available
+	}
+
+	for key, status := range m.statuses.all() {
+		if !key.Confidential && status.state == client.SeriesError && errors.Is(status.err, errNoPricingFound) {
+			set(key.Provider, key.Region, key.InstanceType, false)
+		}
+	}
+	for _, entry := range m.snapshot.Entries() {
+		if p := entry.Pricing; !p.Confidential {
+			set(p.Provider, p.Region, p.InstanceType, true)
+		}
+	}
+
+	for loc, families := range offered {
+		m.metrics.RecordServiceAvailability(loc.provider, loc.region, serviceInstanceFamily, families)
+	}
+}
+
+// recordServiceAvailability exports whether a region offers each of the services of a kind
+// that were looked up in its catalog, from the services the lookup found
+func (m *Monitor) recordServiceAvailability(provider, region, kind string, requested []string, found func(service string) bool) {
+	offered := make(map[string]bool, len(requested))
+	for _, service := range requested {
+		offered[service] = found(service)
+	}
+	m.metrics.RecordServiceAvailability(provider, region, kind, offered)
+}
@@ -0,0 +1,494 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/aws-sdk-go-v2/service/pricing/types"
+	"github.com/jazware/cloud-pricing-monitor/pkg/providers"
+	"github.com/shopspring/decimal"
+)
+
+type AWSPricingFetcher struct {
+	cfg    aws.Config
+	client *pricing.Client
+
+	// pin, when set, prices instances from a fixed price list version instead of the live catalog
+	pin    *PriceListPin
+	pinned *pinnedPriceLists
+
+	// bulk, when set, prices instances from the current price list file of each region
+	bulk *bulkPriceLists
+
+	savingsPlans *savingsPlanRates
+
+	// http downloads the price list and offer files, which aren't served by the SDK's clients
+	http *http.Client
+}
+
+func NewAWSPricingFetcher(ctx context.Context) (*AWSPricingFetcher, error) {
+	// AWS Pricing API is only available in us-east-1 and ap-south-1
+	cfg, err := loadAWSConfig(ctx, config.WithRegion("us-east-1"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &AWSPricingFetcher{
+		cfg:          cfg,
+		client:       pricing.NewFromConfig(cfg),
+		savingsPlans: newSavingsPlanRates(),
+		http:         egressFrom(ctx).Client("aws"),
+	}, nil
+}
+
+func (f *AWSPricingFetcher) FetchPricing(ctx context.Context, region, instanceType string) (*VMPricing, error) {
+	slog.Debug("fetching AWS pricing",
+		"region", region,
+		"instance_type", instanceType,
+	)
+
+	if f.pin != nil {
+		return f.fetchPinnedPricing(ctx, region, instanceType)
+	}
+	if f.bulk != nil {
+		return f.fetchBulkPricing(ctx, region, instanceType)
+	}
+
+	hourlyPrice, attributes, err := f.getOnDemandProduct(ctx, region, instanceType, "Linux", "NA")
+	if err != nil {
+		return nil, err
+	}
+
+	// Extract memory and vCPU
+	memoryStr, _ := attributes["memory"].(string)
+	vcpuStr, _ := attributes["vcpu"].(string)
+
+	memory, err := parseMemory(memoryStr)
+	if err != nil {
+		slog.Warn("failed to parse memory", "memory", memoryStr, "error", err)
+	}
+
+	vcpu, err := strconv.Atoi(vcpuStr)
+	if err != nil {
+		slog.Warn("failed to parse vcpu", "vcpu", vcpuStr, "error", err)
+	}
+
+	gpuStr, _ := attributes["gpu"].(string)
+	gpuType, gpuCount := awsAccelerators(instanceType, gpuStr)
+
+	// Instance hours are billed under the product's usage type, as it appears on the bill
+	usageType, _ := attributes["usagetype"].(string)
+	providers.MatchTraceFrom(ctx).Component(providers.PriceComponent{
+		ID:          usageType,
+		Description: "on-demand Linux instance hour",
+		Rate:        hourlyPrice,
+		Quantity:    decimal.NewFromInt(1),
+		Unit:        "instance",
+	})
+
+	slog.Debug("fetched AWS pricing",
+		"region", region,
+		"instance_type", instanceType,
+		"hourly_price", hourlyPrice,
+		"memory_gb", memory,
+		"vcpus", vcpu,
+	)
+
+	return &VMPricing{
+		Provider:     "aws",
+		Region:       region,
+		InstanceType: instanceType,
+		TotalCost:    hourlyPrice,
+		MemoryGB:     memory,
+		VCPUs:        vcpu,
+		GPUType:      gpuType,
+		GPUCount:     gpuCount,
+	}, nil
+}
+
+// getProduct returns the price list product of an instance type running an operating system
+// with pre-installed software (NA for none) on shared tenancy, and its attributes.
+// License-included products are preferred over bring-your-own-license ones.
+func (f *AWSPricingFetcher) getProduct(ctx context.Context, region, instanceType, operatingSystem, preInstalledSw string) (map[string]interface{}, map[string]interface{}, error) {
+	// Build filters for the pricing query
+	filters := []types.Filter{
+		{
+			Type:  types.FilterTypeTermMatch,
+			Field: aws.String("ServiceCode"),
+			Value: aws.String("AmazonEC2"),
+		},
+		{
+			Type:  types.FilterTypeTermMatch,
+			Field: aws.String("instanceType"),
+			Value: aws.String(instanceType),
+		},
+		{
+			Type:  types.FilterTypeTermMatch,
+			Field: aws.String("regionCode"),
+			Value: aws.String(region),
+		},
+		{
+			Type:  types.FilterTypeTermMatch,
+			Field: aws.String("operatingSystem"),
+			Value: aws.String(operatingSystem),
+		},
+		{
+			Type:  types.FilterTypeTermMatch,
+			Field: aws.String("tenancy"),
+			Value: aws.String("Shared"),
+		},
+		{
+			Type:  types.FilterTypeTermMatch,
+			Field: aws.String("capacitystatus"),
+			Value: aws.String("Used"),
+		},
+		{
+			Type:  types.FilterTypeTermMatch,
+			Field: aws.String("preInstalledSw"),
+			Value: aws.String(preInstalledSw),
+		},
+	}
+
+	input := &pricing.GetProductsInput{
+		ServiceCode: aws.String("AmazonEC2"),
+		Filters:     filters,
+		MaxResults:  aws.Int32(10),
+	}
+
+	trace := providers.MatchTraceFrom(ctx)
+	if trace != nil {
+		terms := make([]string, 0, len(filters))
+		for _, filter := range filters {
+			terms = append(terms, aws.ToString(filter.Field)+"="+aws.ToString(filter.Value))
+		}
+		trace.Query("GetProducts %s", strings.Join(terms, ", "))
+	}
+
+	output, err := f.client.GetProducts(ctx, input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get AWS pricing: %w", err)
+	}
+
+	if len(output.PriceList) == 0 {
+		return nil, nil, fmt.Errorf("%w for instance type %s in region %s", errNoPricingFound, instanceType, region)
+	}
+
+	// Parse the first result, skipping bring-your-own-license products, whose license isn't priced
+	var priceData map[string]interface{}
+	var attributes map[string]interface{}
+	for _, item := range output.PriceList {
+		priceData = nil
+		if err := json.Unmarshal([]byte(item), &priceData); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse pricing data: %w: %w", errParse, err)
+		}
+
+		// Extract instance attributes
+		product, ok := priceData["product"].(map[string]interface{})
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: invalid product data structure", errParse)
+		}
+
+		attributes, ok = product["attributes"].(map[string]interface{})
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: invalid attributes data structure", errParse)
+		}
+
+		sku, _ := product["sku"].(string)
+		description := fmt.Sprintf("%v %v, %v", attributes["usagetype"], attributes["operation"], attributes["licenseModel"])
+		if attributes["licenseModel"] != "Bring your own license" {
+			trace.Match(sku, description, "first license-included product")
+			break
+		}
+		trace.Reject(sku, description, "bring your own license, whose license isn't priced")
+	}
+
+	return priceData, attributes, nil
+}
+
+// getOnDemandProduct returns the on-demand hourly price and attributes of an instance type
+// running an operating system with pre-installed software (NA for none) on shared tenancy.
+// License-included products are preferred over bring-your-own-license ones.
+func (f *AWSPricingFetcher) getOnDemandProduct(ctx context.Context, region, instanceType, operatingSystem, preInstalledSw string) (decimal.Decimal, map[string]interface{}, error) {
+	priceData, attributes, err := f.getProduct(ctx, region, instanceType, operatingSystem, preInstalledSw)
+	if err != nil {
+		return decimal.Zero, nil, err
+	}
+
+	// Extract on-demand pricing
+	terms, ok := priceData["terms"].(map[string]interface{})
+	if !ok {
+		return decimal.Zero, nil, fmt.Errorf("%w: invalid terms data structure", errParse)
+	}
+
+	onDemand, ok := terms["OnDemand"].(map[string]interface{})
+	if !ok {
+		return decimal.Zero, nil, fmt.Errorf("no OnDemand pricing found")
+	}
+
+	// Get the first (and usually only) pricing term
+	var hourlyPrice decimal.Decimal
+	for _, termData := range onDemand {
+		termMap, ok := termData.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		priceDimensions, ok := termMap["priceDimensions"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, dimension := range priceDimensions {
+			dimMap, ok := dimension.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			pricePerUnit, ok := dimMap["pricePerUnit"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			usdPrice, ok := pricePerUnit["USD"].(string)
+			if !ok {
+				continue
+			}
+
+			hourlyPrice, err = decimal.NewFromString(usdPrice)
+			if err != nil {
+				continue
+			}
+
+			break
+		}
+
+		if hourlyPrice.IsPositive() {
+			break
+		}
+	}
+
+	if hourlyPrice.IsZero() {
+		return decimal.Zero, nil, fmt.Errorf("no valid pricing found")
+	}
+
+	return hourlyPrice, attributes, nil
+}
+
+// PriceListVersion identifies a published version of a regional EC2 price list
+type PriceListVersion struct {
+	ARN         string
+	Region      string
+	Version     string
+	PublishedAt time.Time
+}
+
+// PriceListVersion returns the EC2 price list version in effect for a region at a point in time
+func (f *AWSPricingFetcher) PriceListVersion(ctx context.Context, region string, effective time.Time) (*PriceListVersion, error) {
+	output, err := f.client.ListPriceLists(ctx, &pricing.ListPriceListsInput{
+		ServiceCode:   aws.String("AmazonEC2"),
+		CurrencyCode:  aws.String("USD"),
+		RegionCode:    aws.String(region),
+		EffectiveDate: aws.Time(effective),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list AWS price lists: %w", err)
+	}
+
+	if len(output.PriceLists) == 0 || output.PriceLists[0].PriceListArn == nil {
+		return nil, fmt.Errorf("no price list found for region %s at %s", region, effective.Format(time.RFC3339))
+	}
+
+	return parsePriceListArn(*output.PriceLists[0].PriceListArn)
+}
+
+// parsePriceListArn extracts the version from a price list ARN like
+// arn:aws:pricing:::price-list/aws/AmazonEC2/USD/20230328234721/us-east-1
+func parsePriceListArn(arn string) (*PriceListVersion, error) {
+	_, resource, ok := strings.Cut(arn, "price-list/")
+	if !ok {
+		return nil, fmt.Errorf("invalid price list ARN: %s", arn)
+	}
+
+	parts := strings.Split(resource, "/")
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("invalid price list ARN: %s", arn)
+	}
+
+	publishedAt, err := time.Parse("20060102150405", parts[3])
+	if err != nil {
+		return nil, fmt.Errorf("invalid price list version in ARN %s: %w", arn, err)
+	}
+
+	return &PriceListVersion{
+		ARN:         arn,
+		Region:      parts[4],
+		Version:     parts[3],
+		PublishedAt: publishedAt,
+	}, nil
+}
+
+// ListInstanceTypes returns every EC2 instance type in the price list catalog
+func (f *AWSPricingFetcher) ListInstanceTypes(ctx context.Context) ([]string, error) {
+	paginator := pricing.NewGetAttributeValuesPaginator(f.client, &pricing.GetAttributeValuesInput{
+		ServiceCode:   aws.String("AmazonEC2"),
+		AttributeName: aws.String("instanceType"),
+		MaxResults:    aws.Int32(100),
+	})
+
+	var instanceTypes []string
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list AWS instance types: %w", err)
+		}
+
+		for _, value := range page.AttributeValues {
+			if value.Value != nil {
+				instanceTypes = append(instanceTypes, *value.Value)
+			}
+		}
+	}
+
+	return instanceTypes, nil
+}
+
+// ListRegions returns every region with EC2 prices in the price list catalog
+func (f *AWSPricingFetcher) ListRegions(ctx context.Context) ([]string, error) {
+	paginator := pricing.NewGetAttributeValuesPaginator(f.client, &pricing.GetAttributeValuesInput{
+		ServiceCode:   aws.String("AmazonEC2"),
+		AttributeName: aws.String("regionCode"),
+		MaxResults:    aws.Int32(100),
+	})
+
+	var regions []string
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list AWS regions: %w", err)
+		}
+
+		for _, value := range page.AttributeValues {
+			if value.Value != nil {
+				regions = append(regions, *value.Value)
+			}
+		}
+	}
+
+	return regions, nil
+}
+
+// FetchConfidentialPricing returns the price of an instance type as a Nitro Enclaves host.
+// Enclaves are carved out of the parent instance's resources, so there is no premium over
+// on-demand; the variant is only recorded for types that support enclaves in the region.
+func (f *AWSPricingFetcher) FetchConfidentialPricing(ctx context.Context, standard VMPricing) (*VMPricing, error) {
+	// DescribeInstanceTypes is regional, unlike the Pricing API
+	client := ec2.NewFromConfig(f.cfg, func(o *ec2.Options) {
+		o.Region = standard.Region
+	})
+
+	output, err := client.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{
+		InstanceTypes: []ec2types.InstanceType{ec2types.InstanceType(standard.InstanceType)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe instance type: %w", err)
+	}
+
+	if len(output.InstanceTypes) == 0 {
+		return nil, fmt.Errorf("instance type %s not offered in region %s", standard.InstanceType, standard.Region)
+	}
+
+	if output.InstanceTypes[0].NitroEnclavesSupport != ec2types.NitroEnclavesSupportSupported {
+		return nil, fmt.Errorf("%w: %s", errConfidentialUnsupported, standard.InstanceType)
+	}
+
+	confidential := standard
+	confidential.Confidential = true
+	return &confidential, nil
+}
+
+// SpotPrice returns the current Linux spot price of an instance type in a region, averaged
+// over the availability zones that offer it
+func (f *AWSPricingFetcher) SpotPrice(ctx context.Context, region, instanceType string) (decimal.Decimal, error) {
+	prices, err := f.SpotPricesByZone(ctx, region, instanceType)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	var total decimal.Decimal
+	for _, price := range prices {
+		total = total.Add(price)
+	}
+	return total.Div(decimal.NewFromInt(int64(len(prices)))), nil
+}
+
+// SpotPricesByZone returns the current Linux spot price of an instance type in each
+// availability zone of a region that offers it. Prices are listed newest first, so the first
+// price of a zone is the one in effect.
+func (f *AWSPricingFetcher) SpotPricesByZone(ctx context.Context, region, instanceType string) (map[string]decimal.Decimal, error) {
+	client := ec2.NewFromConfig(f.cfg, func(o *ec2.Options) {
+		o.Region = region
+	})
+
+	// A start time of now returns the price in effect in each zone
+	now := time.Now()
+	output, err := client.DescribeSpotPriceHistory(ctx, &ec2.DescribeSpotPriceHistoryInput{
+		InstanceTypes:       []ec2types.InstanceType{ec2types.InstanceType(instanceType)},
+		ProductDescriptions: []string{"Linux/UNIX"},
+		StartTime:           aws.Time(now),
+		EndTime:             aws.Time(now),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AWS spot price history: %w", err)
+	}
+
+	prices := make(map[string]decimal.Decimal)
+	for _, sp := range output.SpotPriceHistory {
+		zone := aws.ToString(sp.AvailabilityZone)
+		if _, ok := prices[zone]; ok {
+			continue
+		}
+		price, err := decimal.NewFromString(aws.ToString(sp.SpotPrice))
+		if err != nil {
+			continue
+		}
+		prices[zone] = price
+	}
+
+	if len(prices) == 0 {
+		return nil, fmt.Errorf("%w for spot instance type %s in region %s", errNoPricingFound, instanceType, region)
+	}
+	return prices, nil
+}
+
+// parseMemory converts AWS memory strings like "8 GiB" to float64 in GB
+func parseMemory(memStr string) (float64, error) {
+	memStr = strings.TrimSpace(memStr)
+	parts := strings.Fields(memStr)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid memory format: %s", memStr)
+	}
+
+	value, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	// Convert GiB to GB if needed
+	unit := strings.ToUpper(parts[1])
+	if unit == "GIB" {
+		return gibToGB(value), nil
+	}
+
+	return value, nil
+}
@@ -0,0 +1,390 @@
+package monitor
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/jazware/cloud-pricing-monitor/pkg/providers"
+	"github.com/shopspring/decimal"
+)
+
+// priceListTimestampLayout is the layout of price list versions and dates given on the command line
+const priceListTimestampLayout = "20060102150405"
+
+// PriceListPin fixes AWS pricing to a single price list version instead of the live catalog.
+// Either Version pins an exact version in every region, or At pins whichever version was in
+// effect in each region at that time.
+type PriceListPin struct {
+	Version string
+	At      time.Time
+}
+
+// ParsePriceListPin builds a pin from the version and date flags. Dates may be given as
+// YYYY-MM-DD or RFC 3339; an empty version and date returns nil.
+func ParsePriceListPin(version, date string) (*PriceListPin, error) {
+	switch {
+	case version == "" && date == "":
+		return nil, nil
+	case version != "" && date != "":
+		return nil, fmt.Errorf("aws-price-list-version and aws-price-list-date are mutually exclusive")
+	case version != "":
+		if _, err := time.Parse(priceListTimestampLayout, version); err != nil {
+			return nil, fmt.Errorf("invalid AWS price list version %q (expected YYYYMMDDhhmmss): %w", version, err)
+		}
+		return &PriceListPin{Version: version}, nil
+	}
+
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if at, err := time.Parse(layout, date); err == nil {
+			return &PriceListPin{At: at}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid AWS price list date %q (expected YYYY-MM-DD or RFC 3339)", date)
+}
+
+// priceListArn returns the ARN of an EC2 price list version for a region
+func priceListArn(version, region string) string {
+	return fmt.Sprintf("arn:aws:pricing:::price-list/aws/AmazonEC2/USD/%s/%s", version, region)
+}
+
+// pinnedPriceLists caches the parsed prices of pinned price list versions by region. Versions
+// are immutable, so each region is downloaded at most once.
+type pinnedPriceLists struct {
+	mu     sync.Mutex
+	prices map[string]map[string]VMPricing
+}
+
+// PinPriceList makes the fetcher price instances from a fixed price list version
+func (f *AWSPricingFetcher) PinPriceList(pin *PriceListPin) {
+	f.pin = pin
+	f.pinned = &pinnedPriceLists{
+		prices: make(map[string]map[string]VMPricing),
+	}
+}
+
+func (f *AWSPricingFetcher) fetchPinnedPricing(ctx context.Context, region, instanceType string) (*VMPricing, error) {
+	prices, err := f.pinnedPrices(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	providers.MatchTraceFrom(ctx).Query("%s in the %d instance types of the pinned price list of %s", instanceType, len(prices), region)
+	p, ok := prices[instanceType]
+	if !ok {
+		return nil, fmt.Errorf("%w for instance type %s in region %s in the pinned price list", errNoPricingFound, instanceType, region)
+	}
+	tracePriceListComponent(ctx, p, "pinned price list")
+	return &p, nil
+}
+
+func (f *AWSPricingFetcher) pinnedPrices(ctx context.Context, region string) (map[string]VMPricing, error) {
+	f.pinned.mu.Lock()
+	defer f.pinned.mu.Unlock()
+
+	if prices, ok := f.pinned.prices[region]; ok {
+		return prices, nil
+	}
+
+	arn := priceListArn(f.pin.Version, region)
+	if f.pin.Version == "" {
+		version, err := f.PriceListVersion(ctx, region, f.pin.At)
+		if err != nil {
+			return nil, err
+		}
+		arn = version.ARN
+	}
+
+	prices, err := f.LoadPriceList(ctx, arn)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("loaded pinned AWS price list",
+		"region", region,
+		"price_list_arn", arn,
+		"instance_types", len(prices),
+	)
+	f.pinned.prices[region] = prices
+	return prices, nil
+}
+
+// bulkPriceLists caches the prices of the current price list version of each region. A region
+// is checked for a new version once per poll, and only a new version is downloaded, so a poll
+// makes a call or two per region rather than one per instance type.
+type bulkPriceLists struct {
+	mu      sync.Mutex
+	regions map[string]*bulkPriceList
+}
+
+// bulkPriceList is the price list of a region. Regions are downloaded concurrently, each
+// under its own lock.
+type bulkPriceList struct {
+	mu     sync.Mutex
+	arn    string
+	prices map[string]VMPricing
+
+	// checked is set once the region was checked for a new version in the current poll, and
+	// err to the failure of that check when there are no prices to fall back on
+	checked bool
+	err     error
+}
+
+// UseBulkPriceLists makes the fetcher price instances from the current price list file of each
+// region instead of looking up every instance type in the live catalog
+func (f *AWSPricingFetcher) UseBulkPriceLists() {
+	f.bulk = &bulkPriceLists{
+		regions: make(map[string]*bulkPriceList),
+	}
+}
+
+// ResetPriceLists makes the next lookup in each region check for a new price list version. The
+// monitor resets them at the start of every poll.
+func (f *AWSPricingFetcher) ResetPriceLists() {
+	if f.bulk == nil {
+		return
+	}
+
+	f.bulk.mu.Lock()
+	defer f.bulk.mu.Unlock()
+	for _, list := range f.bulk.regions {
+		list.mu.Lock()
+		list.checked, list.err = false, nil
+		list.mu.Unlock()
+	}
+}
+
+func (f *AWSPricingFetcher) fetchBulkPricing(ctx context.Context, region, instanceType string) (*VMPricing, error) {
+	prices, err := f.bulkPrices(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	providers.MatchTraceFrom(ctx).Query("%s in the %d instance types of the bulk price list of %s", instanceType, len(prices), region)
+	p, ok := prices[instanceType]
+	if !ok {
+		return nil, fmt.Errorf("%w for instance type %s in region %s in the price list", errNoPricingFound, instanceType, region)
+	}
+	tracePriceListComponent(ctx, p, "price list")
+	return &p, nil
+}
+
+func (f *AWSPricingFetcher) bulkPrices(ctx context.Context, region string) (map[string]VMPricing, error) {
+	f.bulk.mu.Lock()
+	list, ok := f.bulk.regions[region]
+	if !ok {
+		list = &bulkPriceList{}
+		f.bulk.regions[region] = list
+	}
+	f.bulk.mu.Unlock()
+
+	list.mu.Lock()
+	defer list.mu.Unlock()
+
+	// A failed check isn't retried until the next poll, which would download the file again
+	// for every instance type
+	if !list.checked {
+		list.checked = true
+		list.err = f.refreshBulkPriceList(ctx, region, list)
+		if list.err != nil && list.prices != nil {
+			slog.Warn("failed to refresh AWS price list, pricing from the previous version",
+				"region", region,
+				"price_list_arn", list.arn,
+				"error", list.err,
+			)
+			list.err = nil
+		}
+	}
+	return list.prices, list.err
+}
+
+// refreshBulkPriceList downloads the current price list version of a region if it is newer
+// than the one the list holds
+func (f *AWSPricingFetcher) refreshBulkPriceList(ctx context.Context, region string, list *bulkPriceList) error {
+	version, err := f.PriceListVersion(ctx, region, time.Now())
+	if err != nil {
+		return err
+	}
+	if version.ARN == list.arn {
+		return nil
+	}
+
+	prices, err := f.LoadPriceList(ctx, version.ARN)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("loaded AWS price list",
+		"region", region,
+		"price_list_arn", version.ARN,
+		"instance_types", len(prices),
+	)
+	list.arn, list.prices = version.ARN, prices
+	return nil
+}
+
+// LoadPriceList downloads a regional EC2 price list version and returns the Linux on-demand
+// shared tenancy price of every instance type in it
+func (f *AWSPricingFetcher) LoadPriceList(ctx context.Context, arn string) (map[string]VMPricing, error) {
+	version, err := parsePriceListArn(arn)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := f.client.GetPriceListFileUrl(ctx, &pricing.GetPriceListFileUrlInput{
+		PriceListArn: aws.String(arn),
+		FileFormat:   aws.String("csv"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AWS price list file URL: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, aws.ToString(output.Url), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create price list request: %w", err)
+	}
+
+	resp, err := f.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download AWS price list: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download AWS price list: unexpected status %s", resp.Status)
+	}
+
+	prices, err := parsePriceListCSV(resp.Body, version.Region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse AWS price list %s: %w", arn, err)
+	}
+	return prices, nil
+}
+
+// tracePriceListComponent records a price taken from a price list as the whole of a traced
+// price. Price lists are kept by instance type, without the SKU a price came from.
+func tracePriceListComponent(ctx context.Context, p VMPricing, list string) {
+	providers.MatchTraceFrom(ctx).Component(providers.PriceComponent{
+		Description: "on-demand Linux instance hour in the " + list,
+		Rate:        p.TotalCost,
+		Quantity:    decimal.NewFromInt(1),
+		Unit:        "instance",
+	})
+}
+
+// parsePriceListCSV reads an EC2 bulk price list in CSV format. The file starts with a few
+// metadata rows followed by the column header, and has one row per price dimension of
+// every SKU, so only the rows matching the live API filters are kept.
+func parsePriceListCSV(r io.Reader, region string) (map[string]VMPricing, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	columns := make(map[string]int)
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find price list header: %w", err)
+		}
+		if len(record) > 0 && record[0] == "SKU" {
+			for i, name := range record {
+				columns[name] = i
+			}
+			break
+		}
+	}
+
+	required := []string{
+		"TermType", "Unit", "PricePerUnit", "Currency", "Instance Type", "vCPU", "Memory",
+		"Tenancy", "Operating System", "Pre Installed S/W", "CapacityStatus",
+	}
+	for _, name := range required {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("price list is missing column %q", name)
+		}
+	}
+
+	matches := map[string]string{
+		"TermType":          "OnDemand",
+		"Unit":              "Hrs",
+		"Currency":          "USD",
+		"Tenancy":           "Shared",
+		"Operating System":  "Linux",
+		"Pre Installed S/W": "NA",
+		"CapacityStatus":    "Used",
+	}
+
+	prices := make(map[string]VMPricing)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !recordMatches(record, columns, matches) {
+			continue
+		}
+
+		instanceType := record[columns["Instance Type"]]
+		if _, ok := prices[instanceType]; ok {
+			continue
+		}
+
+		price, err := decimal.NewFromString(record[columns["PricePerUnit"]])
+		if err != nil || price.IsZero() {
+			continue
+		}
+
+		memory, err := parseMemory(record[columns["Memory"]])
+		if err != nil {
+			slog.Warn("failed to parse memory", "memory", record[columns["Memory"]], "error", err)
+		}
+
+		vcpu, err := strconv.Atoi(record[columns["vCPU"]])
+		if err != nil {
+			slog.Warn("failed to parse vcpu", "vcpu", record[columns["vCPU"]], "error", err)
+		}
+
+		// GPU isn't a required column, so a price list without it still parses
+		gpu := ""
+		if i, ok := columns["GPU"]; ok && i < len(record) {
+			gpu = record[i]
+		}
+		gpuType, gpuCount := awsAccelerators(instanceType, gpu)
+
+		prices[instanceType] = VMPricing{
+			Provider:     "aws",
+			Region:       region,
+			InstanceType: instanceType,
+			TotalCost:    price,
+			MemoryGB:     memory,
+			VCPUs:        vcpu,
+			GPUType:      gpuType,
+			GPUCount:     gpuCount,
+		}
+	}
+
+	return prices, nil
+}
+
+func recordMatches(record []string, columns map[string]int, matches map[string]string) bool {
+	for name, want := range matches {
+		i := columns[name]
+		if i >= len(record) || record[i] != want {
+			return false
+		}
+	}
+	return true
+}
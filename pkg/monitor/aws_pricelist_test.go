@@ -0,0 +1,84 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// testPriceListCSV is an EC2 bulk price list with its metadata rows, and rows that don't match
+// the live API filters before those that do
+const testPriceListCSV = `"FormatVersion","v1.0"
+"Disclaimer","This pricing list is for informational purposes only."
+"Publication Date","2026-09-01T00:00:00Z"
+"Version","20260901000000"
+"OfferCode","AmazonEC2"
+"SKU","TermType","Unit","PricePerUnit","Currency","Instance Type","vCPU","Memory","Tenancy","Operating System","Pre Installed S/W","CapacityStatus","GPU"
+"A1","Reserved","Hrs","0.0600000000","USD","m5.large","2","8 GiB","Shared","Linux","NA","Used",""
+"A2","OnDemand","Hrs","0.1880000000","USD","m5.large","2","8 GiB","Shared","Windows","NA","Used",""
+"A3","OnDemand","Hrs","0.1010000000","USD","m5.large","2","8 GiB","Dedicated","Linux","NA","Used",""
+"A4","OnDemand","Hrs","0.0960000000","USD","m5.large","2","8 GiB","Shared","Linux","NA","UnusedCapacityReservation",""
+"A5","OnDemand","Hrs","0.1200000000","USD","m5.large","2","8 GiB","Shared","Linux","SQL Std","Used",""
+"A6","OnDemand","Quantity","0.0960000000","USD","m5.large","2","8 GiB","Shared","Linux","NA","Used",""
+"A7","OnDemand","Hrs","0.0960000000","USD","m5.large","2","8 GiB","Shared","Linux","NA","Used",""
+"A8","OnDemand","Hrs","0.0970000000","USD","m5.large","2","8 GiB","Shared","Linux","NA","Used",""
+"B1","OnDemand","Hrs","0.0000000000","USD","c5.xlarge","4","8 GiB","Shared","Linux","NA","Used",""
+"B2","OnDemand","Hrs","0.1700000000","USD","c5.xlarge","4","8 GiB","Shared","Linux","NA","Used",""
+"C1","OnDemand","Hrs","0.0104000000","USD","t3.micro","2","1 GiB","Shared","Linux","NA","Used",""
+"D1","OnDemand","Hrs","1.0060000000","USD","g5.xlarge","4","16 GiB","Shared","Linux","NA","Used","1"
+"E1","OnDemand","Hrs"
+`
+
+func TestParsePriceListCSV(t *testing.T) {
+	prices, err := parsePriceListCSV(strings.NewReader(testPriceListCSV), "us-east-1")
+	if err != nil {
+		t.Fatalf("parsePriceListCSV failed: %v", err)
+	}
+
+	want := map[string]VMPricing{
+		"m5.large":  {TotalCost: decimal.RequireFromString("0.096"), VCPUs: 2, MemoryGB: 8.589934592},
+		"c5.xlarge": {TotalCost: decimal.RequireFromString("0.17"), VCPUs: 4, MemoryGB: 8.589934592},
+		"t3.micro":  {TotalCost: decimal.RequireFromString("0.0104"), VCPUs: 2, MemoryGB: 1.073741824},
+		"g5.xlarge": {TotalCost: decimal.RequireFromString("1.006"), VCPUs: 4, MemoryGB: 17.179869184, GPUType: "nvidia-a10g", GPUCount: 1},
+	}
+	if len(prices) != len(want) {
+		t.Errorf("parsed %d prices, want %d: %v", len(prices), len(want), prices)
+	}
+	for instanceType, w := range want {
+		got, ok := prices[instanceType]
+		if !ok {
+			t.Errorf("%s wasn't parsed", instanceType)
+			continue
+		}
+		if got.Provider != "aws" || got.Region != "us-east-1" || got.InstanceType != instanceType ||
+			!got.TotalCost.Equal(w.TotalCost) || got.VCPUs != w.VCPUs || got.MemoryGB != w.MemoryGB ||
+			got.GPUType != w.GPUType || got.GPUCount != w.GPUCount {
+			t.Errorf("%s = %+v, want %s with %d vCPUs, %v GB, and %d %q GPUs",
+				instanceType, got, w.TotalCost, w.VCPUs, w.MemoryGB, w.GPUCount, w.GPUType)
+		}
+	}
+}
+
+func TestParsePriceListCSVErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		err  string
+	}{
+		{"no header", "\"FormatVersion\",\"v1.0\"\n\"Version\",\"20260901000000\"\n", "failed to find price list header"},
+		{"empty", "", "failed to find price list header"},
+		{
+			"missing column",
+			"\"SKU\",\"TermType\",\"Unit\",\"PricePerUnit\",\"Currency\",\"Instance Type\",\"vCPU\",\"Memory\",\"Tenancy\",\"Operating System\",\"Pre Installed S/W\"\n",
+			`price list is missing column "CapacityStatus"`,
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := parsePriceListCSV(strings.NewReader(tt.csv), "us-east-1")
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: parsePriceListCSV error = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
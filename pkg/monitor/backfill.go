@@ -0,0 +1,147 @@
+package monitor
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/bluesky-social/go-util/pkg/telemetry"
+	"github.com/shopspring/decimal"
+	cli "github.com/urfave/cli/v2"
+)
+
+var BackfillCommand = &cli.Command{
+	Name:  "backfill",
+	Usage: "Load historical AWS on-demand prices from archived price list versions into the history store",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "since",
+			Usage:    "Earliest date to backfill from (YYYY-MM-DD)",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "until",
+			Usage: "Latest date to backfill to (YYYY-MM-DD, defaults to today)",
+		},
+		&cli.DurationFlag{
+			Name:  "step",
+			Usage: "How far apart to sample price list versions; every distinct version found is loaded once",
+			Value: 7 * 24 * time.Hour,
+		},
+	},
+	Action: runBackfill,
+}
+
+func runBackfill(cctx *cli.Context) error {
+	ctx := cctx.Context
+	logger := telemetry.StartLogger(cctx)
+
+	regions := cctx.StringSlice("aws-regions")
+	instanceTypes := cctx.StringSlice("aws-instance-types")
+	if len(regions) == 0 || len(instanceTypes) == 0 {
+		return fmt.Errorf("backfill requires aws-regions and aws-instance-types")
+	}
+
+	historyFile := cctx.String("history-file")
+	if historyFile == "" {
+		return fmt.Errorf("backfill requires history-file")
+	}
+
+	since, err := time.Parse(time.DateOnly, cctx.String("since"))
+	if err != nil {
+		return fmt.Errorf("invalid since date: %w", err)
+	}
+
+	until := time.Now().UTC()
+	if s := cctx.String("until"); s != "" {
+		if until, err = time.Parse(time.DateOnly, s); err != nil {
+			return fmt.Errorf("invalid until date: %w", err)
+		}
+	}
+
+	step := cctx.Duration("step")
+	if step <= 0 {
+		return fmt.Errorf("step must be positive")
+	}
+
+	rounding, err := NewPriceRounding(cctx.Int("price-decimal-places"), cctx.Int("price-significant-digits"), cctx.String("price-rounding-mode"))
+	if err != nil {
+		return err
+	}
+
+	history, err := NewFileHistoryStore(historyFile)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+
+	existing, err := history.Records()
+	if err != nil {
+		return err
+	}
+	stored := make(map[PriceKey][]time.Time)
+	for _, r := range existing {
+		stored[r.Key()] = append(stored[r.Key()], r.Time)
+	}
+
+	fetcher, err := NewAWSPricingFetcher(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, region := range regions {
+		seen := make(map[string]bool)
+		last := make(map[string]decimal.Decimal)
+		written := 0
+
+		for at := since; !at.After(until); at = at.Add(step) {
+			version, err := fetcher.PriceListVersion(ctx, region, at)
+			if err != nil {
+				return err
+			}
+			if seen[version.Version] {
+				continue
+			}
+			seen[version.Version] = true
+
+			logger.Info("loading AWS price list version", "region", region, "version", version.Version)
+			prices, err := fetcher.LoadPriceList(ctx, version.ARN)
+			if err != nil {
+				return err
+			}
+
+			var records []PriceRecord
+			for _, instanceType := range instanceTypes {
+				p, ok := prices[instanceType]
+				if !ok {
+					continue
+				}
+
+				// Only changes are stored, as the live monitor does
+				if cost, ok := last[instanceType]; ok && cost.Equal(p.TotalCost) {
+					continue
+				}
+				last[instanceType] = p.TotalCost
+
+				if slices.ContainsFunc(stored[p.Key()], version.PublishedAt.Equal) {
+					continue
+				}
+				records = append(records, NewPriceRecord(rounding.Pricing(p), version.PublishedAt, "price_list:"+version.Version))
+			}
+
+			if err := history.Append(records...); err != nil {
+				return err
+			}
+			written += len(records)
+		}
+
+		logger.Info("backfilled AWS price history",
+			"region", region,
+			"price_list_versions", len(seen),
+			"records", written,
+		)
+	}
+
+	logger.Info("backfill complete", "history_file", historyFile)
+	return nil
+}
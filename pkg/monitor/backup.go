@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// gcpBackupDRService is the display name of Backup and DR's Cloud Billing service
+const gcpBackupDRService = "Backup and DR Service"
+
+// awsBackupDimension returns the storage type and dimension an AWS Backup usage type, without
+// its region prefix, prices. Usage types name the backed up resource last, such as
+// WarmStorage-ByteHrs-EFS, and backups moved to cold storage are priced as their own type.
+func awsBackupDimension(usageType string) (storageType, dimension, unit string, ok bool) {
+	i := strings.LastIndex(usageType, "-")
+	if i < 0 {
+		return "", "", "", false
+	}
+	usage, resource := usageType[:i], usageType[i+1:]
+
+	cold := strings.Contains(usage, "Cold")
+	switch {
+	case strings.Contains(usage, "Storage-ByteHrs"):
+		dimension, unit = storageCapacity, storageUnitGBMonth
+	case strings.Contains(usage, "Restore"):
+		// Item-level restores are billed per request rather than per GB
+		if strings.Contains(usage, "Item") {
+			return "", "", "", false
+		}
+		dimension, unit = storageRestore, storageUnitGB
+	default:
+		return "", "", "", false
+	}
+
+	storageType = "backup-" + strings.ToLower(resource)
+	if cold {
+		storageType += "-cold"
+	}
+	return storageType, dimension, unit, true
+}
+
+// FetchBackupPricing returns the unit prices of AWS Backup in a region for each resource type
+// it backs up: the capacity of warm and cold backups and the data restored from them, named by
+// storage type such as backup-efs or backup-efs-cold
+func (f *AWSPricingFetcher) FetchBackupPricing(ctx context.Context, region string) ([]ResourcePrice, error) {
+	items, err := f.getProducts(ctx, "AWSBackup", map[string]string{"regionCode": region})
+	if err != nil {
+		return nil, err
+	}
+
+	dimensions := make(map[string][]ResourceDimension)
+	seen := make(map[string]bool)
+	for _, item := range items {
+		usageType := item.Product.Attributes["usagetype"]
+		if prefix, rest, ok := strings.Cut(usageType, "-"); ok && prefix == strings.ToUpper(prefix) {
+			usageType = rest
+		}
+
+		storageType, dimension, unit, ok := awsBackupDimension(usageType)
+		if !ok || seen[storageType+"/"+dimension] {
+			continue
+		}
+
+		price, _, ok := item.firstTier()
+		if !ok {
+			continue
+		}
+		seen[storageType+"/"+dimension] = true
+		dimensions[storageType] = append(dimensions[storageType], ResourceDimension{Dimension: dimension, Unit: unit, Price: price})
+	}
+
+	if len(dimensions) == 0 {
+		return nil, fmt.Errorf("%w for AWS Backup in region %s", errNoPricingFound, region)
+	}
+	return resourcePrices(storageKind.Name, "aws", region, dimensions), nil
+}
+
+// gcpBackupDimension returns the storage type and dimension a Backup and DR SKU prices: the
+// capacity of backup vaults, or the management fee charged per GB of protected data
+func gcpBackupDimension(product string) (string, bool) {
+	switch {
+	case strings.Contains(product, "vault") && strings.Contains(product, "storage"):
+		return "backup-vault/" + storageCapacity, true
+	case strings.Contains(product, "management") || strings.Contains(product, "protected"):
+		return "backup-management/" + storageProtected, true
+	}
+	return "", false
+}
+
+// FetchBackupPricing returns the unit prices of Backup and DR in a region, named by storage
+// type: backup-vault for the capacity of backup vaults, and backup-management for the fee per
+// GB of the data it protects
+func (f *GCPPricingFetcher) FetchBackupPricing(ctx context.Context, region string) ([]ResourcePrice, error) {
+	serviceId, err := f.serviceID(ctx, gcpBackupDRService)
+	if err != nil {
+		return nil, err
+	}
+
+	rates, err := f.findSkuRates(ctx, serviceId, region, gcpBackupDimension)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Backup and DR pricing: %w", err)
+	}
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("%w for Backup and DR in region %s", errNoPricingFound, region)
+	}
+
+	dimensions := make(map[string][]ResourceDimension)
+	for key, rate := range rates {
+		storageType, dimension, _ := strings.Cut(key, "/")
+		dimensions[storageType] = append(dimensions[storageType], ResourceDimension{Dimension: dimension, Unit: storageUnitGBMonth, Price: rate.Monthly()})
+	}
+	return resourcePrices(storageKind.Name, "gcp", region, dimensions), nil
+}
@@ -0,0 +1,64 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/shopspring/decimal"
+)
+
+// Baseline holds point-in-time prices that budgets were planned against
+type Baseline struct {
+	margin float64
+	prices map[PriceKey]decimal.Decimal
+}
+
+// LoadBaseline reads baseline prices from a file of price records in the history file format.
+// When a series appears more than once the last record wins, so a baseline can be cut from
+// the history by keeping the records up to the planning date.
+func LoadBaseline(path string, margin float64) (*Baseline, error) {
+	if margin < 0 {
+		return nil, fmt.Errorf("baseline margin must not be negative")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open baseline file: %w", err)
+	}
+	defer file.Close()
+
+	records, err := readPriceRecords(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load baseline: %w", err)
+	}
+
+	prices := make(map[PriceKey]decimal.Decimal, len(records))
+	for _, r := range records {
+		if r.TotalCost <= 0 {
+			return nil, fmt.Errorf("baseline price for %s %s in %s must be positive", r.Provider, r.InstanceType, r.Region)
+		}
+		prices[r.Key()] = decimal.NewFromFloat(r.TotalCost)
+	}
+
+	return &Baseline{
+		margin: margin,
+		prices: prices,
+	}, nil
+}
+
+// Compare returns the ratio of a live price to its baseline and whether it exceeds the
+// baseline by more than the margin. ok is false when the series has no baseline.
+func (b *Baseline) Compare(p VMPricing) (ratio float64, exceeded bool, ok bool) {
+	baseline, ok := b.prices[p.Key()]
+	if !ok {
+		return 0, false, false
+	}
+
+	ratio = p.TotalCost.Div(baseline).InexactFloat64()
+	return ratio, ratio > 1+b.margin/100, true
+}
+
+// Len returns the number of series with a baseline price
+func (b *Baseline) Len() int {
+	return len(b.prices)
+}
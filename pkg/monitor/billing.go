@@ -0,0 +1,110 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// BillingEntityRule names the entity that bills the regions it matches and the currency it
+// bills in
+type BillingEntityRule struct {
+	// Provider and Region select the regions the rule applies to. An empty field matches
+	// anything, and the region may be a pattern such as eu-*.
+	Provider string `json:"provider,omitempty"`
+	Region   string `json:"region,omitempty"`
+
+	Entity   string `json:"billing_entity"`
+	Currency string `json:"currency"`
+}
+
+// BillingEntities assign each region its billing entity and currency. The first matching rule
+// applies, and regions no rule matches fall back to the defaults.
+type BillingEntities []BillingEntityRule
+
+// defaultBillingEntities are the entities that bill accounts with a US billing address, and the
+// operators of the China regions, which are billed separately in yuan
+var defaultBillingEntities = BillingEntities{
+	{Provider: "aws", Region: "cn-north-1", Entity: "Beijing Sinnet Technology Co., Ltd.", Currency: "CNY"},
+	{Provider: "aws", Region: "cn-northwest-1", Entity: "Ningxia Western Cloud Data Technology Co., Ltd.", Currency: "CNY"},
+	{Provider: "aws", Entity: "Amazon Web Services, Inc.", Currency: "USD"},
+	{Provider: "gcp", Entity: "Google LLC", Currency: "USD"},
+	{Provider: "azure", Region: "china*", Entity: "Shanghai Blue Cloud Technology Co., Ltd.", Currency: "CNY"},
+	{Provider: "azure", Entity: "Microsoft Corporation", Currency: "USD"},
+}
+
+// currencyCode matches an ISO 4217 currency code
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// LoadBillingEntities reads billing entity rules from a JSON file holding a list of rules
+func LoadBillingEntities(file string) (BillingEntities, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read billing entities file: %w", err)
+	}
+
+	var rules BillingEntities
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse billing entities file: %w", err)
+	}
+
+	for i, r := range rules {
+		if _, err := path.Match(r.Region, ""); err != nil {
+			return nil, fmt.Errorf("billing entity rule %d has an invalid region pattern %q: %w", i, r.Region, err)
+		}
+		if r.Entity == "" {
+			return nil, fmt.Errorf("billing entity rule %d must name a billing entity", i)
+		}
+		if !currencyCode.MatchString(r.Currency) {
+			return nil, fmt.Errorf("billing entity rule %d must have a 3 letter ISO 4217 currency code, got %q", i, r.Currency)
+		}
+	}
+
+	return rules, nil
+}
+
+// matches reports whether a rule applies to a region
+func (r BillingEntityRule) matches(provider, region string) bool {
+	if r.Provider != "" && r.Provider != provider {
+		return false
+	}
+	if r.Region == "" {
+		return true
+	}
+	matched, _ := path.Match(r.Region, region)
+	return matched
+}
+
+// Lookup returns the rule of the billing entity and currency of a region
+func (b BillingEntities) Lookup(provider, region string) (BillingEntityRule, bool) {
+	for _, rules := range []BillingEntities{b, defaultBillingEntities} {
+		for _, r := range rules {
+			if r.matches(provider, region) {
+				return r, true
+			}
+		}
+	}
+	return BillingEntityRule{}, false
+}
+
+// recordBillingEntities exports the billing entity and currency of every monitored region, and
+// the billing account GCP is priced with
+func (m *Monitor) recordBillingEntities() {
+	entities := make(map[regionKey]BillingEntityRule)
+	for provider, watched := range m.watching {
+		for _, region := range watched.regions {
+			if rule, ok := m.billingEntities.Lookup(provider, region); ok {
+				entities[regionKey{provider, region}] = rule
+			}
+		}
+	}
+	m.metrics.RecordBillingEntities(entities)
+
+	if m.gcpBillingAccount != "" {
+		m.metrics.BillingAccountInfo.With(prometheus.Labels{"provider": "gcp", "billing_account": m.gcpBillingAccount}).Set(1)
+	}
+}
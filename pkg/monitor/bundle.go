@@ -0,0 +1,320 @@
+package monitor
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/bluesky-social/go-util/pkg/telemetry"
+	"github.com/jazware/cloud-pricing-monitor/pkg/providers"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shopspring/decimal"
+	cli "github.com/urfave/cli/v2"
+)
+
+// bundleFormatVersion is the version of the bundle format written by export-bundle
+const bundleFormatVersion = 1
+
+// PriceBundle is a snapshot of published prices that a monitor can serve without network
+// access. Bundles are stored as gzip-compressed JSON.
+type PriceBundle struct {
+	FormatVersion int       `json:"format_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	// MonitorVersion is the version of the monitor that exported the bundle
+	MonitorVersion string        `json:"monitor_version"`
+	Prices         []PriceRecord `json:"prices"`
+}
+
+// WriteBundle writes a bundle to a file, replacing it only once the bundle is complete
+func WriteBundle(path string, bundle *PriceBundle) error {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer os.Remove(tmp)
+
+	zw := gzip.NewWriter(file)
+	if err := json.NewEncoder(zw).Encode(bundle); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// LoadBundle reads a bundle written by WriteBundle
+func LoadBundle(path string) (*PriceBundle, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer file.Close()
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	defer zr.Close()
+
+	var bundle PriceBundle
+	if err := json.NewDecoder(zr).Decode(&bundle); err != nil {
+		return nil, fmt.Errorf("failed to decode bundle: %w", err)
+	}
+
+	if bundle.FormatVersion != bundleFormatVersion {
+		return nil, fmt.Errorf("unsupported bundle format version %d (expected %d)", bundle.FormatVersion, bundleFormatVersion)
+	}
+
+	return &bundle, nil
+}
+
+// publishBundle publishes every price of an offline bundle as of when it was fetched
+func (m *Monitor) publishBundle() {
+	for _, record := range m.bundle.Prices {
+		p := m.publishRecord(record, m.overrides)
+		if m.mqtt != nil {
+			m.mqtt.Publish(NewPriceRecord(m.rounding.Pricing(p), record.Time, record.Source))
+		}
+	}
+	m.recordAggregates()
+	m.recordBillingEntities()
+
+	slog.Info("serving prices from offline bundle",
+		"created_at", m.bundle.CreatedAt,
+		"series", len(m.bundle.Prices),
+	)
+}
+
+// publishRecord publishes the price of a record as of when it was fetched, by another monitor
+// or an earlier run of this one, with the overrides given applied. Prices a peer published have
+// been through the peer's overrides already, so they are published as they are.
+func (m *Monitor) publishRecord(record PriceRecord, overrides PriceOverrides) VMPricing {
+	p := overrides.Apply(VMPricing{
+		Provider:     record.Provider,
+		Region:       record.Region,
+		InstanceType: record.InstanceType,
+		TotalCost:    decimal.NewFromFloat(record.TotalCost),
+		MemoryGB:     record.MemoryGB,
+		VCPUs:        record.VCPUs,
+		Confidential: record.Confidential,
+		GPUType:      record.GPUType,
+		GPUCount:     record.GPUCount,
+	})
+
+	m.metrics.RecordPricing(p)
+	m.metrics.RecordDerived(p)
+	m.snapshot.Set(p, record.Time)
+
+	if m.baseline != nil {
+		if ratio, exceeded, ok := m.baseline.Compare(p); ok {
+			m.metrics.RecordBaselineComparison(p, ratio, exceeded)
+		}
+	}
+
+	if factor, cost, ok := m.slaAssumptions.EffectiveCost(p); ok {
+		m.metrics.RecordEffectiveCost(p, factor, cost)
+	}
+	if cost, ok := m.normalization.Cost(p); ok {
+		m.metrics.RecordNormalizedCost(p, cost)
+	}
+	m.metrics.RecordScenarioCosts(p, m.scenarios, time.Now())
+	m.recordAccountCosts(p)
+
+	// The last update is when the record's price was fetched, so dashboards show its age
+	m.metrics.LastUpdateTime.With(prometheus.Labels{
+		"provider": p.Provider,
+		"region":   p.Region,
+	}).Set(float64(record.Time.Unix()))
+	return p
+}
+
+// recordAggregates records the blended prices and the fleet comparison of the published prices
+func (m *Monitor) recordAggregates() {
+	if m.weights != nil {
+		m.recordBlendedPrices()
+	}
+	if m.comparison != nil {
+		m.metrics.RecordComparison(m.comparison.Costs(m.snapshot))
+	}
+}
+
+// errOffline is returned for every HTTP request made in offline mode
+var errOffline = errors.New("network access is disabled in offline mode")
+
+// offlineTransport refuses every request so nothing reaches the network by accident
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("%w: %s %s", errOffline, req.Method, req.URL.Host)
+}
+
+// offlineIncompatibleFlags are the flags of features that call provider or third-party APIs
+var offlineIncompatibleFlags = []string{
+	"aws-price-list-version",
+	"aws-price-list-date",
+	"aws-bulk-pricing",
+	"price-list-check-interval",
+	"track-new-generations",
+	"export-size-steps",
+	"track-availability",
+	"export-quota-ceilings",
+	"kubernetes-discovery",
+	"nomad-discovery",
+	"ecs-discovery-regions",
+	"fleet-config-file",
+	"software-config-file",
+	"gcp-template-config-file",
+	"gcp-gpu-types",
+	"aws-volume-types",
+	"gcp-disk-types",
+	"export-file-storage",
+	"export-backup-pricing",
+	"export-platform-services",
+	"aws-spot-pricing",
+	"aws-commitment-pricing",
+	"aws-capacity-block-pricing",
+	"gcp-spot-pricing",
+	"gcp-billing-account",
+	"regression-threshold",
+	"alert-rules-file",
+	"consensus-threshold",
+	"canary-threshold",
+	"cache-url",
+	"sync-from",
+	"match-debug",
+	"stale-after-polls",
+	"hook-script",
+}
+
+// checkOfflineFlags rejects features that can't work without network access
+func checkOfflineFlags(cctx *cli.Context) error {
+	for _, name := range offlineIncompatibleFlags {
+		if cctx.IsSet(name) {
+			return fmt.Errorf("%s can't be used with offline-bundle", name)
+		}
+	}
+	return nil
+}
+
+var ExportBundleCommand = &cli.Command{
+	Name:  "export-bundle",
+	Usage: "Fetch the configured prices once and save them as a bundle for offline use",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "output",
+			Usage:    "Path to write the bundle to (e.g., prices.json.gz)",
+			Required: true,
+		},
+	},
+	Action: runExportBundle,
+}
+
+func runExportBundle(cctx *cli.Context) error {
+	ctx, cancel := context.WithCancel(cctx.Context)
+	defer cancel()
+	logger := telemetry.StartLogger(cctx)
+
+	list := flagWatchList(cctx)
+	priced := false
+	for _, watched := range list {
+		priced = priced || (len(watched.regions) > 0 && len(watched.instanceTypes) > 0)
+	}
+	if !priced {
+		return fmt.Errorf("export-bundle requires the regions and instance types of at least one provider")
+	}
+
+	priceListPin, err := ParsePriceListPin(cctx.String("aws-price-list-version"), cctx.String("aws-price-list-date"))
+	if err != nil {
+		return err
+	}
+	if priceListPin != nil && cctx.Bool("aws-bulk-pricing") {
+		return fmt.Errorf("aws-bulk-pricing can't be combined with a pinned price list")
+	}
+
+	rounding, err := NewPriceRounding(cctx.Int("price-decimal-places"), cctx.Int("price-significant-digits"), cctx.String("price-rounding-mode"))
+	if err != nil {
+		return err
+	}
+
+	egress, err := egressFlags(cctx)
+	if err != nil {
+		return err
+	}
+	ctx = withEgress(ctx, egress)
+
+	registry, err := newProviderRegistry(priceListPin, cctx.Bool("aws-bulk-pricing"), cctx.String("gcp-billing-account"))
+	if err != nil {
+		return err
+	}
+
+	// Nothing serves the metrics of an export, so they aren't registered
+	metrics, err := NewMetrics(nil, memoryUnitGB)
+	if err != nil {
+		return err
+	}
+
+	snapshot := NewPriceSnapshot()
+	monitor := &Monitor{
+		watching:        list.watching(),
+		confidential:    flagConfidential(cctx),
+		metrics:         metrics,
+		snapshot:        snapshot,
+		awsPriceListPin: priceListPin,
+		rounding:        rounding,
+		registry:        registry,
+		providers:       make(map[string]providers.PricingProvider),
+		gcpProject:      cctx.String("gcp-project"),
+	}
+	if err := monitor.takeAllRegions(); err != nil {
+		return err
+	}
+	if err := monitor.takeInstanceTypeGlobs(); err != nil {
+		return err
+	}
+
+	if err := monitor.initFetchers(ctx); err != nil {
+		return err
+	}
+	if err := monitor.fetchAllPricing(ctx); err != nil {
+		return err
+	}
+
+	entries := snapshot.Entries()
+	if len(entries) == 0 {
+		return fmt.Errorf("no prices were fetched")
+	}
+	if expected := len(monitor.watchedPairs()); len(entries) < expected {
+		logger.Warn("some prices could not be fetched and are missing from the bundle", "fetched", len(entries), "expected", expected)
+	}
+
+	bundle := &PriceBundle{
+		FormatVersion:  bundleFormatVersion,
+		CreatedAt:      time.Now().UTC(),
+		MonitorVersion: cctx.App.Version,
+	}
+	for _, entry := range entries {
+		bundle.Prices = append(bundle.Prices, NewPriceRecord(rounding.Pricing(entry.Pricing), entry.UpdatedAt, "live"))
+	}
+
+	output := cctx.String("output")
+	if err := WriteBundle(output, bundle); err != nil {
+		return err
+	}
+
+	logger.Info("exported price bundle", "output", output, "series", len(bundle.Prices))
+	return nil
+}
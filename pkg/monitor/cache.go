@@ -0,0 +1,117 @@
+package monitor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// CacheBackend is a key-value store the replicas of the monitor share
+type CacheBackend interface {
+	// Get returns the value of a key, and whether the key exists
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value of a key, which expires after ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Close() error
+}
+
+// sharedCache keeps what replicas fetch from upstream APIs, SKU catalogs and prices, in a
+// backend they share, so only the first replica to need a value fetches it until it expires.
+// Values are stored as gzipped JSON. The cache is best-effort: a failing backend is logged and
+// counted, and the value is fetched from upstream as if the cache were empty. A nil cache
+// caches nothing.
+type sharedCache struct {
+	backend CacheBackend
+	prefix  string
+	ttl     time.Duration
+	metrics *Metrics
+}
+
+// load decodes the value of a key of a kind, such as skus or price, into v and tells whether it
+// was found
+func (c *sharedCache) load(ctx context.Context, kind, key string, v any) bool {
+	if c == nil {
+		return false
+	}
+
+	data, found, err := c.backend.Get(ctx, c.key(kind, key))
+	if err == nil && found {
+		err = decodeCacheValue(data, v)
+	}
+	switch {
+	case err != nil:
+		slog.Warn("failed to read shared cache", "kind", kind, "key", key, "error", err)
+		c.metrics.RecordCacheRequest(kind, "error")
+		return false
+	case !found:
+		c.metrics.RecordCacheRequest(kind, "miss")
+		return false
+	default:
+		c.metrics.RecordCacheRequest(kind, "hit")
+		return true
+	}
+}
+
+// store encodes v as the value of a key of a kind
+func (c *sharedCache) store(ctx context.Context, kind, key string, v any) {
+	if c == nil {
+		return
+	}
+
+	data, err := encodeCacheValue(v)
+	if err == nil {
+		err = c.backend.Set(ctx, c.key(kind, key), data, c.ttl)
+	}
+	if err != nil {
+		slog.Warn("failed to write shared cache", "kind", kind, "key", key, "error", err)
+	}
+}
+
+func (c *sharedCache) key(kind, key string) string {
+	return c.prefix + kind + ":" + key
+}
+
+func encodeCacheValue(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to encode cache value: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress cache value: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeCacheValue(data []byte, v any) error {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to decompress cache value: %w", err)
+	}
+	data, err = io.ReadAll(zr)
+	if err != nil {
+		return fmt.Errorf("failed to decompress cache value: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode cache value: %w", err)
+	}
+	return nil
+}
+
+type sharedCacheKey struct{}
+
+// withSharedCache returns a context that fetchers created with cache what they list in the
+// shared cache
+func withSharedCache(ctx context.Context, cache *sharedCache) context.Context {
+	return context.WithValue(ctx, sharedCacheKey{}, cache)
+}
+
+func sharedCacheFrom(ctx context.Context) *sharedCache {
+	cache, _ := ctx.Value(sharedCacheKey{}).(*sharedCache)
+	return cache
+}
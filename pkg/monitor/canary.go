@@ -0,0 +1,197 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CanaryCheck holds back a poll's prices of a provider when too many of its published series
+// changed or failed to resolve at once. Real price changes trickle in a few series at a time,
+// so a sweeping change is more likely a change to the format of the provider's catalog that
+// the monitor misparses, and the served prices are kept until a poll looks normal again.
+type CanaryCheck struct {
+	threshold float64
+
+	mu      sync.Mutex
+	staging bool
+	staged  map[string][]stagedFetch
+	// held are the providers whose prices the check is holding back
+	held map[string]bool
+}
+
+// stagedFetch is the outcome of a fetch waiting on the canary check
+type stagedFetch struct {
+	fetch   vmFetch
+	pricing *VMPricing
+	err     error
+}
+
+// NewCanaryCheck creates a check that holds back a provider's prices when more than threshold
+// percent of its published series change or fail to resolve in a poll
+func NewCanaryCheck(threshold float64) (*CanaryCheck, error) {
+	if threshold <= 0 || threshold > 100 {
+		return nil, fmt.Errorf("canary-threshold must be above 0 and at most 100")
+	}
+	return &CanaryCheck{threshold: threshold, held: make(map[string]bool)}, nil
+}
+
+// start stages the fetches that follow instead of publishing them. A nil check stages nothing.
+func (c *CanaryCheck) start() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.staging = true
+	c.staged = make(map[string][]stagedFetch)
+}
+
+// stage keeps the outcome of a fetch for the check, and reports whether it was kept
+func (c *CanaryCheck) stage(f vmFetch, pricing *VMPricing, err error) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.staging {
+		return false
+	}
+	c.staged[f.provider] = append(c.staged[f.provider], stagedFetch{f, pricing, err})
+	return true
+}
+
+// finish stops staging and returns the staged fetches by provider
+func (c *CanaryCheck) finish() map[string][]stagedFetch {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	staged := c.staged
+	c.staging = false
+	c.staged = nil
+	return staged
+}
+
+// take returns the fetches of a provider staged so far, leaving the check staging those that
+// follow
+func (c *CanaryCheck) take(provider string) []stagedFetch {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	staged := c.staged[provider]
+	delete(c.staged, provider)
+	return staged
+}
+
+// canaryChanges counts the staged series of a provider that have a published price, and how
+// many of them resolved to another price or didn't resolve at all
+func (m *Monitor) canaryChanges(staged []stagedFetch) (changed, compared int) {
+	for _, s := range staged {
+		entry, ok := m.snapshot.Get(PriceKey{Provider: s.fetch.provider, Region: s.fetch.region, InstanceType: s.fetch.instanceType})
+		if !ok {
+			continue
+		}
+		compared++
+		if s.err != nil || !m.overrides.Apply(*s.pricing).TotalCost.Equal(entry.Pricing.TotalCost) {
+			changed++
+		}
+	}
+	return changed, compared
+}
+
+// releaseCanary checks the fetches staged since the canary check started, publishing those of
+// every provider that passes it, and stops staging
+func (m *Monitor) releaseCanary(ctx context.Context) {
+	if m.canary == nil {
+		return
+	}
+
+	staged := m.canary.finish()
+	for _, provider := range slices.Sorted(maps.Keys(staged)) {
+		m.checkCanary(ctx, provider, staged[provider])
+	}
+}
+
+// releaseCanaryOf checks the fetches of a provider staged so far, so they are published before
+// the rest of the poll, which is checked on its own when it is released
+func (m *Monitor) releaseCanaryOf(ctx context.Context, provider string) {
+	if m.canary == nil {
+		return
+	}
+	if staged := m.canary.take(provider); len(staged) > 0 {
+		m.checkCanary(ctx, provider, staged)
+	}
+}
+
+// checkCanary publishes the staged fetches of a provider if they pass the canary check, and
+// holds them back otherwise
+func (m *Monitor) checkCanary(ctx context.Context, provider string, staged []stagedFetch) {
+	changed, compared := m.canaryChanges(staged)
+	percent := 0.0
+	if compared > 0 {
+		percent = float64(changed) / float64(compared) * 100
+	}
+	m.metrics.CanaryChanged.With(prometheus.Labels{"provider": provider}).Set(percent)
+
+	if percent > m.canary.threshold {
+		m.metrics.CanaryHeld.With(prometheus.Labels{"provider": provider}).Set(1)
+		m.holdCanary(ctx, provider, staged, changed, compared)
+		return
+	}
+
+	m.metrics.CanaryHeld.With(prometheus.Labels{"provider": provider}).Set(0)
+	if m.canary.held[provider] {
+		slog.Info("canary check passed, publishing prices again", "provider", provider, "changed", changed, "series", compared)
+		delete(m.canary.held, provider)
+	}
+	for _, s := range staged {
+		m.publishVMFetch(ctx, s.fetch, s.pricing, s.err)
+	}
+}
+
+// holdCanary keeps serving the published prices of a provider that failed the canary check,
+// and opens an issue the first time it fails in a row. Failed fetches are still reported as
+// failures.
+func (m *Monitor) holdCanary(ctx context.Context, provider string, staged []stagedFetch, changed, compared int) {
+	slog.Error("canary check failed, holding back prices",
+		"provider", provider,
+		"changed", changed,
+		"series", compared,
+		"threshold_percent", m.canary.threshold,
+	)
+	for _, s := range staged {
+		if s.err != nil {
+			m.publishVMFetch(ctx, s.fetch, nil, s.err)
+			continue
+		}
+		m.statuses.Hold(s.pricing.Key())
+	}
+
+	if m.canary.held[provider] {
+		return
+	}
+	m.canary.held[provider] = true
+
+	title := fmt.Sprintf("Canary check held back %s prices", provider)
+	body := fmt.Sprintf("%d of the %d published %s series changed or failed to resolve in a single poll, more than the canary threshold of %g%%. "+
+		"The previous prices are still served. Check the provider's catalog for a format change before trusting the new prices.",
+		changed, compared, provider, m.canary.threshold)
+	for _, tracker := range m.issueTrackers {
+		result := "opened"
+		err := tracker.CreateIssue(ctx, title, body)
+		if err != nil {
+			result = "failed"
+		}
+		m.metrics.CanaryIssues.With(prometheus.Labels{
+			"provider": provider,
+			"tracker":  tracker.Name(),
+			"result":   result,
+		}).Inc()
+		if err != nil {
+			slog.Error("failed to open canary issue", "tracker", tracker.Name(), "provider", provider, "error", err)
+		}
+	}
+}
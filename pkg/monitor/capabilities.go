@@ -0,0 +1,154 @@
+package monitor
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/client"
+	cli "github.com/urfave/cli/v2"
+)
+
+// providerCapabilities are the capabilities a provider is described by. Reservations are only
+// priced for AWS, as Reserved Instance, Savings Plan, and Capacity Block rates.
+var providerCapabilities = []string{"spot", "reservations", "storage", "discovery", "confidential", "gpus"}
+
+func clusterDiscovery(cctx *cli.Context) bool {
+	return cctx.Bool("kubernetes-discovery") || cctx.Bool("nomad-discovery")
+}
+
+// capabilityFlags tells whether the flags enable each capability a provider has. Capabilities
+// missing from a provider's map are unsupported.
+var capabilityFlags = map[string]map[string]func(cctx *cli.Context) bool{
+	"aws": {
+		"spot": func(cctx *cli.Context) bool { return cctx.Bool("aws-spot-pricing") },
+		"reservations": func(cctx *cli.Context) bool {
+			return cctx.Bool("aws-commitment-pricing") || cctx.Bool("aws-capacity-block-pricing")
+		},
+		"storage": func(cctx *cli.Context) bool {
+			return len(cctx.StringSlice("aws-volume-types")) > 0 || cctx.Bool("export-file-storage") || cctx.Bool("export-backup-pricing")
+		},
+		"discovery": func(cctx *cli.Context) bool {
+			return clusterDiscovery(cctx) || len(cctx.StringSlice("ecs-discovery-regions")) > 0
+		},
+		"confidential": func(cctx *cli.Context) bool { return cctx.Bool("aws-confidential") },
+	},
+	"gcp": {
+		"spot": func(cctx *cli.Context) bool { return cctx.Bool("gcp-spot-pricing") },
+		"storage": func(cctx *cli.Context) bool {
+			return len(cctx.StringSlice("gcp-disk-types")) > 0 || cctx.Bool("export-file-storage") || cctx.Bool("export-backup-pricing")
+		},
+		"discovery":    clusterDiscovery,
+		"confidential": func(cctx *cli.Context) bool { return cctx.Bool("gcp-confidential") },
+		"gpus":         func(cctx *cli.Context) bool { return len(cctx.StringSlice("gcp-gpu-types")) > 0 },
+	},
+	"azure": {
+		"discovery":    clusterDiscovery,
+		"confidential": func(cctx *cli.Context) bool { return cctx.Bool("azure-confidential") },
+	},
+}
+
+// describeProviders describes what the monitor prices with each provider, as configured by the
+// flags and the regions assigned to it. Fleets and templates configure a provider even without
+// regions, since they add their own.
+func describeProviders(cctx *cli.Context, providers []string, regions map[string][]string) []client.ProviderStatus {
+	described := make([]client.ProviderStatus, 0, len(providers))
+	for _, name := range providers {
+		flags := capabilityFlags[name]
+		configured := len(regions[name]) > 0 ||
+			(flags["discovery"] != nil && flags["discovery"](cctx)) ||
+			(name == "aws" && cctx.String("fleet-config-file") != "") ||
+			(name == "gcp" && cctx.String("gcp-template-config-file") != "")
+
+		capabilities := make(map[string]string, len(providerCapabilities))
+		for _, capability := range providerCapabilities {
+			enabled, ok := flags[capability]
+			switch {
+			case !ok:
+				capabilities[capability] = client.CapabilityUnsupported
+			case configured && enabled(cctx):
+				capabilities[capability] = client.CapabilityEnabled
+			default:
+				capabilities[capability] = client.CapabilityDisabled
+			}
+		}
+
+		described = append(described, client.ProviderStatus{
+			Name:         name,
+			Configured:   configured,
+			Capabilities: capabilities,
+		})
+	}
+	return described
+}
+
+// providerHealth summarizes the fetch statuses of a provider's series. A provider whose
+// fetcher isn't initialized yet, or that hasn't fetched anything, is pending.
+func providerHealth(statuses map[PriceKey]fetchStatus, name string, initialized bool) client.ProviderHealth {
+	var health client.ProviderHealth
+	var lastFailure time.Time
+	for key, status := range statuses {
+		if key.Provider != name {
+			continue
+		}
+
+		health.Series++
+		switch status.state {
+		case client.SeriesError:
+			health.Failing++
+			if health.FailingReasons == nil {
+				health.FailingReasons = make(map[string]int)
+			}
+			health.FailingReasons[errorReason(status.err)]++
+			if status.attemptedAt.After(lastFailure) {
+				lastFailure = status.attemptedAt
+				health.LastError = status.err.Error()
+			}
+		case client.SeriesHeld:
+			health.Held++
+		default:
+			if health.LastSuccessAt == nil || status.attemptedAt.After(*health.LastSuccessAt) {
+				attemptedAt := status.attemptedAt
+				health.LastSuccessAt = &attemptedAt
+			}
+		}
+	}
+
+	switch {
+	case !initialized || health.Series == 0:
+		health.Status = client.ProviderPending
+	case health.Failing == health.Series:
+		health.Status = client.ProviderFailing
+	case health.Failing > 0:
+		health.Status = client.ProviderDegraded
+	default:
+		health.Status = client.ProviderHealthy
+	}
+	return health
+}
+
+// providersHandler serves the description of every provider with its current health. A
+// provider reloaded into the watch list counts as configured once it has been fetched.
+func providersHandler(described []client.ProviderStatus, statuses *FetchStatuses, initialized func(name string) bool, offline bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := statuses.all()
+
+		providers := make([]client.ProviderStatus, 0, len(described))
+		for _, p := range described {
+			p.Health = providerHealth(current, p.Name, initialized(p.Name))
+			p.Configured = p.Configured || p.Health.Series > 0
+			switch {
+			case !p.Configured:
+				p.Health.Status = client.ProviderUnconfigured
+			case offline:
+				p.Health.Status = client.ProviderOffline
+			}
+			providers = append(providers, p)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{"providers": providers}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/shopspring/decimal"
+)
+
+// defaultCapacityBlockDuration is the length of the Capacity Blocks priced unless
+// --aws-capacity-block-duration says otherwise, the shortest AWS sells
+const defaultCapacityBlockDuration = 24 * time.Hour
+
+// checkCapacityBlockDuration rejects durations Capacity Blocks aren't sold in. AWS sells them
+// by the day up to 14 days, then by the week up to 182 days.
+func checkCapacityBlockDuration(d time.Duration) error {
+	days := d / (24 * time.Hour)
+	switch {
+	case d <= 0 || d%(24*time.Hour) != 0:
+		return fmt.Errorf("aws-capacity-block-duration must be a whole number of days")
+	case days > 182 || (days > 14 && days%7 != 0):
+		return fmt.Errorf("aws-capacity-block-duration must be 1 to 14 days, or a whole number of weeks up to 182 days")
+	}
+	return nil
+}
+
+// CapacityBlockPrices returns the lowest effective hourly price per instance of a Capacity
+// Block for ML of an instance type, in each availability zone of a region that offers one. A
+// block is paid for up front, so its fee is spread over the hours it reserves and the
+// instances in it. The Price List doesn't carry Capacity Block prices, which AWS sets by
+// supply and demand, so they come from the offerings EC2 would sell right now.
+func (f *AWSPricingFetcher) CapacityBlockPrices(ctx context.Context, region, instanceType string, duration time.Duration) (map[string]decimal.Decimal, error) {
+	client := ec2.NewFromConfig(f.cfg, func(o *ec2.Options) {
+		o.Region = region
+	})
+
+	paginator := ec2.NewDescribeCapacityBlockOfferingsPaginator(client, &ec2.DescribeCapacityBlockOfferingsInput{
+		InstanceType:          aws.String(instanceType),
+		InstanceCount:         aws.Int32(1),
+		CapacityDurationHours: aws.Int32(int32(duration.Hours())),
+	})
+
+	prices := make(map[string]decimal.Decimal)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe AWS Capacity Block offerings: %w", err)
+		}
+
+		for _, offering := range page.CapacityBlockOfferings {
+			if aws.ToString(offering.CurrencyCode) != "USD" {
+				continue
+			}
+			fee, err := decimal.NewFromString(aws.ToString(offering.UpfrontFee))
+			if err != nil {
+				continue
+			}
+
+			// A block may end a few minutes short of its last hour
+			minutes := int64(aws.ToInt32(offering.CapacityBlockDurationHours))*60 + int64(aws.ToInt32(offering.CapacityBlockDurationMinutes))
+			instances := int64(aws.ToInt32(offering.InstanceCount))
+			if minutes <= 0 || instances <= 0 {
+				continue
+			}
+			hours := decimal.NewFromInt(minutes).Div(decimal.NewFromInt(60))
+			price := fee.Div(hours).Div(decimal.NewFromInt(instances))
+
+			zone := aws.ToString(offering.AvailabilityZone)
+			if lowest, ok := prices[zone]; !ok || price.LessThan(lowest) {
+				prices[zone] = price
+			}
+		}
+	}
+
+	if len(prices) == 0 {
+		return nil, fmt.Errorf("%w for Capacity Blocks of instance type %s in region %s", errNoPricingFound, instanceType, region)
+	}
+	return prices, nil
+}
+
+// recordCapacityBlockPrices exports the Capacity Block prices of every monitored AWS instance
+// type. Only some accelerated instance types are sold as Capacity Blocks, and offerings sell
+// out, so a type without any has its series dropped without an error.
+func (m *Monitor) recordCapacityBlockPrices(ctx context.Context) {
+	for _, region := range m.watch("aws").regions {
+		for _, instanceType := range m.watch("aws").instanceTypes {
+			prices, err := m.awsFetcher.CapacityBlockPrices(ctx, region, instanceType, m.awsCapacityBlockDuration)
+			if errors.Is(err, errNoPricingFound) {
+				slog.Debug("no AWS Capacity Block offerings", "region", region, "instance_type", instanceType)
+			} else if err != nil {
+				slog.Error("failed to fetch AWS Capacity Block pricing",
+					"region", region,
+					"instance_type", instanceType,
+					"reason", errorReason(err),
+					"error", err,
+				)
+				continue
+			}
+			m.metrics.RecordCapacityBlockPrices("aws", region, instanceType, prices)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// ClusterNode is a cloud instance discovered from a cluster scheduler
+type ClusterNode struct {
+	Name         string
+	Provider     string
+	Region       string
+	InstanceType string
+
+	// VCPUs and MemoryGB are the resources the scheduler can place work on
+	VCPUs    float64
+	MemoryGB float64
+}
+
+// ClusterAllocation is the resources a workload has requested on a node
+type ClusterAllocation struct {
+	Node         string
+	Namespace    string
+	WorkloadKind string
+	Workload     string
+	VCPUs        float64
+	MemoryGB     float64
+}
+
+// ClusterState is a point-in-time view of a cluster's nodes and the work placed on them
+type ClusterState struct {
+	Nodes       []ClusterNode
+	Allocations []ClusterAllocation
+}
+
+// ClusterDiscoverer lists the nodes and workloads of a cluster scheduler
+type ClusterDiscoverer interface {
+	// Scheduler names the scheduler in metric labels
+	Scheduler() string
+	Discover(ctx context.Context) (*ClusterState, error)
+}
+
+// WorkloadKey identifies a workload within a scheduler namespace
+type WorkloadKey struct {
+	Namespace string
+	Kind      string
+	Name      string
+}
+
+// ClusterCosts is the hourly cost of a cluster broken down by node, namespace, and workload
+type ClusterCosts struct {
+	Nodes      map[string]decimal.Decimal
+	Namespaces map[string]decimal.Decimal
+	Workloads  map[WorkloadKey]decimal.Decimal
+}
+
+// AttributeCosts splits the price of each node across the workloads placed on it. A workload
+// is charged the average of its share of the node's vCPUs and of its memory, so a node that
+// is fully requested is fully attributed. Nodes without a published price are skipped.
+func AttributeCosts(state *ClusterState, snapshot *PriceSnapshot) *ClusterCosts {
+	costs := &ClusterCosts{
+		Nodes:      make(map[string]decimal.Decimal),
+		Namespaces: make(map[string]decimal.Decimal),
+		Workloads:  make(map[WorkloadKey]decimal.Decimal),
+	}
+
+	nodes := make(map[string]ClusterNode, len(state.Nodes))
+	for _, node := range state.Nodes {
+		entry, ok := snapshot.Get(PriceKey{
+			Provider:     node.Provider,
+			Region:       node.Region,
+			InstanceType: node.InstanceType,
+		})
+		if !ok {
+			continue
+		}
+
+		nodes[node.Name] = node
+		costs.Nodes[node.Name] = entry.Pricing.TotalCost
+	}
+
+	for _, alloc := range state.Allocations {
+		node, ok := nodes[alloc.Node]
+		if !ok || node.VCPUs == 0 || node.MemoryGB == 0 {
+			continue
+		}
+
+		share := (alloc.VCPUs/node.VCPUs + alloc.MemoryGB/node.MemoryGB) / 2
+		cost := costs.Nodes[alloc.Node].Mul(decimal.NewFromFloat(share))
+
+		costs.Namespaces[alloc.Namespace] = costs.Namespaces[alloc.Namespace].Add(cost)
+		workload := WorkloadKey{
+			Namespace: alloc.Namespace,
+			Kind:      alloc.WorkloadKind,
+			Name:      alloc.Workload,
+		}
+		costs.Workloads[workload] = costs.Workloads[workload].Add(cost)
+	}
+
+	return costs
+}
+
+// quantitySuffixes are the multipliers of Kubernetes resource quantity suffixes
+var quantitySuffixes = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"Ki", 1 << 10},
+	{"Mi", 1 << 20},
+	{"Gi", 1 << 30},
+	{"Ti", 1 << 40},
+	{"Pi", 1 << 50},
+	{"Ei", 1 << 60},
+	{"n", 1e-9},
+	{"u", 1e-6},
+	{"m", 1e-3},
+	{"k", 1e3},
+	{"M", 1e6},
+	{"G", 1e9},
+	{"T", 1e12},
+	{"P", 1e15},
+	{"E", 1e18},
+}
+
+// parseQuantity converts a Kubernetes resource quantity like "500m" or "1Gi" to a number
+func parseQuantity(q string) (float64, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return 0, nil
+	}
+
+	multiplier := 1.0
+	for _, s := range quantitySuffixes {
+		if strings.HasSuffix(q, s.suffix) {
+			q = strings.TrimSuffix(q, s.suffix)
+			multiplier = s.multiplier
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(q, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q: %w", q, err)
+	}
+	return value * multiplier, nil
+}
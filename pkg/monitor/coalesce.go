@@ -0,0 +1,59 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// errProviderNotMonitored is returned when a price is requested from a provider that isn't configured
+var errProviderNotMonitored = errors.New("provider is not monitored")
+
+// fetchPricing fetches the current price of a series from its provider. Concurrent fetches of
+// the same series, from the poller, size steps, or API requests, share a single upstream call
+// so that more consumers don't mean more calls against the provider's API quota. With a shared
+// cache, a price another replica fetched recently is taken from the cache instead.
+func (m *Monitor) fetchPricing(ctx context.Context, provider, region, instanceType string) (*VMPricing, error) {
+	p := m.provider(provider)
+	if p == nil {
+		return nil, fmt.Errorf("%w: %s", errProviderNotMonitored, provider)
+	}
+
+	// The shared call outlives any one caller giving up, so it mustn't fail the others
+	key := provider + "/" + region + "/" + instanceType
+	cacheKey := key
+	if provider == "gcp" && m.gcpBillingAccount != "" {
+		cacheKey = m.gcpBillingAccount + "/" + key
+	}
+	results := m.fetches.DoChan(key, func() (any, error) {
+		ctx := context.WithoutCancel(ctx)
+		var cached VMPricing
+		if m.cache.load(ctx, "price", cacheKey, &cached) {
+			return &cached, nil
+		}
+		pricing, err := m.fetchUpstream(ctx, p, region, instanceType)
+		if err != nil {
+			return nil, err
+		}
+		m.cache.store(ctx, "price", cacheKey, pricing)
+		return pricing, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-results:
+		if result.Shared {
+			m.metrics.CoalescedFetches.With(prometheus.Labels{"provider": provider}).Inc()
+		}
+		if result.Err != nil {
+			return nil, result.Err
+		}
+
+		// Every caller gets its own copy of the shared result
+		pricing := *result.Val.(*VMPricing)
+		return &pricing, nil
+	}
+}
@@ -0,0 +1,336 @@
+package monitor
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// hoursPerYear is how many hours the upfront payment of a Reserved Instance is spread over per
+// year of its term
+const hoursPerYear = 8760
+
+// awsSavingsPlanOffersURL serves the bulk offer files of Savings Plans, whose rates the Price
+// List Query API doesn't expose
+const awsSavingsPlanOffersURL = "https://pricing.us-east-1.amazonaws.com"
+
+// awsComputeSavingsPlanIndex lists the current Compute Savings Plan offer file of each region
+const awsComputeSavingsPlanIndex = "/savingsPlan/v1.0/aws/AWSComputeSavingsPlan/current/region_index.json"
+
+// CommitmentRate is the effective hourly cost in USD of an instance type bought with a
+// commitment, upfront payments included
+type CommitmentRate struct {
+	// Term is the length of the commitment, 1yr or 3yr
+	Term string
+
+	// PaymentOption is no_upfront, partial_upfront, or all_upfront
+	PaymentOption string
+
+	Cost decimal.Decimal
+}
+
+// paymentOptionLabel turns a purchase option of the price list, such as Partial Upfront, into
+// the value of the payment_option label
+func paymentOptionLabel(option string) string {
+	return strings.ReplaceAll(strings.ToLower(option), " ", "_")
+}
+
+func sortCommitmentRates(rates []CommitmentRate) {
+	slices.SortFunc(rates, func(a, b CommitmentRate) int {
+		return cmp.Or(cmp.Compare(a.Term, b.Term), cmp.Compare(a.PaymentOption, b.PaymentOption))
+	})
+}
+
+// reservedTerm is a Reserved Instance offering of a price list product. Its price dimensions
+// are an hourly fee, in Hrs, and an upfront fee, in Quantity, either of which may be zero.
+type reservedTerm struct {
+	TermAttributes struct {
+		LeaseContractLength string `json:"LeaseContractLength"`
+		OfferingClass       string `json:"OfferingClass"`
+		PurchaseOption      string `json:"PurchaseOption"`
+	} `json:"termAttributes"`
+	PriceDimensions map[string]struct {
+		Unit         string            `json:"unit"`
+		PricePerUnit map[string]string `json:"pricePerUnit"`
+	} `json:"priceDimensions"`
+}
+
+// ReservedRates returns the effective hourly cost of a standard Reserved Instance of a Linux
+// instance type on shared tenancy, for every term and payment option it is offered with. The
+// upfront fee is spread over every hour of the term.
+func (f *AWSPricingFetcher) ReservedRates(ctx context.Context, region, instanceType string) ([]CommitmentRate, error) {
+	priceData, _, err := f.getProduct(ctx, region, instanceType, "Linux", "NA")
+	if err != nil {
+		return nil, err
+	}
+
+	terms, ok := priceData["terms"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid terms data structure", errParse)
+	}
+
+	// Round trip the reserved terms to decode them into their structure
+	data, err := json.Marshal(terms["Reserved"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode reserved terms: %w", err)
+	}
+	var reserved map[string]reservedTerm
+	if err := json.Unmarshal(data, &reserved); err != nil {
+		return nil, fmt.Errorf("%w: invalid reserved terms: %w", errParse, err)
+	}
+
+	var rates []CommitmentRate
+	for _, term := range reserved {
+		attributes := term.TermAttributes
+		if attributes.OfferingClass != "standard" {
+			continue
+		}
+		years, err := strconv.Atoi(strings.TrimSuffix(attributes.LeaseContractLength, "yr"))
+		if err != nil || years <= 0 {
+			return nil, fmt.Errorf("%w: invalid reserved term length %q", errParse, attributes.LeaseContractLength)
+		}
+
+		var hourly, upfront decimal.Decimal
+		for _, dimension := range term.PriceDimensions {
+			price, err := decimal.NewFromString(dimension.PricePerUnit["USD"])
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid reserved price %q: %w", errParse, dimension.PricePerUnit["USD"], err)
+			}
+			switch dimension.Unit {
+			case "Hrs":
+				hourly = price
+			case "Quantity":
+				upfront = price
+			}
+		}
+
+		rates = append(rates, CommitmentRate{
+			Term:          attributes.LeaseContractLength,
+			PaymentOption: paymentOptionLabel(attributes.PurchaseOption),
+			Cost:          hourly.Add(upfront.Div(decimal.NewFromInt(int64(years * hoursPerYear)))),
+		})
+	}
+
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("%w for reserved instance type %s in region %s", errNoPricingFound, instanceType, region)
+	}
+	sortCommitmentRates(rates)
+	return rates, nil
+}
+
+// savingsPlanRates caches the Compute Savings Plan rates of each region by instance type.
+// Offer files are large and only change when AWS publishes a new version, so a region is only
+// downloaded again when the index points at a new one.
+type savingsPlanRates struct {
+	mu       sync.Mutex
+	versions map[string]string
+	rates    map[string]map[string][]CommitmentRate
+}
+
+func newSavingsPlanRates() *savingsPlanRates {
+	return &savingsPlanRates{
+		versions: make(map[string]string),
+		rates:    make(map[string]map[string][]CommitmentRate),
+	}
+}
+
+// savingsPlanOfferFile is the part of a regional Savings Plan offer file the rates are read from
+type savingsPlanOfferFile struct {
+	Products []struct {
+		SKU           string `json:"sku"`
+		ProductFamily string `json:"productFamily"`
+		Attributes    struct {
+			PurchaseOption string `json:"purchaseOption"`
+			PurchaseTerm   string `json:"purchaseTerm"`
+		} `json:"attributes"`
+	} `json:"products"`
+	Terms struct {
+		SavingsPlan []struct {
+			SKU   string `json:"sku"`
+			Rates []struct {
+				DiscountedUsageType   string `json:"discountedUsageType"`
+				DiscountedOperation   string `json:"discountedOperation"`
+				DiscountedServiceCode string `json:"discountedServiceCode"`
+				Unit                  string `json:"unit"`
+				DiscountedRate        struct {
+					Price    string `json:"price"`
+					Currency string `json:"currency"`
+				} `json:"discountedRate"`
+			} `json:"rates"`
+		} `json:"savingsPlan"`
+	} `json:"terms"`
+}
+
+// SavingsPlanRates returns the Compute Savings Plan rate of every Linux instance type on shared
+// tenancy in a region, for every term and payment option
+func (f *AWSPricingFetcher) SavingsPlanRates(ctx context.Context, region string) (map[string][]CommitmentRate, error) {
+	var index struct {
+		Regions []struct {
+			RegionCode string `json:"regionCode"`
+			VersionURL string `json:"versionUrl"`
+		} `json:"regions"`
+	}
+	if err := f.getJSON(ctx, awsSavingsPlanOffersURL+awsComputeSavingsPlanIndex, &index); err != nil {
+		return nil, fmt.Errorf("failed to get AWS Savings Plan offer index: %w", err)
+	}
+
+	var version string
+	for _, r := range index.Regions {
+		if r.RegionCode == region {
+			version = r.VersionURL
+		}
+	}
+	if version == "" {
+		return nil, fmt.Errorf("%w for Savings Plans in region %s", errNoPricingFound, region)
+	}
+
+	f.savingsPlans.mu.Lock()
+	defer f.savingsPlans.mu.Unlock()
+
+	if f.savingsPlans.versions[region] == version {
+		return f.savingsPlans.rates[region], nil
+	}
+
+	var offers savingsPlanOfferFile
+	if err := f.getJSON(ctx, awsSavingsPlanOffersURL+version, &offers); err != nil {
+		return nil, fmt.Errorf("failed to get AWS Savings Plan offers for region %s: %w", region, err)
+	}
+	rates, err := parseSavingsPlanOffers(offers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse AWS Savings Plan offers for region %s: %w", region, err)
+	}
+
+	slog.Info("loaded AWS Savings Plan rates",
+		"region", region,
+		"version_url", version,
+		"instance_types", len(rates),
+	)
+	f.savingsPlans.versions[region] = version
+	f.savingsPlans.rates[region] = rates
+	return rates, nil
+}
+
+// parseSavingsPlanOffers picks the rates of Compute Savings Plans out of an offer file, which
+// also discounts Fargate and Lambda, other operating systems, and dedicated tenancy. Linux is
+// the RunInstances operation, and shared tenancy the BoxUsage usage type, which is prefixed
+// with the region everywhere but us-east-1.
+func parseSavingsPlanOffers(offers savingsPlanOfferFile) (map[string][]CommitmentRate, error) {
+	type plan struct{ term, paymentOption string }
+	plans := make(map[string]plan)
+	for _, p := range offers.Products {
+		if p.ProductFamily == "ComputeSavingsPlans" {
+			plans[p.SKU] = plan{p.Attributes.PurchaseTerm, paymentOptionLabel(p.Attributes.PurchaseOption)}
+		}
+	}
+
+	rates := make(map[string][]CommitmentRate)
+	for _, term := range offers.Terms.SavingsPlan {
+		plan, ok := plans[term.SKU]
+		if !ok {
+			continue
+		}
+		for _, rate := range term.Rates {
+			prefix, instanceType, ok := strings.Cut(rate.DiscountedUsageType, "BoxUsage:")
+			if !ok || (prefix != "" && !strings.HasSuffix(prefix, "-")) || rate.DiscountedServiceCode != "AmazonEC2" || rate.DiscountedOperation != "RunInstances" ||
+				rate.Unit != "Hrs" || rate.DiscountedRate.Currency != "USD" {
+				continue
+			}
+
+			cost, err := decimal.NewFromString(rate.DiscountedRate.Price)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid Savings Plan rate %q of %s: %w", errParse, rate.DiscountedRate.Price, instanceType, err)
+			}
+			rates[instanceType] = append(rates[instanceType], CommitmentRate{
+				Term:          plan.term,
+				PaymentOption: plan.paymentOption,
+				Cost:          cost,
+			})
+		}
+	}
+
+	for _, r := range rates {
+		sortCommitmentRates(r)
+	}
+	return rates, nil
+}
+
+// getJSON decodes the JSON document at a URL
+func (f *AWSPricingFetcher) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := f.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if class := statusClass(resp.StatusCode); class != nil {
+		return fmt.Errorf("%w: unexpected status %s", class, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%w: %w", errParse, err)
+	}
+	return nil
+}
+
+// recordCommitmentPrices exports the Reserved Instance and Compute Savings Plan rates of every
+// published AWS instance type, next to its on-demand price
+func (m *Monitor) recordCommitmentPrices(ctx context.Context) {
+	regions := make(map[string][]VMPricing)
+	for _, entry := range m.snapshot.Entries() {
+		if p := entry.Pricing; p.Provider == "aws" && !p.Confidential {
+			regions[p.Region] = append(regions[p.Region], p)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for region, prices := range regions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			savingsPlans, err := m.awsFetcher.SavingsPlanRates(ctx, region)
+			if err != nil {
+				slog.Error("failed to fetch AWS Savings Plan rates",
+					"region", region,
+					"reason", errorReason(err),
+					"error", err,
+				)
+			}
+
+			for _, p := range prices {
+				if rates, ok := savingsPlans[p.InstanceType]; ok {
+					m.metrics.RecordSavingsPlanRates(p, rates)
+				}
+
+				rates, err := m.awsFetcher.ReservedRates(ctx, p.Region, p.InstanceType)
+				if err != nil {
+					slog.Error("failed to fetch AWS Reserved Instance rates",
+						"region", p.Region,
+						"instance_type", p.InstanceType,
+						"reason", errorReason(err),
+						"error", err,
+					)
+					continue
+				}
+				m.metrics.RecordReservedRates(p, rates)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,132 @@
+package monitor
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/client"
+	cli "github.com/urfave/cli/v2"
+)
+
+// CompareCommand ranks the configured instance types of every provider and region that fit a
+// resource spec by price, to shop for the cheapest place to run a workload
+var CompareCommand = &cli.Command{
+	Name:  "compare",
+	Usage: "Fetch the prices of the configured instance types once and rank those with enough vCPUs and memory, cheapest first",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "vcpus",
+			Usage: "Only rank instance types with at least this many vCPUs",
+		},
+		&cli.Float64Flag{
+			Name:  "memory-gb",
+			Usage: "Only rank instance types with at least this much memory in GB (or GiB with --memory-unit GiB)",
+		},
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "Only print the cheapest prices (0 prints all)",
+			Value: 10,
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print the prices as JSON, like the /api/v1/prices listing, instead of a table",
+		},
+	},
+	Action: runCompare,
+}
+
+func runCompare(cctx *cli.Context) error {
+	ctx, cancel := context.WithCancel(cctx.Context)
+	defer cancel()
+	startLookupLogger(cctx)
+
+	vcpus, memory := cctx.Int("vcpus"), cctx.Float64("memory-gb")
+	if vcpus < 0 || memory < 0 {
+		return fmt.Errorf("vcpus and memory-gb must not be negative")
+	}
+	if cctx.Int("limit") < 0 {
+		return fmt.Errorf("limit must not be negative")
+	}
+
+	registry, err := newLookupRegistry(cctx)
+	if err != nil {
+		return err
+	}
+
+	rounding, err := NewPriceRounding(cctx.Int("price-decimal-places"), cctx.Int("price-significant-digits"), cctx.String("price-rounding-mode"))
+	if err != nil {
+		return err
+	}
+
+	memoryUnit, err := ParseMemoryUnit(cctx.String("memory-unit"))
+	if err != nil {
+		return err
+	}
+
+	ctx, monitor, err := newLookupMonitor(ctx, cctx, registry, memoryUnit)
+	if err != nil {
+		return err
+	}
+	monitor.watching = flagWatchList(cctx).watching()
+	monitor.gcpProject = cctx.String("gcp-project")
+	if err := monitor.takeAllRegions(); err != nil {
+		return err
+	}
+	if err := monitor.takeInstanceTypeGlobs(); err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(monitor.pricingTargets(), func(t pricingTarget) bool {
+		return (len(t.regions) > 0 || monitor.allRegions[t.name]) && (len(t.instanceTypes) > 0 || len(monitor.instanceTypeGlobs[t.name]) > 0)
+	}) {
+		return fmt.Errorf("compare requires the regions and instance types of at least one provider")
+	}
+
+	// A provider that can't be initialized is left out of the comparison rather than failing it
+	if err := monitor.initFetchers(ctx); err != nil {
+		slog.Warn("comparing without the providers that failed to initialize", "error", err)
+	}
+	monitor.listAllRegions(ctx)
+	monitor.expandInstanceTypeGlobs(ctx)
+	monitor.runVMFetches(ctx, monitor.vmFetches())
+
+	var (
+		prices        []client.Price
+		unknownMemory int
+	)
+	for _, entry := range monitor.snapshot.Entries() {
+		price := newAPIPrice(entry, time.Time{}, rounding, memoryUnit)
+		switch {
+		case price.VCPUs < vcpus:
+			continue
+		case memory > 0 && price.MemoryGB == 0:
+			unknownMemory++
+			continue
+		case price.MemoryGB < memory:
+			continue
+		}
+		prices = append(prices, price)
+	}
+	if unknownMemory > 0 {
+		slog.Warn("left out instance types whose memory isn't known", "count", unknownMemory)
+	}
+	if len(prices) == 0 {
+		return fmt.Errorf("no configured instance type with a price has at least %d vCPUs and %g %s of memory", vcpus, memory, memoryUnit)
+	}
+
+	slices.SortFunc(prices, func(a, b client.Price) int {
+		return cmp.Or(
+			cmp.Compare(a.TotalCost, b.TotalCost),
+			cmp.Compare(a.Provider, b.Provider),
+			cmp.Compare(a.Region, b.Region),
+			cmp.Compare(a.InstanceType, b.InstanceType),
+		)
+	})
+	if limit := cctx.Int("limit"); limit > 0 {
+		prices = prices[:min(limit, len(prices))]
+	}
+	return printPrices(prices, cctx.Bool("json"))
+}
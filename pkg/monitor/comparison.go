@@ -0,0 +1,97 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/client"
+	"github.com/shopspring/decimal"
+)
+
+// FleetComparison is a pair of fleets priced side by side, such as the current fleet and the
+// fleet a migration would replace it with
+type FleetComparison struct {
+	Current  client.SimulationRequest
+	Proposed client.SimulationRequest
+}
+
+// ComparisonCost is the cost of one side of a comparison. Items without a published price are
+// counted in Unpriced and left out of the hourly cost.
+type ComparisonCost struct {
+	Hourly   decimal.Decimal
+	Unpriced int
+}
+
+// LoadFleetComparison reads the current and proposed fleets from files in the format of
+// POST /api/v1/simulate
+func LoadFleetComparison(currentPath, proposedPath string) (*FleetComparison, error) {
+	current, err := loadComparisonFleet(currentPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load current fleet: %w", err)
+	}
+	proposed, err := loadComparisonFleet(proposedPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load proposed fleet: %w", err)
+	}
+	return &FleetComparison{Current: current, Proposed: proposed}, nil
+}
+
+func loadComparisonFleet(path string) (client.SimulationRequest, error) {
+	var req client.SimulationRequest
+	file, err := os.Open(path)
+	if err != nil {
+		return req, err
+	}
+	defer file.Close()
+
+	dec := json.NewDecoder(file)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return req, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := validateSimulation(req); err != nil {
+		return req, fmt.Errorf("invalid fleet %s: %w", path, err)
+	}
+	return req, nil
+}
+
+// Costs prices both fleets at the published prices
+func (c *FleetComparison) Costs(snapshot *PriceSnapshot) (current, proposed ComparisonCost) {
+	return comparisonCost(c.Current, snapshot), comparisonCost(c.Proposed, snapshot)
+}
+
+func comparisonCost(req client.SimulationRequest, snapshot *PriceSnapshot) ComparisonCost {
+	var cost ComparisonCost
+	for _, item := range req.Items {
+		entry, ok := snapshot.Get(simulationKey(item))
+		if !ok {
+			cost.Unpriced++
+			continue
+		}
+		cost.Hourly = cost.Hourly.Add(entry.Pricing.TotalCost.Mul(decimal.NewFromFloat(item.Count)))
+	}
+	return cost
+}
+
+// watchComparison starts pricing the regions and instance types of the items of both fleets
+// that aren't monitored yet. A comparison is only priced unsharded, so every item's region is
+// the monitor's own.
+func (m *Monitor) watchComparison(ctx context.Context) {
+	registered := m.registry.Names()
+	for _, req := range []client.SimulationRequest{m.comparison.Current, m.comparison.Proposed} {
+		for _, item := range req.Items {
+			if slices.Contains(registered, item.Provider) {
+				watched := m.watch(item.Provider)
+				watched.regions = appendMissing(watched.regions, item.Region)
+				watched.instanceTypes = appendMissing(watched.instanceTypes, item.InstanceType)
+			}
+		}
+	}
+	if err := m.initFetchers(ctx); err != nil {
+		slog.Error("failed to initialize fetchers for the fleet comparison", "error", err)
+	}
+}
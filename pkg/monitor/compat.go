@@ -0,0 +1,220 @@
+package monitor
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// legacyWindowMonths is how long after a migration the metric is still exported as it was
+// before with --legacy-metrics
+const legacyWindowMonths = 6
+
+// metricMigration records a breaking change to a metric: a rename, labels added to its series,
+// or both. With --legacy-metrics, the metric is exported as it was before the change until its
+// deprecation window ends, so dashboards and recording rules keep working while they move.
+type metricMigration struct {
+	// Metric is the metric's current name
+	Metric string
+
+	// Legacy is the metric's name before it was renamed, and empty if it wasn't. The legacy
+	// series are exported under it as well as under the current name.
+	Legacy string
+
+	// Labels were added to the metric's series. The legacy series leave them out and replace
+	// the current series, since a series exported both with and without them would be counted
+	// twice by any query that doesn't match on them.
+	Labels []addedLabel
+
+	// MigratedAt is when the change was made. Its window ends legacyWindowMonths later.
+	MigratedAt time.Time
+}
+
+// addedLabel is a label added to the series of a metric
+type addedLabel struct {
+	Name string
+
+	// New is the value of the series that only exist since the label was added, which have no
+	// legacy series, and empty when every series existed before
+	New string
+}
+
+// vmCostMetrics are the gauges of the current price, which have been exported since the first
+// release
+var vmCostMetrics = []string{"cloud_vm_total_cost_per_hour", "cloud_vm_cost_per_gb_hour", "cloud_vm_cost_per_vcpu_hour"}
+
+// metricMigrations are the breaking changes to metrics, kept until their windows have ended
+var metricMigrations = slices.Concat(
+	// Confidential computing variants are series of their own
+	labelMigrations(vmCostMetrics, time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC), addedLabel{"confidential", "true"}),
+	// Overridden prices are told apart from fetched ones
+	labelMigrations(vmCostMetrics, time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC), addedLabel{Name: "overridden"}),
+	// The GPUs of an instance type are on its price series rather than joined from an info metric
+	labelMigrations(vmCostMetrics, time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC), addedLabel{Name: "gpu_type"}, addedLabel{Name: "gpu_count"}),
+)
+
+// labelMigrations records labels added to the series of several metrics at once
+func labelMigrations(metrics []string, migratedAt time.Time, labels ...addedLabel) []metricMigration {
+	migrations := make([]metricMigration, 0, len(metrics))
+	for _, metric := range metrics {
+		migrations = append(migrations, metricMigration{Metric: metric, Labels: labels, MigratedAt: migratedAt})
+	}
+	return migrations
+}
+
+// windowEnd returns when the migration's legacy series stop being exported
+func (m metricMigration) windowEnd() time.Time {
+	return m.MigratedAt.AddDate(0, legacyWindowMonths, 0)
+}
+
+// activeMigrations returns the migrations whose window is still open at a time. The legacy
+// series of the others are no longer exported, whether or not they were removed from
+// metricMigrations yet.
+func activeMigrations(now time.Time) []metricMigration {
+	return slices.DeleteFunc(slices.Clone(metricMigrations), func(m metricMigration) bool {
+		return !now.Before(m.windowEnd())
+	})
+}
+
+// migratedMetricName returns the current name of a metric that was renamed from a legacy name
+func migratedMetricName(name string) (string, bool) {
+	for _, m := range metricMigrations {
+		if m.Legacy != "" && m.Legacy == name {
+			return m.Metric, true
+		}
+	}
+	return name, false
+}
+
+// warnLegacyMetrics logs every migration whose legacy series are exported and when they stop
+// being, so their removal doesn't come as a surprise, and every one whose window has ended
+func warnLegacyMetrics(logger *slog.Logger, now time.Time) {
+	for _, m := range metricMigrations {
+		attrs := []any{"metric", m.Metric}
+		if m.Legacy != "" {
+			attrs = append(attrs, "legacy_name", m.Legacy)
+		}
+		if len(m.Labels) > 0 {
+			attrs = append(attrs, "added_labels", strings.Join(labelNames(m.Labels), ","))
+		}
+		attrs = append(attrs, "migrated_at", m.MigratedAt.Format(time.DateOnly), "window_ends", m.windowEnd().Format(time.DateOnly))
+
+		if now.Before(m.windowEnd()) {
+			logger.Warn("exporting deprecated metric series", attrs...)
+		} else {
+			logger.Warn("deprecation window ended, exporting the current metric series only", attrs...)
+		}
+	}
+}
+
+// labelNames returns the names of added labels
+func labelNames(labels []addedLabel) []string {
+	names := make([]string, 0, len(labels))
+	for _, l := range labels {
+		names = append(names, l.Name)
+	}
+	return names
+}
+
+// legacyLabels returns the labels of a series without those added by the migrations, and false
+// if the series didn't exist before them
+func legacyLabels[L any](labels []L, name, value func(L) string, migrations []metricMigration) ([]L, bool) {
+	for _, m := range migrations {
+		for _, added := range m.Labels {
+			i := slices.IndexFunc(labels, func(l L) bool { return name(l) == added.Name })
+			if i < 0 {
+				continue
+			}
+			if added.New != "" && value(labels[i]) == added.New {
+				return nil, false
+			}
+			labels = slices.Delete(slices.Clone(labels), i, i+1)
+		}
+	}
+	return labels, true
+}
+
+// migrationsOf returns the migrations of a metric
+func migrationsOf(metric string, migrations []metricMigration) []metricMigration {
+	return slices.DeleteFunc(slices.Clone(migrations), func(m metricMigration) bool {
+		return m.Metric != metric
+	})
+}
+
+// legacyGatherer exports every migrated metric family as it was before its migrations, as well
+// as under its legacy name when it was renamed
+type legacyGatherer struct {
+	prometheus.Gatherer
+	migrations []metricMigration
+}
+
+func (g legacyGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.Gatherer.Gather()
+
+	var renamed []*dto.MetricFamily
+	for i, family := range families {
+		migrations := migrationsOf(family.GetName(), g.migrations)
+		if len(migrations) == 0 {
+			continue
+		}
+
+		legacy := proto.Clone(family).(*dto.MetricFamily)
+		legacy.Metric = nil
+		for _, metric := range family.GetMetric() {
+			labels, ok := legacyLabels(metric.GetLabel(), (*dto.LabelPair).GetName, (*dto.LabelPair).GetValue, migrations)
+			if !ok {
+				continue
+			}
+			metric = proto.Clone(metric).(*dto.Metric)
+			metric.Label = labels
+			legacy.Metric = append(legacy.Metric, metric)
+		}
+		families[i] = legacy
+
+		for _, m := range migrations {
+			if m.Legacy == "" {
+				continue
+			}
+			copied := proto.Clone(legacy).(*dto.MetricFamily)
+			copied.Name = proto.String(m.Legacy)
+			copied.Help = proto.String("Deprecated, renamed to " + family.GetName() + ". " + family.GetHelp())
+			renamed = append(renamed, copied)
+		}
+	}
+	families = append(families, renamed...)
+
+	slices.SortFunc(families, func(a, b *dto.MetricFamily) int {
+		return cmp.Compare(a.GetName(), b.GetName())
+	})
+	return families, err
+}
+
+// legacySamples returns the samples of migrated metrics as they were before their migrations,
+// along with a copy under the legacy name of every renamed one
+func legacySamples(samples []SinkSample, migrations []metricMigration) []SinkSample {
+	legacy := make([]SinkSample, 0, len(samples))
+	for _, s := range samples {
+		migrated := migrationsOf(s.Name, migrations)
+		labels, ok := legacyLabels(s.Labels, func(l SinkLabel) string { return l.Name }, func(l SinkLabel) string { return l.Value }, migrated)
+		if !ok {
+			continue
+		}
+		s.Labels = labels
+		legacy = append(legacy, s)
+
+		for _, m := range migrated {
+			if m.Legacy != "" {
+				renamed := s
+				renamed.Name = m.Legacy
+				legacy = append(legacy, renamed)
+			}
+		}
+	}
+	return legacy
+}
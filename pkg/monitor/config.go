@@ -0,0 +1,251 @@
+package monitor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	cli "github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFile is a YAML description of what the daemon monitors and how often. Each setting
+// stands in for the flag of the same meaning, which still takes precedence when it is set on
+// the command line or through its environment variable. The metrics section has no flags.
+type ConfigFile struct {
+	Poll      PollConfig      `yaml:"poll"`
+	Providers ProvidersConfig `yaml:"providers"`
+	Metrics   MetricsConfig   `yaml:"metrics"`
+}
+
+// PollConfig sets the poll intervals and the window polling is allowed in
+type PollConfig struct {
+	Interval      time.Duration `yaml:"interval"`
+	Adaptive      bool          `yaml:"adaptive"`
+	MinInterval   time.Duration `yaml:"min_interval"`
+	MaxInterval   time.Duration `yaml:"max_interval"`
+	PriorityTypes []string      `yaml:"priority_instance_types"`
+	Window        string        `yaml:"window"`
+	Timezone      string        `yaml:"timezone"`
+}
+
+// MetricsConfig documents the exported metrics for their consumers
+type MetricsConfig struct {
+	// Help overrides the help text of metrics, by metric name
+	Help map[string]MetricHelp `yaml:"help"`
+}
+
+// ProvidersConfig holds the settings of each provider
+type ProvidersConfig struct {
+	AWS   AWSProviderConfig   `yaml:"aws"`
+	GCP   GCPProviderConfig   `yaml:"gcp"`
+	Azure AzureProviderConfig `yaml:"azure"`
+}
+
+// AWSProviderConfig is what is priced on AWS and the credentials to price it with
+type AWSProviderConfig struct {
+	Regions       []string `yaml:"regions"`
+	InstanceTypes []string `yaml:"instance_types"`
+	VolumeTypes   []string `yaml:"volume_types"`
+	Confidential  bool     `yaml:"confidential"`
+	SpotPricing   bool     `yaml:"spot_pricing"`
+
+	// CommitmentPricing exports Reserved Instance and Savings Plan rates
+	CommitmentPricing bool `yaml:"commitment_pricing"`
+
+	// CapacityBlockPricing exports the prices of Capacity Blocks for ML
+	CapacityBlockPricing bool `yaml:"capacity_block_pricing"`
+
+	// AccountsFile lists the accounts to price at their negotiated discounts
+	AccountsFile string `yaml:"accounts_file"`
+
+	// BulkPricing prices instance types from the price list files instead of the live catalog
+	BulkPricing bool `yaml:"bulk_pricing"`
+
+	// ProxyURL routes the AWS API requests through a proxy, or direct to bypass it
+	ProxyURL string `yaml:"proxy_url"`
+
+	Credentials struct {
+		Profile               string `yaml:"profile"`
+		SharedCredentialsFile string `yaml:"shared_credentials_file"`
+		ConfigFile            string `yaml:"config_file"`
+	} `yaml:"credentials"`
+}
+
+// GCPProviderConfig is what is priced on GCP and the credentials to price it with
+type GCPProviderConfig struct {
+	Regions       []string `yaml:"regions"`
+	InstanceTypes []string `yaml:"instance_types"`
+	GPUTypes      []string `yaml:"gpu_types"`
+	DiskTypes     []string `yaml:"disk_types"`
+	Confidential  bool     `yaml:"confidential"`
+	SpotPricing   bool     `yaml:"spot_pricing"`
+	Project       string   `yaml:"project"`
+	ProxyURL      string   `yaml:"proxy_url"`
+
+	// BillingAccount prices SKUs at the contract prices of a Cloud Billing account
+	BillingAccount string `yaml:"billing_account"`
+
+	Credentials struct {
+		File string `yaml:"file"`
+	} `yaml:"credentials"`
+}
+
+// AzureProviderConfig is what is priced on Azure. Its prices are public, so it needs no
+// credentials.
+type AzureProviderConfig struct {
+	Regions      []string `yaml:"regions"`
+	VMSizes      []string `yaml:"vm_sizes"`
+	Confidential bool     `yaml:"confidential"`
+	ProxyURL     string   `yaml:"proxy_url"`
+}
+
+// LoadConfigFile reads a YAML config file, rejecting settings it doesn't know so a misspelled
+// one isn't silently ignored
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var cfg ConfigFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if err := checkMetricHelp(cfg.Metrics.Help); err != nil {
+		return nil, fmt.Errorf("invalid config file: %w", err)
+	}
+	return &cfg, nil
+}
+
+// flagValues returns the values the file gives each flag, in the form the flag parses them.
+// Settings left out of the file have no values.
+func (c *ConfigFile) flagValues() map[string][]string {
+	duration := func(d time.Duration) []string {
+		if d == 0 {
+			return nil
+		}
+		return []string{d.String()}
+	}
+	enabled := func(b bool) []string {
+		if !b {
+			return nil
+		}
+		return []string{strconv.FormatBool(b)}
+	}
+	str := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		return []string{s}
+	}
+
+	aws, gcp, azure := c.Providers.AWS, c.Providers.GCP, c.Providers.Azure
+	return map[string][]string{
+		"poll-interval":              duration(c.Poll.Interval),
+		"adaptive-polling":           enabled(c.Poll.Adaptive),
+		"min-poll-interval":          duration(c.Poll.MinInterval),
+		"max-poll-interval":          duration(c.Poll.MaxInterval),
+		"priority-instance-types":    c.Poll.PriorityTypes,
+		"poll-window":                str(c.Poll.Window),
+		"poll-window-timezone":       str(c.Poll.Timezone),
+		"aws-regions":                aws.Regions,
+		"aws-instance-types":         aws.InstanceTypes,
+		"aws-volume-types":           aws.VolumeTypes,
+		"aws-confidential":           enabled(aws.Confidential),
+		"aws-spot-pricing":           enabled(aws.SpotPricing),
+		"aws-commitment-pricing":     enabled(aws.CommitmentPricing),
+		"aws-capacity-block-pricing": enabled(aws.CapacityBlockPricing),
+		"aws-accounts-file":          str(aws.AccountsFile),
+		"aws-proxy-url":              str(aws.ProxyURL),
+		"aws-bulk-pricing":           enabled(aws.BulkPricing),
+		"gcp-regions":                gcp.Regions,
+		"gcp-instance-types":         gcp.InstanceTypes,
+		"gcp-gpu-types":              gcp.GPUTypes,
+		"gcp-disk-types":             gcp.DiskTypes,
+		"gcp-confidential":           enabled(gcp.Confidential),
+		"gcp-spot-pricing":           enabled(gcp.SpotPricing),
+		"gcp-project":                str(gcp.Project),
+		"gcp-billing-account":        str(gcp.BillingAccount),
+		"gcp-proxy-url":              str(gcp.ProxyURL),
+		"azure-regions":              azure.Regions,
+		"azure-vm-sizes":             azure.VMSizes,
+		"azure-confidential":         enabled(azure.Confidential),
+		"azure-proxy-url":            str(azure.ProxyURL),
+	}
+}
+
+// credentialEnv returns the environment variables the SDKs read the file's credentials from
+func (c *ConfigFile) credentialEnv() map[string]string {
+	aws, gcp := c.Providers.AWS.Credentials, c.Providers.GCP.Credentials
+	return map[string]string{
+		"AWS_PROFILE":                    aws.Profile,
+		"AWS_SHARED_CREDENTIALS_FILE":    aws.SharedCredentialsFile,
+		"AWS_CONFIG_FILE":                aws.ConfigFile,
+		"GOOGLE_APPLICATION_CREDENTIALS": gcp.File,
+	}
+}
+
+// configFileFlagsKey is the app metadata key of the flags ApplyConfigFile set
+const configFileFlagsKey = "config-file-flags"
+
+// configFileFlags returns the flags ApplyConfigFile set from the --config file
+func configFileFlags(cctx *cli.Context) []string {
+	names, _ := cctx.App.Metadata[configFileFlagsKey].([]string)
+	return names
+}
+
+// ApplyConfigFile sets the flags the --config file configures and that aren't set otherwise.
+// Credentials are passed on through the environment variables the AWS and GCP SDKs read, so
+// they apply to every client and are reloaded when rotated like any other; variables already
+// set take precedence.
+func ApplyConfigFile(cctx *cli.Context) error {
+	path := cctx.String("config")
+	if path == "" {
+		return nil
+	}
+
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	var applied []string
+	values := cfg.flagValues()
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		if cctx.IsSet(name) || len(values[name]) == 0 {
+			continue
+		}
+		for _, value := range values[name] {
+			if err := cctx.Set(name, value); err != nil {
+				return fmt.Errorf("invalid %s in config file: %w", name, err)
+			}
+		}
+		applied = append(applied, name)
+	}
+
+	// A reload has to tell the flags the file set from those that override it
+	if cctx.App.Metadata == nil {
+		cctx.App.Metadata = make(map[string]interface{})
+	}
+	cctx.App.Metadata[configFileFlagsKey] = applied
+	cctx.App.Metadata[metricHelpKey] = cfg.Metrics.Help
+
+	for name, value := range cfg.credentialEnv() {
+		if value == "" || os.Getenv(name) != "" {
+			continue
+		}
+		if err := os.Setenv(name, value); err != nil {
+			return fmt.Errorf("failed to set %s from config file: %w", name, err)
+		}
+	}
+	return nil
+}
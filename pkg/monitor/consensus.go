@@ -0,0 +1,95 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// consensusRefetch confirms a large change by fetching the price again immediately
+	consensusRefetch = "refetch"
+	// consensusConsecutive confirms a large change by requiring it on the next poll as well
+	consensusConsecutive = "consecutive"
+)
+
+// PriceConsensus holds back large price changes until they are confirmed, smoothing out
+// transient bad data from upstream pricing APIs
+type PriceConsensus struct {
+	threshold float64
+	mode      string
+
+	mu      sync.Mutex
+	pending map[PriceKey]decimal.Decimal
+}
+
+// NewPriceConsensus creates a consensus check for changes larger than threshold percent.
+// A threshold of zero publishes every change immediately.
+func NewPriceConsensus(threshold float64, mode string) (*PriceConsensus, error) {
+	if threshold < 0 {
+		return nil, fmt.Errorf("consensus threshold must not be negative")
+	}
+
+	if mode != consensusRefetch && mode != consensusConsecutive {
+		return nil, fmt.Errorf("unknown consensus mode %q (expected %q or %q)", mode, consensusRefetch, consensusConsecutive)
+	}
+
+	return &PriceConsensus{
+		threshold: threshold,
+		mode:      mode,
+		pending:   make(map[PriceKey]decimal.Decimal),
+	}, nil
+}
+
+// Confirm reports whether a fetched price may be published given the last published price
+func (c *PriceConsensus) Confirm(ctx context.Context, p VMPricing, previous *PriceEntry, refetch func(context.Context) (*VMPricing, error)) bool {
+	key := p.Key()
+
+	if c.threshold == 0 || previous == nil || previous.Pricing.TotalCost.IsZero() {
+		c.clearPending(key)
+		return true
+	}
+
+	change := percentIncrease(previous.Pricing.TotalCost, p.TotalCost).Abs()
+	if change.LessThanOrEqual(decimal.NewFromFloat(c.threshold)) {
+		c.clearPending(key)
+		return true
+	}
+
+	switch c.mode {
+	case consensusRefetch:
+		second, err := refetch(ctx)
+		if err != nil {
+			slog.Warn("failed to re-fetch price to confirm change",
+				"provider", p.Provider,
+				"region", p.Region,
+				"instance_type", p.InstanceType,
+				"error", err,
+			)
+			return false
+		}
+		return second.TotalCost.Equal(p.TotalCost)
+
+	case consensusConsecutive:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if candidate, ok := c.pending[key]; ok && candidate.Equal(p.TotalCost) {
+			delete(c.pending, key)
+			return true
+		}
+		c.pending[key] = p.TotalCost
+		return false
+	}
+
+	return true
+}
+
+func (c *PriceConsensus) clearPending(key PriceKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, key)
+}
@@ -0,0 +1,117 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// consensusTestPrice returns a price of the series every consensus test checks
+func consensusTestPrice(cost string) VMPricing {
+	return VMPricing{Provider: "aws", Region: "us-east-1", InstanceType: "m5.large", TotalCost: decimal.RequireFromString(cost)}
+}
+
+func TestPriceConsensusRefetch(t *testing.T) {
+	tests := []struct {
+		name       string
+		threshold  float64
+		previous   string
+		fetched    string
+		refetched  string
+		refetchErr error
+		want       bool
+		refetches  int
+	}{
+		{"first price", 10, "", "0.2", "", nil, true, 0},
+		{"previous price of zero", 10, "0", "0.2", "", nil, true, 0},
+		{"no threshold", 0, "0.1", "0.2", "", nil, true, 0},
+		{"unchanged", 10, "0.1", "0.1", "", nil, true, 0},
+		{"change at the threshold", 10, "0.1", "0.11", "", nil, true, 0},
+		{"decrease at the threshold", 10, "0.1", "0.09", "", nil, true, 0},
+		{"change confirmed", 10, "0.1", "0.2", "0.2", nil, true, 1},
+		{"decrease confirmed", 10, "0.1", "0.05", "0.05", nil, true, 1},
+		{"change not confirmed", 10, "0.1", "0.2", "0.1", nil, false, 1},
+		{"change confirmed differently", 10, "0.1", "0.2", "0.3", nil, false, 1},
+		{"re-fetch failed", 10, "0.1", "0.2", "", errors.New("throttled"), false, 1},
+	}
+
+	for _, tt := range tests {
+		consensus, err := NewPriceConsensus(tt.threshold, consensusRefetch)
+		if err != nil {
+			t.Fatalf("NewPriceConsensus failed: %v", err)
+		}
+
+		var previous *PriceEntry
+		if tt.previous != "" {
+			previous = &PriceEntry{Pricing: consensusTestPrice(tt.previous), UpdatedAt: time.Now()}
+		}
+		refetches := 0
+		refetch := func(ctx context.Context) (*VMPricing, error) {
+			refetches++
+			if tt.refetchErr != nil {
+				return nil, tt.refetchErr
+			}
+			p := consensusTestPrice(tt.refetched)
+			return &p, nil
+		}
+
+		got := consensus.Confirm(context.Background(), consensusTestPrice(tt.fetched), previous, refetch)
+		if got != tt.want || refetches != tt.refetches {
+			t.Errorf("%s: Confirm = %t with %d re-fetches, want %t with %d", tt.name, got, refetches, tt.want, tt.refetches)
+		}
+	}
+}
+
+func TestPriceConsensusConsecutive(t *testing.T) {
+	tests := []struct {
+		name  string
+		polls []string
+		want  []bool
+	}{
+		{"change confirmed on the next poll", []string{"0.2", "0.2"}, []bool{false, true}},
+		{"change to another price", []string{"0.2", "0.3", "0.3"}, []bool{false, false, true}},
+		{"change reverted", []string{"0.2", "0.1", "0.2"}, []bool{false, true, false}},
+		{"change within the threshold in between", []string{"0.2", "0.105", "0.2", "0.2"}, []bool{false, true, false, true}},
+	}
+
+	for _, tt := range tests {
+		consensus, err := NewPriceConsensus(10, consensusConsecutive)
+		if err != nil {
+			t.Fatalf("NewPriceConsensus failed: %v", err)
+		}
+		refetch := func(ctx context.Context) (*VMPricing, error) {
+			t.Errorf("%s: re-fetched in consecutive mode", tt.name)
+			return nil, errors.New("unexpected re-fetch")
+		}
+
+		// The published price stays the same while changes are held back
+		previous := &PriceEntry{Pricing: consensusTestPrice("0.1")}
+		for i, poll := range tt.polls {
+			if got := consensus.Confirm(context.Background(), consensusTestPrice(poll), previous, refetch); got != tt.want[i] {
+				t.Errorf("%s: poll %d of %s: Confirm = %t, want %t", tt.name, i+1, poll, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestNewPriceConsensusErrors(t *testing.T) {
+	tests := []struct {
+		threshold float64
+		mode      string
+		err       string
+	}{
+		{-1, consensusRefetch, "must not be negative"},
+		{10, "majority", `unknown consensus mode "majority"`},
+	}
+
+	for _, tt := range tests {
+		_, err := NewPriceConsensus(tt.threshold, tt.mode)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("NewPriceConsensus(%v, %q) error = %v, want %q", tt.threshold, tt.mode, err, tt.err)
+		}
+	}
+}
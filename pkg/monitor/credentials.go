@@ -0,0 +1,267 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+// credentialsCheckInterval is how often provider credential files are checked for changes
+const credentialsCheckInterval = 30 * time.Second
+
+// fileStamp identifies a version of a file. Secrets mounted by Kubernetes are swapped by
+// replacing a symlink, which Stat follows, so a rotation changes the stamp as well.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+func statFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size(), exists: true}
+}
+
+// Secret is a credential given inline or read from a file, such as one written by Vault agent or
+// a CSI secrets driver. A file is read again whenever it changes, so rotated credentials are
+// used without a restart.
+type Secret struct {
+	path string
+
+	mu    sync.Mutex
+	value string
+	stamp fileStamp
+}
+
+// NewSecret returns a secret with an inline value, or read from path when it is set. Setting
+// both is an error.
+func NewSecret(value, path string) (*Secret, error) {
+	if path == "" {
+		return &Secret{value: value}, nil
+	}
+	if value != "" {
+		return nil, fmt.Errorf("a secret can't be given both inline and as the file %s", path)
+	}
+
+	s := &Secret{path: path}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// reload reads the secret's file if it changed since it was last read
+func (s *Secret) reload() error {
+	stamp := statFile(s.path)
+	if stamp == s.stamp {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read secret file: %w", err)
+	}
+	if s.stamp.exists {
+		slog.Info("reloaded rotated secret", "secret_file", s.path)
+	}
+	s.value = strings.TrimSpace(string(data))
+	s.stamp = stamp
+	return nil
+}
+
+// Value returns the current value of the secret. When a changed file can't be read, such as
+// mid-rotation, the last value read is returned.
+func (s *Secret) Value() string {
+	if s == nil {
+		return ""
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path != "" {
+		if err := s.reload(); err != nil {
+			slog.Warn("failed to reload secret, using the last value read", "secret_file", s.path, "error", err)
+		}
+	}
+	return s.value
+}
+
+// credentialFiles detects changes to a set of credential files, checking at most once per
+// credentialsCheckInterval
+type credentialFiles struct {
+	paths []string
+
+	mu      sync.Mutex
+	checked time.Time
+	stamps  []fileStamp
+}
+
+func newCredentialFiles(paths ...string) *credentialFiles {
+	c := &credentialFiles{paths: paths, checked: time.Now()}
+	for _, path := range paths {
+		c.stamps = append(c.stamps, statFile(path))
+	}
+	return c
+}
+
+// changed reports whether any file changed since the last check that found a change
+func (c *credentialFiles) changed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.checked) < credentialsCheckInterval {
+		return false
+	}
+	c.checked = time.Now()
+
+	changed := false
+	for i, path := range c.paths {
+		if stamp := statFile(path); stamp != c.stamps[i] {
+			c.stamps[i] = stamp
+			changed = true
+		}
+	}
+	return changed
+}
+
+// envOr returns the value of an environment variable, or a default when it is unset
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// reloadingAWSCredentials retrieves credentials through the default credential chain, loading
+// the chain again when the shared credentials or config file changes. The SDK caches static
+// credentials from those files for good, so without it a rotated key is never picked up.
+type reloadingAWSCredentials struct {
+	files   *credentialFiles
+	optFns  []func(*config.LoadOptions) error
+	mu      sync.Mutex
+	current aws.CredentialsProvider
+}
+
+func newReloadingAWSCredentials(current aws.CredentialsProvider, optFns []func(*config.LoadOptions) error) *reloadingAWSCredentials {
+	return &reloadingAWSCredentials{
+		files: newCredentialFiles(
+			envOr("AWS_SHARED_CREDENTIALS_FILE", config.DefaultSharedCredentialsFilename()),
+			envOr("AWS_CONFIG_FILE", config.DefaultSharedConfigFilename()),
+		),
+		optFns:  optFns,
+		current: current,
+	}
+}
+
+func (r *reloadingAWSCredentials) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	r.mu.Lock()
+	if r.files.changed() {
+		cfg, err := config.LoadDefaultConfig(ctx, r.optFns...)
+		if err != nil {
+			slog.Warn("failed to reload rotated AWS credentials, using the previous ones", "error", err)
+		} else {
+			slog.Info("reloaded rotated AWS credentials")
+			r.current = cfg.Credentials
+		}
+	}
+	current := r.current
+	r.mu.Unlock()
+
+	if current == nil {
+		return aws.Credentials{}, fmt.Errorf("no AWS credentials configured")
+	}
+	return current.Retrieve(ctx)
+}
+
+// gcpCredentialsFile returns the file Application Default Credentials are read from, if any
+func gcpCredentialsFile() string {
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		return path
+	}
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(config, "gcloud", "application_default_credentials.json")
+}
+
+// gcpClientOptions authenticates a GCP client with Application Default Credentials that are
+// found again when rotated, keeping the quota project they name
+func gcpClientOptions(ctx context.Context, scopes ...string) ([]option.ClientOption, error) {
+	creds, err := google.FindDefaultCredentials(ctx, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find GCP credentials: %w", err)
+	}
+
+	ts := &reloadingGCPTokenSource{
+		files:   newCredentialFiles(gcpCredentialsFile()),
+		scopes:  scopes,
+		current: creds.TokenSource,
+	}
+	opts := []option.ClientOption{option.WithTokenSource(ts)}
+
+	var file struct {
+		QuotaProjectID string `json:"quota_project_id"`
+	}
+	if json.Unmarshal(creds.JSON, &file) == nil && file.QuotaProjectID != "" {
+		opts = append(opts, option.WithQuotaProject(file.QuotaProjectID))
+	}
+
+	// Requests are routed and counted beneath the authenticating transport, which an HTTP
+	// client option would otherwise replace
+	base := egressFrom(ctx).Transport("gcp")
+	if metrics := apiCallMetrics(ctx); metrics != nil {
+		base = &gcpAPICallTransport{base: base, metrics: metrics}
+	}
+	if base != http.DefaultTransport {
+		transport, err := htransport.NewTransport(ctx, base, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GCP transport: %w", err)
+		}
+		opts = []option.ClientOption{option.WithHTTPClient(&http.Client{Transport: transport})}
+	}
+	return opts, nil
+}
+
+// reloadingGCPTokenSource issues tokens from Application Default Credentials, finding the
+// credentials again when their file changes. A service account key is otherwise read once.
+type reloadingGCPTokenSource struct {
+	files   *credentialFiles
+	scopes  []string
+	mu      sync.Mutex
+	current oauth2.TokenSource
+}
+
+func (r *reloadingGCPTokenSource) Token() (*oauth2.Token, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.files.changed() {
+		creds, err := google.FindDefaultCredentials(context.Background(), r.scopes...)
+		if err != nil {
+			slog.Warn("failed to reload rotated GCP credentials, using the previous ones", "error", err)
+		} else {
+			slog.Info("reloaded rotated GCP credentials")
+			r.current = creds.TokenSource
+		}
+	}
+	return r.current.Token()
+}
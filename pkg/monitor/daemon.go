@@ -0,0 +1,1438 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bluesky-social/go-util/pkg/telemetry"
+	"github.com/jazware/cloud-pricing-monitor/pkg/providers"
+	cli "github.com/urfave/cli/v2"
+)
+
+// Flags configure the monitoring daemon
+var Flags = []cli.Flag{
+	telemetry.CLIFlagDebug,
+	telemetry.CLIFlagMetricsListenAddress,
+	&cli.StringFlag{
+		Name:    "metrics-path",
+		Usage:   "Path to serve Prometheus metrics on, with the metrics of each provider below it",
+		EnvVars: []string{"METRICS_PATH"},
+		Value:   "/metrics",
+	},
+	&cli.StringFlag{
+		Name:    "config",
+		Usage:   "YAML file of providers, regions, instance types, poll intervals, and credentials; flags that are set take precedence",
+		EnvVars: []string{"CONFIG_FILE"},
+	},
+	&cli.StringSliceFlag{
+		Name:     "aws-regions",
+		Usage:    "AWS regions to monitor, or all for every region enabled in the account (e.g., us-east-1,us-west-2)",
+		EnvVars:  []string{"AWS_REGIONS"},
+		Required: false,
+	},
+	&cli.StringSliceFlag{
+		Name:     "aws-instance-types",
+		Usage:    "AWS EC2 instance types to track, or patterns matched against the EC2 catalog on every poll (e.g., t3.micro,m5.large,'c7g.*')",
+		EnvVars:  []string{"AWS_INSTANCE_TYPES"},
+		Required: false,
+	},
+	&cli.StringSliceFlag{
+		Name:     "gcp-regions",
+		Usage:    "GCP regions to monitor, or all for every region of gcp-project (e.g., us-central1,us-east1)",
+		EnvVars:  []string{"GCP_REGIONS"},
+		Required: false,
+	},
+	&cli.StringSliceFlag{
+		Name:     "gcp-instance-types",
+		Usage:    "GCP machine types to track, or patterns matched against the machine types of gcp-project on every poll (e.g., e2-micro,n2-standard-2,'c3-standard-*')",
+		EnvVars:  []string{"GCP_INSTANCE_TYPES"},
+		Required: false,
+	},
+	&cli.StringSliceFlag{
+		Name:    "azure-regions",
+		Usage:   "Azure regions to monitor (e.g., eastus,westeurope)",
+		EnvVars: []string{"AZURE_REGIONS"},
+	},
+	&cli.StringSliceFlag{
+		Name:    "azure-vm-sizes",
+		Usage:   "Azure VM sizes to track (e.g., Standard_D4s_v5,Standard_E8s_v5)",
+		EnvVars: []string{"AZURE_VM_SIZES"},
+	},
+	&cli.StringSliceFlag{
+		Name:    "gcp-gpu-types",
+		Usage:   "GCP accelerator types to export the per-GPU cost of in every GCP region, with the license of virtual workstation types (e.g., nvidia-l4,nvidia-tesla-t4-vws)",
+		EnvVars: []string{"GCP_GPU_TYPES"},
+	},
+	&cli.StringSliceFlag{
+		Name:    "aws-volume-types",
+		Usage:   "EBS volume types to export the capacity, provisioned IOPS, and provisioned throughput prices of in every AWS region (e.g., gp3,io2)",
+		EnvVars: []string{"AWS_VOLUME_TYPES"},
+	},
+	&cli.StringSliceFlag{
+		Name:    "gcp-disk-types",
+		Usage:   "GCP persistent disk types to export the capacity, provisioned IOPS, and provisioned throughput prices of in every GCP region (e.g., pd-balanced,hyperdisk-balanced)",
+		EnvVars: []string{"GCP_DISK_TYPES"},
+	},
+	&cli.BoolFlag{
+		Name:    "aws-confidential",
+		Usage:   "Also record Nitro Enclaves-capable AWS instance types as confidential computing variants",
+		EnvVars: []string{"AWS_CONFIDENTIAL"},
+	},
+	&cli.BoolFlag{
+		Name:    "aws-spot-pricing",
+		Usage:   "Also export the current spot price of every AWS instance type in each availability zone",
+		EnvVars: []string{"AWS_SPOT_PRICING"},
+	},
+	&cli.BoolFlag{
+		Name:    "aws-commitment-pricing",
+		Usage:   "Also export the 1 and 3 year standard Reserved Instance and Compute Savings Plan rates of every AWS instance type, by payment option",
+		EnvVars: []string{"AWS_COMMITMENT_PRICING"},
+	},
+	&cli.BoolFlag{
+		Name:    "aws-capacity-block-pricing",
+		Usage:   "Also export the lowest Capacity Block for ML price of every AWS instance type sold in Capacity Blocks, in each availability zone",
+		EnvVars: []string{"AWS_CAPACITY_BLOCK_PRICING"},
+	},
+	&cli.DurationFlag{
+		Name:    "aws-capacity-block-duration",
+		Usage:   "Length of the Capacity Blocks priced with aws-capacity-block-pricing: 1 to 14 days, or whole weeks up to 182 days",
+		Value:   defaultCapacityBlockDuration,
+		EnvVars: []string{"AWS_CAPACITY_BLOCK_DURATION"},
+	},
+	&cli.StringFlag{
+		Name:    "aws-accounts-file",
+		Usage:   "Also export what every AWS instance type costs each account in this JSON file, after its negotiated discount off the list price",
+		EnvVars: []string{"AWS_ACCOUNTS_FILE"},
+	},
+	&cli.StringFlag{
+		Name:    "aws-proxy-url",
+		Usage:   "Proxy to send AWS API requests through (http, https, socks5, or socks5h URL), or direct to bypass HTTPS_PROXY; defaults to the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment",
+		EnvVars: []string{"AWS_PROXY_URL"},
+	},
+	&cli.StringFlag{
+		Name:    "gcp-proxy-url",
+		Usage:   "Proxy to send GCP API requests through (http, https, socks5, or socks5h URL), or direct to bypass HTTPS_PROXY; defaults to the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment",
+		EnvVars: []string{"GCP_PROXY_URL"},
+	},
+	&cli.StringFlag{
+		Name:    "azure-proxy-url",
+		Usage:   "Proxy to send Azure Retail Prices API requests through (http, https, socks5, or socks5h URL), or direct to bypass HTTPS_PROXY; defaults to the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment",
+		EnvVars: []string{"AZURE_PROXY_URL"},
+	},
+	&cli.BoolFlag{
+		Name:    "azure-confidential",
+		Usage:   "Also price the confidential counterpart of AMD Azure VM sizes that have one (DCas_v5, DCads_v5, ECas_v5, ECads_v5)",
+		EnvVars: []string{"AZURE_CONFIDENTIAL"},
+	},
+	&cli.BoolFlag{
+		Name:    "gcp-spot-pricing",
+		Usage:   "Also export the current Spot price of every predefined GCP machine type",
+		EnvVars: []string{"GCP_SPOT_PRICING"},
+	},
+	&cli.BoolFlag{
+		Name:    "gcp-confidential",
+		Usage:   "Also price the Confidential VM variant of supported GCP machine types (N2D, C2D, C3D, C3)",
+		EnvVars: []string{"GCP_CONFIDENTIAL"},
+	},
+	&cli.DurationFlag{
+		Name:    "poll-interval",
+		Usage:   "How often to refresh pricing data",
+		EnvVars: []string{"POLL_INTERVAL"},
+		Value:   1 * time.Hour,
+	},
+	&cli.BoolFlag{
+		Name:    "adaptive-polling",
+		Usage:   "Poll each price more or less often than poll-interval depending on how often it changes, within min-poll-interval and max-poll-interval",
+		EnvVars: []string{"ADAPTIVE_POLLING"},
+	},
+	&cli.DurationFlag{
+		Name:    "min-poll-interval",
+		Usage:   "Shortest interval a volatile price is polled at with adaptive-polling",
+		EnvVars: []string{"MIN_POLL_INTERVAL"},
+		Value:   5 * time.Minute,
+	},
+	&cli.DurationFlag{
+		Name:    "max-poll-interval",
+		Usage:   "Longest interval a stable price is polled at with adaptive-polling",
+		EnvVars: []string{"MAX_POLL_INTERVAL"},
+		Value:   24 * time.Hour,
+	},
+	&cli.StringFlag{
+		Name:    "poll-window",
+		Usage:   "Cron expression of the minutes polling is allowed in (e.g., \"* 8-18 * * mon-fri\" for business hours); polls outside it are deferred until it opens",
+		EnvVars: []string{"POLL_WINDOW"},
+	},
+	&cli.StringFlag{
+		Name:    "poll-window-timezone",
+		Usage:   "IANA time zone poll-window is evaluated in (e.g., Europe/Berlin)",
+		EnvVars: []string{"POLL_WINDOW_TIMEZONE"},
+		Value:   "UTC",
+	},
+	&cli.StringSliceFlag{
+		Name:    "priority-instance-types",
+		Usage:   "Instance types to fetch first on startup, before discovery and the other instance types (e.g., m5.large,n2-standard-4)",
+		EnvVars: []string{"PRIORITY_INSTANCE_TYPES"},
+	},
+	&cli.StringSliceFlag{
+		Name:    "max-concurrent-fetches",
+		Usage:   "Most prices fetched from a provider at the same time, for every provider or as provider=count (e.g., 16,aws=4)",
+		EnvVars: []string{"MAX_CONCURRENT_FETCHES"},
+		Value:   cli.NewStringSlice(strconv.Itoa(defaultMaxConcurrentFetches)),
+	},
+	&cli.StringSliceFlag{
+		Name:    "fetch-rate-limit",
+		Usage:   "Most prices fetched from a provider per second, for every provider or as provider=rate (e.g., aws=5); unlimited by default",
+		EnvVars: []string{"FETCH_RATE_LIMIT"},
+	},
+	&cli.IntFlag{
+		Name:    "fetch-retries",
+		Usage:   "Times to retry a price fetch that was throttled or failed with a server or network error (0 to not retry)",
+		EnvVars: []string{"FETCH_RETRIES"},
+		Value:   2,
+	},
+	&cli.DurationFlag{
+		Name:    "fetch-retry-backoff",
+		Usage:   "Backoff before the first retry of a price fetch, doubled on every retry after it up to 30s, with jitter",
+		EnvVars: []string{"FETCH_RETRY_BACKOFF"},
+		Value:   time.Second,
+	},
+	&cli.IntFlag{
+		Name:    "stale-after-polls",
+		Usage:   "Consider a published price stale once it hasn't been fetched successfully for this many poll intervals (0 to never)",
+		EnvVars: []string{"STALE_AFTER_POLLS"},
+	},
+	&cli.StringFlag{
+		Name:    "stale-series-action",
+		Usage:   "What to do with a stale price: flag (keep publishing it, with cloud_vm_pricing_stale set) or delete (stop publishing it until it is fetched again)",
+		EnvVars: []string{"STALE_SERIES_ACTION"},
+		Value:   staleFlag,
+	},
+	&cli.DurationFlag{
+		Name:    "fetcher-init-timeout",
+		Usage:   "How long to wait for each provider's pricing client to initialize before fetching without it, retrying on the next poll (0 to wait indefinitely)",
+		EnvVars: []string{"FETCHER_INIT_TIMEOUT"},
+		Value:   30 * time.Second,
+	},
+	&cli.StringFlag{
+		Name:    "aws-price-list-version",
+		Usage:   "Pin AWS pricing to a price list version (e.g., 20230328234721) for reproducible reports",
+		EnvVars: []string{"AWS_PRICE_LIST_VERSION"},
+	},
+	&cli.StringFlag{
+		Name:    "aws-price-list-date",
+		Usage:   "Pin AWS pricing to the price list version in effect at a date (YYYY-MM-DD or RFC 3339) for backtesting",
+		EnvVars: []string{"AWS_PRICE_LIST_DATE"},
+	},
+	&cli.BoolFlag{
+		Name:    "aws-bulk-pricing",
+		Usage:   "Price AWS instance types from each region's current price list file, checked for a new version once per poll, instead of a GetProducts call per instance type",
+		EnvVars: []string{"AWS_BULK_PRICING"},
+	},
+	&cli.DurationFlag{
+		Name:    "price-list-check-interval",
+		Usage:   "How often to check for a new AWS price list version and refresh immediately when one is published (0 disables)",
+		EnvVars: []string{"PRICE_LIST_CHECK_INTERVAL"},
+	},
+	&cli.BoolFlag{
+		Name:    "track-new-generations",
+		Usage:   "Watch provider catalogs for newer generations of the monitored instance types",
+		EnvVars: []string{"TRACK_NEW_GENERATIONS"},
+	},
+	&cli.BoolFlag{
+		Name:    "auto-add-new-generations",
+		Usage:   "Start pricing newer generations that launch while running (requires --track-new-generations)",
+		EnvVars: []string{"AUTO_ADD_NEW_GENERATIONS"},
+	},
+	&cli.BoolFlag{
+		Name:    "export-file-storage",
+		Usage:   "Export the unit prices of EFS in every AWS region and of Filestore tiers in every GCP region",
+		EnvVars: []string{"EXPORT_FILE_STORAGE"},
+	},
+	&cli.BoolFlag{
+		Name:    "export-backup-pricing",
+		Usage:   "Export the backup storage and restore unit prices of AWS Backup in every AWS region and of Backup and DR in every GCP region",
+		EnvVars: []string{"EXPORT_BACKUP_PRICING"},
+	},
+	&cli.BoolFlag{
+		Name:    "export-platform-services",
+		Usage:   "Export the unit prices of KMS, Secrets Manager, and CloudWatch Logs in every AWS region and of Cloud KMS, Secret Manager, and Cloud Logging in every GCP region",
+		EnvVars: []string{"EXPORT_PLATFORM_SERVICES"},
+	},
+	&cli.BoolFlag{
+		Name:    "export-size-steps",
+		Usage:   "Export the price of the next size down and up in the same family as each monitored instance type",
+		EnvVars: []string{"EXPORT_SIZE_STEPS"},
+	},
+	&cli.BoolFlag{
+		Name:    "track-availability",
+		Usage:   "Export which zones of each region offer the monitored instance types",
+		EnvVars: []string{"TRACK_AVAILABILITY"},
+	},
+	&cli.BoolFlag{
+		Name:    "export-quota-ceilings",
+		Usage:   "Export the on-demand vCPU quotas of each region and the most they permit spending per hour at current prices",
+		EnvVars: []string{"EXPORT_QUOTA_CEILINGS"},
+	},
+	&cli.StringFlag{
+		Name:    "gcp-project",
+		Usage:   "GCP project to read zones, machine types, and quotas of (required by --track-availability and --export-quota-ceilings with GCP regions)",
+		EnvVars: []string{"GCP_PROJECT", "GOOGLE_CLOUD_PROJECT"},
+	},
+	&cli.StringFlag{
+		Name:    "gcp-billing-account",
+		Usage:   "Cloud Billing account to price GCP SKUs at the contract prices of, with its negotiated discounts, instead of the public list prices (e.g., 012345-567890-ABCDEF)",
+		EnvVars: []string{"GCP_BILLING_ACCOUNT"},
+	},
+	&cli.StringFlag{
+		Name:    "offline-bundle",
+		Usage:   "Serve the prices of a bundle written by export-bundle without making any network calls",
+		EnvVars: []string{"OFFLINE_BUNDLE"},
+	},
+	&cli.BoolFlag{
+		Name:    "export-timestamps",
+		Usage:   "Export price gauges with explicit sample timestamps equal to the time each price was fetched",
+		EnvVars: []string{"EXPORT_TIMESTAMPS"},
+	},
+	&cli.IntFlag{
+		Name:    "shard-count",
+		Usage:   "Number of monitor instances the provider regions are split across",
+		EnvVars: []string{"SHARD_COUNT"},
+		Value:   1,
+	},
+	&cli.IntFlag{
+		Name:    "shard-index",
+		Usage:   "Index of this instance among the shards, starting at 0",
+		EnvVars: []string{"SHARD_INDEX"},
+	},
+	&cli.StringSliceFlag{
+		Name:    "shard-peers",
+		Usage:   "Scrape addresses of every shard in index order (e.g., monitor-0:6009,monitor-1:6009), served for service discovery on /api/v1/sd",
+		EnvVars: []string{"SHARD_PEERS"},
+	},
+	&cli.StringFlag{
+		Name:    "sd-file",
+		Usage:   "Write the shard scrape targets to this Prometheus file SD file (requires --shard-peers)",
+		EnvVars: []string{"SD_FILE"},
+	},
+	&cli.StringFlag{
+		Name:    "cache-url",
+		Usage:   "Share fetched SKU catalogs and prices with the other replicas through the Redis or Valkey server at this URL (redis://[user:password@]host[:port][/db], or rediss:// for TLS)",
+		EnvVars: []string{"CACHE_URL"},
+	},
+	&cli.StringFlag{
+		Name:    "cache-key-prefix",
+		Usage:   "Prefix of the shared cache keys; replicas share what is cached under the same prefix",
+		EnvVars: []string{"CACHE_KEY_PREFIX"},
+		Value:   "cloud-pricing-monitor:",
+	},
+	&cli.DurationFlag{
+		Name:    "cache-ttl",
+		Usage:   "How long shared cache entries are kept (defaults to poll-interval)",
+		EnvVars: []string{"CACHE_TTL"},
+	},
+	&cli.StringFlag{
+		Name:    "sync-from",
+		Usage:   "Base URL of a peer monitor (e.g., http://monitor-0:6009) to copy the published prices from on startup, so they are served before the first fetch completes",
+		EnvVars: []string{"SYNC_FROM"},
+	},
+	&cli.BoolFlag{
+		Name:    "match-debug",
+		Usage:   "Serve /api/v1/debug/match, which fetches a price again and explains which SKUs or price list products were matched or rejected and why",
+		EnvVars: []string{"MATCH_DEBUG"},
+	},
+	&cli.StringFlag{
+		Name:    "history-file",
+		Usage:   "Append every price change to this JSON lines file",
+		EnvVars: []string{"HISTORY_FILE"},
+	},
+	&cli.StringFlag{
+		Name:    "baseline-file",
+		Usage:   "Compare live prices against the baseline prices in this file (price records as JSON lines, as written to --history-file)",
+		EnvVars: []string{"BASELINE_FILE"},
+	},
+	&cli.Float64Flag{
+		Name:    "baseline-margin",
+		Usage:   "Percent above the baseline price at which a price is flagged as exceeding it",
+		EnvVars: []string{"BASELINE_MARGIN"},
+	},
+	&cli.BoolFlag{
+		Name:    "kubernetes-discovery",
+		Usage:   "Price the nodes of the Kubernetes cluster and export estimated cost per namespace and workload from pod resource requests",
+		EnvVars: []string{"KUBERNETES_DISCOVERY"},
+	},
+	&cli.StringFlag{
+		Name:    "kubernetes-api-url",
+		Usage:   "Kubernetes API server URL to use without authentication (e.g., http://localhost:8001 with kubectl proxy); defaults to the in-cluster service account",
+		EnvVars: []string{"KUBERNETES_API_URL"},
+	},
+	&cli.BoolFlag{
+		Name:    "nomad-discovery",
+		Usage:   "Price the Nomad cluster's client nodes and export estimated cost per namespace and job from allocated resources",
+		EnvVars: []string{"NOMAD_DISCOVERY"},
+	},
+	&cli.StringFlag{
+		Name:    "nomad-address",
+		Usage:   "Nomad HTTP API address",
+		EnvVars: []string{"NOMAD_ADDR"},
+		Value:   "http://127.0.0.1:4646",
+	},
+	&cli.StringFlag{
+		Name:    "nomad-token",
+		Usage:   "Nomad ACL token with node:read and namespace read-job access",
+		EnvVars: []string{"NOMAD_TOKEN"},
+	},
+	&cli.StringFlag{
+		Name:    "nomad-token-file",
+		Usage:   "File holding the Nomad ACL token, read again when it changes",
+		EnvVars: []string{"NOMAD_TOKEN_FILE"},
+	},
+	&cli.StringSliceFlag{
+		Name:    "ecs-discovery-regions",
+		Usage:   "Price the EC2 container instances of every ECS cluster in these regions and export estimated cost per cluster and service",
+		EnvVars: []string{"ECS_DISCOVERY_REGIONS"},
+	},
+	&cli.StringFlag{
+		Name:    "autoscaler-expander-listen-address",
+		Usage:   "Serve the cluster-autoscaler gRPC expander on this address so it scales up the cheapest node group (e.g., :7000)",
+		EnvVars: []string{"AUTOSCALER_EXPANDER_LISTEN_ADDRESS"},
+	},
+	&cli.StringFlag{
+		Name:    "autoscaler-expander-tls-cert",
+		Usage:   "TLS certificate for the cluster-autoscaler expander",
+		EnvVars: []string{"AUTOSCALER_EXPANDER_TLS_CERT"},
+	},
+	&cli.StringFlag{
+		Name:    "autoscaler-expander-tls-key",
+		Usage:   "TLS private key for the cluster-autoscaler expander",
+		EnvVars: []string{"AUTOSCALER_EXPANDER_TLS_KEY"},
+	},
+	&cli.StringFlag{
+		Name:    "usage-weights-file",
+		Usage:   "Export a usage-weighted cost per vCPU for each region from the fleet share of each instance type in this JSON file",
+		EnvVars: []string{"USAGE_WEIGHTS_FILE"},
+	},
+	&cli.StringFlag{
+		Name:    "billing-entities-file",
+		Usage:   "Label each monitored region with the billing entity and currency of the first rule matching it in this JSON file, ahead of the built-in defaults",
+		EnvVars: []string{"BILLING_ENTITIES_FILE"},
+	},
+	&cli.StringFlag{
+		Name:    "sla-assumptions-file",
+		Usage:   "Export an effective cost per hour including over-provisioning from the availability and interruption assumptions in this JSON file",
+		EnvVars: []string{"SLA_ASSUMPTIONS_FILE"},
+	},
+	&cli.StringFlag{
+		Name:    "price-overrides-file",
+		Usage:   "Publish the prices in this JSON file, or the fetched prices scaled by its multipliers, instead of the fetched prices of the series its rules match",
+		EnvVars: []string{"PRICE_OVERRIDES_FILE"},
+	},
+	&cli.StringFlag{
+		Name:    "scenarios-file",
+		Usage:   "Export every price under the hypothetical price changes of each scenario in this JSON file, such as +5% on AWS from a date, as cloud_vm_scenario_cost_per_hour",
+		EnvVars: []string{"SCENARIOS_FILE"},
+	},
+	&cli.StringFlag{
+		Name:    "derived-metrics-file",
+		Usage:   "JSON file of custom gauges to compute from every price with an expression, such as TotalCost / (VCPUs*0.6 + MemoryGB*0.1)",
+		EnvVars: []string{"DERIVED_METRICS_FILE"},
+	},
+	&cli.StringFlag{
+		Name:    "metric-naming-file",
+		Usage:   "JSON file of templates to name and label the per-series price gauges with, such as acme_{{.Provider}}_vm_cost_per_hour",
+		EnvVars: []string{"METRIC_NAMING_FILE"},
+	},
+	&cli.BoolFlag{
+		Name:    "legacy-metrics",
+		Usage:   "Export metrics as they were before a rename or added labels until their deprecation window ends, for dashboards and recording rules that haven't moved yet",
+		EnvVars: []string{"LEGACY_METRICS"},
+	},
+	&cli.StringFlag{
+		Name:    "compare-current-file",
+		Usage:   "Fleet to export the cost of against --compare-proposed-file, in the format of POST /api/v1/simulate",
+		EnvVars: []string{"COMPARE_CURRENT_FILE"},
+	},
+	&cli.StringFlag{
+		Name:    "compare-proposed-file",
+		Usage:   "Fleet to export the cost of, and the cost difference to, --compare-current-file, in the format of POST /api/v1/simulate",
+		EnvVars: []string{"COMPARE_PROPOSED_FILE"},
+	},
+	&cli.StringFlag{
+		Name:    "fleet-config-file",
+		Usage:   "Export the per-unit on-demand, spot, and blended cost of the Auto Scaling groups and EC2 Fleet configurations in this JSON file",
+		EnvVars: []string{"FLEET_CONFIG_FILE"},
+	},
+	&cli.StringFlag{
+		Name:    "software-config-file",
+		Usage:   "Export the hourly fee of the paid operating systems, pre-installed software, and Marketplace products in this JSON file on every monitored AWS instance type",
+		EnvVars: []string{"SOFTWARE_CONFIG_FILE"},
+	},
+	&cli.StringFlag{
+		Name:    "gcp-template-config-file",
+		Usage:   "Export the all-in hourly cost of the GCP instance templates and managed instance groups in this JSON file",
+		EnvVars: []string{"GCP_TEMPLATE_CONFIG_FILE"},
+	},
+	&cli.Float64Flag{
+		Name:    "regression-threshold",
+		Usage:   "Percent increase over the previous price above which a sustained increase is reported (0 disables)",
+		EnvVars: []string{"REGRESSION_THRESHOLD"},
+	},
+	&cli.IntFlag{
+		Name:    "regression-polls",
+		Usage:   "Consecutive polls an increase must last before it is reported",
+		EnvVars: []string{"REGRESSION_POLLS"},
+		Value:   3,
+	},
+	&cli.StringFlag{
+		Name:    "alert-rules-file",
+		Usage:   "JSON file of alert rules evaluated over the prices after each poll, such as SpotCost > 0.8 * TotalCost for 3 polls",
+		EnvVars: []string{"ALERT_RULES_FILE"},
+	},
+	&cli.StringFlag{
+		Name:    "hook-script",
+		Usage:   "Starlark script whose on_poll(prices, state) runs after each poll, to export metrics of its own with metric() and open issues with notify()",
+		EnvVars: []string{"HOOK_SCRIPT"},
+	},
+	&cli.StringFlag{
+		Name:    "github-issues-repo",
+		Usage:   "Open an issue in this GitHub repository (owner/name) for each sustained price increase, firing alert, and hook script notification",
+		EnvVars: []string{"GITHUB_ISSUES_REPO"},
+	},
+	&cli.StringFlag{
+		Name:    "github-api-url",
+		Usage:   "GitHub REST API URL, for GitHub Enterprise Server",
+		EnvVars: []string{"GITHUB_API_URL"},
+		Value:   "https://api.github.com",
+	},
+	&cli.StringFlag{
+		Name:    "github-token",
+		Usage:   "GitHub token with permission to create issues",
+		EnvVars: []string{"GITHUB_TOKEN"},
+	},
+	&cli.StringFlag{
+		Name:    "github-token-file",
+		Usage:   "File holding the GitHub token, read again when it changes",
+		EnvVars: []string{"GITHUB_TOKEN_FILE"},
+	},
+	&cli.StringSliceFlag{
+		Name:    "github-issue-labels",
+		Usage:   "Labels to add to opened GitHub issues",
+		EnvVars: []string{"GITHUB_ISSUE_LABELS"},
+	},
+	&cli.StringFlag{
+		Name:    "jira-url",
+		Usage:   "Open an issue in Jira at this URL for each sustained price increase, firing alert, and hook script notification",
+		EnvVars: []string{"JIRA_URL"},
+	},
+	&cli.StringFlag{
+		Name:    "jira-project",
+		Usage:   "Key of the Jira project to open issues in",
+		EnvVars: []string{"JIRA_PROJECT"},
+	},
+	&cli.StringFlag{
+		Name:    "jira-issue-type",
+		Usage:   "Type of the opened Jira issues",
+		EnvVars: []string{"JIRA_ISSUE_TYPE"},
+		Value:   "Task",
+	},
+	&cli.StringFlag{
+		Name:    "jira-user",
+		Usage:   "Jira account email, for API token authentication on Jira Cloud (leave empty to use a personal access token)",
+		EnvVars: []string{"JIRA_USER"},
+	},
+	&cli.StringFlag{
+		Name:    "jira-token",
+		Usage:   "Jira API token or personal access token",
+		EnvVars: []string{"JIRA_TOKEN"},
+	},
+	&cli.StringFlag{
+		Name:    "jira-token-file",
+		Usage:   "File holding the Jira token, read again when it changes",
+		EnvVars: []string{"JIRA_TOKEN_FILE"},
+	},
+	&cli.Float64Flag{
+		Name:    "consensus-threshold",
+		Usage:   "Percent change above which a new price must be confirmed before it is published (0 disables)",
+		EnvVars: []string{"CONSENSUS_THRESHOLD"},
+	},
+	&cli.StringFlag{
+		Name:    "consensus-mode",
+		Usage:   "How large price changes are confirmed: refetch (fetch again immediately) or consecutive (require the new price on the next poll)",
+		EnvVars: []string{"CONSENSUS_MODE"},
+		Value:   consensusRefetch,
+	},
+	&cli.Float64Flag{
+		Name:    "canary-threshold",
+		Usage:   "Percent of a provider's published series that may change or fail to resolve in a poll before its new prices are held back as a likely catalog format change (0 disables)",
+		EnvVars: []string{"CANARY_THRESHOLD"},
+	},
+	&cli.IntFlag{
+		Name:    "price-decimal-places",
+		Usage:   "Round published prices to this many decimal places (0 leaves prices unrounded)",
+		EnvVars: []string{"PRICE_DECIMAL_PLACES"},
+	},
+	&cli.IntFlag{
+		Name:    "price-significant-digits",
+		Usage:   "Round published prices to this many significant digits instead of a fixed number of decimal places (0 leaves prices unrounded)",
+		EnvVars: []string{"PRICE_SIGNIFICANT_DIGITS"},
+	},
+	&cli.StringFlag{
+		Name:    "price-rounding-mode",
+		Usage:   "How prices halfway between two rounded values are rounded: half-even (to the even digit, banker's rounding) or half-up (away from zero)",
+		EnvVars: []string{"PRICE_ROUNDING_MODE"},
+		Value:   roundingHalfEven,
+	},
+	&cli.StringFlag{
+		Name:    "memory-unit",
+		Usage:   "Unit to publish memory sizes and per-memory costs in: GB (10^9 bytes) or GiB (2^30 bytes)",
+		EnvVars: []string{"MEMORY_UNIT"},
+		Value:   string(memoryUnitGB),
+	},
+	&cli.Float64Flag{
+		Name:    "normalized-vcpu-weight",
+		Usage:   "Weight of vCPUs in the normalized unit cloud_vm_normalized_cost_score prices, from 0 to 1, with memory weighing the rest",
+		EnvVars: []string{"NORMALIZED_VCPU_WEIGHT"},
+		Value:   defaultNormalizedVCPUWeight,
+	},
+	&cli.Float64Flag{
+		Name:    "normalized-memory-per-vcpu",
+		Usage:   "Memory of a vCPU in the normalized unit, in the memory unit",
+		EnvVars: []string{"NORMALIZED_MEMORY_PER_VCPU"},
+		Value:   defaultNormalizedMemoryPerVCPU,
+	},
+	&cli.BoolFlag{
+		Name:    "check-permissions",
+		Usage:   "Check at startup that the cloud credentials allow the calls of every enabled feature, and report the features that are unavailable",
+		EnvVars: []string{"CHECK_PERMISSIONS"},
+		Value:   true,
+	},
+	&cli.DurationFlag{
+		Name:    "push-interval",
+		Usage:   "How often to push the published prices to the enabled push sinks",
+		EnvVars: []string{"PUSH_INTERVAL"},
+		Value:   time.Minute,
+	},
+	&cli.StringFlag{
+		Name:    "statsd-address",
+		Usage:   "Push the pricing gauges to a StatsD server at this UDP host:port",
+		EnvVars: []string{"STATSD_ADDRESS"},
+	},
+	&cli.StringFlag{
+		Name:    "dogstatsd-address",
+		Usage:   "Push the pricing gauges, tagged with their labels, to a Datadog agent at this UDP host:port or unix:///path socket",
+		EnvVars: []string{"DOGSTATSD_ADDRESS"},
+	},
+	&cli.StringFlag{
+		Name:    "statsd-prefix",
+		Usage:   "Prefix of the metric names pushed to StatsD and DogStatsD",
+		EnvVars: []string{"STATSD_PREFIX"},
+	},
+	&cli.StringSliceFlag{
+		Name:    "dogstatsd-tags",
+		Usage:   "Tags to add to every gauge pushed to DogStatsD (e.g., env:prod)",
+		EnvVars: []string{"DOGSTATSD_TAGS"},
+	},
+	&cli.StringFlag{
+		Name:    "influxdb-url",
+		Usage:   "Push the pricing gauges in line protocol to InfluxDB at this URL",
+		EnvVars: []string{"INFLUXDB_URL"},
+	},
+	&cli.StringFlag{
+		Name:    "influxdb-org",
+		Usage:   "InfluxDB organization to write to",
+		EnvVars: []string{"INFLUXDB_ORG"},
+	},
+	&cli.StringFlag{
+		Name:    "influxdb-bucket",
+		Usage:   "InfluxDB bucket to write to (database/retention-policy on InfluxDB 1.8)",
+		EnvVars: []string{"INFLUXDB_BUCKET"},
+	},
+	&cli.StringFlag{
+		Name:    "influxdb-token",
+		Usage:   "InfluxDB API token (username:password on InfluxDB 1.8)",
+		EnvVars: []string{"INFLUXDB_TOKEN"},
+	},
+	&cli.StringFlag{
+		Name:    "influxdb-token-file",
+		Usage:   "File holding the InfluxDB API token, read again when it changes",
+		EnvVars: []string{"INFLUXDB_TOKEN_FILE"},
+	},
+	&cli.StringFlag{
+		Name:    "graphite-address",
+		Usage:   "Push the pricing gauges to a Graphite Carbon plaintext receiver at this TCP host:port",
+		EnvVars: []string{"GRAPHITE_ADDRESS"},
+	},
+	&cli.StringFlag{
+		Name:    "graphite-prefix",
+		Usage:   "Prefix of the metric paths pushed to Graphite",
+		EnvVars: []string{"GRAPHITE_PREFIX"},
+	},
+	&cli.StringFlag{
+		Name:    "mqtt-broker",
+		Usage:   "Publish each price change as a JSON price record to the MQTT broker at this URL (tcp://, ssl://, or ws://)",
+		EnvVars: []string{"MQTT_BROKER"},
+	},
+	&cli.StringFlag{
+		Name:    "mqtt-topic-prefix",
+		Usage:   "Prefix of the <provider>/<region>/<instance type> topics price updates are published to",
+		EnvVars: []string{"MQTT_TOPIC_PREFIX"},
+		Value:   "cloud-pricing",
+	},
+	&cli.StringFlag{
+		Name:    "mqtt-client-id",
+		Usage:   "MQTT client ID (default cloud-pricing-monitor-<hostname>)",
+		EnvVars: []string{"MQTT_CLIENT_ID"},
+	},
+	&cli.StringFlag{
+		Name:    "mqtt-username",
+		Usage:   "MQTT username",
+		EnvVars: []string{"MQTT_USERNAME"},
+	},
+	&cli.StringFlag{
+		Name:    "mqtt-password",
+		Usage:   "MQTT password",
+		EnvVars: []string{"MQTT_PASSWORD"},
+	},
+	&cli.StringFlag{
+		Name:    "mqtt-password-file",
+		Usage:   "File holding the MQTT password, read again when it changes",
+		EnvVars: []string{"MQTT_PASSWORD_FILE"},
+	},
+	&cli.UintFlag{
+		Name:    "mqtt-qos",
+		Usage:   "QoS level of published price updates (0, 1, or 2)",
+		EnvVars: []string{"MQTT_QOS"},
+		Value:   1,
+	},
+	&cli.BoolFlag{
+		Name:    "mqtt-retain",
+		Usage:   "Publish price updates as retained messages, so new subscribers receive the current price of every series",
+		EnvVars: []string{"MQTT_RETAIN"},
+		Value:   true,
+	},
+}
+
+// Run starts the monitoring daemon and serves its metrics and API until interrupted
+func Run(cctx *cli.Context) error {
+	return RunWith(cctx, Server{})
+}
+
+// RunWith is Run for programs embedding the monitor, mounting its metrics and API where the
+// server says
+func RunWith(cctx *cli.Context, server Server) error {
+	ctx, cancel := context.WithCancel(cctx.Context)
+	defer cancel()
+
+	// Set up logging
+	logger := telemetry.StartLogger(cctx)
+	server = server.withDefaults()
+	mux, err := server.serveMetrics(cctx)
+	if err != nil {
+		return err
+	}
+	var legacyMetrics []metricMigration
+	if cctx.Bool("legacy-metrics") {
+		warnLegacyMetrics(logger, time.Now())
+		legacyMetrics = activeMigrations(time.Now())
+	}
+
+	// Validate that at least one cloud provider is configured
+	list := flagWatchList(cctx)
+
+	kubernetesDiscovery := cctx.Bool("kubernetes-discovery")
+	nomadDiscovery := cctx.Bool("nomad-discovery")
+	ecsRegions := cctx.StringSlice("ecs-discovery-regions")
+	fleetConfigFile := cctx.String("fleet-config-file")
+	gcpTemplateConfigFile := cctx.String("gcp-template-config-file")
+	bundlePath := cctx.String("offline-bundle")
+	if !list.hasRegions() && !kubernetesDiscovery && !nomadDiscovery && len(ecsRegions) == 0 && fleetConfigFile == "" && gcpTemplateConfigFile == "" && bundlePath == "" {
+		return fmt.Errorf("must specify at least one AWS, GCP, or Azure region, enable cluster discovery, configure fleets or templates, or serve an offline bundle")
+	}
+
+	if bundlePath != "" {
+		if err := checkOfflineFlags(cctx); err != nil {
+			return err
+		}
+
+		// Clients fall back to the default transport, so this catches any request made by mistake
+		http.DefaultTransport = offlineTransport{}
+	}
+
+	for provider, flags := range watchListFlags {
+		if len(list[provider].regions) > 0 && len(list[provider].instanceTypes) == 0 {
+			return fmt.Errorf("%s specified but no %s provided", flags.regions, flags.instanceTypes)
+		}
+	}
+
+	if len(cctx.StringSlice("gcp-gpu-types")) > 0 && len(list["gcp"].regions) == 0 {
+		return fmt.Errorf("gcp-gpu-types requires gcp-regions to price the GPUs in")
+	}
+
+	if len(cctx.StringSlice("aws-volume-types")) > 0 && len(list["aws"].regions) == 0 {
+		return fmt.Errorf("aws-volume-types requires aws-regions to price the volumes in")
+	}
+
+	if len(cctx.StringSlice("gcp-disk-types")) > 0 && len(list["gcp"].regions) == 0 {
+		return fmt.Errorf("gcp-disk-types requires gcp-regions to price the disks in")
+	}
+	for _, diskType := range cctx.StringSlice("gcp-disk-types") {
+		if _, ok := gcpDiskSkuProducts[diskType]; !ok {
+			return fmt.Errorf("unknown GCP disk type %s", diskType)
+		}
+	}
+
+	// Discovery, fleets, and templates can add instance types later, so these only warn
+	for _, instanceType := range cctx.StringSlice("priority-instance-types") {
+		configured := false
+		for _, watched := range list {
+			configured = configured || matchesInstanceTypeGlob(instanceType, watched.instanceTypes)
+		}
+		if !configured {
+			logger.Warn("priority instance type is not configured for any provider", "instance_type", instanceType)
+		}
+	}
+
+	if (cctx.Bool("track-availability") || cctx.Bool("export-quota-ceilings")) && len(list["gcp"].regions) > 0 && cctx.String("gcp-project") == "" {
+		return fmt.Errorf("track-availability and export-quota-ceilings require gcp-project to check GCP regions")
+	}
+	if cctx.Bool("export-size-steps") && len(list["gcp"].regions) > 0 && cctx.String("gcp-project") == "" {
+		return fmt.Errorf("export-size-steps requires gcp-project to list the sizes of GCP machine types")
+	}
+
+	priceListPin, err := ParsePriceListPin(cctx.String("aws-price-list-version"), cctx.String("aws-price-list-date"))
+	if err != nil {
+		return err
+	}
+	if priceListPin != nil && cctx.Bool("aws-bulk-pricing") {
+		return fmt.Errorf("aws-bulk-pricing can't be combined with a pinned price list")
+	}
+
+	registry, err := newProviderRegistry(priceListPin, cctx.Bool("aws-bulk-pricing"), cctx.String("gcp-billing-account"))
+	if err != nil {
+		return err
+	}
+
+	shards, err := NewShardConfig(cctx.Int("shard-count"), cctx.Int("shard-index"), cctx.StringSlice("shard-peers"))
+	if err != nil {
+		return err
+	}
+
+	if len(shards.Peers) > 0 {
+		groups := shards.TargetGroups(cctx.String("metrics-path"), registry.Names(), list.regions())
+		mux.Handle("GET /api/v1/sd", sdHandler(groups))
+
+		if path := cctx.String("sd-file"); path != "" {
+			if err := writeSDFile(path, groups); err != nil {
+				return err
+			}
+		}
+	} else if cctx.String("sd-file") != "" {
+		return fmt.Errorf("sd-file requires shard-peers")
+	}
+
+	for provider, watched := range list {
+		watched.regions = shards.Filter(provider, watched.regions)
+		list[provider] = watched
+	}
+	if !list.hasRegions() {
+		logger.Warn("no regions assigned to this shard", "shard_index", shards.Index, "shard_count", shards.Count)
+	}
+
+	var consensus *PriceConsensus
+	if threshold := cctx.Float64("consensus-threshold"); threshold != 0 {
+		var err error
+		consensus, err = NewPriceConsensus(threshold, cctx.String("consensus-mode"))
+		if err != nil {
+			return err
+		}
+	}
+
+	var canary *CanaryCheck
+	if threshold := cctx.Float64("canary-threshold"); threshold != 0 {
+		var err error
+		canary, err = NewCanaryCheck(threshold)
+		if err != nil {
+			return err
+		}
+	}
+
+	rounding, err := NewPriceRounding(cctx.Int("price-decimal-places"), cctx.Int("price-significant-digits"), cctx.String("price-rounding-mode"))
+	if err != nil {
+		return err
+	}
+
+	memoryUnit, err := ParseMemoryUnit(cctx.String("memory-unit"))
+	if err != nil {
+		return err
+	}
+
+	normalization, err := NewCostNormalization(cctx.Float64("normalized-vcpu-weight"), cctx.Float64("normalized-memory-per-vcpu"), memoryUnit)
+	if err != nil {
+		return err
+	}
+
+	started := []any{"version", cctx.App.Version}
+	for _, provider := range registry.Names() {
+		flags := watchListFlags[provider]
+		started = append(started,
+			strings.ReplaceAll(flags.regions, "-", "_"), strings.Join(list[provider].regions, ","),
+			strings.ReplaceAll(flags.instanceTypes, "-", "_"), strings.Join(list[provider].instanceTypes, ","),
+		)
+	}
+	logger.Info("starting cloud pricing monitor", append(started,
+		"shard", fmt.Sprintf("%d/%d", shards.Index, shards.Count),
+		"poll_interval", cctx.Duration("poll-interval"),
+		"metrics_addr", cctx.String("metrics-addr"),
+	)...)
+
+	// Initialize metrics
+	metrics, err := NewMetrics(server.Registerer, memoryUnit)
+	if err != nil {
+		return err
+	}
+	metrics.SetPriceRounding(rounding)
+	ctx = withAPICallMetrics(ctx, metrics)
+
+	egress, err := egressFlags(cctx)
+	if err != nil {
+		return err
+	}
+	for _, provider := range slices.Sorted(maps.Keys(egress)) {
+		logger.Info("routing provider API requests", "provider", provider, "egress", egress[provider].String())
+	}
+	ctx = withEgress(ctx, egress)
+
+	var cache *sharedCache
+	if cacheURL := cctx.String("cache-url"); cacheURL != "" {
+		backend, err := NewRedisCache(cacheURL)
+		if err != nil {
+			return err
+		}
+		defer backend.Close()
+
+		ttl := cctx.Duration("cache-ttl")
+		if ttl < 0 {
+			return fmt.Errorf("cache-ttl must not be negative")
+		}
+		if ttl == 0 {
+			ttl = cctx.Duration("poll-interval")
+		}
+		cache = &sharedCache{
+			backend: backend,
+			prefix:  cctx.String("cache-key-prefix"),
+			ttl:     ttl,
+			metrics: metrics,
+		}
+		ctx = withSharedCache(ctx, cache)
+		logger.Info("sharing fetched prices through cache", "cache_key_prefix", cache.prefix, "cache_ttl", ttl)
+	}
+
+	var syncFrom string
+	if peer := cctx.String("sync-from"); peer != "" {
+		syncFrom, err = syncPeerURL(peer)
+		if err != nil {
+			return err
+		}
+	}
+
+	limits, err := fetchLimitFlags(cctx, registry.Names(), metrics)
+	if err != nil {
+		return err
+	}
+	if cctx.Int("fetch-retries") < 0 || cctx.Duration("fetch-retry-backoff") < 0 {
+		return fmt.Errorf("fetch-retries and fetch-retry-backoff must not be negative")
+	}
+	stale, err := newStalePolicy(cctx.Int("stale-after-polls"), cctx.String("stale-series-action"))
+	if err != nil {
+		return err
+	}
+	snapshot := NewPriceSnapshot()
+
+	var naming *MetricNaming
+	if path := cctx.String("metric-naming-file"); path != "" {
+		naming, err = LoadMetricNaming(path)
+		if err != nil {
+			return err
+		}
+		if err := naming.Validate(configuredPriceKeys(registry.Names(), list, flagConfidential(cctx))); err != nil {
+			return err
+		}
+		if err := metrics.ExportWithNaming(snapshot, naming, cctx.Bool("export-timestamps")); err != nil {
+			return err
+		}
+		logger.Info("loaded metric naming", "metric_naming_file", path)
+	} else if cctx.Bool("export-timestamps") {
+		if err := metrics.ExportWithTimestamps(snapshot); err != nil {
+			return err
+		}
+	}
+
+	var history HistoryStore
+	if path := cctx.String("history-file"); path != "" {
+		history, err = NewFileHistoryStore(path)
+		if err != nil {
+			return err
+		}
+		defer history.Close()
+	}
+
+	var baseline *Baseline
+	if path := cctx.String("baseline-file"); path != "" {
+		baseline, err = LoadBaseline(path, cctx.Float64("baseline-margin"))
+		if err != nil {
+			return err
+		}
+		logger.Info("loaded baseline prices", "baseline_file", path, "series", baseline.Len())
+	}
+
+	var weights UsageWeights
+	if path := cctx.String("usage-weights-file"); path != "" {
+		weights, err = LoadUsageWeights(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	var slaAssumptions SLAAssumptions
+	if path := cctx.String("sla-assumptions-file"); path != "" {
+		slaAssumptions, err = LoadSLAAssumptions(path)
+		if err != nil {
+			return err
+		}
+		logger.Info("loaded SLA assumptions", "sla_assumptions_file", path, "rules", len(slaAssumptions))
+	}
+
+	var billingEntities BillingEntities
+	if path := cctx.String("billing-entities-file"); path != "" {
+		billingEntities, err = LoadBillingEntities(path)
+		if err != nil {
+			return err
+		}
+		logger.Info("loaded billing entities", "billing_entities_file", path, "rules", len(billingEntities))
+	}
+
+	var overrides PriceOverrides
+	if path := cctx.String("price-overrides-file"); path != "" {
+		overrides, err = LoadPriceOverrides(path)
+		if err != nil {
+			return err
+		}
+		logger.Info("loaded price overrides", "price_overrides_file", path, "rules", len(overrides))
+	}
+
+	var scenarios PriceScenarios
+	if path := cctx.String("scenarios-file"); path != "" {
+		scenarios, err = LoadPriceScenarios(path)
+		if err != nil {
+			return err
+		}
+		logger.Info("loaded price scenarios", "scenarios_file", path, "scenarios", len(scenarios))
+	}
+
+	if path := cctx.String("derived-metrics-file"); path != "" {
+		derived, err := LoadDerivedMetrics(path)
+		if err != nil {
+			return err
+		}
+		if err := metrics.RegisterDerivedMetrics(derived); err != nil {
+			return err
+		}
+		logger.Info("loaded derived metrics", "derived_metrics_file", path, "metrics", len(derived))
+	}
+
+	var comparison *FleetComparison
+	currentFleet, proposedFleet := cctx.String("compare-current-file"), cctx.String("compare-proposed-file")
+	if (currentFleet == "") != (proposedFleet == "") {
+		return fmt.Errorf("--compare-current-file and --compare-proposed-file must be given together")
+	}
+	// Both fleets are priced in full, so their items can't be split across shards like fleets are
+	if currentFleet != "" && shards.Count > 1 {
+		return fmt.Errorf("--compare-current-file and --compare-proposed-file can't be combined with --shard-count, run the comparison on an unsharded replica")
+	}
+	if currentFleet != "" {
+		comparison, err = LoadFleetComparison(currentFleet, proposedFleet)
+		if err != nil {
+			return err
+		}
+		logger.Info("loaded fleet comparison", "current_items", len(comparison.Current.Items), "proposed_items", len(comparison.Proposed.Items))
+	}
+
+	var fleets []FleetConfig
+	if fleetConfigFile != "" {
+		fleets, err = LoadFleetConfigs(fleetConfigFile)
+		if err != nil {
+			return err
+		}
+
+		// Each fleet is priced by the shard that owns its region
+		fleets = slices.DeleteFunc(fleets, func(f FleetConfig) bool {
+			return len(shards.Filter("aws", []string{f.Region})) == 0
+		})
+	}
+
+	var software []SoftwareProduct
+	if path := cctx.String("software-config-file"); path != "" {
+		software, err = LoadSoftwareProducts(path)
+		if err != nil {
+			return err
+		}
+		logger.Info("loaded software products", "software_config_file", path, "products", len(software))
+	}
+
+	var awsAccounts []AWSAccount
+	if path := cctx.String("aws-accounts-file"); path != "" {
+		awsAccounts, err = LoadAWSAccounts(path)
+		if err != nil {
+			return err
+		}
+		logger.Info("loaded AWS accounts", "aws_accounts_file", path, "accounts", len(awsAccounts))
+	}
+
+	var gcpTemplates []GCPTemplateConfig
+	if gcpTemplateConfigFile != "" {
+		gcpTemplates, err = LoadGCPTemplateConfigs(gcpTemplateConfigFile)
+		if err != nil {
+			return err
+		}
+
+		gcpTemplates = slices.DeleteFunc(gcpTemplates, func(t GCPTemplateConfig) bool {
+			return len(shards.Filter("gcp", []string{t.PricingRegion()})) == 0
+		})
+	}
+
+	var bundle *PriceBundle
+	if bundlePath != "" {
+		bundle, err = LoadBundle(bundlePath)
+		if err != nil {
+			return err
+		}
+
+		bundle.Prices = slices.DeleteFunc(bundle.Prices, func(r PriceRecord) bool {
+			return len(shards.Filter(r.Provider, []string{r.Region})) == 0
+		})
+		logger.Info("loaded offline bundle", "offline_bundle", bundlePath, "created_at", bundle.CreatedAt, "series", len(bundle.Prices))
+	}
+
+	var regressions *RegressionTracker
+	if threshold := cctx.Float64("regression-threshold"); threshold != 0 {
+		regressions, err = NewRegressionTracker(threshold, cctx.Int("regression-polls"))
+		if err != nil {
+			return err
+		}
+	}
+
+	var alerts *AlertEvaluator
+	if path := cctx.String("alert-rules-file"); path != "" {
+		rules, err := LoadAlertRules(path)
+		if err != nil {
+			return err
+		}
+		alerts = NewAlertEvaluator(rules)
+		logger.Info("loaded alert rules", "alert_rules_file", path, "rules", len(rules))
+	}
+
+	var hook *HookScript
+	if path := cctx.String("hook-script"); path != "" {
+		hook, err = LoadHookScript(path)
+		if err != nil {
+			return err
+		}
+		if err := metrics.RegisterHookScript(hook); err != nil {
+			return err
+		}
+		logger.Info("loaded hook script", "hook_script", path)
+	}
+
+	var issueTrackers []IssueTracker
+	if regressions != nil || alerts != nil || hook != nil {
+		if repo := cctx.String("github-issues-repo"); repo != "" {
+			token, err := NewSecret(cctx.String("github-token"), cctx.String("github-token-file"))
+			if err != nil {
+				return fmt.Errorf("failed to load GitHub token: %w", err)
+			}
+			tracker, err := NewGitHubIssues(cctx.String("github-api-url"), repo, token, cctx.StringSlice("github-issue-labels"))
+			if err != nil {
+				return err
+			}
+			issueTrackers = append(issueTrackers, tracker)
+		}
+
+		if url := cctx.String("jira-url"); url != "" {
+			token, err := NewSecret(cctx.String("jira-token"), cctx.String("jira-token-file"))
+			if err != nil {
+				return fmt.Errorf("failed to load Jira token: %w", err)
+			}
+			tracker, err := NewJiraIssues(url, cctx.String("jira-project"), cctx.String("jira-issue-type"), cctx.String("jira-user"), token)
+			if err != nil {
+				return err
+			}
+			issueTrackers = append(issueTrackers, tracker)
+		}
+	} else if cctx.String("github-issues-repo") != "" || cctx.String("jira-url") != "" {
+		return fmt.Errorf("opening issues requires regression-threshold, alert-rules-file, or hook-script")
+	}
+
+	var discoverers []ClusterDiscoverer
+	if kubernetesDiscovery {
+		discoverer, err := NewKubernetesDiscoverer(cctx.String("kubernetes-api-url"))
+		if err != nil {
+			return err
+		}
+		discoverers = append(discoverers, discoverer)
+	}
+
+	if nomadDiscovery {
+		token, err := NewSecret(cctx.String("nomad-token"), cctx.String("nomad-token-file"))
+		if err != nil {
+			return fmt.Errorf("failed to load Nomad token: %w", err)
+		}
+		discoverers = append(discoverers, NewNomadDiscoverer(cctx.String("nomad-address"), token))
+	}
+
+	if len(ecsRegions) > 0 {
+		discoverer, err := NewECSDiscoverer(ctx, ecsRegions)
+		if err != nil {
+			return err
+		}
+		discoverers = append(discoverers, discoverer)
+	}
+
+	var sinks []Sink
+	if addr := cctx.String("statsd-address"); addr != "" {
+		sink, err := NewStatsDSink(addr, cctx.String("statsd-prefix"))
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, sink)
+	}
+	if addr := cctx.String("dogstatsd-address"); addr != "" {
+		sink, err := NewDogStatsDSink(addr, cctx.String("statsd-prefix"), cctx.StringSlice("dogstatsd-tags"))
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, sink)
+	}
+	if url := cctx.String("influxdb-url"); url != "" {
+		token, err := NewSecret(cctx.String("influxdb-token"), cctx.String("influxdb-token-file"))
+		if err != nil {
+			return fmt.Errorf("failed to load InfluxDB token: %w", err)
+		}
+		sink, err := NewInfluxDBSink(url, cctx.String("influxdb-org"), cctx.String("influxdb-bucket"), token)
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, sink)
+	}
+	if addr := cctx.String("graphite-address"); addr != "" {
+		sink, err := NewGraphiteSink(addr, cctx.String("graphite-prefix"))
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, sink)
+	}
+	var schedule *AdaptiveSchedule
+	if cctx.Bool("adaptive-polling") {
+		minInterval, maxInterval := cctx.Duration("min-poll-interval"), cctx.Duration("max-poll-interval")
+		pollInterval := cctx.Duration("poll-interval")
+		if minInterval <= 0 || minInterval > pollInterval || maxInterval < pollInterval {
+			return fmt.Errorf("adaptive-polling requires 0 < min-poll-interval <= poll-interval <= max-poll-interval")
+		}
+		schedule = NewAdaptiveSchedule(minInterval, pollInterval, maxInterval)
+	}
+
+	var window *PollWindow
+	if expr := cctx.String("poll-window"); expr != "" {
+		if window, err = ParsePollWindow(expr, cctx.String("poll-window-timezone")); err != nil {
+			return err
+		}
+	}
+
+	var capacityBlockDuration time.Duration
+	if cctx.Bool("aws-capacity-block-pricing") {
+		capacityBlockDuration = cctx.Duration("aws-capacity-block-duration")
+		if err := checkCapacityBlockDuration(capacityBlockDuration); err != nil {
+			return err
+		}
+	}
+
+	if cctx.Duration("fetcher-init-timeout") < 0 {
+		return fmt.Errorf("fetcher-init-timeout must not be negative")
+	}
+	if len(sinks) > 0 && cctx.Duration("push-interval") <= 0 {
+		return fmt.Errorf("push-interval must be positive")
+	}
+
+	var mqttPublisher *MQTTPublisher
+	if broker := cctx.String("mqtt-broker"); broker != "" {
+		password, err := NewSecret(cctx.String("mqtt-password"), cctx.String("mqtt-password-file"))
+		if err != nil {
+			return fmt.Errorf("failed to load MQTT password: %w", err)
+		}
+		if cctx.Uint("mqtt-qos") > 2 {
+			return fmt.Errorf("mqtt-qos must be 0, 1, or 2")
+		}
+		mqttPublisher, err = NewMQTTPublisher(MQTTConfig{
+			Broker:      broker,
+			ClientID:    cctx.String("mqtt-client-id"),
+			Username:    cctx.String("mqtt-username"),
+			Password:    password,
+			TopicPrefix: cctx.String("mqtt-topic-prefix"),
+			QoS:         byte(cctx.Uint("mqtt-qos")),
+			Retain:      cctx.Bool("mqtt-retain"),
+		}, metrics)
+		if err != nil {
+			return err
+		}
+		defer mqttPublisher.Close()
+	}
+
+	mux.Handle("GET /api/v1/karpenter/pricing", karpenterPricingHandler(snapshot, rounding))
+	mux.Handle("POST /api/v1/simulate", simulateHandler(snapshot, rounding))
+
+	// Create monitor
+	statuses := NewFetchStatuses()
+	monitor := &Monitor{
+		watching:         list.watching(),
+		confidential:     flagConfidential(cctx),
+		gcpGPUTypes:      cctx.StringSlice("gcp-gpu-types"),
+		awsVolumeTypes:   cctx.StringSlice("aws-volume-types"),
+		gcpDiskTypes:     cctx.StringSlice("gcp-disk-types"),
+		fileStorage:      cctx.Bool("export-file-storage"),
+		backupPricing:    cctx.Bool("export-backup-pricing"),
+		platformServices: cctx.Bool("export-platform-services"),
+		awsSpotPricing:   cctx.Bool("aws-spot-pricing"),
+		gcpSpotPricing:   cctx.Bool("gcp-spot-pricing"),
+		awsCommitments:   cctx.Bool("aws-commitment-pricing"),
+		pollInterval:     cctx.Duration("poll-interval"),
+		schedule:         schedule,
+		metrics:          metrics,
+		snapshot:         snapshot,
+		consensus:        consensus,
+		history:          history,
+		baseline:         baseline,
+		comparison:       comparison,
+		slaAssumptions:   slaAssumptions,
+		billingEntities:  billingEntities,
+		weights:          weights,
+		regressions:      regressions,
+		issueTrackers:    issueTrackers,
+		alerts:           alerts,
+		discoverers:      discoverers,
+		fleets:           fleets,
+		software:         software,
+		gcpTemplates:     gcpTemplates,
+		autoAddNewGens:   cctx.Bool("auto-add-new-generations"),
+		sizeSteps:        cctx.Bool("export-size-steps"),
+		availability:     cctx.Bool("track-availability"),
+		quotaCeilings:    cctx.Bool("export-quota-ceilings"),
+		gcpProject:       cctx.String("gcp-project"),
+		bundle:           bundle,
+		rounding:         rounding,
+		naming:           naming,
+		legacyMetrics:    legacyMetrics,
+		sinks:            sinks,
+		mqtt:             mqttPublisher,
+		pushInterval:     cctx.Duration("push-interval"),
+
+		awsPriceListPin:        priceListPin,
+		priceListCheckInterval: cctx.Duration("price-list-check-interval"),
+
+		awsCapacityBlockDuration: capacityBlockDuration,
+		awsAccounts:              awsAccounts,
+		gcpBillingAccount:        cctx.String("gcp-billing-account"),
+
+		fetcherInitTimeout: cctx.Duration("fetcher-init-timeout"),
+		priorityTypes:      cctx.StringSlice("priority-instance-types"),
+		window:             window,
+		statuses:           statuses,
+
+		registry:  registry,
+		providers: make(map[string]providers.PricingProvider),
+		cache:     cache,
+		limits:    limits,
+		retries: retryPolicy{
+			retries: cctx.Int("fetch-retries"),
+			backoff: cctx.Duration("fetch-retry-backoff"),
+		},
+		syncFrom:  syncFrom,
+		stale:     stale,
+		overrides: overrides,
+		scenarios: scenarios,
+		hook:      hook,
+
+		normalization: normalization,
+		shards:        shards,
+		canary:        canary,
+	}
+	if err := monitor.takeAllRegions(); err != nil {
+		return err
+	}
+	if err := monitor.takeInstanceTypeGlobs(); err != nil {
+		return err
+	}
+
+	if cctx.Bool("track-new-generations") {
+		monitor.generations = NewGenerationTracker()
+	}
+
+	mux.Handle("GET /api/v1/prices", pricesHandler(snapshot, monitor.fetchOverridden, monitor.refreshEntries, monitor.expiresAt, rounding, memoryUnit))
+	mux.Handle("GET /api/v1/pricing", pricingHandler(snapshot, statuses, monitor.expiresAt, rounding, memoryUnit))
+	initialized := func(name string) bool { return monitor.provider(name) != nil }
+	mux.Handle("GET /api/v1/providers", providersHandler(describeProviders(cctx, registry.Names(), list.regions()), statuses, initialized, bundle != nil))
+	mux.Handle("GET /api/v1/snapshot", snapshotHandler(snapshot, cctx.App.Version))
+	mux.Handle("GET /api/v1/history", historyHandler(history))
+	mux.Handle("GET /api/v1/query", queryHandler(snapshot, history, rounding, memoryUnit))
+	mux.Handle("GET /api/v1/explain", explainHandler(snapshot, monitor.traceFetch, overrides, rounding))
+	mux.Handle("GET /api/v1/schemas", schemasHandler())
+	mux.Handle("GET /api/v1/schemas/{name}", schemasHandler())
+	if cctx.Bool("match-debug") {
+		mux.Handle("GET /api/v1/debug/match", matchDebugHandler(monitor.traceFetch, rounding))
+	}
+
+	if cctx.Bool("check-permissions") && bundle == nil {
+		CheckPermissions(ctx, cctx, metrics)
+	}
+
+	// Start monitoring
+	if err := monitor.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start monitor: %w", err)
+	}
+
+	if addr := cctx.String("autoscaler-expander-listen-address"); addr != "" {
+		expander := NewPricingExpander(snapshot)
+		if err := expander.Serve(ctx, addr, cctx.String("autoscaler-expander-tls-cert"), cctx.String("autoscaler-expander-tls-key")); err != nil {
+			return err
+		}
+	}
+
+	// Handle graceful shutdown, reloading the watch list of the config file on SIGHUP
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := <-sigCh; sig == syscall.SIGHUP; sig = <-sigCh {
+		if cctx.String("config") == "" {
+			logger.Warn("ignoring SIGHUP, no config file to reload")
+			continue
+		}
+		list, err := loadWatchList(cctx)
+		if err != nil {
+			logger.Error("failed to reload config file, keeping the current watch list", "error", err)
+			continue
+		}
+		logger.Info("reloading config file", "config", cctx.String("config"))
+		monitor.requestReload(list)
+	}
+
+	logger.Info("shutting down...")
+	cancel()
+	time.Sleep(1 * time.Second)
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shopspring/decimal"
+)
+
+// metricNamePattern is the Prometheus metric name syntax
+var metricNamePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// derivedVars are the fields of a price a derived metric's expression can use
+var derivedVars = []string{"TotalCost", "VCPUs", "MemoryGB", "Memory", "CostPerVCPU", "CostPerMemory", "Confidential"}
+
+// DerivedMetric is a custom gauge computed from every published price with an expression,
+// such as a team's own normalized cost
+type DerivedMetric struct {
+	Name       string `json:"name"`
+	Help       string `json:"help,omitempty"`
+	Expression string `json:"expression"`
+
+	expr *Expr
+}
+
+// LoadDerivedMetrics reads derived metric definitions from a JSON file holding a list of them,
+// and parses their expressions
+func LoadDerivedMetrics(file string) ([]DerivedMetric, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read derived metrics file: %w", err)
+	}
+
+	var metrics []DerivedMetric
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		return nil, fmt.Errorf("failed to parse derived metrics file: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for i := range metrics {
+		d := &metrics[i]
+		if !metricNamePattern.MatchString(d.Name) {
+			return nil, fmt.Errorf("derived metric %d has an invalid Prometheus metric name %q", i, d.Name)
+		}
+		if names[d.Name] {
+			return nil, fmt.Errorf("derived metric %s is defined more than once", d.Name)
+		}
+		names[d.Name] = true
+
+		d.expr, err = ParseExpr(d.Expression, derivedVars)
+		if err != nil {
+			return nil, fmt.Errorf("derived metric %s: %w", d.Name, err)
+		}
+		if d.Help == "" {
+			d.Help = "Derived from prices as " + d.Expression
+		}
+	}
+
+	return metrics, nil
+}
+
+// derivedValues returns the fields of a price as expression variables. Fields that are unknown
+// for the price, such as the cost per vCPU without a vCPU count, are left out.
+func derivedValues(p VMPricing, memoryUnit MemoryUnit) map[string]decimal.Decimal {
+	vars := map[string]decimal.Decimal{
+		"TotalCost":    p.TotalCost,
+		"Confidential": decimal.Zero,
+	}
+	if p.Confidential {
+		vars["Confidential"] = decimal.NewFromInt(1)
+	}
+	if p.VCPUs > 0 {
+		vars["VCPUs"] = decimal.NewFromInt(int64(p.VCPUs))
+	}
+	if p.MemoryGB > 0 {
+		vars["MemoryGB"] = decimal.NewFromFloat(p.MemoryGB)
+		vars["Memory"] = memoryUnit.FromGB(p.MemoryGB)
+	}
+	if cost, ok := p.CostPerVCPU(); ok {
+		vars["CostPerVCPU"] = cost
+	}
+	if cost, ok := p.CostPerMemory(memoryUnit); ok {
+		vars["CostPerMemory"] = cost
+	}
+	return vars
+}
+
+// RegisterDerivedMetrics creates a gauge for each derived metric, labelled like the price
+// gauges. It fails when a name is taken by another metric.
+func (m *Metrics) RegisterDerivedMetrics(metrics []DerivedMetric) error {
+	for _, d := range metrics {
+		gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: d.Name, Help: d.Help}, vmPriceLabels)
+		if m.registerer != nil {
+			if err := m.registerer.Register(gauge); err != nil {
+				return fmt.Errorf("failed to register derived metric %s: %w", d.Name, err)
+			}
+		}
+		m.derived = append(m.derived, derivedGauge{d, gauge})
+	}
+	return nil
+}
+
+// derivedGauge is the gauge a derived metric is exported as
+type derivedGauge struct {
+	metric DerivedMetric
+	gauge  *prometheus.GaugeVec
+}
+
+// RecordDerived evaluates every derived metric for a price. A metric whose expression can't be
+// evaluated for the price, such as one dividing by a vCPU count that isn't known, is skipped.
+func (m *Metrics) RecordDerived(p VMPricing) {
+	if len(m.derived) == 0 {
+		return
+	}
+
+	vars := derivedValues(p, m.memoryUnit)
+	labels := prometheus.Labels{
+		"provider":      p.Provider,
+		"region":        p.Region,
+		"instance_type": p.InstanceType,
+		"confidential":  strconv.FormatBool(p.Confidential),
+	}
+	for _, d := range m.derived {
+		v, err := d.metric.expr.Eval(vars)
+		if err != nil {
+			slog.Debug("skipping derived metric",
+				"metric", d.metric.Name,
+				"provider", p.Provider,
+				"region", p.Region,
+				"instance_type", p.InstanceType,
+				"error", err,
+			)
+			continue
+		}
+		d.gauge.With(labels).Set(m.rounding.Float(v))
+	}
+}
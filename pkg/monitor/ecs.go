@@ -0,0 +1,222 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// ecsDescribeBatchSize is the most container instances or tasks a describe call accepts
+const ecsDescribeBatchSize = 100
+
+// ECSDiscoverer discovers EC2 container instances and the tasks placed on them from every
+// ECS cluster in a set of regions. Fargate tasks have no container instance and are skipped.
+type ECSDiscoverer struct {
+	cfg     aws.Config
+	regions []string
+}
+
+func NewECSDiscoverer(ctx context.Context, regions []string) (*ECSDiscoverer, error) {
+	cfg, err := loadAWSConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ECSDiscoverer{
+		cfg:     cfg,
+		regions: regions,
+	}, nil
+}
+
+func (d *ECSDiscoverer) Scheduler() string {
+	return "ecs"
+}
+
+func (d *ECSDiscoverer) Discover(ctx context.Context) (*ClusterState, error) {
+	state := &ClusterState{}
+	for _, region := range d.regions {
+		client := ecs.NewFromConfig(d.cfg, func(o *ecs.Options) {
+			o.Region = region
+		})
+
+		var clusters []string
+		paginator := ecs.NewListClustersPaginator(client, &ecs.ListClustersInput{})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list ECS clusters in %s: %w", region, err)
+			}
+			clusters = append(clusters, page.ClusterArns...)
+		}
+
+		for _, cluster := range clusters {
+			if err := d.discoverCluster(ctx, client, region, cluster, state); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return state, nil
+}
+
+func (d *ECSDiscoverer) discoverCluster(ctx context.Context, client *ecs.Client, region, cluster string, state *ClusterState) error {
+	clusterName := cluster[strings.LastIndex(cluster, "/")+1:]
+
+	var instanceArns []string
+	instances := ecs.NewListContainerInstancesPaginator(client, &ecs.ListContainerInstancesInput{
+		Cluster: aws.String(cluster),
+	})
+	for instances.HasMorePages() {
+		page, err := instances.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list container instances of ECS cluster %s: %w", clusterName, err)
+		}
+		instanceArns = append(instanceArns, page.ContainerInstanceArns...)
+	}
+
+	// Tasks reference container instances by ARN, but nodes are named by EC2 instance ID
+	names := make(map[string]string)
+	for batch := range slices.Chunk(instanceArns, ecsDescribeBatchSize) {
+		output, err := client.DescribeContainerInstances(ctx, &ecs.DescribeContainerInstancesInput{
+			Cluster:            aws.String(cluster),
+			ContainerInstances: batch,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to describe container instances of ECS cluster %s: %w", clusterName, err)
+		}
+
+		for _, ci := range output.ContainerInstances {
+			node, ok := ecsClusterNode(ci, region)
+			if !ok {
+				continue
+			}
+			state.Nodes = append(state.Nodes, node)
+			names[aws.ToString(ci.ContainerInstanceArn)] = node.Name
+		}
+	}
+
+	var taskArns []string
+	tasks := ecs.NewListTasksPaginator(client, &ecs.ListTasksInput{
+		Cluster:       aws.String(cluster),
+		DesiredStatus: ecstypes.DesiredStatusRunning,
+	})
+	for tasks.HasMorePages() {
+		page, err := tasks.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list tasks of ECS cluster %s: %w", clusterName, err)
+		}
+		taskArns = append(taskArns, page.TaskArns...)
+	}
+
+	for batch := range slices.Chunk(taskArns, ecsDescribeBatchSize) {
+		output, err := client.DescribeTasks(ctx, &ecs.DescribeTasksInput{
+			Cluster: aws.String(cluster),
+			Tasks:   batch,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to describe tasks of ECS cluster %s: %w", clusterName, err)
+		}
+
+		for _, task := range output.Tasks {
+			node, ok := names[aws.ToString(task.ContainerInstanceArn)]
+			if !ok {
+				continue
+			}
+
+			kind, workload := ecsWorkload(aws.ToString(task.Group))
+			cpu, memory := ecsTaskResources(task)
+			state.Allocations = append(state.Allocations, ClusterAllocation{
+				Node:         node,
+				Namespace:    clusterName,
+				WorkloadKind: kind,
+				Workload:     workload,
+				VCPUs:        cpu / 1024,
+				MemoryGB:     memory * 1024 * 1024 / 1e9,
+			})
+		}
+	}
+
+	return nil
+}
+
+// ecsClusterNode maps a container instance to its EC2 instance using the attributes the ECS
+// agent registers. Resources are registered in CPU units (1024 per vCPU) and MiB.
+func ecsClusterNode(ci ecstypes.ContainerInstance, region string) (ClusterNode, bool) {
+	var instanceType string
+	for _, attr := range ci.Attributes {
+		if aws.ToString(attr.Name) == "ecs.instance-type" {
+			instanceType = aws.ToString(attr.Value)
+		}
+	}
+	if instanceType == "" {
+		return ClusterNode{}, false
+	}
+
+	node := ClusterNode{
+		Name:         aws.ToString(ci.Ec2InstanceId),
+		Provider:     "aws",
+		Region:       region,
+		InstanceType: instanceType,
+	}
+	for _, r := range ci.RegisteredResources {
+		switch aws.ToString(r.Name) {
+		case "CPU":
+			node.VCPUs = float64(r.IntegerValue) / 1024
+		case "MEMORY":
+			node.MemoryGB = float64(r.IntegerValue) * 1024 * 1024 / 1e9
+		}
+	}
+	return node, true
+}
+
+// ecsTaskResources returns the CPU units and MiB of memory reserved by a task, from the task
+// size when it has one and otherwise from its containers
+func ecsTaskResources(task ecstypes.Task) (cpu, memory float64) {
+	cpu, _ = strconv.ParseFloat(aws.ToString(task.Cpu), 64)
+	memory, _ = strconv.ParseFloat(aws.ToString(task.Memory), 64)
+	if cpu > 0 && memory > 0 {
+		return cpu, memory
+	}
+
+	var containerCPU, containerMemory float64
+	for _, c := range task.Containers {
+		v, _ := strconv.ParseFloat(aws.ToString(c.Cpu), 64)
+		containerCPU += v
+
+		m, _ := strconv.ParseFloat(aws.ToString(c.Memory), 64)
+		if m == 0 {
+			m, _ = strconv.ParseFloat(aws.ToString(c.MemoryReservation), 64)
+		}
+		containerMemory += m
+	}
+
+	if cpu == 0 {
+		cpu = containerCPU
+	}
+	if memory == 0 {
+		memory = containerMemory
+	}
+	return cpu, memory
+}
+
+// ecsWorkload splits a task group like "service:web" into its kind and name
+func ecsWorkload(group string) (kind, name string) {
+	prefix, name, ok := strings.Cut(group, ":")
+	if !ok {
+		return "Task", group
+	}
+
+	switch prefix {
+	case "service":
+		return "Service", name
+	case "family":
+		return "TaskFamily", name
+	}
+	return prefix, name
+}
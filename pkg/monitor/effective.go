@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/shopspring/decimal"
+)
+
+// SLARule is an availability assumption for the series it matches. Empty provider, region,
+// and instance type fields match anything, and the instance type may be a glob such as m5.*.
+type SLARule struct {
+	Provider     string `json:"provider,omitempty"`
+	Region       string `json:"region,omitempty"`
+	InstanceType string `json:"instance_type,omitempty"`
+
+	// Availability is the percentage of time capacity is expected to be up, 100 when unset
+	Availability *float64 `json:"availability,omitempty"`
+	// InterruptionRate is the percentage of capacity expected to be lost to interruptions,
+	// such as spot reclaims, at any time
+	InterruptionRate float64 `json:"interruption_rate,omitempty"`
+	// OverprovisionFactor sets the factor directly, such as 1.5 for N+1 across two zones, in
+	// place of deriving it from availability and interruptions
+	OverprovisionFactor float64 `json:"overprovision_factor,omitempty"`
+}
+
+// SLAAssumptions adjust prices for the redundancy each option needs to deliver the same
+// usable capacity. The first matching rule applies.
+type SLAAssumptions []SLARule
+
+// LoadSLAAssumptions reads availability assumptions from a JSON file holding a list of rules
+func LoadSLAAssumptions(file string) (SLAAssumptions, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SLA assumptions file: %w", err)
+	}
+
+	var rules SLAAssumptions
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse SLA assumptions file: %w", err)
+	}
+
+	for i, r := range rules {
+		if _, err := path.Match(r.InstanceType, ""); err != nil {
+			return nil, fmt.Errorf("SLA rule %d has an invalid instance type pattern %q: %w", i, r.InstanceType, err)
+		}
+		// Capacity that is never up can't be made usable by buying more of it
+		if r.Availability != nil && (*r.Availability <= 0 || *r.Availability > 100) {
+			return nil, fmt.Errorf("SLA rule %d availability must be a percentage above 0 and up to 100", i)
+		}
+		if r.InterruptionRate < 0 || r.InterruptionRate >= 100 {
+			return nil, fmt.Errorf("SLA rule %d interruption rate must be a percentage below 100", i)
+		}
+		if r.OverprovisionFactor != 0 && r.OverprovisionFactor < 1 {
+			return nil, fmt.Errorf("SLA rule %d overprovision factor must be at least 1", i)
+		}
+	}
+
+	return rules, nil
+}
+
+// matches reports whether a rule applies to a series
+func (r SLARule) matches(p VMPricing) bool {
+	if r.Provider != "" && r.Provider != p.Provider {
+		return false
+	}
+	if r.Region != "" && r.Region != p.Region {
+		return false
+	}
+	if r.InstanceType == "" {
+		return true
+	}
+	matched, _ := path.Match(r.InstanceType, p.InstanceType)
+	return matched
+}
+
+// usable returns the share of bought capacity that is usable, availability × (1 - interruption
+// rate)
+func (r SLARule) usable() decimal.Decimal {
+	one := decimal.NewFromInt(1)
+	hundred := decimal.NewFromInt(100)
+	availability := hundred
+	if r.Availability != nil {
+		availability = decimal.NewFromFloat(*r.Availability)
+	}
+	return availability.Div(hundred).Mul(one.Sub(decimal.NewFromFloat(r.InterruptionRate).Div(hundred)))
+}
+
+// EffectiveCost returns how much capacity has to be bought per unit of usable capacity for a
+// series, and its cost per hour of usable capacity. ok is false when no rule matches the series.
+func (s SLAAssumptions) EffectiveCost(p VMPricing) (factor, cost decimal.Decimal, ok bool) {
+	for _, r := range s {
+		if !r.matches(p) {
+			continue
+		}
+		if r.OverprovisionFactor > 0 {
+			factor = decimal.NewFromFloat(r.OverprovisionFactor)
+			return factor, p.TotalCost.Mul(factor), true
+		}
+		usable := r.usable()
+		return decimal.NewFromInt(1).Div(usable), p.TotalCost.Div(usable), true
+	}
+	return decimal.Zero, decimal.Zero, false
+}
@@ -0,0 +1,45 @@
+package monitor
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestEffectiveCost(t *testing.T) {
+	percent := func(v float64) *float64 { return &v }
+	assumptions := SLAAssumptions{
+		{Provider: "gcp", InstanceType: "n2-*", OverprovisionFactor: 1.5},
+		{Provider: "aws", InstanceType: "m5.*", Availability: percent(80)},
+		{Provider: "aws", Region: "us-west-2", Availability: percent(50), InterruptionRate: 20},
+		{Provider: "aws", Region: "eu-west-1", Availability: percent(99.9)},
+		{Provider: "aws", Region: "eu-west-2"},
+	}
+
+	tests := []struct {
+		provider, region, instanceType string
+		totalCost                      string
+		factor                         string
+		cost                           string
+		ok                             bool
+	}{
+		{"gcp", "us-central1", "n2-standard-4", "0.194236", "1.5", "0.291354", true},
+		{"aws", "us-east-1", "m5.large", "0.096", "1.25", "0.12", true},
+		// The first matching rule applies
+		{"aws", "us-west-2", "m5.large", "0.096", "1.25", "0.12", true},
+		{"aws", "us-west-2", "c5.large", "0.1", "2.5", "0.25", true},
+		{"aws", "eu-west-1", "c5.large", "0.999", "1.001001001001001", "1", true},
+		{"aws", "eu-west-2", "c5.large", "0.1", "1", "0.1", true},
+		{"aws", "ap-south-1", "c5.large", "0.1", "0", "0", false},
+		{"gcp", "us-central1", "e2-medium", "0.1", "0", "0", false},
+	}
+
+	for _, tt := range tests {
+		p := VMPricing{Provider: tt.provider, Region: tt.region, InstanceType: tt.instanceType, TotalCost: decimal.RequireFromString(tt.totalCost)}
+		factor, cost, ok := assumptions.EffectiveCost(p)
+		if ok != tt.ok || !factor.Equal(decimal.RequireFromString(tt.factor)) || !cost.Equal(decimal.RequireFromString(tt.cost)) {
+			t.Errorf("EffectiveCost(%s/%s/%s at %s) = (%s, %s, %t), want (%s, %s, %t)",
+				tt.provider, tt.region, tt.instanceType, tt.totalCost, factor, cost, ok, tt.factor, tt.cost, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+// egressDirect is the proxy URL value that sends a provider's requests straight to its API,
+// ignoring the HTTP_PROXY and HTTPS_PROXY environment variables
+const egressDirect = "direct"
+
+// Egress routes the API requests of each provider through its own proxy. Providers without a
+// route use the proxy of the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment variables, like
+// any other client.
+type Egress map[string]*egressRoute
+
+// egressRoute is the proxy a provider's requests go through, or none for direct requests
+type egressRoute struct {
+	proxy *url.URL
+}
+
+// ParseEgress parses the proxy URL of each provider, by provider name. A URL is an http,
+// https, socks5, or socks5h proxy, and "direct" bypasses proxies. Empty URLs leave a provider
+// on the environment's proxy.
+func ParseEgress(proxies map[string]string) (Egress, error) {
+	egress := make(Egress)
+	for provider, value := range proxies {
+		if value == "" {
+			continue
+		}
+		if value == egressDirect {
+			egress[provider] = &egressRoute{}
+			continue
+		}
+
+		proxy, err := url.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s proxy URL: %w", provider, err)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("invalid %s proxy URL %s: scheme must be http, https, socks5, or socks5h", provider, proxy.Redacted())
+		}
+		if proxy.Host == "" {
+			return nil, fmt.Errorf("invalid %s proxy URL %s: missing host", provider, proxy.Redacted())
+		}
+		egress[provider] = &egressRoute{proxy: proxy}
+	}
+	return egress, nil
+}
+
+// egressFlags parses the proxy URL flags of the providers
+func egressFlags(cctx *cli.Context) (Egress, error) {
+	return ParseEgress(map[string]string{
+		"aws":   cctx.String("aws-proxy-url"),
+		"gcp":   cctx.String("gcp-proxy-url"),
+		"azure": cctx.String("azure-proxy-url"),
+	})
+}
+
+// String describes the route for logs, without the proxy's credentials
+func (r *egressRoute) String() string {
+	if r.proxy == nil {
+		return egressDirect
+	}
+	return r.proxy.Redacted()
+}
+
+// configure routes a transport's requests
+func (r *egressRoute) configure(t *http.Transport) {
+	t.Proxy = nil
+	if r.proxy != nil {
+		t.Proxy = http.ProxyURL(r.proxy)
+	}
+}
+
+// transport returns a transport routing requests like the default transport does otherwise.
+// Offline mode replaces the default transport to refuse every request, which is kept.
+func (r *egressRoute) transport() http.RoundTripper {
+	base, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return http.DefaultTransport
+	}
+	t := base.Clone()
+	r.configure(t)
+	return t
+}
+
+// Transport returns the transport of a provider's requests
+func (e Egress) Transport(provider string) http.RoundTripper {
+	if route, ok := e[provider]; ok {
+		return route.transport()
+	}
+	return http.DefaultTransport
+}
+
+// Client returns the HTTP client of a provider's requests
+func (e Egress) Client(provider string) *http.Client {
+	if _, ok := e[provider]; ok {
+		return &http.Client{Transport: e.Transport(provider)}
+	}
+	return http.DefaultClient
+}
+
+type egressKey struct{}
+
+// withEgress returns a context that clients configured with route their requests through the
+// egress of their provider. Like API call counting, routes are taken from the context fetchers
+// are created with.
+func withEgress(ctx context.Context, egress Egress) context.Context {
+	return context.WithValue(ctx, egressKey{}, egress)
+}
+
+func egressFrom(ctx context.Context) Egress {
+	egress, _ := ctx.Value(egressKey{}).(Egress)
+	return egress
+}
@@ -0,0 +1,107 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/jazware/cloud-pricing-monitor/pkg/providers"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+)
+
+var (
+	// errNoPricingFound is returned when a provider catalog has no price for a region and instance type
+	errNoPricingFound = providers.ErrNoPricingFound
+
+	// errThrottled, errAuth, and errParse are the classes of failed fetches other than a
+	// missing price, see providers.ErrorReason
+	errThrottled = providers.ErrThrottled
+	errAuth      = providers.ErrAuth
+	errParse     = providers.ErrParse
+
+	// errUnavailable is the class of server errors, which are retried like throttling
+	errUnavailable = providers.ErrUnavailable
+
+	// errConfidentialUnsupported is returned when an instance type has no confidential computing variant
+	errConfidentialUnsupported = errors.New("confidential computing not supported")
+)
+
+// awsAuthErrorCodes are the AWS API error codes of rejected credentials or permissions
+var awsAuthErrorCodes = map[string]bool{
+	"AccessDenied":                true,
+	"AccessDeniedException":       true,
+	"UnauthorizedOperation":       true,
+	"UnauthorizedException":       true,
+	"UnrecognizedClientException": true,
+	"InvalidClientTokenId":        true,
+	"ExpiredToken":                true,
+	"ExpiredTokenException":       true,
+	"AuthFailure":                 true,
+	"SignatureDoesNotMatch":       true,
+	"InvalidSignatureException":   true,
+	"MissingAuthenticationToken":  true,
+}
+
+// classifyError wraps an error of the AWS or GCP SDKs in the class it falls into, so it can be
+// told apart with errors.Is like the errors providers classify themselves. Errors already
+// classified, or of no known class, are returned as they are.
+func classifyError(err error) error {
+	if err == nil || providers.ErrorReason(err) != providers.ReasonOther {
+		return err
+	}
+
+	var class error
+	var apiErr smithy.APIError
+	var respErr *smithyhttp.ResponseError
+	var googleErr *googleapi.Error
+	var retrieveErr *oauth2.RetrieveError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &apiErr) && isThrottleCode(apiErr.ErrorCode()):
+		class = errThrottled
+	case errors.As(err, &apiErr) && awsAuthErrorCodes[apiErr.ErrorCode()]:
+		class = errAuth
+	case errors.As(err, &respErr):
+		class = statusClass(respErr.HTTPStatusCode())
+	case errors.As(err, &googleErr):
+		class = statusClass(googleErr.Code)
+	case errors.As(err, &retrieveErr):
+		class = errAuth
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		class = errParse
+	}
+	if class == nil {
+		return err
+	}
+	return fmt.Errorf("%w: %w", class, err)
+}
+
+func isThrottleCode(code string) bool {
+	_, ok := retry.DefaultThrottleErrorCodes[code]
+	return ok
+}
+
+// statusClass returns the class of an HTTP status an API rejected a request with, if any
+func statusClass(status int) error {
+	switch status {
+	case http.StatusTooManyRequests:
+		return errThrottled
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errAuth
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return errUnavailable
+	default:
+		return nil
+	}
+}
+
+// errorReason returns the reason of the class an error falls into, for metric labels and logs
+func errorReason(err error) string {
+	return providers.ErrorReason(classifyError(err))
+}
@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/client"
+	"github.com/jazware/cloud-pricing-monitor/pkg/providers"
+	"github.com/shopspring/decimal"
+)
+
+// explainPrice lays out how a fetched price adds up from the components of its trace to the
+// price published at the rounding's precision, through the override of its series if any
+func explainPrice(pricing *VMPricing, trace *providers.MatchTrace, overrides PriceOverrides, rounding PriceRounding) client.Explanation {
+	explanation := client.Explanation{
+		Provider:     pricing.Provider,
+		Region:       pricing.Region,
+		InstanceType: pricing.InstanceType,
+		Components:   []client.PriceComponent{},
+		Adjustments:  []client.PriceAdjustment{},
+	}
+
+	listCost := decimal.Zero
+	for _, c := range trace.Components {
+		explanation.Components = append(explanation.Components, client.PriceComponent{
+			ID:          c.ID,
+			Description: c.Description,
+			Rate:        c.Rate.String(),
+			Quantity:    c.Quantity.String(),
+			Unit:        c.Unit,
+			Cost:        c.Cost().String(),
+		})
+		listCost = listCost.Add(c.Cost())
+	}
+	explanation.ListCost = listCost.String()
+
+	// adjusted is the list cost with the adjustments so far, which rounding makes up the rest of
+	cost, adjusted := pricing.TotalCost, listCost
+	if rule, ok := overrides.match(*pricing); ok {
+		overridden := overrides.Apply(*pricing)
+		description := "overridden by the price overrides file"
+		if rule.Reason != "" {
+			description += ": " + rule.Reason
+		}
+		amount := overridden.TotalCost.Sub(pricing.TotalCost)
+		explanation.Adjustments = append(explanation.Adjustments, client.PriceAdjustment{
+			Kind:        "override",
+			Description: description,
+			Amount:      amount.String(),
+		})
+		cost, adjusted = overridden.TotalCost, adjusted.Add(amount)
+	}
+
+	totalCost := rounding.Round(cost)
+	if diff := totalCost.Sub(adjusted); !diff.IsZero() {
+		explanation.Adjustments = append(explanation.Adjustments, client.PriceAdjustment{
+			Kind:        "rounding",
+			Description: "rounded to the precision prices are published at",
+			Amount:      diff.String(),
+		})
+	}
+	explanation.TotalCost = totalCost.String()
+	return explanation
+}
+
+// explainHandler serves how the price of the series named by ?provider=, ?region=, and
+// ?instance_type= (or ?type=) adds up. Published prices don't keep their components, so the
+// price is fetched again with them traced, and served alongside the published price it is
+// expected to match.
+func explainHandler(snapshot *PriceSnapshot, trace func(ctx context.Context, provider, region, instanceType string) (*VMPricing, *providers.MatchTrace, error), overrides PriceOverrides, rounding PriceRounding) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		provider, region, instanceType := query.Get("provider"), query.Get("region"), query.Get("instance_type")
+		if instanceType == "" {
+			instanceType = query.Get("type")
+		}
+		if provider == "" || region == "" || instanceType == "" {
+			http.Error(w, "provider, region, and instance_type are required", http.StatusBadRequest)
+			return
+		}
+
+		pricing, components, err := trace(r.Context(), provider, region, instanceType)
+		switch {
+		case errors.Is(err, errProviderNotMonitored):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, errNoPricingFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case err != nil:
+			http.Error(w, fmt.Sprintf("failed to fetch price: %v", err), http.StatusBadGateway)
+			return
+		}
+
+		explanation := explainPrice(pricing, components, overrides, rounding)
+		if entry, ok := snapshot.Get(PriceKey{Provider: provider, Region: region, InstanceType: instanceType}); ok {
+			explanation.PublishedCost = rounding.Round(entry.Pricing.TotalCost).String()
+			explanation.PublishedAt = &entry.UpdatedAt
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(explanation); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,518 @@
+package monitor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+)
+
+// Expr is a parsed expression over named values, such as TotalCost / (VCPUs*0.6 + MemoryGB*0.1).
+// It supports numbers, variables, + - * / with the usual precedence, parentheses, unary minus,
+// the functions min, max, and abs, the comparisons < <= > >= == !=, and the boolean operators
+// and, or, and not. Comparisons and boolean operators give 1 for true and 0 for false, and any
+// value other than 0 is true.
+type Expr struct {
+	source string
+	root   exprNode
+	used   []string
+}
+
+// exprFuncs are the functions an expression can call, with their number of arguments (0 for
+// any number of at least one)
+var exprFuncs = map[string]int{
+	"min": 0,
+	"max": 0,
+	"abs": 1,
+}
+
+// ParseExpr parses an expression whose variables must be among vars
+func ParseExpr(source string, vars []string) (*Expr, error) {
+	tokens, err := lexExpr(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", source, err)
+	}
+
+	p := &exprParser{tokens: tokens, vars: vars}
+	root, err := p.parseOr()
+	if err == nil && p.peek().kind != exprEOF {
+		err = fmt.Errorf("unexpected %s", p.peek())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", source, err)
+	}
+	return &Expr{source: source, root: root, used: p.used}, nil
+}
+
+func (e *Expr) String() string {
+	return e.source
+}
+
+// Uses reports whether the expression refers to a variable
+func (e *Expr) Uses(name string) bool {
+	return slices.Contains(e.used, name)
+}
+
+// Eval evaluates the expression. It fails when a variable it uses has no value or it divides
+// by zero.
+func (e *Expr) Eval(vars map[string]decimal.Decimal) (decimal.Decimal, error) {
+	return e.root.eval(vars)
+}
+
+type exprNode interface {
+	eval(vars map[string]decimal.Decimal) (decimal.Decimal, error)
+}
+
+type exprNumber struct {
+	value decimal.Decimal
+}
+
+func (n exprNumber) eval(map[string]decimal.Decimal) (decimal.Decimal, error) {
+	return n.value, nil
+}
+
+type exprVar struct {
+	name string
+}
+
+func (n exprVar) eval(vars map[string]decimal.Decimal) (decimal.Decimal, error) {
+	v, ok := vars[n.name]
+	if !ok {
+		return decimal.Zero, fmt.Errorf("%s is unknown", n.name)
+	}
+	return v, nil
+}
+
+type exprNeg struct {
+	x exprNode
+}
+
+func (n exprNeg) eval(vars map[string]decimal.Decimal) (decimal.Decimal, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return x.Neg(), nil
+}
+
+type exprBinary struct {
+	op   byte
+	x, y exprNode
+}
+
+func (n exprBinary) eval(vars map[string]decimal.Decimal) (decimal.Decimal, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	y, err := n.y.eval(vars)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	switch n.op {
+	case '+':
+		return x.Add(y), nil
+	case '-':
+		return x.Sub(y), nil
+	case '*':
+		return x.Mul(y), nil
+	default:
+		if y.IsZero() {
+			return decimal.Zero, fmt.Errorf("division by zero")
+		}
+		return x.Div(y), nil
+	}
+}
+
+type exprCall struct {
+	fn   string
+	args []exprNode
+}
+
+func (n exprCall) eval(vars map[string]decimal.Decimal) (decimal.Decimal, error) {
+	args := make([]decimal.Decimal, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(vars)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		args[i] = v
+	}
+
+	switch n.fn {
+	case "min":
+		return decimal.Min(args[0], args[1:]...), nil
+	case "max":
+		return decimal.Max(args[0], args[1:]...), nil
+	default:
+		return args[0].Abs(), nil
+	}
+}
+
+type exprCompare struct {
+	op   string
+	x, y exprNode
+}
+
+func (n exprCompare) eval(vars map[string]decimal.Decimal) (decimal.Decimal, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	y, err := n.y.eval(vars)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	var holds bool
+	switch c := x.Cmp(y); n.op {
+	case "<":
+		holds = c < 0
+	case "<=":
+		holds = c <= 0
+	case ">":
+		holds = c > 0
+	case ">=":
+		holds = c >= 0
+	case "==":
+		holds = c == 0
+	default:
+		holds = c != 0
+	}
+	return exprBool(holds), nil
+}
+
+// exprLogic is "and" or "or", which only evaluate their second operand when the first doesn't
+// decide the result, so that a guard like VCPUs > 0 and TotalCost / VCPUs > 0.05 works
+type exprLogic struct {
+	and  bool
+	x, y exprNode
+}
+
+func (n exprLogic) eval(vars map[string]decimal.Decimal) (decimal.Decimal, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if x.IsZero() == n.and {
+		return exprBool(!n.and), nil
+	}
+
+	y, err := n.y.eval(vars)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return exprBool(!y.IsZero()), nil
+}
+
+type exprNot struct {
+	x exprNode
+}
+
+func (n exprNot) eval(vars map[string]decimal.Decimal) (decimal.Decimal, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return exprBool(x.IsZero()), nil
+}
+
+// exprBool returns 1 for true and 0 for false
+func exprBool(b bool) decimal.Decimal {
+	if b {
+		return decimal.NewFromInt(1)
+	}
+	return decimal.Zero
+}
+
+type exprTokenKind int
+
+const (
+	exprEOF exprTokenKind = iota
+	exprNum
+	exprIdent
+	exprOp
+)
+
+type exprToken struct {
+	kind exprTokenKind
+	text string
+	pos  int
+}
+
+func (t exprToken) String() string {
+	if t.kind == exprEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q at position %d", t.text, t.pos+1)
+}
+
+// lexExpr splits an expression into numbers, identifiers, and operators
+func lexExpr(s string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		start := i
+		switch {
+		case unicode.IsSpace(c):
+			i++
+			continue
+		case c >= '0' && c <= '9' || c == '.':
+			for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, exprToken{exprNum, s[start:i], start})
+		case c == '_' || unicode.IsLetter(c):
+			for i < len(s) && (s[i] == '_' || unicode.IsLetter(rune(s[i])) || unicode.IsDigit(rune(s[i]))) {
+				i++
+			}
+			tokens = append(tokens, exprToken{exprIdent, s[start:i], start})
+		case strings.ContainsRune("+-*/(),", c):
+			i++
+			tokens = append(tokens, exprToken{exprOp, s[start:i], start})
+		case strings.ContainsRune("<>=!", c):
+			i++
+			if i < len(s) && s[i] == '=' {
+				i++
+			}
+			op := s[start:i]
+			if op == "=" || op == "!" {
+				return nil, fmt.Errorf("unexpected character %q at position %d (expected == or !=)", c, start+1)
+			}
+			tokens = append(tokens, exprToken{exprOp, op, start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i+1)
+		}
+	}
+	return append(tokens, exprToken{kind: exprEOF, pos: len(s)}), nil
+}
+
+// exprParser is a recursive descent parser over the grammar
+//
+//	or      = and { "or" and }
+//	and     = not { "and" not }
+//	not     = "not" not | compare
+//	compare = sum [ ("<" | "<=" | ">" | ">=" | "==" | "!=") sum ]
+//	sum     = product { ("+" | "-") product }
+//	product = unary { ("*" | "/") unary }
+//	unary   = "-" unary | primary
+//	primary = number | ident | ident "(" or { "," or } ")" | "(" or ")"
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+	vars   []string
+	used   []string
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	t := p.tokens[p.pos]
+	if t.kind != exprEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the operator op
+func (p *exprParser) accept(op string) bool {
+	if t := p.peek(); t.kind == exprOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// acceptKeyword consumes the next token if it is the keyword word
+func (p *exprParser) acceptKeyword(word string) bool {
+	if t := p.peek(); t.kind == exprIdent && t.text == word {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	x, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("or") {
+		y, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		x = exprLogic{false, x, y}
+	}
+	return x, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	x, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("and") {
+		y, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		x = exprLogic{true, x, y}
+	}
+	return x, nil
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if p.acceptKeyword("not") {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return exprNot{x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *exprParser) parseCompare() (exprNode, error) {
+	x, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"<", "<=", ">", ">=", "==", "!="} {
+		if p.accept(op) {
+			y, err := p.parseSum()
+			if err != nil {
+				return nil, err
+			}
+			return exprCompare{op, x, y}, nil
+		}
+	}
+	return x, nil
+}
+
+func (p *exprParser) parseSum() (exprNode, error) {
+	x, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op byte
+		switch {
+		case p.accept("+"):
+			op = '+'
+		case p.accept("-"):
+			op = '-'
+		default:
+			return x, nil
+		}
+		y, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		x = exprBinary{op, x, y}
+	}
+}
+
+func (p *exprParser) parseProduct() (exprNode, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op byte
+		switch {
+		case p.accept("*"):
+			op = '*'
+		case p.accept("/"):
+			op = '/'
+		default:
+			return x, nil
+		}
+		y, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		x = exprBinary{op, x, y}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.accept("-") {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return exprNeg{x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	t := p.next()
+	switch t.kind {
+	case exprNum:
+		v, err := decimal.NewFromString(t.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", t)
+		}
+		return exprNumber{v}, nil
+
+	case exprIdent:
+		if p.accept("(") {
+			return p.parseCall(t)
+		}
+		if !slices.Contains(p.vars, t.text) {
+			return nil, fmt.Errorf("unknown variable %s (expected one of %s)", t, strings.Join(p.vars, ", "))
+		}
+		if !slices.Contains(p.used, t.text) {
+			p.used = append(p.used, t.text)
+		}
+		return exprVar{t.text}, nil
+
+	case exprOp:
+		if t.text == "(" {
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.accept(")") {
+				return nil, fmt.Errorf("expected \")\" before %s", p.peek())
+			}
+			return x, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %s", t)
+}
+
+// parseCall parses the arguments of a function call after its opening parenthesis
+func (p *exprParser) parseCall(fn exprToken) (exprNode, error) {
+	arity, ok := exprFuncs[fn.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", fn)
+	}
+
+	var args []exprNode
+	for {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.accept(")") {
+			break
+		}
+		if !p.accept(",") {
+			return nil, fmt.Errorf("expected \",\" or \")\" before %s", p.peek())
+		}
+	}
+
+	if arity > 0 && len(args) != arity {
+		return nil, fmt.Errorf("%s takes %d argument(s), not %d", fn.text, arity, len(args))
+	}
+	return exprCall{fn.text, args}, nil
+}
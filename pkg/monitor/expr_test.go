@@ -0,0 +1,118 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+var exprTestVars = []string{"TotalCost", "VCPUs", "MemoryGB"}
+
+func TestEvalExpr(t *testing.T) {
+	vars := map[string]decimal.Decimal{
+		"TotalCost": decimal.RequireFromString("0.192"),
+		"VCPUs":     decimal.NewFromInt(4),
+		"MemoryGB":  decimal.NewFromInt(16),
+	}
+	noVCPUs := map[string]decimal.Decimal{
+		"TotalCost": decimal.RequireFromString("0.192"),
+		"VCPUs":     decimal.Zero,
+		"MemoryGB":  decimal.NewFromInt(16),
+	}
+
+	tests := []struct {
+		source string
+		vars   map[string]decimal.Decimal
+		want   string
+	}{
+		{"1 + 2 * 3", vars, "7"},
+		{"(1 + 2) * 3", vars, "9"},
+		{"10 - 4 - 3", vars, "3"},
+		{"12 / 3 / 2", vars, "2"},
+		{"-2 * 3", vars, "-6"},
+		{"2 - -3", vars, "5"},
+		{"--VCPUs", vars, "4"},
+		{"-(1 + 2) * 2", vars, "-6"},
+		{"TotalCost / (VCPUs*0.6 + MemoryGB*0.1)", vars, "0.048"},
+		{"min(VCPUs, MemoryGB, 8)", vars, "4"},
+		{"max(VCPUs, MemoryGB)", vars, "16"},
+		{"abs(-TotalCost)", vars, "0.192"},
+		{"1 + 2 < 4", vars, "1"},
+		{"VCPUs >= 4 and MemoryGB != 16", vars, "0"},
+		{"not VCPUs == 4 or MemoryGB <= 16", vars, "1"},
+		{"not 0", vars, "1"},
+		{"2 and 3", vars, "1"},
+		{"0 or 0", vars, "0"},
+		{"VCPUs > 0 and TotalCost / VCPUs > 0.05", vars, "0"},
+		{"VCPUs > 0 and TotalCost / VCPUs > 0.05", noVCPUs, "0"},
+		{"VCPUs == 0 or TotalCost / VCPUs > 0.05", noVCPUs, "1"},
+	}
+
+	for _, tt := range tests {
+		expr, err := ParseExpr(tt.source, exprTestVars)
+		if err != nil {
+			t.Errorf("ParseExpr(%q) failed: %v", tt.source, err)
+			continue
+		}
+		got, err := expr.Eval(tt.vars)
+		if err != nil {
+			t.Errorf("Eval(%q) failed: %v", tt.source, err)
+			continue
+		}
+		if !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("Eval(%q) = %s, want %s", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestEvalExprErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		vars   map[string]decimal.Decimal
+		err    string
+	}{
+		{"TotalCost / VCPUs", map[string]decimal.Decimal{"TotalCost": decimal.NewFromInt(1), "VCPUs": decimal.Zero}, "division by zero"},
+		{"1 / (2 - 2)", nil, "division by zero"},
+		{"VCPUs > 0 or TotalCost / VCPUs > 1", map[string]decimal.Decimal{"TotalCost": decimal.NewFromInt(1), "VCPUs": decimal.Zero}, "division by zero"},
+		{"TotalCost * 2", map[string]decimal.Decimal{}, "TotalCost is unknown"},
+	}
+
+	for _, tt := range tests {
+		expr, err := ParseExpr(tt.source, exprTestVars)
+		if err != nil {
+			t.Errorf("ParseExpr(%q) failed: %v", tt.source, err)
+			continue
+		}
+		if _, err := expr.Eval(tt.vars); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Eval(%q) error = %v, want %q", tt.source, err, tt.err)
+		}
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{"SpotCost * 2", `unknown variable "SpotCost" at position 1`},
+		{"median(VCPUs, 2)", `unknown function "median" at position 1`},
+		{"abs(VCPUs, 2)", "abs takes 1 argument(s), not 2"},
+		{"abs()", `unexpected ")" at position 5`},
+		{"min()", `unexpected ")" at position 5`},
+		{"VCPUs = 4", "expected == or !="},
+		{"!VCPUs", "expected == or !="},
+		{"VCPUs ! 4", "expected == or !="},
+		{"1 +", "unexpected end of expression"},
+		{"(1 + 2", `expected ")" before end of expression`},
+		{"1 2", `unexpected "2" at position 3`},
+		{"VCPUs $ 2", `unexpected character '$' at position 7`},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseExpr(tt.source, exprTestVars)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ParseExpr(%q) error = %v, want %q", tt.source, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package monitor
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/client"
+)
+
+// fetchStatus is the outcome of the latest fetch of a series
+type fetchStatus struct {
+	state       string
+	attemptedAt time.Time
+	err         error
+
+	// failures counts the fetches that failed in a row
+	failures int
+}
+
+// FetchStatuses holds the outcome of the latest fetch of every series, so consumers can tell a
+// price that is current from one left over from before a failing fetch
+type FetchStatuses struct {
+	mu       sync.RWMutex
+	statuses map[PriceKey]fetchStatus
+}
+
+func NewFetchStatuses() *FetchStatuses {
+	return &FetchStatuses{
+		statuses: make(map[PriceKey]fetchStatus),
+	}
+}
+
+// Record stores the outcome of a fetch of a series, failed if err is non-nil
+func (s *FetchStatuses) Record(key PriceKey, err error) {
+	state := client.SeriesOK
+	if err != nil {
+		state = client.SeriesError
+	}
+	s.record(key, state, err)
+}
+
+// Hold records that a fetch of a series changed its price, which awaits confirmation
+func (s *FetchStatuses) Hold(key PriceKey) {
+	s.record(key, client.SeriesHeld, nil)
+}
+
+func (s *FetchStatuses) record(key PriceKey, state string, err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := fetchStatus{state: state, attemptedAt: time.Now(), err: err}
+	if state == client.SeriesError {
+		status.failures = s.statuses[key].failures + 1
+	}
+	s.statuses[key] = status
+}
+
+// Delete removes the statuses of an instance type in a region, confidential variant included
+func (s *FetchStatuses) Delete(provider, region, instanceType string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, confidential := range []bool{false, true} {
+		delete(s.statuses, PriceKey{Provider: provider, Region: region, InstanceType: instanceType, Confidential: confidential})
+	}
+}
+
+// all returns a copy of every status
+func (s *FetchStatuses) all() map[PriceKey]fetchStatus {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statuses := make(map[PriceKey]fetchStatus, len(s.statuses))
+	for key, status := range s.statuses {
+		statuses[key] = status
+	}
+	return statuses
+}
+
+// pricingHandler serves the state of every series that has a published price or has been
+// fetched: its price, if any, and the outcome of its latest fetch. ?provider=, ?region=, and
+// ?instance_type= narrow it like the price listing.
+func pricingHandler(snapshot *PriceSnapshot, statuses *FetchStatuses, expiry func(PriceEntry) time.Time, rounding PriceRounding, memoryUnit MemoryUnit) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		matches := func(key PriceKey) bool {
+			return (query.Get("provider") == "" || key.Provider == query.Get("provider")) &&
+				(query.Get("region") == "" || key.Region == query.Get("region")) &&
+				(query.Get("instance_type") == "" || key.InstanceType == query.Get("instance_type"))
+		}
+
+		series := make(map[PriceKey]*client.SeriesStatus)
+		get := func(key PriceKey) *client.SeriesStatus {
+			if s, ok := series[key]; ok {
+				return s
+			}
+			s := &client.SeriesStatus{
+				Provider:     key.Provider,
+				Region:       key.Region,
+				InstanceType: key.InstanceType,
+				Confidential: key.Confidential,
+				Status:       client.SeriesOK,
+			}
+			series[key] = s
+			return s
+		}
+
+		for _, entry := range snapshot.Entries() {
+			if key := entry.Pricing.Key(); matches(key) {
+				price := newAPIPrice(entry, expiry(entry), rounding, memoryUnit)
+				get(key).Price = &price
+			}
+		}
+		for key, status := range statuses.all() {
+			if !matches(key) {
+				continue
+			}
+			s := get(key)
+			s.Status = status.state
+			s.LastAttemptAt = &status.attemptedAt
+			s.ConsecutiveFailures = status.failures
+			if status.err != nil {
+				s.Error = status.err.Error()
+				s.ErrorReason = errorReason(status.err)
+			}
+		}
+
+		list := make([]client.SeriesStatus, 0, len(series))
+		for _, s := range series {
+			list = append(list, *s)
+		}
+		slices.SortFunc(list, func(a, b client.SeriesStatus) int {
+			return cmp.Or(
+				cmp.Compare(a.Provider, b.Provider),
+				cmp.Compare(a.Region, b.Region),
+				cmp.Compare(a.InstanceType, b.InstanceType),
+				compareBool(a.Confidential, b.Confidential),
+			)
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{"series": list}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// EFS storage types. Capacity is billed by storage class, while throughput is billed for the
+// file system as a whole.
+const (
+	efsStandard         = "efs-standard"
+	efsInfrequentAccess = "efs-infrequent-access"
+	efsFileSystem       = "efs"
+)
+
+// gcpFilestoreService is the display name of Filestore's Cloud Billing service
+const gcpFilestoreService = "Cloud Filestore"
+
+// efsUsageTypes maps the usage types of EFS price list products, without their region prefix,
+// to the storage type and dimension they price. One Zone storage classes are left out.
+var efsUsageTypes = map[string]struct {
+	storageType, dimension, unit string
+}{
+	"TimedStorage-ByteHrs":   {efsStandard, storageCapacity, storageUnitGBMonth},
+	"IATimedStorage-ByteHrs": {efsInfrequentAccess, storageCapacity, storageUnitGBMonth},
+	"IADataAccess-Bytes":     {efsInfrequentAccess, storageAccess, storageUnitGB},
+	"ProvisionedTP-MiBpsHrs": {efsFileSystem, storageThroughput, storageUnitMiBpsMonth},
+}
+
+// efsElasticDimension returns the dimension of an Elastic Throughput usage type, which bills
+// the data read from and written to a file system
+func efsElasticDimension(usageType string) (string, bool) {
+	if !strings.Contains(usageType, "ElasticThroughput") {
+		return "", false
+	}
+	switch {
+	case strings.Contains(usageType, "Read"):
+		return storageRead, true
+	case strings.Contains(usageType, "Write"):
+		return storageWrite, true
+	}
+	return "", false
+}
+
+// FetchEFSPricing returns the unit prices of EFS in a region: the capacity of the Standard and
+// Infrequent Access storage classes, reads of Infrequent Access data, and the data transferred
+// with Elastic Throughput or the throughput provisioned for a file system
+func (f *AWSPricingFetcher) FetchEFSPricing(ctx context.Context, region string) ([]ResourcePrice, error) {
+	items, err := f.getProducts(ctx, "AmazonEFS", map[string]string{"regionCode": region})
+	if err != nil {
+		return nil, err
+	}
+
+	dimensions := make(map[string][]ResourceDimension)
+	seen := make(map[string]bool)
+	for _, item := range items {
+		// Usage types start with a region prefix, such as USE1-, everywhere but us-east-1
+		usageType := item.Product.Attributes["usagetype"]
+		if prefix, rest, ok := strings.Cut(usageType, "-"); ok && prefix == strings.ToUpper(prefix) {
+			usageType = rest
+		}
+
+		storageType, dimension, unit := "", "", ""
+		if u, ok := efsUsageTypes[usageType]; ok {
+			storageType, dimension, unit = u.storageType, u.dimension, u.unit
+		} else if d, ok := efsElasticDimension(usageType); ok {
+			storageType, dimension, unit = efsFileSystem, d, storageUnitGB
+		} else {
+			continue
+		}
+		if seen[storageType+"/"+dimension] {
+			continue
+		}
+
+		price, priceUnit, ok := item.firstTier()
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(priceUnit, "GiBps") {
+			price = price.Div(decimal.NewFromInt(1024))
+		}
+		seen[storageType+"/"+dimension] = true
+		dimensions[storageType] = append(dimensions[storageType], ResourceDimension{Dimension: dimension, Unit: unit, Price: price})
+	}
+
+	if len(dimensions[efsStandard]) == 0 {
+		return nil, fmt.Errorf("%w for EFS in region %s", errNoPricingFound, region)
+	}
+	return resourcePrices(storageKind.Name, "aws", region, dimensions), nil
+}
+
+// filestoreTiers are the Filestore service tiers, with the words their capacity SKU may be
+// described with. Tiers are tried in order, since the older names of Basic tiers are generic.
+var filestoreTiers = []struct {
+	tier  string
+	words [][]string
+}{
+	{"enterprise", [][]string{{"enterprise"}}},
+	{"regional", [][]string{{"regional"}}},
+	{"zonal", [][]string{{"zonal"}, {"high scale"}}},
+	{"basic-ssd", [][]string{{"basic", "ssd"}, {"premium"}}},
+	{"basic-hdd", [][]string{{"basic", "hdd"}, {"standard"}}},
+}
+
+// filestoreTier returns the tier a Filestore SKU prices the capacity of. Snapshots, backups, and
+// committed use discounts are billed by other SKUs.
+func filestoreTier(product string) (string, bool) {
+	if !strings.Contains(product, "capacity") {
+		return "", false
+	}
+	for _, excluded := range []string{"snapshot", "backup", "commit"} {
+		if strings.Contains(product, excluded) {
+			return "", false
+		}
+	}
+
+	for _, t := range filestoreTiers {
+		for _, words := range t.words {
+			if !slices.ContainsFunc(words, func(word string) bool { return !strings.Contains(product, word) }) {
+				return t.tier, true
+			}
+		}
+	}
+	return "", false
+}
+
+// FetchFilestorePricing returns the capacity price of each Filestore tier in a region, named by
+// storage type such as filestore-zonal
+func (f *GCPPricingFetcher) FetchFilestorePricing(ctx context.Context, region string) ([]ResourcePrice, error) {
+	serviceId, err := f.serviceID(ctx, gcpFilestoreService)
+	if err != nil {
+		return nil, err
+	}
+
+	rates, err := f.findSkuRates(ctx, serviceId, region, filestoreTier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Filestore pricing: %w", err)
+	}
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("%w for Filestore in region %s", errNoPricingFound, region)
+	}
+
+	dimensions := make(map[string][]ResourceDimension)
+	for tier, rate := range rates {
+		dimensions["filestore-"+tier] = []ResourceDimension{{Dimension: storageCapacity, Unit: storageUnitGBMonth, Price: rate.Monthly()}}
+	}
+	return resourcePrices(storageKind.Name, "gcp", region, dimensions), nil
+}
@@ -0,0 +1,235 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/shopspring/decimal"
+)
+
+// Allocation strategies of a mixed-instances policy that change which instance types are
+// launched. Every other spot strategy spreads capacity across the overrides.
+const (
+	fleetOnDemandPrioritized = "prioritized"
+	fleetLowestPrice         = "lowest-price"
+)
+
+// FleetOverride is an instance type a fleet can launch and the capacity units it provides
+type FleetOverride struct {
+	InstanceType string  `json:"instance_type"`
+	Weight       float64 `json:"weight"`
+}
+
+// FleetConfig describes an Auto Scaling group mixed-instances policy or an EC2 Fleet request.
+// When ASG is set the overrides and instances distribution are read from the named Auto
+// Scaling group on every poll and the other fields are ignored.
+type FleetConfig struct {
+	Name   string `json:"name"`
+	Region string `json:"region"`
+	ASG    string `json:"asg"`
+
+	Overrides                  []FleetOverride `json:"overrides"`
+	OnDemandBaseCapacity       float64         `json:"on_demand_base_capacity"`
+	OnDemandPercentage         *float64        `json:"on_demand_percentage"`
+	OnDemandAllocationStrategy string          `json:"on_demand_allocation_strategy"`
+	SpotAllocationStrategy     string          `json:"spot_allocation_strategy"`
+	Capacity                   float64         `json:"capacity"`
+}
+
+// LoadFleetConfigs reads a JSON list of fleet configurations
+func LoadFleetConfigs(path string) ([]FleetConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fleet config file: %w", err)
+	}
+
+	var fleets []FleetConfig
+	if err := json.Unmarshal(data, &fleets); err != nil {
+		return nil, fmt.Errorf("failed to parse fleet config file: %w", err)
+	}
+
+	for i, fleet := range fleets {
+		if fleet.Name == "" || fleet.Region == "" {
+			return nil, fmt.Errorf("fleet %d must have a name and a region", i)
+		}
+		if fleet.ASG == "" && len(fleet.Overrides) == 0 {
+			return nil, fmt.Errorf("fleet %s must name an Auto Scaling group or list instance type overrides", fleet.Name)
+		}
+		if p := fleet.OnDemandPercentage; p != nil && (*p < 0 || *p > 100) {
+			return nil, fmt.Errorf("on-demand percentage of fleet %s must be between 0 and 100", fleet.Name)
+		}
+		for j, o := range fleet.Overrides {
+			if o.Weight < 0 {
+				return nil, fmt.Errorf("weight of %s in fleet %s must not be negative", o.InstanceType, fleet.Name)
+			}
+			if o.Weight == 0 {
+				fleets[i].Overrides[j].Weight = 1
+			}
+		}
+	}
+
+	return fleets, nil
+}
+
+// InstanceTypes returns the instance types the fleet can launch
+func (c FleetConfig) InstanceTypes() []string {
+	types := make([]string, 0, len(c.Overrides))
+	for _, o := range c.Overrides {
+		types = append(types, o.InstanceType)
+	}
+	return types
+}
+
+// OnDemandFraction returns the share of the fleet's capacity launched on demand. Without a
+// known capacity the base capacity can't be accounted for and only the percentage is used.
+func (c FleetConfig) OnDemandFraction() float64 {
+	percentage := 100.0
+	if c.OnDemandPercentage != nil {
+		percentage = *c.OnDemandPercentage
+	}
+
+	if c.Capacity <= 0 {
+		return percentage / 100
+	}
+
+	base := math.Min(c.OnDemandBaseCapacity, c.Capacity)
+	onDemand := base + (c.Capacity-base)*percentage/100
+	return onDemand / c.Capacity
+}
+
+// FleetCost is the hourly cost of one unit of a fleet's capacity in USD
+type FleetCost struct {
+	OnDemand decimal.Decimal
+	// Spot is null for fleets that run entirely on demand
+	Spot    decimal.NullDecimal
+	Blended decimal.Decimal
+}
+
+// EstimateFleetCost prices a unit of fleet capacity from the prices of its instance types.
+// On-demand capacity comes from the first override when prioritized, or the cheapest per unit
+// otherwise. Spot capacity comes from the cheapest per unit with the lowest-price strategy, or
+// the average of the overrides for strategies that spread across pools. Overrides without a
+// price are left out, and spot is only required when part of the fleet runs on spot.
+func EstimateFleetCost(c FleetConfig, onDemand, spot map[string]decimal.Decimal) (FleetCost, error) {
+	var cost FleetCost
+
+	prioritized := c.OnDemandAllocationStrategy == "" || c.OnDemandAllocationStrategy == fleetOnDemandPrioritized
+	priced := false
+	for _, o := range c.Overrides {
+		price, ok := onDemand[o.InstanceType]
+		if !ok {
+			continue
+		}
+		perUnit := price.Div(decimal.NewFromFloat(o.Weight))
+		if !priced || perUnit.LessThan(cost.OnDemand) {
+			cost.OnDemand = perUnit
+		}
+		priced = true
+		if prioritized {
+			break
+		}
+	}
+	if !priced {
+		return FleetCost{}, fmt.Errorf("no on-demand price for any instance type of fleet %s", c.Name)
+	}
+
+	fraction := c.OnDemandFraction()
+	if fraction >= 1 {
+		cost.Blended = cost.OnDemand
+		return cost, nil
+	}
+
+	var total, cheapest decimal.Decimal
+	var pools int
+	for _, o := range c.Overrides {
+		price, ok := spot[o.InstanceType]
+		if !ok {
+			continue
+		}
+		perUnit := price.Div(decimal.NewFromFloat(o.Weight))
+		total = total.Add(perUnit)
+		if pools == 0 || perUnit.LessThan(cheapest) {
+			cheapest = perUnit
+		}
+		pools++
+	}
+	if pools == 0 {
+		return FleetCost{}, fmt.Errorf("no spot price for any instance type of fleet %s", c.Name)
+	}
+
+	spotCost := total.Div(decimal.NewFromInt(int64(pools)))
+	if c.SpotAllocationStrategy == fleetLowestPrice {
+		spotCost = cheapest
+	}
+	cost.Spot = decimal.NewNullDecimal(spotCost)
+
+	onDemandShare := decimal.NewFromFloat(fraction)
+	cost.Blended = cost.OnDemand.Mul(onDemandShare).Add(spotCost.Mul(decimal.NewFromInt(1).Sub(onDemandShare)))
+	return cost, nil
+}
+
+// ResolveAutoScalingGroup fills in a fleet's overrides, instances distribution, and desired
+// capacity from its Auto Scaling group
+func (f *AWSPricingFetcher) ResolveAutoScalingGroup(ctx context.Context, c FleetConfig) (FleetConfig, error) {
+	client := autoscaling.NewFromConfig(f.cfg, func(o *autoscaling.Options) {
+		o.Region = c.Region
+	})
+
+	output, err := client.DescribeAutoScalingGroups(ctx, &autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []string{c.ASG},
+	})
+	if err != nil {
+		return c, fmt.Errorf("failed to describe Auto Scaling group %s: %w", c.ASG, err)
+	}
+	if len(output.AutoScalingGroups) == 0 {
+		return c, fmt.Errorf("no Auto Scaling group %s in %s", c.ASG, c.Region)
+	}
+
+	group := output.AutoScalingGroups[0]
+	policy := group.MixedInstancesPolicy
+	if policy == nil || policy.LaunchTemplate == nil {
+		return c, fmt.Errorf("no mixed instances policy on Auto Scaling group %s", c.ASG)
+	}
+
+	resolved := FleetConfig{
+		Name:     c.Name,
+		Region:   c.Region,
+		ASG:      c.ASG,
+		Capacity: float64(aws.ToInt32(group.DesiredCapacity)),
+	}
+
+	for _, o := range policy.LaunchTemplate.Overrides {
+		if o.InstanceType == nil {
+			continue
+		}
+		weight, err := strconv.ParseFloat(aws.ToString(o.WeightedCapacity), 64)
+		if err != nil || weight <= 0 {
+			weight = 1
+		}
+		resolved.Overrides = append(resolved.Overrides, FleetOverride{
+			InstanceType: aws.ToString(o.InstanceType),
+			Weight:       weight,
+		})
+	}
+	if len(resolved.Overrides) == 0 {
+		return c, fmt.Errorf("no instance type overrides on Auto Scaling group %s", c.ASG)
+	}
+
+	if d := policy.InstancesDistribution; d != nil {
+		resolved.OnDemandBaseCapacity = float64(aws.ToInt32(d.OnDemandBaseCapacity))
+		if d.OnDemandPercentageAboveBaseCapacity != nil {
+			percentage := float64(*d.OnDemandPercentageAboveBaseCapacity)
+			resolved.OnDemandPercentage = &percentage
+		}
+		resolved.OnDemandAllocationStrategy = aws.ToString(d.OnDemandAllocationStrategy)
+		resolved.SpotAllocationStrategy = aws.ToString(d.SpotAllocationStrategy)
+	}
+
+	return resolved, nil
+}
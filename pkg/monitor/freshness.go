@@ -0,0 +1,50 @@
+package monitor
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// expiresAt returns when a published price is next due to be fetched: at its own interval with
+// an adaptive schedule and one poll interval after it was fetched otherwise. The prices of an
+// offline bundle are never fetched again, so they don't expire.
+func (m *Monitor) expiresAt(entry PriceEntry) time.Time {
+	if m.bundle != nil {
+		return time.Time{}
+	}
+
+	p := entry.Pricing
+	if m.schedule != nil {
+		if next, ok := m.schedule.Next(scheduledSeries{seriesOnDemand, p.Provider, p.Region, p.InstanceType}); ok {
+			return next
+		}
+	}
+	return entry.UpdatedAt.Add(m.pollInterval)
+}
+
+// refreshEntries fetches and publishes published prices again, such as those older than an API
+// consumer accepts. A confidential variant is fetched along with its standard instance type.
+func (m *Monitor) refreshEntries(ctx context.Context, entries []PriceEntry) {
+	if m.bundle != nil {
+		return
+	}
+
+	fetches := make(map[vmFetch]bool)
+	for _, entry := range entries {
+		p := entry.Pricing
+		f := vmFetch{provider: p.Provider, region: p.Region, instanceType: p.InstanceType}
+		fetches[f] = fetches[f] || p.Confidential
+	}
+
+	var wg sync.WaitGroup
+	for f, confidential := range fetches {
+		f.confidential = confidential
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.fetchVMPricing(ctx, f)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,828 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/providers"
+	"github.com/shopspring/decimal"
+	cloudbilling "google.golang.org/api/cloudbilling/v1"
+	cloudbillingbeta "google.golang.org/api/cloudbilling/v1beta"
+	compute "google.golang.org/api/compute/v1"
+)
+
+// gcpComputeServiceID is the Cloud Billing service ID for Compute Engine
+const gcpComputeServiceID = "services/6F81-5844-456A"
+
+type GCPPricingFetcher struct {
+	service *cloudbilling.APIService
+	compute *compute.Service
+
+	// serviceIDs caches the IDs of the services other than Compute Engine, by display name
+	serviceIDs sync.Map
+
+	// catalog holds the SKUs listed in the current poll cycle
+	catalog skuCatalog
+	// cache shares the listed SKUs with other replicas, when set
+	cache *sharedCache
+
+	// billingAccount, when set, prices SKUs at the account's contract prices, which
+	// accountService lists
+	billingAccount string
+	accountService *cloudbillingbeta.Service
+}
+
+func NewGCPPricingFetcher(ctx context.Context) (*GCPPricingFetcher, error) {
+	opts, err := gcpClientOptions(ctx, cloudbilling.CloudBillingReadonlyScope)
+	if err != nil {
+		return nil, err
+	}
+	service, err := cloudbilling.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCP billing service: %w", err)
+	}
+
+	// Compute Engine is only read to resolve instance templates and managed instance groups
+	opts, err = gcpClientOptions(ctx, compute.ComputeReadonlyScope)
+	if err != nil {
+		return nil, err
+	}
+	computeService, err := compute.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCP compute service: %w", err)
+	}
+
+	return &GCPPricingFetcher{
+		service: service,
+		compute: computeService,
+		cache:   sharedCacheFrom(ctx),
+	}, nil
+}
+
+// serviceID returns the Cloud Billing service ID of a service by its display name, such as
+// Cloud Filestore
+func (f *GCPPricingFetcher) serviceID(ctx context.Context, displayName string) (string, error) {
+	if id, ok := f.serviceIDs.Load(displayName); ok {
+		return id.(string), nil
+	}
+
+	var id string
+	err := f.service.Services.List().Pages(ctx, func(page *cloudbilling.ListServicesResponse) error {
+		for _, service := range page.Services {
+			if service.DisplayName == displayName {
+				id = service.Name
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list GCP billing services: %w", err)
+	}
+	if id == "" {
+		return "", fmt.Errorf("GCP billing service %s not found", displayName)
+	}
+
+	f.serviceIDs.Store(displayName, id)
+	return id, nil
+}
+
+// FetchPricing returns the price of a GCP instance type in a region: its machine type, and the
+// GPUs that come with or are attached to it
+func (f *GCPPricingFetcher) FetchPricing(ctx context.Context, region, instanceType string) (*VMPricing, error) {
+	machineType, gpuType, gpuCount, err := parseGCPInstanceType(instanceType)
+	if err != nil {
+		return nil, err
+	}
+	pricing, err := f.fetchMachinePricing(ctx, region, machineType)
+	if err != nil || gpuCount == 0 {
+		return pricing, err
+	}
+	return f.addGPUCost(ctx, pricing, instanceType, gpuType, gpuCount)
+}
+
+func (f *GCPPricingFetcher) fetchMachinePricing(ctx context.Context, region, machineType string) (*VMPricing, error) {
+	slog.Debug("fetching GCP pricing",
+		"region", region,
+		"machine_type", machineType,
+	)
+
+	// Custom machine types are billed with their own per-unit SKUs
+	if isCustomMachineType(machineType) {
+		return f.fetchCustomPricing(ctx, region, machineType)
+	}
+
+	// Parse machine type to get family and specs
+	// GCP machine types follow patterns like: e2-micro, n2-standard-2, n1-standard-4
+	family, vcpus, memoryGiB, err := parseMachineType(machineType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse machine type: %w", err)
+	}
+
+	// Look up both vCPU and memory pricing in the SKU catalog
+	vcpuRate, memoryRate, err := f.getPricing(ctx, gcpComputeServiceID, region, family)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pricing: %w", err)
+	}
+
+	trace := providers.MatchTraceFrom(ctx)
+	totalCost := vcpuRate.cost(trace, decimal.NewFromInt(int64(vcpus)), "vCPU").Add(memoryRate.cost(trace, decimal.NewFromFloat(memoryGiB), "GiB"))
+
+	slog.Debug("fetched GCP pricing",
+		"region", region,
+		"machine_type", machineType,
+		"vcpu_price", vcpuRate.price,
+		"memory_price", memoryRate.price,
+		"total_cost", totalCost,
+		"vcpus", vcpus,
+		"memory_gib", memoryGiB,
+	)
+
+	return &VMPricing{
+		Provider:     "gcp",
+		Region:       region,
+		InstanceType: machineType,
+		TotalCost:    totalCost,
+		MemoryGB:     gibToGB(memoryGiB),
+		VCPUs:        vcpus,
+	}, nil
+}
+
+// FetchSpotPricing returns the Spot price of a predefined machine type in a region. Spot
+// prices are set per region rather than per zone. Custom machine types and machine types with
+// GPUs aren't supported.
+func (f *GCPPricingFetcher) FetchSpotPricing(ctx context.Context, region, machineType string) (*VMPricing, error) {
+	if isCustomMachineType(machineType) {
+		return nil, fmt.Errorf("spot pricing of custom machine type %s is not supported", machineType)
+	}
+	if hasGPUs(machineType) {
+		return nil, fmt.Errorf("spot pricing of machine type %s with GPUs is not supported", machineType)
+	}
+
+	family, vcpus, memoryGiB, err := parseMachineType(machineType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse machine type: %w", err)
+	}
+
+	vcpuRate, memoryRate, err := f.findPricing(ctx, gcpComputeServiceID, region, family, spotVCPUSkuRejection, spotMemorySkuRejection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spot pricing: %w", err)
+	}
+
+	trace := providers.MatchTraceFrom(ctx)
+	return &VMPricing{
+		Provider:     "gcp",
+		Region:       region,
+		InstanceType: machineType,
+		TotalCost:    vcpuRate.cost(trace, decimal.NewFromInt(int64(vcpus)), "vCPU").Add(memoryRate.cost(trace, decimal.NewFromFloat(memoryGiB), "GiB")),
+		MemoryGB:     gibToGB(memoryGiB),
+		VCPUs:        vcpus,
+	}, nil
+}
+
+// ListMachineFamilies returns every machine family with on-demand vCPU SKUs in the catalog
+func (f *GCPPricingFetcher) ListMachineFamilies(ctx context.Context) ([]string, error) {
+	skus, err := f.skus(ctx, gcpComputeServiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list GCP machine families: %w", err)
+	}
+
+	var families []string
+	for _, sku := range skus {
+		product, _ := normalizeSkuDescription(sku.Description)
+
+		// Matches "n4 instance core" and "n1 predefined instance core"
+		fields := strings.Fields(strings.Replace(product, " predefined ", " ", 1))
+		if len(fields) != 3 || fields[1] != "instance" || fields[2] != "core" {
+			continue
+		}
+
+		if !slices.Contains(families, fields[0]) {
+			families = append(families, fields[0])
+		}
+	}
+
+	return families, nil
+}
+
+// gcpMaxMemoryPerVCPU is the memory per vCPU (GiB) that custom machine types can have before
+// the remainder is billed at the extended memory rate
+var gcpMaxMemoryPerVCPU = map[string]float64{
+	"n1":  6.5,
+	"n2":  8,
+	"n2d": 8,
+	"n4":  8,
+	"e2":  8,
+}
+
+// fetchCustomPricing prices custom machine types such as n2-custom-4-16384 or
+// n2-custom-4-49152-ext. Memory beyond the family's standard ratio is billed at the
+// extended memory rate and requires the -ext suffix.
+func (f *GCPPricingFetcher) fetchCustomPricing(ctx context.Context, region, machineType string) (*VMPricing, error) {
+	custom, err := parseCustomMachineType(machineType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse machine type: %w", err)
+	}
+
+	maxPerVCPU, ok := gcpMaxMemoryPerVCPU[custom.family]
+	if !ok {
+		return nil, fmt.Errorf("custom machine types are not supported for family %s", custom.family)
+	}
+
+	standardMemoryGiB := min(custom.memoryGiB, maxPerVCPU*float64(custom.vcpus))
+	extendedMemoryGiB := custom.memoryGiB - standardMemoryGiB
+
+	if extendedMemoryGiB > 0 && !custom.extended {
+		return nil, fmt.Errorf("machine type %s exceeds %.1f GiB per vCPU and needs the -ext suffix", machineType, maxPerVCPU)
+	}
+
+	if extendedMemoryGiB > 0 && custom.family == "e2" {
+		return nil, fmt.Errorf("extended memory is not supported for family e2")
+	}
+
+	var vcpuRate, memoryRate, extendedRate gcpRate
+	if custom.family == "e2" {
+		// E2 custom machine types are billed at the predefined E2 rates
+		vcpuRate, memoryRate, err = f.getPricing(ctx, gcpComputeServiceID, region, custom.family)
+	} else {
+		vcpuRate, memoryRate, extendedRate, err = f.getCustomPricing(ctx, gcpComputeServiceID, region, custom.family, extendedMemoryGiB > 0)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pricing: %w", err)
+	}
+
+	trace := providers.MatchTraceFrom(ctx)
+	totalCost := decimal.Sum(
+		vcpuRate.cost(trace, decimal.NewFromInt(int64(custom.vcpus)), "vCPU"),
+		memoryRate.cost(trace, decimal.NewFromFloat(standardMemoryGiB), "GiB"),
+		extendedRate.cost(trace, decimal.NewFromFloat(extendedMemoryGiB), "GiB"),
+	)
+
+	slog.Debug("fetched GCP custom pricing",
+		"region", region,
+		"machine_type", machineType,
+		"vcpu_price", vcpuRate.price,
+		"memory_price", memoryRate.price,
+		"extended_memory_price", extendedRate.price,
+		"extended_memory_gib", extendedMemoryGiB,
+		"total_cost", totalCost,
+	)
+
+	return &VMPricing{
+		Provider:     "gcp",
+		Region:       region,
+		InstanceType: machineType,
+		TotalCost:    totalCost,
+		MemoryGB:     gibToGB(custom.memoryGiB),
+		VCPUs:        custom.vcpus,
+	}, nil
+}
+
+// getCustomPricing looks up the custom vCPU, memory, and (optionally) extended memory rates in the SKU catalog
+func (f *GCPPricingFetcher) getCustomPricing(ctx context.Context, serviceId, region, family string, needExtended bool) (vcpuRate, memoryRate, extendedRate gcpRate, err error) {
+	skus, err := f.skus(ctx, serviceId)
+	if err != nil {
+		return gcpRate{}, gcpRate{}, gcpRate{}, err
+	}
+
+	// Only the SKUs taken are traced, as custom SKUs name their family inconsistently
+	trace := providers.MatchTraceFrom(ctx)
+	trace.Query("custom machine SKUs of service %s in region %s for family %s", serviceId, region, family)
+
+	var foundVCPU, foundMemory, foundExtended bool
+
+	for _, sku := range skus {
+		if len(sku.PricingInfo) == 0 || len(sku.PricingInfo[0].PricingExpression.TieredRates) == 0 {
+			continue
+		}
+		rate := newGCPRate(sku)
+		price := rate.price
+
+		switch {
+		case !foundVCPU && f.matchesCustomSku(sku, region, family, "core"):
+			vcpuRate = rate
+			foundVCPU = true
+			trace.Match(sku.SkuId, sku.Description, fmt.Sprintf("custom vCPU price %s per hour", price))
+		case !foundMemory && f.matchesCustomSku(sku, region, family, "ram"):
+			memoryRate = rate
+			foundMemory = true
+			trace.Match(sku.SkuId, sku.Description, fmt.Sprintf("custom memory price %s per GiB hour", price))
+		case needExtended && !foundExtended && f.matchesCustomSku(sku, region, family, "extended"):
+			extendedRate = rate
+			foundExtended = true
+			trace.Match(sku.SkuId, sku.Description, fmt.Sprintf("extended memory price %s per GiB hour", price))
+		}
+
+		if foundVCPU && foundMemory && (foundExtended || !needExtended) {
+			break
+		}
+	}
+
+	if !foundVCPU || !foundMemory {
+		return gcpRate{}, gcpRate{}, gcpRate{}, fmt.Errorf("%w for custom machines in region %s and family %s", errNoPricingFound, region, family)
+	}
+
+	if needExtended && !foundExtended {
+		return gcpRate{}, gcpRate{}, gcpRate{}, fmt.Errorf("%w for extended memory in region %s and family %s", errNoPricingFound, region, family)
+	}
+
+	return vcpuRate, memoryRate, extendedRate, nil
+}
+
+// matchesCustomSku matches on-demand custom machine SKUs. kind is "core", "ram", or "extended".
+// SKU descriptions look like "N2 Custom Extended Instance Ram running in Americas"; N1 custom
+// SKUs have no family prefix ("Custom Instance Core running in Americas").
+func (f *GCPPricingFetcher) matchesCustomSku(sku *cloudbilling.Sku, region, family, kind string) bool {
+	desc, _ := normalizeSkuDescription(sku.Description)
+
+	if !strings.Contains(desc, "custom") {
+		return false
+	}
+
+	if strings.Contains(desc, "preemptible") ||
+		strings.Contains(desc, "spot") ||
+		strings.Contains(desc, "commit") ||
+		strings.Contains(desc, "confidential") ||
+		strings.Contains(desc, "sole tenancy") {
+		return false
+	}
+
+	extended := strings.Contains(desc, "extended")
+	switch kind {
+	case "core":
+		if extended || (!strings.Contains(desc, "core") && !strings.Contains(desc, "vcpu")) {
+			return false
+		}
+	case "ram":
+		if extended || (!strings.Contains(desc, "ram") && !strings.Contains(desc, "memory")) {
+			return false
+		}
+	case "extended":
+		if !extended || (!strings.Contains(desc, "ram") && !strings.Contains(desc, "memory")) {
+			return false
+		}
+	default:
+		return false
+	}
+
+	prefix, _, _ := strings.Cut(desc, " ")
+	if family == "n1" {
+		if prefix != "custom" {
+			return false
+		}
+	} else if prefix != family {
+		return false
+	}
+
+	return skuMatchesRegion(sku, region)
+}
+
+// gcpConfidentialFamilies lists the machine families that can run as Confidential VMs
+var gcpConfidentialFamilies = []string{"n2d", "c2d", "c3d", "c3"}
+
+// FetchConfidentialPricing returns the price of the Confidential VM variant of a machine type.
+// GCP bills Confidential VMs as the standard vCPU and RAM rates plus a per-unit premium.
+func (f *GCPPricingFetcher) FetchConfidentialPricing(ctx context.Context, standard VMPricing) (*VMPricing, error) {
+	family, _, _ := strings.Cut(standard.InstanceType, "-")
+	if !slices.Contains(gcpConfidentialFamilies, family) {
+		return nil, fmt.Errorf("%w: %s", errConfidentialUnsupported, standard.InstanceType)
+	}
+
+	vcpuPremium, memoryPremium, err := f.getConfidentialPremium(ctx, gcpComputeServiceID, standard.Region, family)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get confidential pricing: %w", err)
+	}
+
+	// Memory SKUs are priced per GiB
+	premium := vcpuPremium.Mul(decimal.NewFromInt(int64(standard.VCPUs))).Add(memoryPremium.Mul(memoryUnitGiB.FromGB(standard.MemoryGB)))
+
+	slog.Debug("fetched GCP confidential pricing",
+		"region", standard.Region,
+		"machine_type", standard.InstanceType,
+		"vcpu_premium", vcpuPremium,
+		"memory_premium", memoryPremium,
+		"premium", premium,
+	)
+
+	confidential := standard
+	confidential.TotalCost = confidential.TotalCost.Add(premium)
+	confidential.Confidential = true
+	return &confidential, nil
+}
+
+// getConfidentialPremium looks up the Confidential VM vCPU and memory surcharges for a family
+func (f *GCPPricingFetcher) getConfidentialPremium(ctx context.Context, serviceId, region, family string) (vcpuPremium, memoryPremium decimal.Decimal, err error) {
+	skus, err := f.skus(ctx, serviceId)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+
+	var foundVCPU, foundMemory bool
+
+	for _, sku := range skus {
+		if len(sku.PricingInfo) == 0 || len(sku.PricingInfo[0].PricingExpression.TieredRates) == 0 {
+			continue
+		}
+		rate := sku.PricingInfo[0].PricingExpression.TieredRates[0].UnitPrice
+		price := moneyToDecimal(rate)
+
+		if !foundVCPU && f.matchesConfidentialSku(sku, region, family, false) {
+			vcpuPremium = price
+			foundVCPU = true
+		}
+
+		if !foundMemory && f.matchesConfidentialSku(sku, region, family, true) {
+			memoryPremium = price
+			foundMemory = true
+		}
+
+		if foundVCPU && foundMemory {
+			break
+		}
+	}
+
+	if !foundVCPU || !foundMemory {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("%w for Confidential VM in region %s and family %s", errNoPricingFound, region, family)
+	}
+
+	return vcpuPremium, memoryPremium, nil
+}
+
+// matchesConfidentialSku matches the on-demand Confidential VM surcharge SKUs for a family
+func (f *GCPPricingFetcher) matchesConfidentialSku(sku *cloudbilling.Sku, region, family string, memory bool) bool {
+	desc, _ := normalizeSkuDescription(sku.Description)
+
+	if !strings.Contains(desc, "confidential") {
+		return false
+	}
+
+	if strings.Contains(desc, "preemptible") ||
+		strings.Contains(desc, "spot") ||
+		strings.Contains(desc, "commit") {
+		return false
+	}
+
+	if memory {
+		if !strings.Contains(desc, "ram") && !strings.Contains(desc, "memory") {
+			return false
+		}
+	} else if !strings.Contains(desc, "core") && !strings.Contains(desc, "vcpu") {
+		return false
+	}
+
+	// Match the family as a whole word so that "c3" doesn't match "c3d"
+	if !slices.Contains(strings.Fields(desc), family) {
+		return false
+	}
+
+	return skuMatchesRegion(sku, region)
+}
+
+// moneyToDecimal converts a catalog amount of whole units and nanos to an exact decimal
+func moneyToDecimal(m *cloudbilling.Money) decimal.Decimal {
+	return decimal.NewFromInt(m.Units).Add(decimal.New(m.Nanos, -9))
+}
+
+// gcpRate is the price of a unit of a resource for an hour, and the SKU it was taken from
+type gcpRate struct {
+	sku   *cloudbilling.Sku
+	price decimal.Decimal
+}
+
+// newGCPRate returns the rate of a SKU's first pricing tier
+func newGCPRate(sku *cloudbilling.Sku) gcpRate {
+	return gcpRate{sku: sku, price: moneyToDecimal(sku.PricingInfo[0].PricingExpression.TieredRates[0].UnitPrice)}
+}
+
+// cost returns the price of a quantity of units at the rate, recording it as a component of a
+// traced price. A rate that wasn't looked up costs nothing.
+func (r gcpRate) cost(trace *providers.MatchTrace, quantity decimal.Decimal, unit string) decimal.Decimal {
+	if r.sku == nil {
+		return decimal.Zero
+	}
+	trace.Component(providers.PriceComponent{
+		ID:          r.sku.SkuId,
+		Description: r.sku.Description,
+		Rate:        r.price,
+		Quantity:    quantity,
+		Unit:        unit,
+	})
+	return r.price.Mul(quantity)
+}
+
+// skuRejection returns why a SKU doesn't price a machine family in a region, or nothing when
+// it does
+type skuRejection func(sku *cloudbilling.Sku, region, family string) string
+
+// getPricing looks up both vCPU and memory pricing in the SKU catalog
+func (f *GCPPricingFetcher) getPricing(ctx context.Context, serviceId, region, family string) (vcpuRate, memoryRate gcpRate, err error) {
+	return f.findPricing(ctx, serviceId, region, family, vcpuSkuRejection, memorySkuRejection)
+}
+
+// findPricing looks up the vCPU and memory prices of a family from the first SKUs of the
+// catalog the rejections accept. A traced fetch records every SKU that names the family, as
+// the SKUs of other families would bury the ones worth reading.
+func (f *GCPPricingFetcher) findPricing(ctx context.Context, serviceId, region, family string, rejectVCPU, rejectMemory skuRejection) (vcpuRate, memoryRate gcpRate, err error) {
+	skus, err := f.skus(ctx, serviceId)
+	if err != nil {
+		return gcpRate{}, gcpRate{}, err
+	}
+
+	trace := providers.MatchTraceFrom(ctx)
+	trace.Query("SKUs of service %s in region %s for family %s", serviceId, region, family)
+
+	var foundVCPU, foundMemory bool
+
+	for _, sku := range skus {
+		vcpuReason, memoryReason := rejectVCPU(sku, region, family), rejectMemory(sku, region, family)
+		hasRate := len(sku.PricingInfo) > 0 && len(sku.PricingInfo[0].PricingExpression.TieredRates) > 0
+
+		// Check for vCPU pricing
+		matched := false
+		if !foundVCPU && vcpuReason == "" && hasRate {
+			vcpuRate = newGCPRate(sku)
+			foundVCPU = true
+			matched = true
+			trace.Match(sku.SkuId, sku.Description, fmt.Sprintf("vCPU price %s per hour", vcpuRate.price))
+		}
+
+		// Check for memory pricing
+		if !foundMemory && memoryReason == "" && hasRate {
+			memoryRate = newGCPRate(sku)
+			foundMemory = true
+			matched = true
+			trace.Match(sku.SkuId, sku.Description, fmt.Sprintf("memory price %s per GiB hour", memoryRate.price))
+		}
+
+		if trace != nil && !matched {
+			desc, _ := normalizeSkuDescription(sku.Description)
+			if skuMatchesFamily(desc, family) {
+				trace.Reject(sku.SkuId, sku.Description, traceRejection(vcpuReason, memoryReason, hasRate))
+			}
+		}
+
+		// Early exit if we found both prices, unless the remaining SKUs are traced
+		if foundVCPU && foundMemory && trace == nil {
+			break
+		}
+	}
+
+	if !foundVCPU {
+		return gcpRate{}, gcpRate{}, fmt.Errorf("%w for vCPU in region %s and family %s", errNoPricingFound, region, family)
+	}
+
+	if !foundMemory {
+		return gcpRate{}, gcpRate{}, fmt.Errorf("%w for memory in region %s and family %s", errNoPricingFound, region, family)
+	}
+
+	return vcpuRate, memoryRate, nil
+}
+
+// traceRejection explains why a SKU wasn't taken for either price of a family
+func traceRejection(vcpuReason, memoryReason string, hasRate bool) string {
+	switch {
+	case vcpuReason == "" || memoryReason == "":
+		if !hasRate {
+			return "no rate"
+		}
+		return "an earlier SKU was taken"
+	case vcpuReason == memoryReason:
+		return vcpuReason
+	default:
+		return vcpuReason + "; " + memoryReason
+	}
+}
+
+func (f *GCPPricingFetcher) matchesVCPUSku(sku *cloudbilling.Sku, region, family string) bool {
+	return vcpuSkuRejection(sku, region, family) == ""
+}
+
+func (f *GCPPricingFetcher) matchesMemorySku(sku *cloudbilling.Sku, region, family string) bool {
+	return memorySkuRejection(sku, region, family) == ""
+}
+
+// vcpuSkuRejection returns why a SKU isn't the on-demand price of a family's vCPUs
+func vcpuSkuRejection(sku *cloudbilling.Sku, region, family string) string {
+	return onDemandSkuRejection(sku, region, family, "vCPU", "core", "vcpu")
+}
+
+// memorySkuRejection returns why a SKU isn't the on-demand price of a family's memory
+func memorySkuRejection(sku *cloudbilling.Sku, region, family string) string {
+	return onDemandSkuRejection(sku, region, family, "memory", "ram", "memory")
+}
+
+// onDemandSkuRejection returns why a SKU isn't the on-demand price of a resource of a
+// predefined machine of a family in a region, the resource being named by one of the keywords
+func onDemandSkuRejection(sku *cloudbilling.Sku, region, family, resource string, keywords ...string) string {
+	desc, _ := normalizeSkuDescription(sku.Description)
+
+	// Exclude preemptible, spot, commitment-based, confidential computing, and custom machine pricing
+	switch {
+	case strings.Contains(desc, "preemptible"), strings.Contains(desc, "spot"):
+		return "Spot or preemptible price"
+	case strings.Contains(desc, "commit"):
+		return "commitment price"
+	case strings.Contains(desc, "confidential"):
+		return "Confidential VM price"
+	case strings.Contains(desc, "custom"):
+		return "custom machine price"
+	}
+
+	// Exclude continued use discounts
+	if strings.Contains(desc, "discount") || strings.Contains(desc, "cud") {
+		return "discount"
+	}
+
+	// Check if it's a SKU of the resource
+	if !slices.ContainsFunc(keywords, func(k string) bool { return strings.Contains(desc, k) }) {
+		return "not a " + resource + " SKU"
+	}
+
+	// Check if it's for the right family
+	if !skuMatchesFamily(desc, family) {
+		return "not the " + family + " family"
+	}
+
+	// Check region match
+	if !skuMatchesRegion(sku, region) {
+		return "not offered in " + region
+	}
+	return ""
+}
+
+// spotVCPUSkuRejection returns why a SKU isn't the Spot price of a family's vCPUs. Spot SKUs
+// kept the "Preemptible" name of the VMs they replaced, as in "Spot Preemptible N2 Instance Core".
+func spotVCPUSkuRejection(sku *cloudbilling.Sku, region, family string) string {
+	desc, _ := normalizeSkuDescription(sku.Description)
+	if !strings.Contains(desc, "core") && !strings.Contains(desc, "vcpu") {
+		return "not a vCPU SKU"
+	}
+	return spotSkuRejection(sku, desc, region, family)
+}
+
+// spotMemorySkuRejection returns why a SKU isn't the Spot price of a family's memory
+func spotMemorySkuRejection(sku *cloudbilling.Sku, region, family string) string {
+	desc, _ := normalizeSkuDescription(sku.Description)
+	if !strings.Contains(desc, "ram") && !strings.Contains(desc, "memory") {
+		return "not a memory SKU"
+	}
+	return spotSkuRejection(sku, desc, region, family)
+}
+
+// spotSkuRejection returns why a SKU with a normalized description isn't a Spot price of a
+// predefined machine of a family in a region
+func spotSkuRejection(sku *cloudbilling.Sku, desc, region, family string) string {
+	switch {
+	case !strings.Contains(desc, "preemptible"):
+		return "not a Spot price"
+	case strings.Contains(desc, "commit"):
+		return "commitment price"
+	case strings.Contains(desc, "confidential"):
+		return "Confidential VM price"
+	case strings.Contains(desc, "custom"):
+		return "custom machine price"
+	case strings.Contains(desc, "discount"):
+		return "discount"
+	case !skuMatchesFamily(desc, family):
+		return "not the " + family + " family"
+	case !skuMatchesRegion(sku, region):
+		return "not offered in " + region
+	default:
+		return ""
+	}
+}
+
+// skuMatchesFamily reports whether a normalized SKU description names a machine family
+func skuMatchesFamily(desc, family string) bool {
+	switch family {
+	case "e2":
+		return strings.Contains(desc, "e2 instance")
+	case "n1":
+		return strings.Contains(desc, "n1 predefined") || strings.Contains(desc, "n1 instance")
+	case "n2", "n2d":
+		return strings.Contains(desc, "n2 instance") || strings.Contains(desc, "n2d instance")
+	case "n4", "n4d":
+		return strings.Contains(desc, "n4 instance") || strings.Contains(desc, "n4d instance")
+	case "c2", "c2d", "c3", "c4":
+		return strings.Contains(desc, family+" instance")
+	default:
+		return strings.Contains(desc, family)
+	}
+}
+
+// parseMachineType extracts the machine family, vCPU count, and memory in GiB from GCP machine type
+func parseMachineType(machineType string) (family string, vcpus int, memoryGiB float64, err error) {
+	if isCustomMachineType(machineType) {
+		custom, err := parseCustomMachineType(machineType)
+		if err != nil {
+			return "", 0, 0, err
+		}
+		return custom.family, custom.vcpus, custom.memoryGiB, nil
+	}
+
+	if shape, ok := gcpAcceleratorMachineTypes[machineType]; ok {
+		family, _, _ = strings.Cut(machineType, "-")
+		return family, shape.vcpus, shape.memoryGiB, nil
+	}
+
+	// Standard machine types: e2-micro, e2-small, e2-medium, n1-standard-1, n2-standard-2, etc.
+	parts := strings.Split(machineType, "-")
+	if len(parts) < 2 {
+		return "", 0, 0, fmt.Errorf("invalid machine type format: %s", machineType)
+	}
+
+	family = parts[0]
+	machineClass := parts[1]
+
+	// Handle predefined machine types
+	switch machineType {
+	case "e2-micro":
+		return "e2", 2, 1.0, nil
+	case "e2-small":
+		return "e2", 2, 2.0, nil
+	case "e2-medium":
+		return "e2", 2, 4.0, nil
+	case "f1-micro":
+		return "f1", 1, 0.6, nil
+	case "g1-small":
+		return "g1", 1, 1.7, nil
+	}
+
+	// For standard machine types, extract vCPU count from the name
+	var vcpuCount int
+	if len(parts) >= 3 {
+		_, err := fmt.Sscanf(parts[2], "%d", &vcpuCount)
+		if err != nil {
+			return "", 0, 0, fmt.Errorf("invalid vCPU count in machine type: %w", err)
+		}
+	}
+
+	if vcpuCount == 0 {
+		return "", 0, 0, fmt.Errorf("could not determine vCPU count for machine type: %s", machineType)
+	}
+
+	// Calculate memory based on machine class
+	var memory float64
+	switch machineClass {
+	case "standard":
+		memory = float64(vcpuCount) * 3.75 // 3.75 GiB per vCPU
+	case "highmem":
+		memory = float64(vcpuCount) * 6.5 // 6.5 GiB per vCPU
+	case "highcpu":
+		memory = float64(vcpuCount) * 0.9 // 0.9 GiB per vCPU
+	default:
+		memory = float64(vcpuCount) * 4.0 // Default ratio
+	}
+
+	return family, vcpuCount, memory, nil
+}
+
+// customMachineType describes a GCP custom machine type
+type customMachineType struct {
+	family    string
+	vcpus     int
+	memoryGiB float64
+	extended  bool
+}
+
+// isCustomMachineType reports whether the machine type is a custom machine type
+func isCustomMachineType(machineType string) bool {
+	return strings.HasPrefix(machineType, "custom-") || strings.Contains(machineType, "-custom-")
+}
+
+// parseCustomMachineType parses custom machine types in the form [FAMILY-]custom-VCPUS-MEMORY_MB[-ext].
+// N1 custom machine types omit the family prefix.
+func parseCustomMachineType(machineType string) (*customMachineType, error) {
+	parts := strings.Split(machineType, "-")
+	if parts[0] == "custom" {
+		parts = append([]string{"n1"}, parts...)
+	}
+
+	custom := &customMachineType{family: parts[0]}
+	if parts[len(parts)-1] == "ext" {
+		custom.extended = true
+		parts = parts[:len(parts)-1]
+	}
+
+	if len(parts) != 4 || parts[1] != "custom" {
+		return nil, fmt.Errorf("invalid custom machine type format: %s", machineType)
+	}
+
+	vcpus, err := strconv.Atoi(parts[2])
+	if err != nil || vcpus <= 0 {
+		return nil, fmt.Errorf("invalid vCPU count in machine type: %s", machineType)
+	}
+
+	memoryMB, err := strconv.Atoi(parts[3])
+	if err != nil || memoryMB <= 0 {
+		return nil, fmt.Errorf("invalid memory size in machine type: %s", machineType)
+	}
+
+	custom.vcpus = vcpus
+	custom.memoryGiB = float64(memoryMB) / 1024
+	return custom, nil
+}
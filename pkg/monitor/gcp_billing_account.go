@@ -0,0 +1,193 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	cloudbilling "google.golang.org/api/cloudbilling/v1"
+	cloudbillingbeta "google.golang.org/api/cloudbilling/v1beta"
+)
+
+// gcpBillingAccountID matches the ID of a Cloud Billing account, such as 012345-567890-ABCDEF
+var gcpBillingAccountID = regexp.MustCompile(`^[0-9A-F]{6}-[0-9A-F]{6}-[0-9A-F]{6}$`)
+
+// checkGCPBillingAccount rejects a malformed billing account ID before any SKU is listed with it
+func checkGCPBillingAccount(id string) error {
+	if id != "" && !gcpBillingAccountID.MatchString(id) {
+		return fmt.Errorf("gcp-billing-account must be a billing account ID such as 012345-567890-ABCDEF, got %q", id)
+	}
+	return nil
+}
+
+// contractPriceListing is the contract price of every SKU of the billing account by SKU ID,
+// ready once done is closed
+type contractPriceListing struct {
+	done   chan struct{}
+	prices map[string]*cloudbillingbeta.GoogleCloudBillingBillingaccountpricesV1betaBillingAccountPrice
+	err    error
+}
+
+// UseBillingAccount makes the fetcher price SKUs at the contract prices of a Cloud Billing
+// account, which carry its negotiated discounts, instead of the list prices of the public
+// catalog
+func (f *GCPPricingFetcher) UseBillingAccount(ctx context.Context, id string) error {
+	opts, err := gcpClientOptions(ctx, cloudbillingbeta.CloudBillingReadonlyScope)
+	if err != nil {
+		return err
+	}
+	service, err := cloudbillingbeta.NewService(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create GCP billing account pricing service: %w", err)
+	}
+	f.billingAccount = id
+	f.accountService = service
+	return nil
+}
+
+// contractPrices returns the contract prices of the billing account, listing them on the first
+// lookup of the cycle. Like the SKUs, concurrent lookups wait for the same listing, and a
+// failed listing isn't kept.
+func (f *GCPPricingFetcher) contractPrices(ctx context.Context) (map[string]*cloudbillingbeta.GoogleCloudBillingBillingaccountpricesV1betaBillingAccountPrice, error) {
+	f.catalog.mu.Lock()
+	listing := f.catalog.prices
+	listed := listing != nil
+	if !listed {
+		listing = &contractPriceListing{done: make(chan struct{})}
+		f.catalog.prices = listing
+	}
+	f.catalog.mu.Unlock()
+
+	if !listed {
+		listing.prices, listing.err = f.listContractPrices(ctx)
+		if listing.err != nil {
+			f.catalog.mu.Lock()
+			if f.catalog.prices == listing {
+				f.catalog.prices = nil
+			}
+			f.catalog.mu.Unlock()
+		}
+		close(listing.done)
+	}
+
+	select {
+	case <-listing.done:
+		return listing.prices, listing.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// listContractPrices pages through the price of every SKU of the billing account in USD
+func (f *GCPPricingFetcher) listContractPrices(ctx context.Context) (map[string]*cloudbillingbeta.GoogleCloudBillingBillingaccountpricesV1betaBillingAccountPrice, error) {
+	start := time.Now()
+
+	parent := "billingAccounts/" + f.billingAccount + "/skus/-"
+	call := f.accountService.BillingAccounts.Skus.Prices.List(parent)
+	call.CurrencyCode("USD")
+	call.PageSize(5000)
+
+	prices := make(map[string]*cloudbillingbeta.GoogleCloudBillingBillingaccountpricesV1betaBillingAccountPrice)
+	err := call.Pages(ctx, func(page *cloudbillingbeta.GoogleCloudBillingBillingaccountpricesV1betaListBillingAccountPricesResponse) error {
+		for _, price := range page.BillingAccountPrices {
+			// Prices are named billingAccounts/{account}/skus/{sku}/price
+			skuID := strings.TrimSuffix(strings.TrimPrefix(price.Name, "billingAccounts/"+f.billingAccount+"/skus/"), "/price")
+			prices[skuID] = price
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list GCP contract prices of billing account %s: %w", f.billingAccount, err)
+	}
+
+	slog.Debug("listed GCP contract prices",
+		"billing_account", f.billingAccount,
+		"skus", len(prices),
+		"duration", time.Since(start),
+	)
+	return prices, nil
+}
+
+// applyContractPrices replaces the list prices of SKUs with the billing account's contract
+// prices. A SKU without a contract price, or whose contract price is in another unit than its
+// list price, keeps its list price.
+func (f *GCPPricingFetcher) applyContractPrices(ctx context.Context, serviceId string, skus []*cloudbilling.Sku) error {
+	prices, err := f.contractPrices(ctx)
+	if err != nil {
+		return err
+	}
+
+	applied := 0
+	for _, sku := range skus {
+		price, ok := prices[sku.SkuId]
+		if !ok || len(sku.PricingInfo) == 0 || sku.PricingInfo[0].PricingExpression == nil {
+			continue
+		}
+		expression := sku.PricingInfo[0].PricingExpression
+		rates, ok := contractTieredRates(price, expression.UsageUnit)
+		if !ok {
+			slog.Debug("GCP contract price doesn't match the SKU's list price, keeping the list price",
+				"sku", sku.SkuId,
+				"description", sku.Description,
+			)
+			continue
+		}
+		expression.TieredRates = rates
+		applied++
+	}
+
+	slog.Debug("applied GCP contract prices",
+		"service", serviceId,
+		"billing_account", f.billingAccount,
+		"skus", len(skus),
+		"contract_priced", applied,
+	)
+	return nil
+}
+
+// contractTieredRates converts the tiers of a contract price into the tiered rates of the
+// public catalog, priced per usage unit, and tells whether the price could be converted
+func contractTieredRates(price *cloudbillingbeta.GoogleCloudBillingBillingaccountpricesV1betaBillingAccountPrice, usageUnit string) ([]*cloudbilling.TierRate, bool) {
+	rate := price.Rate
+	if price.CurrencyCode != "USD" || rate == nil || len(rate.Tiers) == 0 || rate.UnitInfo == nil || rate.UnitInfo.Unit != usageUnit {
+		return nil, false
+	}
+	quantity := decimal.NewFromInt(1)
+	if q := rate.UnitInfo.UnitQuantity; q != nil && q.Value != "" {
+		var err error
+		if quantity, err = decimal.NewFromString(q.Value); err != nil || !quantity.IsPositive() {
+			return nil, false
+		}
+	}
+
+	rates := make([]*cloudbilling.TierRate, 0, len(rate.Tiers))
+	for _, tier := range rate.Tiers {
+		if tier.ContractPrice == nil {
+			return nil, false
+		}
+		start := decimal.Zero
+		if tier.StartAmount != nil && tier.StartAmount.Value != "" {
+			var err error
+			if start, err = decimal.NewFromString(tier.StartAmount.Value); err != nil {
+				return nil, false
+			}
+		}
+
+		contract := decimal.NewFromInt(tier.ContractPrice.Units).Add(decimal.New(tier.ContractPrice.Nanos, -9))
+		unitPrice := contract.Div(quantity)
+		units := unitPrice.Truncate(0)
+		rates = append(rates, &cloudbilling.TierRate{
+			StartUsageAmount: start.InexactFloat64(),
+			UnitPrice: &cloudbilling.Money{
+				CurrencyCode: "USD",
+				Units:        units.IntPart(),
+				Nanos:        unitPrice.Sub(units).Shift(9).Round(0).IntPart(),
+			},
+		})
+	}
+	return rates, true
+}
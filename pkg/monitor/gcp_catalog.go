@@ -0,0 +1,121 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	cloudbilling "google.golang.org/api/cloudbilling/v1"
+)
+
+// skuCatalog holds the SKUs of each billing service for the current poll cycle. The Compute
+// Engine catalog takes dozens of pages to list, so it is listed once per cycle and every
+// machine type, disk, and GPU of the cycle is priced from memory.
+type skuCatalog struct {
+	mu       sync.Mutex
+	services map[string]*skuListing
+	// prices are the contract prices of the billing account, when SKUs are priced with one
+	prices *contractPriceListing
+}
+
+// skuListing is the SKU list of a service, ready once done is closed
+type skuListing struct {
+	done chan struct{}
+	skus []*cloudbilling.Sku
+	err  error
+}
+
+// ResetCatalog drops the SKUs listed so far, so the next lookup lists the catalog again. The
+// monitor resets it at the start of every poll cycle, which picks up price changes.
+func (f *GCPPricingFetcher) ResetCatalog() {
+	f.catalog.mu.Lock()
+	defer f.catalog.mu.Unlock()
+	f.catalog.services = nil
+	f.catalog.prices = nil
+}
+
+// skus returns every SKU of a billing service priced in USD, listing them on the first lookup
+// of the cycle. Concurrent lookups wait for the same listing. A failed listing isn't kept, so
+// the next lookup tries again.
+func (f *GCPPricingFetcher) skus(ctx context.Context, serviceId string) ([]*cloudbilling.Sku, error) {
+	f.catalog.mu.Lock()
+	listing, listed := f.catalog.services[serviceId]
+	if !listed {
+		if f.catalog.services == nil {
+			f.catalog.services = make(map[string]*skuListing)
+		}
+		listing = &skuListing{done: make(chan struct{})}
+		f.catalog.services[serviceId] = listing
+	}
+	f.catalog.mu.Unlock()
+
+	if !listed {
+		listing.skus, listing.err = f.cachedSkus(ctx, serviceId)
+		if listing.err != nil {
+			f.catalog.mu.Lock()
+			if f.catalog.services[serviceId] == listing {
+				delete(f.catalog.services, serviceId)
+			}
+			f.catalog.mu.Unlock()
+		}
+		close(listing.done)
+	}
+
+	select {
+	case <-listing.done:
+		return listing.skus, listing.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// cachedSkus lists the SKUs of a billing service, at the billing account's contract prices when
+// there is one, or takes them from the shared cache when another replica listed them recently
+func (f *GCPPricingFetcher) cachedSkus(ctx context.Context, serviceId string) ([]*cloudbilling.Sku, error) {
+	key := serviceId
+	if f.billingAccount != "" {
+		key = f.billingAccount + "/" + serviceId
+	}
+
+	var skus []*cloudbilling.Sku
+	if f.cache.load(ctx, "skus", key, &skus) {
+		return skus, nil
+	}
+	skus, err := f.listSkus(ctx, serviceId)
+	if err != nil {
+		return nil, err
+	}
+	if f.billingAccount != "" {
+		if err := f.applyContractPrices(ctx, serviceId, skus); err != nil {
+			return nil, err
+		}
+	}
+	f.cache.store(ctx, "skus", key, skus)
+	return skus, nil
+}
+
+// listSkus pages through every SKU of a billing service
+func (f *GCPPricingFetcher) listSkus(ctx context.Context, serviceId string) ([]*cloudbilling.Sku, error) {
+	start := time.Now()
+
+	call := f.service.Services.Skus.List(serviceId)
+	call.CurrencyCode("USD")
+
+	var skus []*cloudbilling.Sku
+	err := call.Pages(ctx, func(page *cloudbilling.ListSkusResponse) error {
+		skus = append(skus, page.Skus...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list GCP SKUs of %s: %w", serviceId, err)
+	}
+
+	slog.Debug("listed GCP SKU catalog",
+		"service", serviceId,
+		"skus", len(skus),
+		"duration", time.Since(start),
+	)
+	return skus, nil
+}
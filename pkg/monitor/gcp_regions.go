@@ -0,0 +1,98 @@
+package monitor
+
+import (
+	"slices"
+	"strings"
+
+	cloudbilling "google.golang.org/api/cloudbilling/v1"
+)
+
+// gcpRegionLocations maps each GA Compute Engine region to the location name used in
+// SKU descriptions ("N2 Instance Core running in Sao Paulo")
+var gcpRegionLocations = map[string]string{
+	"africa-south1":           "johannesburg",
+	"asia-east1":              "taiwan",
+	"asia-east2":              "hong kong",
+	"asia-northeast1":         "tokyo",
+	"asia-northeast2":         "osaka",
+	"asia-northeast3":         "seoul",
+	"asia-south1":             "mumbai",
+	"asia-south2":             "delhi",
+	"asia-southeast1":         "singapore",
+	"asia-southeast2":         "jakarta",
+	"australia-southeast1":    "sydney",
+	"australia-southeast2":    "melbourne",
+	"europe-central2":         "warsaw",
+	"europe-north1":           "finland",
+	"europe-north2":           "stockholm",
+	"europe-southwest1":       "madrid",
+	"europe-west1":            "belgium",
+	"europe-west2":            "london",
+	"europe-west3":            "frankfurt",
+	"europe-west4":            "netherlands",
+	"europe-west6":            "zurich",
+	"europe-west8":            "milan",
+	"europe-west9":            "paris",
+	"europe-west10":           "berlin",
+	"europe-west12":           "turin",
+	"me-central1":             "doha",
+	"me-central2":             "dammam",
+	"me-west1":                "tel aviv",
+	"northamerica-northeast1": "montreal",
+	"northamerica-northeast2": "toronto",
+	"northamerica-south1":     "queretaro",
+	"southamerica-east1":      "sao paulo",
+	"southamerica-west1":      "santiago",
+	"us-central1":             "iowa",
+	"us-east1":                "south carolina",
+	"us-east4":                "northern virginia",
+	"us-east5":                "columbus",
+	"us-south1":               "dallas",
+	"us-west1":                "oregon",
+	"us-west2":                "los angeles",
+	"us-west3":                "salt lake city",
+	"us-west4":                "las vegas",
+}
+
+// skuDescriptionReplacer folds the diacritics and vendor qualifiers that vary between
+// regions so "N2D AMD Instance Core running in São Paulo" reads "n2d instance core
+// running in sao paulo"
+var skuDescriptionReplacer = strings.NewReplacer(
+	"á", "a", "ã", "a", "â", "a", "à", "a",
+	"é", "e", "ê", "e", "è", "e",
+	"í", "i", "ó", "o", "õ", "o", "ô", "o", "ö", "o",
+	"ú", "u", "ü", "u", "ç", "c", "ñ", "n",
+	" amd ", " ", " intel ", " ", " arm ", " ",
+)
+
+// normalizeSkuDescription lowercases and folds a SKU description and splits off its
+// "running in <location>" suffix, so product matching never sees location names
+func normalizeSkuDescription(description string) (product, location string) {
+	desc := " " + strings.Join(strings.Fields(strings.ToLower(description)), " ") + " "
+	desc = strings.TrimSpace(skuDescriptionReplacer.Replace(desc))
+
+	if i := strings.LastIndex(desc, " running in "); i >= 0 {
+		return desc[:i], desc[i+len(" running in "):]
+	}
+	return desc, ""
+}
+
+// skuMatchesRegion reports whether a SKU is billed in a region. Service regions are
+// authoritative; SKUs that omit them fall back to the geo taxonomy and then to the
+// location named in the description.
+func skuMatchesRegion(sku *cloudbilling.Sku, region string) bool {
+	matchesRegion := func(r string) bool {
+		return strings.EqualFold(strings.TrimSpace(r), region)
+	}
+
+	if len(sku.ServiceRegions) > 0 {
+		return slices.ContainsFunc(sku.ServiceRegions, matchesRegion)
+	}
+
+	if sku.GeoTaxonomy != nil && len(sku.GeoTaxonomy.Regions) > 0 {
+		return slices.ContainsFunc(sku.GeoTaxonomy.Regions, matchesRegion)
+	}
+
+	_, location := normalizeSkuDescription(sku.Description)
+	return location != "" && location == gcpRegionLocations[region]
+}
@@ -0,0 +1,119 @@
+package monitor
+
+import (
+	"testing"
+
+	cloudbilling "google.golang.org/api/cloudbilling/v1"
+)
+
+func TestNormalizeSkuDescription(t *testing.T) {
+	tests := []struct {
+		description string
+		product     string
+		location    string
+	}{
+		{"N2 Instance Core running in Americas", "n2 instance core", "americas"},
+		{"N2D AMD Instance Ram running in São Paulo", "n2d instance ram", "sao paulo"},
+		{"C3 Instance Core running in Zürich", "c3 instance core", "zurich"},
+		{"E2 Instance Core  running in  Montréal", "e2 instance core", "montreal"},
+		{"N2 Instance Core running in Querétaro", "n2 instance core", "queretaro"},
+		{"Licensing Fee for Windows Server", "licensing fee for windows server", ""},
+	}
+
+	for _, tt := range tests {
+		product, location := normalizeSkuDescription(tt.description)
+		if product != tt.product || location != tt.location {
+			t.Errorf("normalizeSkuDescription(%q) = (%q, %q), want (%q, %q)",
+				tt.description, product, location, tt.product, tt.location)
+		}
+	}
+}
+
+func TestSkuMatchesRegionAllGARegions(t *testing.T) {
+	for region, location := range gcpRegionLocations {
+		t.Run(region, func(t *testing.T) {
+			bySvcRegion := &cloudbilling.Sku{
+				Description:    "N2 Instance Core running in Americas",
+				ServiceRegions: []string{region},
+			}
+			if !skuMatchesRegion(bySvcRegion, region) {
+				t.Errorf("SKU with service region %s did not match", region)
+			}
+
+			byTaxonomy := &cloudbilling.Sku{
+				Description: "N2 Instance Core running in Americas",
+				GeoTaxonomy: &cloudbilling.GeoTaxonomy{Type: "REGIONAL", Regions: []string{region}},
+			}
+			if !skuMatchesRegion(byTaxonomy, region) {
+				t.Errorf("SKU with geo taxonomy region %s did not match", region)
+			}
+
+			byDescription := &cloudbilling.Sku{
+				Description: "N2 Instance Core running in " + location,
+			}
+			if !skuMatchesRegion(byDescription, region) {
+				t.Errorf("SKU described as running in %q did not match %s", location, region)
+			}
+
+			other := &cloudbilling.Sku{
+				Description:    "N2 Instance Core running in " + location,
+				ServiceRegions: []string{"not-a-region1"},
+			}
+			if skuMatchesRegion(other, region) {
+				t.Errorf("SKU for another region matched %s", region)
+			}
+		})
+	}
+}
+
+func TestMatchersTolerateRegionalQuirks(t *testing.T) {
+	f := &GCPPricingFetcher{}
+
+	tests := []struct {
+		name   string
+		sku    *cloudbilling.Sku
+		region string
+		family string
+		vcpu   bool
+		memory bool
+	}{
+		{
+			name:   "vendor qualifier in sao paulo",
+			sku:    &cloudbilling.Sku{Description: "N2D AMD Instance Core running in São Paulo", ServiceRegions: []string{"southamerica-east1"}},
+			region: "southamerica-east1",
+			family: "n2d",
+			vcpu:   true,
+		},
+		{
+			name:   "memory in jakarta",
+			sku:    &cloudbilling.Sku{Description: "N2 Instance Ram running in Jakarta", ServiceRegions: []string{"asia-southeast2"}},
+			region: "asia-southeast2",
+			family: "n2",
+			memory: true,
+		},
+		{
+			name:   "description location only",
+			sku:    &cloudbilling.Sku{Description: "C3 Instance Core running in Zurich"},
+			region: "europe-west6",
+			family: "c3",
+			vcpu:   true,
+		},
+		{
+			name:   "spot excluded",
+			sku:    &cloudbilling.Sku{Description: "Spot Preemptible N2 Instance Core running in Jakarta", ServiceRegions: []string{"asia-southeast2"}},
+			region: "asia-southeast2",
+			family: "n2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.matchesVCPUSku(tt.sku, tt.region, tt.family); got != tt.vcpu {
+				t.Errorf("matchesVCPUSku = %v, want %v", got, tt.vcpu)
+			}
+			if got := f.matchesMemorySku(tt.sku, tt.region, tt.family); got != tt.memory {
+				t.Errorf("matchesMemorySku = %v, want %v", got, tt.memory)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package monitor
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	compute "google.golang.org/api/compute/v1"
+)
+
+// gcpDiskSkuProducts maps disk types to the product of their capacity SKU. Instance templates are
+// only priced for capacity, so the provisioned IOPS and throughput of extreme and hyperdisk
+// volumes are left out of their cost.
+var gcpDiskSkuProducts = map[string]string{
+	"pd-standard":          "storage pd capacity",
+	"pd-balanced":          "balanced pd capacity",
+	"pd-ssd":               "ssd backed pd capacity",
+	"pd-extreme":           "extreme pd capacity",
+	"hyperdisk-balanced":   "hyperdisk balanced capacity",
+	"hyperdisk-extreme":    "hyperdisk extreme capacity",
+	"hyperdisk-throughput": "hyperdisk throughput capacity",
+	"local-ssd":            "ssd backed local storage",
+}
+
+// GCPTemplateConfig references an instance template, either directly or through the managed
+// instance group that uses it
+type GCPTemplateConfig struct {
+	Name    string `json:"name"`
+	Project string `json:"project"`
+	Region  string `json:"region"`
+	Zone    string `json:"zone"`
+
+	// InstanceGroup is the name of a managed instance group in Zone, or in Region when no
+	// zone is given
+	InstanceGroup string `json:"instance_group"`
+	// InstanceTemplate is the name or URL of a global or regional instance template
+	InstanceTemplate string `json:"instance_template"`
+}
+
+// LoadGCPTemplateConfigs reads a JSON list of instance template and managed instance group
+// references
+func LoadGCPTemplateConfigs(path string) ([]GCPTemplateConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GCP template config file: %w", err)
+	}
+
+	var templates []GCPTemplateConfig
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("failed to parse GCP template config file: %w", err)
+	}
+
+	for i, t := range templates {
+		if t.Name == "" || t.Project == "" {
+			return nil, fmt.Errorf("GCP template %d must have a name and a project", i)
+		}
+		if (t.InstanceGroup == "") == (t.InstanceTemplate == "") {
+			return nil, fmt.Errorf("GCP template %s must reference exactly one of an instance group or an instance template", t.Name)
+		}
+		if t.PricingRegion() == "" {
+			return nil, fmt.Errorf("GCP template %s must have a region or a zone", t.Name)
+		}
+	}
+
+	return templates, nil
+}
+
+// PricingRegion returns the region whose prices apply to the instances
+func (c GCPTemplateConfig) PricingRegion() string {
+	return cmp.Or(c.Region, gcpRegionFromZone(c.Zone))
+}
+
+// GCPTemplate is the billable shape of an instance template
+type GCPTemplate struct {
+	Name         string
+	Region       string
+	MachineType  string
+	Disks        []GCPTemplateDisk
+	Accelerators []GCPTemplateAccelerator
+
+	// Instances is the target size of the managed instance group, or zero for a template
+	// referenced directly
+	Instances int64
+}
+
+type GCPTemplateDisk struct {
+	Type   string
+	SizeGB float64
+}
+
+type GCPTemplateAccelerator struct {
+	Type  string
+	Count int64
+}
+
+// ResolveTemplate reads the instance template of a config, going through its managed
+// instance group when it references one
+func (f *GCPPricingFetcher) ResolveTemplate(ctx context.Context, c GCPTemplateConfig) (*GCPTemplate, error) {
+	resolved := &GCPTemplate{
+		Name:   c.Name,
+		Region: c.PricingRegion(),
+	}
+
+	templateRef := c.InstanceTemplate
+	if c.InstanceGroup != "" {
+		var mig *compute.InstanceGroupManager
+		var err error
+		if c.Zone != "" {
+			mig, err = f.compute.InstanceGroupManagers.Get(c.Project, c.Zone, c.InstanceGroup).Context(ctx).Do()
+		} else {
+			mig, err = f.compute.RegionInstanceGroupManagers.Get(c.Project, c.Region, c.InstanceGroup).Context(ctx).Do()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get managed instance group %s: %w", c.InstanceGroup, err)
+		}
+
+		// Groups rolling out several versions only report their templates per version; the
+		// first is the one most of the group runs
+		templateRef = mig.InstanceTemplate
+		if templateRef == "" && len(mig.Versions) > 0 {
+			templateRef = mig.Versions[0].InstanceTemplate
+		}
+		if templateRef == "" {
+			return nil, fmt.Errorf("managed instance group %s has no instance template", c.InstanceGroup)
+		}
+		resolved.Instances = mig.TargetSize
+	}
+
+	project, region, name := parseTemplateRef(templateRef)
+	project = cmp.Or(project, c.Project)
+
+	var template *compute.InstanceTemplate
+	var err error
+	if region != "" {
+		template, err = f.compute.RegionInstanceTemplates.Get(project, region, name).Context(ctx).Do()
+	} else {
+		template, err = f.compute.InstanceTemplates.Get(project, name).Context(ctx).Do()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance template %s: %w", name, err)
+	}
+	if template.Properties == nil || template.Properties.MachineType == "" {
+		return nil, fmt.Errorf("instance template %s has no machine type", name)
+	}
+
+	resolved.readProperties(template.Properties)
+	return resolved, nil
+}
+
+// parseTemplateRef splits an instance template name or URL like
+// https://www.googleapis.com/compute/v1/projects/p/regions/us-central1/instanceTemplates/web
+// into the project and region it names, if any, and the template name
+func parseTemplateRef(ref string) (project, region, name string) {
+	parts := strings.Split(ref, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		switch parts[i] {
+		case "projects":
+			project = parts[i+1]
+		case "regions":
+			region = parts[i+1]
+		}
+	}
+	return project, region, parts[len(parts)-1]
+}
+
+// readProperties reads the machine type, disks, and accelerators of instances created from
+// a template
+func (t *GCPTemplate) readProperties(p *compute.InstanceProperties) {
+	t.MachineType = path.Base(p.MachineType)
+
+	for _, d := range p.Disks {
+		disk := GCPTemplateDisk{Type: "pd-standard", SizeGB: float64(d.DiskSizeGb)}
+		if params := d.InitializeParams; params != nil {
+			if params.DiskType != "" {
+				disk.Type = path.Base(params.DiskType)
+			}
+			if params.DiskSizeGb > 0 {
+				disk.SizeGB = float64(params.DiskSizeGb)
+			}
+		}
+
+		// Disks created from an image without a size take the image's size, which is
+		// typically 10 GB, and local SSDs come in 375 GB partitions
+		if disk.SizeGB == 0 {
+			disk.SizeGB = 10
+			if d.Type == "SCRATCH" {
+				disk.SizeGB = 375
+			}
+		}
+		if d.Type == "SCRATCH" {
+			disk.Type = "local-ssd"
+		}
+		t.Disks = append(t.Disks, disk)
+	}
+
+	for _, a := range p.GuestAccelerators {
+		t.Accelerators = append(t.Accelerators, GCPTemplateAccelerator{
+			Type:  path.Base(a.AcceleratorType),
+			Count: a.AcceleratorCount,
+		})
+	}
+}
+
+// GCPTemplateCost is the hourly cost of an instance created from a template in USD, by
+// component
+type GCPTemplateCost struct {
+	Machine     decimal.Decimal
+	Disks       decimal.Decimal
+	GPUs        decimal.Decimal
+	GPULicenses decimal.Decimal
+}
+
+func (c GCPTemplateCost) Total() decimal.Decimal {
+	return decimal.Sum(c.Machine, c.Disks, c.GPUs, c.GPULicenses)
+}
+
+// EstimateTemplateCost prices the disks, GPUs, and virtual workstation licenses of a template
+// from the live SKUs and adds them to the price of its machine type
+func (f *GCPPricingFetcher) EstimateTemplateCost(ctx context.Context, t *GCPTemplate, machineCost decimal.Decimal) (*GCPTemplateCost, error) {
+	products := make(map[string]bool)
+	for _, d := range t.Disks {
+		product, ok := gcpDiskSkuProducts[d.Type]
+		if !ok {
+			return nil, fmt.Errorf("unsupported disk type %s", d.Type)
+		}
+		products[product] = true
+	}
+	for _, a := range t.Accelerators {
+		addGPUSkuProducts(products, a.Type)
+	}
+
+	prices, err := f.getSkuPrices(ctx, gcpComputeServiceID, t.Region, products)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disk and GPU pricing: %w", err)
+	}
+
+	cost := &GCPTemplateCost{Machine: machineCost}
+	for _, d := range t.Disks {
+		price, ok := prices[gcpDiskSkuProducts[d.Type]]
+		if !ok {
+			return nil, fmt.Errorf("%w for disk type %s in region %s", errNoPricingFound, d.Type, t.Region)
+		}
+		cost.Disks = cost.Disks.Add(price.Mul(decimal.NewFromFloat(d.SizeGB)))
+	}
+	for _, a := range t.Accelerators {
+		gpu, err := gpuCost(a.Type, t.Region, prices)
+		if err != nil {
+			return nil, err
+		}
+		count := decimal.NewFromInt(a.Count)
+		cost.GPUs = cost.GPUs.Add(gpu.GPU.Mul(count))
+		cost.GPULicenses = cost.GPULicenses.Add(gpu.License.Mul(count))
+	}
+
+	return cost, nil
+}
+
+// getSkuPrices looks up the hourly on-demand price of each product in a region in a single
+// pass over the catalog. Products are matched exactly, which leaves out the spot,
+// preemptible, commitment, and regional variants whose descriptions extend them.
+func (f *GCPPricingFetcher) getSkuPrices(ctx context.Context, serviceId, region string, products map[string]bool) (map[string]decimal.Decimal, error) {
+	rates, err := f.getSkuRates(ctx, serviceId, region, products)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := make(map[string]decimal.Decimal, len(rates))
+	for product, rate := range rates {
+		prices[product] = rate.Hourly()
+	}
+	return prices, nil
+}
+
+// skuRate is the on-demand price of a SKU per its usage unit, such as GiBy.mo for disk capacity
+type skuRate struct {
+	Price decimal.Decimal
+	Unit  string
+}
+
+// monthly reports whether the SKU is billed per month, such as per GiBy.mo of disk capacity or
+// per mo of provisioned IOPS
+func (r skuRate) monthly() bool {
+	return strings.HasSuffix(r.Unit, "mo")
+}
+
+// Hourly returns the price per hour. Disks are billed per month and prorated by the hour.
+func (r skuRate) Hourly() decimal.Decimal {
+	if r.monthly() {
+		return r.Price.Div(decimal.NewFromInt(hoursPerMonth))
+	}
+	return r.Price
+}
+
+// Monthly returns the price per month of 730 hours
+func (r skuRate) Monthly() decimal.Decimal {
+	if r.monthly() {
+		return r.Price
+	}
+	return r.Price.Mul(decimal.NewFromInt(hoursPerMonth))
+}
+
+// getSkuRates looks up the on-demand rate of each product in a region like getSkuPrices,
+// keeping the usage unit each is billed in
+func (f *GCPPricingFetcher) getSkuRates(ctx context.Context, serviceId, region string, products map[string]bool) (map[string]skuRate, error) {
+	if len(products) == 0 {
+		return make(map[string]skuRate), nil
+	}
+
+	return f.findSkuRates(ctx, serviceId, region, func(product string) (string, bool) {
+		// Some disk SKUs name their location with "in" rather than "running in"
+		if i := strings.LastIndex(product, " in "); i >= 0 && !products[product] {
+			product = product[:i]
+		}
+		return product, products[product]
+	})
+}
+
+// findSkuRates looks up the on-demand rate of the SKUs of a service in a region whose normalized
+// description match picks, keyed by what it returns. The first SKU found for a key is kept.
+func (f *GCPPricingFetcher) findSkuRates(ctx context.Context, serviceId, region string, match func(product string) (string, bool)) (map[string]skuRate, error) {
+	skus, err := f.skus(ctx, serviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make(map[string]skuRate)
+	for _, sku := range skus {
+		product, _ := normalizeSkuDescription(sku.Description)
+		key, ok := match(product)
+		if !ok || !skuMatchesRegion(sku, region) {
+			continue
+		}
+		if _, found := rates[key]; found {
+			continue
+		}
+		if len(sku.PricingInfo) == 0 || len(sku.PricingInfo[0].PricingExpression.TieredRates) == 0 {
+			continue
+		}
+
+		expr := sku.PricingInfo[0].PricingExpression
+		rate := expr.TieredRates[len(expr.TieredRates)-1].UnitPrice
+		rates[key] = skuRate{Price: moneyToDecimal(rate), Unit: expr.UsageUnit}
+	}
+
+	return rates, nil
+}
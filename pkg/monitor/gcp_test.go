@@ -0,0 +1,72 @@
+package monitor
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	cloudbilling "google.golang.org/api/cloudbilling/v1"
+)
+
+func TestMoneyToDecimal(t *testing.T) {
+	tests := []struct {
+		units int64
+		nanos int64
+		want  string
+	}{
+		{0, 0, "0"},
+		{0, 31611000, "0.031611"},
+		{0, 4237000, "0.004237"},
+		{0, 1, "0.000000001"},
+		{0, 999999999, "0.999999999"},
+		{1, 500000000, "1.5"},
+		{12, 345678901, "12.345678901"},
+		{-1, -750000000, "-1.75"},
+	}
+
+	for _, tt := range tests {
+		got := moneyToDecimal(&cloudbilling.Money{Units: tt.units, Nanos: tt.nanos})
+		if !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("moneyToDecimal(%d units, %d nanos) = %s, want %s", tt.units, tt.nanos, got, tt.want)
+		}
+	}
+}
+
+// testGCPRate returns a rate of a SKU priced at an amount in nanos
+func testGCPRate(nanos int64) gcpRate {
+	return newGCPRate(&cloudbilling.Sku{
+		PricingInfo: []*cloudbilling.PricingInfo{{
+			PricingExpression: &cloudbilling.PricingExpression{
+				TieredRates: []*cloudbilling.TierRate{{UnitPrice: &cloudbilling.Money{Nanos: nanos}}},
+			},
+		}},
+	})
+}
+
+func TestGCPRateCostIsExact(t *testing.T) {
+	tests := []struct {
+		vcpuNanos, memoryNanos int64
+		vcpus, memoryGiB       int64
+		want                   string
+	}{
+		// n2-standard-4 in us-central1
+		{31611000, 4237000, 4, 16, "0.194236"},
+		// Rates whose float64 sum isn't exact: 0.1 + 0.2 is 0.30000000000000004
+		{100000000, 200000000, 1, 1, "0.3"},
+		{100000000, 100000000, 3, 0, "0.3"},
+		{1, 1, 1000000, 1000000, "0.002"},
+		{33333333, 0, 3, 0, "0.099999999"},
+	}
+
+	for _, tt := range tests {
+		vcpu, memory := testGCPRate(tt.vcpuNanos), testGCPRate(tt.memoryNanos)
+		got := vcpu.cost(nil, decimal.NewFromInt(tt.vcpus), "vCPU").Add(memory.cost(nil, decimal.NewFromInt(tt.memoryGiB), "GiB"))
+		if !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("%d vCPUs at %d nanos + %d GiB at %d nanos = %s, want %s",
+				tt.vcpus, tt.vcpuNanos, tt.memoryGiB, tt.memoryNanos, got, tt.want)
+		}
+	}
+
+	if got := (gcpRate{}).cost(nil, decimal.NewFromInt(4), "vCPU"); !got.IsZero() {
+		t.Errorf("rate that wasn't looked up costs %s, want 0", got)
+	}
+}
@@ -0,0 +1,149 @@
+package monitor
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	// awsInstanceTypePattern splits EC2 instance types like m6i.2xlarge into series (m),
+	// generation (6), attributes (i), and size (2xlarge)
+	awsInstanceTypePattern = regexp.MustCompile(`^([a-z]+)(\d+)([a-z-]*)\.([a-z0-9-]+)$`)
+
+	// gcpMachineFamilyPattern splits GCP machine families like n2d into series (n),
+	// generation (2), and attributes (d)
+	gcpMachineFamilyPattern = regexp.MustCompile(`^([a-z]+)(\d+)([a-z]*)$`)
+)
+
+// instanceGeneration is an instance type broken down into the parts that stay the same
+// across generations and the generation number itself
+type instanceGeneration struct {
+	series     string
+	attributes string
+	shape      string
+	generation int
+}
+
+func (g instanceGeneration) sameLine(o instanceGeneration) bool {
+	return g.series == o.series && g.attributes == o.attributes && g.shape == o.shape
+}
+
+func parseAWSGeneration(instanceType string) (instanceGeneration, bool) {
+	m := awsInstanceTypePattern.FindStringSubmatch(instanceType)
+	if m == nil {
+		return instanceGeneration{}, false
+	}
+
+	generation, err := strconv.Atoi(m[2])
+	if err != nil {
+		return instanceGeneration{}, false
+	}
+
+	return instanceGeneration{series: m[1], attributes: m[3], shape: m[4], generation: generation}, true
+}
+
+func parseGCPGeneration(machineType string) (instanceGeneration, bool) {
+	family, shape, ok := strings.Cut(machineType, "-")
+	if !ok {
+		return instanceGeneration{}, false
+	}
+
+	m := gcpMachineFamilyPattern.FindStringSubmatch(family)
+	if m == nil {
+		return instanceGeneration{}, false
+	}
+
+	generation, err := strconv.Atoi(m[2])
+	if err != nil {
+		return instanceGeneration{}, false
+	}
+
+	return instanceGeneration{series: m[1], attributes: m[3], shape: shape, generation: generation}, true
+}
+
+// NewerGeneration is a catalog instance type that supersedes a monitored one
+type NewerGeneration struct {
+	Provider     string
+	InstanceType string
+	NewerType    string
+	// Launched is set when the newer type appeared in the catalog after the monitor started
+	Launched bool
+}
+
+// GenerationTracker watches provider catalogs for newer generations of monitored instance types
+type GenerationTracker struct {
+	mu    sync.Mutex
+	known map[string]map[string]bool
+}
+
+func NewGenerationTracker() *GenerationTracker {
+	return &GenerationTracker{
+		known: make(map[string]map[string]bool),
+	}
+}
+
+// Update compares a provider's catalog against the monitored instance types and returns
+// every newer generation available. The first catalog seen for a provider is the baseline
+// for detecting launches.
+func (t *GenerationTracker) Update(provider string, catalog, monitored []string, parse func(string) (instanceGeneration, bool)) []NewerGeneration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	known, initialized := t.known[provider]
+
+	var newer []NewerGeneration
+	for _, instanceType := range monitored {
+		current, ok := parse(instanceType)
+		if !ok {
+			continue
+		}
+
+		for _, candidate := range catalog {
+			if candidate == instanceType || slices.Contains(monitored, candidate) {
+				continue
+			}
+
+			next, ok := parse(candidate)
+			if !ok || !next.sameLine(current) || next.generation <= current.generation {
+				continue
+			}
+
+			newer = append(newer, NewerGeneration{
+				Provider:     provider,
+				InstanceType: instanceType,
+				NewerType:    candidate,
+				Launched:     initialized && !known[candidate],
+			})
+		}
+	}
+
+	seen := make(map[string]bool, len(catalog))
+	for _, instanceType := range catalog {
+		seen[instanceType] = true
+	}
+	t.known[provider] = seen
+
+	return newer
+}
+
+// gcpCatalogTypes expands catalog machine families into machine types with the same
+// shapes as the monitored types, e.g. family n4 and n2-standard-4 give n4-standard-4
+func gcpCatalogTypes(families, monitored []string) []string {
+	var types []string
+	for _, machineType := range monitored {
+		_, shape, ok := strings.Cut(machineType, "-")
+		if !ok {
+			continue
+		}
+		for _, family := range families {
+			candidate := family + "-" + shape
+			if !slices.Contains(types, candidate) {
+				types = append(types, candidate)
+			}
+		}
+	}
+	return types
+}
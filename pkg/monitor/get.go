@@ -0,0 +1,188 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bluesky-social/go-util/pkg/telemetry"
+	"github.com/jazware/cloud-pricing-monitor/pkg/client"
+	"github.com/jazware/cloud-pricing-monitor/pkg/providers"
+	cli "github.com/urfave/cli/v2"
+)
+
+// GetCommand looks up prices once without running the daemon, for a quick answer from the
+// terminal instead of from the metrics
+var GetCommand = &cli.Command{
+	Name:      "get",
+	Usage:     "Fetch the prices of instance types once, print them, and exit",
+	ArgsUsage: "provider/region/instance_type...",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print the prices as JSON, like the /api/v1/prices listing, instead of a table",
+		},
+	},
+	Action: runGet,
+}
+
+// getTarget is a price named on the get command line
+type getTarget struct {
+	provider, region, instanceType string
+}
+
+func runGet(cctx *cli.Context) error {
+	ctx, cancel := context.WithCancel(cctx.Context)
+	defer cancel()
+	startLookupLogger(cctx)
+
+	if cctx.NArg() == 0 {
+		return fmt.Errorf("get requires at least one provider/region/instance_type, such as aws/us-east-1/m5.large")
+	}
+
+	registry, err := newLookupRegistry(cctx)
+	if err != nil {
+		return err
+	}
+
+	var targets []getTarget
+	for _, arg := range cctx.Args().Slice() {
+		parts := strings.Split(arg, "/")
+		if len(parts) != 3 || slices.Contains(parts, "") {
+			return fmt.Errorf("invalid price %q: expected provider/region/instance_type, such as aws/us-east-1/m5.large", arg)
+		}
+		if !slices.Contains(registry.Names(), parts[0]) {
+			return fmt.Errorf("invalid price %q: unknown provider %q (expected one of %s)", arg, parts[0], strings.Join(registry.Names(), ", "))
+		}
+		targets = append(targets, getTarget{parts[0], parts[1], parts[2]})
+	}
+
+	rounding, err := NewPriceRounding(cctx.Int("price-decimal-places"), cctx.Int("price-significant-digits"), cctx.String("price-rounding-mode"))
+	if err != nil {
+		return err
+	}
+
+	memoryUnit, err := ParseMemoryUnit(cctx.String("memory-unit"))
+	if err != nil {
+		return err
+	}
+
+	ctx, monitor, err := newLookupMonitor(ctx, cctx, registry, memoryUnit)
+	if err != nil {
+		return err
+	}
+	// Each provider is configured with the regions and instance types named for it only
+	for _, t := range targets {
+		watched := monitor.watch(t.provider)
+		watched.regions = appendMissing(watched.regions, t.region)
+		watched.instanceTypes = appendMissing(watched.instanceTypes, t.instanceType)
+	}
+
+	if err := monitor.initFetchers(ctx); err != nil {
+		return err
+	}
+
+	prices := []client.Price{}
+	var errs []error
+	for _, t := range targets {
+		pricing, err := monitor.fetchUpstream(ctx, monitor.provider(t.provider), t.region, t.instanceType)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s/%s/%s: %w", t.provider, t.region, t.instanceType, err))
+			continue
+		}
+		entry := PriceEntry{Pricing: *pricing, UpdatedAt: time.Now()}
+		prices = append(prices, newAPIPrice(entry, time.Time{}, rounding, memoryUnit))
+	}
+
+	if err := printPrices(prices, cctx.Bool("json")); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// startLookupLogger logs to stderr, so that stdout holds nothing but the prices a command prints
+func startLookupLogger(cctx *cli.Context) {
+	telemetry.StartLogger(cctx, telemetry.WithHandler(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+}
+
+// newLookupRegistry creates the providers of a command that fetches prices once, priced from
+// the AWS price list the monitor's flags pin or download, if any
+func newLookupRegistry(cctx *cli.Context) (*providers.Registry, error) {
+	priceListPin, err := ParsePriceListPin(cctx.String("aws-price-list-version"), cctx.String("aws-price-list-date"))
+	if err != nil {
+		return nil, err
+	}
+	if priceListPin != nil && cctx.Bool("aws-bulk-pricing") {
+		return nil, fmt.Errorf("aws-bulk-pricing can't be combined with a pinned price list")
+	}
+	return newProviderRegistry(priceListPin, cctx.Bool("aws-bulk-pricing"), cctx.String("gcp-billing-account"))
+}
+
+// newLookupMonitor creates a monitor that fetches prices once for a command, with the egress
+// routes and retries of the monitor's flags and without polling, publishing, or serving them.
+// The regions and instance types to fetch are left to the command. The context it returns
+// routes fetches through the egress routes.
+func newLookupMonitor(ctx context.Context, cctx *cli.Context, registry *providers.Registry, memoryUnit MemoryUnit) (context.Context, *Monitor, error) {
+	egress, err := egressFlags(cctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Nothing serves the metrics of a lookup, so they aren't registered
+	metrics, err := NewMetrics(nil, memoryUnit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	monitor := &Monitor{
+		metrics:            metrics,
+		snapshot:           NewPriceSnapshot(),
+		registry:           registry,
+		providers:          make(map[string]providers.PricingProvider),
+		fetcherInitTimeout: cctx.Duration("fetcher-init-timeout"),
+		retries: retryPolicy{
+			retries: cctx.Int("fetch-retries"),
+			backoff: cctx.Duration("fetch-retry-backoff"),
+		},
+	}
+	return withEgress(ctx, egress), monitor, nil
+}
+
+// printPrices writes prices to stdout as a table, or as JSON
+func printPrices(prices []client.Price, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(prices)
+	}
+	if len(prices) == 0 {
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tREGION\tINSTANCE TYPE\tVCPUS\tMEMORY\t$/HR\t$/VCPU\t$/MEM")
+	for _, p := range prices {
+		memory := "-"
+		if p.MemoryGB > 0 {
+			memory = fmt.Sprintf("%.1f %s", p.MemoryGB, p.MemoryUnit)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%.4f\t%s\t%s\n",
+			p.Provider, p.Region, p.InstanceType, p.VCPUs, memory, p.TotalCost, unitCost(p.CostPerVCPU), unitCost(p.CostPerGB))
+	}
+	return w.Flush()
+}
+
+// unitCost formats a cost per vCPU or per unit of memory, which is zero when the size is unknown
+func unitCost(cost float64) string {
+	if cost == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.5f", cost)
+}
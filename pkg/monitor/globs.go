@@ -0,0 +1,203 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	compute "google.golang.org/api/compute/v1"
+)
+
+// isInstanceTypeGlob reports whether a configured instance type is a pattern, where * matches
+// any run of characters and ? a single one
+func isInstanceTypeGlob(instanceType string) bool {
+	return strings.ContainsAny(instanceType, "*?")
+}
+
+// splitInstanceTypeGlobs separates the patterns of a configured instance type list from the
+// instance types it names
+func splitInstanceTypeGlobs(values []string) (instanceTypes, globs []string) {
+	for _, v := range values {
+		if isInstanceTypeGlob(v) {
+			globs = append(globs, v)
+		} else {
+			instanceTypes = append(instanceTypes, v)
+		}
+	}
+	return instanceTypes, globs
+}
+
+// checkInstanceTypeGlobs rejects patterns for a provider whose catalog can't be listed
+func checkInstanceTypeGlobs(provider string, globs []string) error {
+	if len(globs) > 0 && provider == "azure" {
+		return fmt.Errorf("azure-vm-sizes does not support patterns, got %q", globs[0])
+	}
+	return nil
+}
+
+// takeInstanceTypeGlobs moves the patterns out of the configured instance type lists, to be
+// expanded against each provider's catalog once its fetcher is initialized
+func (m *Monitor) takeInstanceTypeGlobs() error {
+	for provider, watched := range m.watching {
+		instanceTypes, globs := splitInstanceTypeGlobs(watched.instanceTypes)
+		if len(globs) == 0 {
+			continue
+		}
+		if err := checkInstanceTypeGlobs(provider, globs); err != nil {
+			return err
+		}
+		if provider == "gcp" && m.gcpProject == "" {
+			return fmt.Errorf("gcp-instance-types patterns require gcp-project to list machine types")
+		}
+
+		if m.instanceTypeGlobs == nil {
+			m.instanceTypeGlobs = make(map[string][]string)
+		}
+		watched.instanceTypes = instanceTypes
+		m.instanceTypeGlobs[provider] = globs
+	}
+	return nil
+}
+
+// matchesInstanceTypeGlob reports whether an instance type matches any of the patterns
+func matchesInstanceTypeGlob(instanceType string, globs []string) bool {
+	return slices.ContainsFunc(globs, func(glob string) bool {
+		ok, _ := path.Match(glob, instanceType)
+		return ok
+	})
+}
+
+// MatchInstanceTypes returns the instance types of the regions that match any of the patterns.
+// EC2 filters support the same * and ? wildcards, so only the matches are listed.
+func (f *AWSPricingFetcher) MatchInstanceTypes(ctx context.Context, regions, globs []string) ([]string, error) {
+	var matched []string
+	for _, region := range regions {
+		client := ec2.NewFromConfig(f.cfg, func(o *ec2.Options) {
+			o.Region = region
+		})
+
+		paginator := ec2.NewDescribeInstanceTypesPaginator(client, &ec2.DescribeInstanceTypesInput{
+			Filters: []ec2types.Filter{
+				{Name: aws.String("instance-type"), Values: globs},
+			},
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe AWS instance types in region %s: %w", region, err)
+			}
+
+			for _, info := range page.InstanceTypes {
+				instanceType := string(info.InstanceType)
+				if matchesInstanceTypeGlob(instanceType, globs) {
+					matched = appendMissing(matched, instanceType)
+				}
+			}
+		}
+	}
+
+	slices.Sort(matched)
+	return matched, nil
+}
+
+// globRegexp turns a pattern into the regular expression of a GCP API filter
+func globRegexp(glob string) string {
+	pattern := regexp.QuoteMeta(glob)
+	pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+	return strings.ReplaceAll(pattern, `\?`, ".")
+}
+
+// MatchMachineTypes returns the machine types of the regions that match any of the patterns.
+// Machine types are listed by zone through a project, so only those available to the project
+// are matched.
+func (f *GCPPricingFetcher) MatchMachineTypes(ctx context.Context, project string, regions, globs []string) ([]string, error) {
+	patterns := make([]string, len(globs))
+	for i, glob := range globs {
+		patterns[i] = globRegexp(glob)
+	}
+
+	var matched []string
+	call := f.compute.MachineTypes.AggregatedList(project)
+	call.Filter(fmt.Sprintf("name eq '%s'", strings.Join(patterns, "|")))
+	err := call.Pages(ctx, func(page *compute.MachineTypeAggregatedList) error {
+		for _, scoped := range page.Items {
+			for _, machineType := range scoped.MachineTypes {
+				// Zones are named after their region, such as us-central1-a
+				region := machineType.Zone[:max(0, strings.LastIndex(machineType.Zone, "-"))]
+				if slices.Contains(regions, region) && matchesInstanceTypeGlob(machineType.Name, globs) {
+					matched = appendMissing(matched, machineType.Name)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list GCP machine types: %w", err)
+	}
+
+	slices.Sort(matched)
+	return matched, nil
+}
+
+// matchInstanceTypes lists the instance types of a provider's monitored regions that match its
+// patterns
+func (m *Monitor) matchInstanceTypes(ctx context.Context, provider string, globs []string) ([]string, error) {
+	switch provider {
+	case "aws":
+		return m.awsFetcher.MatchInstanceTypes(ctx, m.watch("aws").regions, globs)
+	case "gcp":
+		if m.gcpProject == "" {
+			return nil, fmt.Errorf("gcp-project is required to match machine types")
+		}
+		return m.gcpFetcher.MatchMachineTypes(ctx, m.gcpProject, m.watch("gcp").regions, globs)
+	}
+	return nil, fmt.Errorf("%s does not support instance type patterns", provider)
+}
+
+// expandInstanceTypeGlobs swaps the instance types each provider's patterns matched on the
+// previous poll for those they match now, so types added to a catalog are priced without a
+// restart. A provider whose catalog can't be listed keeps its previous matches.
+func (m *Monitor) expandInstanceTypeGlobs(ctx context.Context) {
+	if m.expandedTypes == nil {
+		m.expandedTypes = make(map[string][]string)
+	}
+
+	for provider, globs := range m.instanceTypeGlobs {
+		var matched []string
+		if len(globs) > 0 {
+			// A provider whose fetcher failed to initialize is matched once it does
+			if m.provider(provider) == nil {
+				continue
+			}
+			var err error
+			matched, err = m.matchInstanceTypes(ctx, provider, globs)
+			if err != nil {
+				slog.Error("failed to match instance type patterns", "provider", provider, "patterns", globs, "error", err)
+				continue
+			}
+			if len(matched) == 0 {
+				slog.Warn("instance type patterns match no instance type", "provider", provider, "patterns", globs)
+			}
+		}
+
+		// A type also configured by name stays when its pattern no longer matches it
+		configured := func(instanceType string) bool {
+			return slices.Contains(m.watched[provider].instanceTypes, instanceType)
+		}
+		matched = slices.DeleteFunc(matched, configured)
+		previous := slices.DeleteFunc(slices.Clone(m.expandedTypes[provider]), configured)
+		if !slices.Equal(matched, previous) {
+			slog.Info("expanded instance type patterns", "provider", provider, "patterns", globs, "instance_types", matched)
+		}
+		watched := m.watch(provider)
+		watched.instanceTypes = replaceWatched(watched.instanceTypes, previous, matched)
+		m.expandedTypes[provider] = matched
+	}
+}
@@ -0,0 +1,282 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/providers"
+	"github.com/shopspring/decimal"
+)
+
+// gcpWorkstationSuffix marks the accelerator types of NVIDIA RTX Virtual Workstations, such as
+// nvidia-tesla-t4-vws, which are billed as the GPU plus a separate workstation license
+const gcpWorkstationSuffix = "-vws"
+
+// gcpAcceleratorShape is a machine type of an accelerator-optimized series, which comes with its
+// GPUs attached. Its vCPUs and memory are billed by the SKUs of its series and its GPUs by the
+// SKU of their accelerator type.
+type gcpAcceleratorShape struct {
+	vcpus     int
+	memoryGiB float64
+	gpuType   string
+	gpuCount  int
+}
+
+// gcpAcceleratorMachineTypes are the shapes of the accelerator-optimized machine types, whose
+// names don't follow the vCPU count and memory ratio of the general purpose ones
+var gcpAcceleratorMachineTypes = map[string]gcpAcceleratorShape{
+	"a2-highgpu-1g":  {12, 85, "nvidia-tesla-a100", 1},
+	"a2-highgpu-2g":  {24, 170, "nvidia-tesla-a100", 2},
+	"a2-highgpu-4g":  {48, 340, "nvidia-tesla-a100", 4},
+	"a2-highgpu-8g":  {96, 680, "nvidia-tesla-a100", 8},
+	"a2-megagpu-16g": {96, 1360, "nvidia-tesla-a100", 16},
+	"a2-ultragpu-1g": {12, 170, "nvidia-a100-80gb", 1},
+	"a2-ultragpu-2g": {24, 340, "nvidia-a100-80gb", 2},
+	"a2-ultragpu-4g": {48, 680, "nvidia-a100-80gb", 4},
+	"a2-ultragpu-8g": {96, 1360, "nvidia-a100-80gb", 8},
+	"a3-highgpu-1g":  {26, 234, "nvidia-h100-80gb", 1},
+	"a3-highgpu-2g":  {52, 468, "nvidia-h100-80gb", 2},
+	"a3-highgpu-4g":  {104, 936, "nvidia-h100-80gb", 4},
+	"a3-highgpu-8g":  {208, 1872, "nvidia-h100-80gb", 8},
+	"g2-standard-4":  {4, 16, "nvidia-l4", 1},
+	"g2-standard-8":  {8, 32, "nvidia-l4", 1},
+	"g2-standard-12": {12, 48, "nvidia-l4", 1},
+	"g2-standard-16": {16, 64, "nvidia-l4", 1},
+	"g2-standard-24": {24, 96, "nvidia-l4", 2},
+	"g2-standard-32": {32, 128, "nvidia-l4", 1},
+	"g2-standard-48": {48, 192, "nvidia-l4", 4},
+	"g2-standard-96": {96, 384, "nvidia-l4", 8},
+}
+
+// parseGCPInstanceType splits a GCP instance type into its machine type and GPUs. GPUs are
+// attached to a general purpose machine type by naming their accelerator type and count after
+// a +, such as n1-standard-8+nvidia-tesla-t4 or n1-standard-8+nvidia-tesla-t4:2, and come with
+// accelerator-optimized machine types such as a2-highgpu-1g.
+func parseGCPInstanceType(instanceType string) (machineType, gpuType string, gpuCount int, err error) {
+	machineType, attached, ok := strings.Cut(instanceType, "+")
+	if shape, optimized := gcpAcceleratorMachineTypes[machineType]; optimized {
+		if ok {
+			return "", "", 0, fmt.Errorf("machine type %s comes with its GPUs, so none can be attached to it: %s", machineType, instanceType)
+		}
+		return machineType, shape.gpuType, shape.gpuCount, nil
+	}
+	if !ok {
+		return machineType, "", 0, nil
+	}
+
+	gpuType, count, counted := strings.Cut(attached, ":")
+	gpuCount = 1
+	if counted {
+		if gpuCount, err = strconv.Atoi(count); err != nil || gpuCount < 1 {
+			return "", "", 0, fmt.Errorf("invalid GPU count in instance type: %s", instanceType)
+		}
+	}
+	if gpuType == "" {
+		return "", "", 0, fmt.Errorf("invalid accelerator type in instance type: %s", instanceType)
+	}
+	return machineType, gpuType, gpuCount, nil
+}
+
+// hasAttachedGPUs reports whether a GCP instance type attaches GPUs to its machine type
+func hasAttachedGPUs(instanceType string) bool {
+	return strings.Contains(instanceType, "+")
+}
+
+// hasGPUs reports whether a GCP instance type has GPUs, attached or built in
+func hasGPUs(instanceType string) bool {
+	_, _, gpuCount, _ := parseGCPInstanceType(instanceType)
+	return gpuCount > 0
+}
+
+// GPUCost is the hourly cost of one GPU in USD, by component
+type GPUCost struct {
+	GPU decimal.Decimal
+	// License is the virtual workstation license of a workstation GPU, and zero otherwise
+	License decimal.Decimal
+}
+
+func (c GPUCost) Total() decimal.Decimal {
+	return c.GPU.Add(c.License)
+}
+
+// gpuSkuProduct returns the product of an accelerator type's on-demand SKU, e.g.
+// nvidia-tesla-t4 is billed as "Nvidia Tesla T4 GPU running in Americas". Workstation types
+// are billed for the same GPU.
+func gpuSkuProduct(acceleratorType string) string {
+	base := strings.TrimSuffix(acceleratorType, gcpWorkstationSuffix)
+	return strings.ReplaceAll(base, "-", " ") + " gpu"
+}
+
+// gpuLicenseSkuProducts returns the products the license SKU of a workstation accelerator type
+// may be described as, e.g. "Nvidia Tesla T4 Virtual Workstation running in Americas" for
+// nvidia-tesla-t4-vws. Other types have no license.
+func gpuLicenseSkuProducts(acceleratorType string) []string {
+	base, ok := strings.CutSuffix(acceleratorType, gcpWorkstationSuffix)
+	if !ok {
+		return nil
+	}
+	product := strings.ReplaceAll(base, "-", " ") + " virtual workstation"
+	return []string{product, product + " gpu"}
+}
+
+// addGPUSkuProducts adds the products an accelerator type is billed by to a set of products to
+// look up with getSkuPrices
+func addGPUSkuProducts(products map[string]bool, acceleratorType string) {
+	products[gpuSkuProduct(acceleratorType)] = true
+	for _, product := range gpuLicenseSkuProducts(acceleratorType) {
+		products[product] = true
+	}
+}
+
+// gpuCost prices an accelerator type from the SKU prices found by getSkuPrices
+func gpuCost(acceleratorType, region string, prices map[string]decimal.Decimal) (GPUCost, error) {
+	gpu, ok := prices[gpuSkuProduct(acceleratorType)]
+	if !ok {
+		return GPUCost{}, fmt.Errorf("%w for accelerator type %s in region %s", errNoPricingFound, acceleratorType, region)
+	}
+
+	cost := GPUCost{GPU: gpu}
+	licenses := gpuLicenseSkuProducts(acceleratorType)
+	if len(licenses) == 0 {
+		return cost, nil
+	}
+	for _, product := range licenses {
+		if license, ok := prices[product]; ok {
+			cost.License = license
+			return cost, nil
+		}
+	}
+	return GPUCost{}, fmt.Errorf("%w for the virtual workstation license of %s in region %s", errNoPricingFound, acceleratorType, region)
+}
+
+// FetchGPUPricing returns the hourly cost of each accelerator type in a region, in a single
+// pass over the catalog. Types that aren't offered in the region are left out.
+func (f *GCPPricingFetcher) FetchGPUPricing(ctx context.Context, region string, acceleratorTypes []string) (map[string]GPUCost, error) {
+	products := make(map[string]bool)
+	for _, t := range acceleratorTypes {
+		addGPUSkuProducts(products, t)
+	}
+
+	prices, err := f.getSkuPrices(ctx, gcpComputeServiceID, region, products)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GPU pricing: %w", err)
+	}
+
+	costs := make(map[string]GPUCost)
+	for _, t := range acceleratorTypes {
+		cost, err := gpuCost(t, region, prices)
+		if err != nil {
+			slog.Debug("skipping GPU type", "region", region, "gpu_type", t, "error", err)
+			continue
+		}
+		costs[t] = cost
+	}
+	return costs, nil
+}
+
+// addGPUCost adds the cost of an instance type's GPUs, and the licenses of workstation GPUs, to
+// the price of its machine type
+func (f *GCPPricingFetcher) addGPUCost(ctx context.Context, machine *VMPricing, instanceType, gpuType string, gpuCount int) (*VMPricing, error) {
+	products := make(map[string]bool)
+	addGPUSkuProducts(products, gpuType)
+	prices, err := f.getSkuPrices(ctx, gcpComputeServiceID, machine.Region, products)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GPU pricing: %w", err)
+	}
+	cost, err := gpuCost(gpuType, machine.Region, prices)
+	if err != nil {
+		return nil, err
+	}
+
+	count := decimal.NewFromInt(int64(gpuCount))
+	trace := providers.MatchTraceFrom(ctx)
+	trace.Component(providers.PriceComponent{Description: gpuType + " GPU", Rate: cost.GPU, Quantity: count, Unit: "GPU"})
+	if !cost.License.IsZero() {
+		trace.Component(providers.PriceComponent{Description: gpuType + " virtual workstation license", Rate: cost.License, Quantity: count, Unit: "GPU"})
+	}
+
+	slog.Debug("fetched GCP GPU pricing",
+		"region", machine.Region,
+		"instance_type", instanceType,
+		"gpu_type", gpuType,
+		"gpu_count", gpuCount,
+		"gpu_price", cost.Total(),
+	)
+
+	priced := *machine
+	priced.InstanceType = instanceType
+	priced.TotalCost = machine.TotalCost.Add(cost.Total().Mul(count))
+	priced.GPUType = gpuType
+	priced.GPUCount = gpuCount
+	return &priced, nil
+}
+
+// recordGPUCosts exports the cost of the monitored GPU types in every GCP region
+func (m *Monitor) recordGPUCosts(ctx context.Context) {
+	for _, region := range m.watch("gcp").regions {
+		costs, err := m.gcpFetcher.FetchGPUPricing(ctx, region, m.gcpGPUTypes)
+		if err != nil {
+			slog.Error("failed to fetch GCP GPU pricing", "region", region, "reason", errorReason(err), "error", err)
+			continue
+		}
+		m.metrics.RecordGPUCosts("gcp", region, costs)
+		m.recordServiceAvailability("gcp", region, serviceGPUType, m.gcpGPUTypes, func(gpuType string) bool {
+			_, ok := costs[gpuType]
+			return ok
+		})
+	}
+}
+
+// awsAcceleratorFamilies maps the accelerated computing instance families of AWS to the
+// accelerator type of their instances, named like GCP's accelerator types
+var awsAcceleratorFamilies = map[string]string{
+	"p3":      "nvidia-tesla-v100",
+	"p3dn":    "nvidia-tesla-v100",
+	"p4d":     "nvidia-a100",
+	"p4de":    "nvidia-a100-80gb",
+	"p5":      "nvidia-h100",
+	"p5e":     "nvidia-h200",
+	"p5en":    "nvidia-h200",
+	"p6-b200": "nvidia-b200",
+	"g4dn":    "nvidia-tesla-t4",
+	"g4ad":    "amd-radeon-pro-v520",
+	"g5":      "nvidia-a10g",
+	"g5g":     "nvidia-t4g",
+	"g6":      "nvidia-l4",
+	"gr6":     "nvidia-l4",
+	"g6e":     "nvidia-l40s",
+	"trn1":    "aws-trainium",
+	"trn1n":   "aws-trainium",
+	"trn2":    "aws-trainium2",
+}
+
+// awsAcceleratorCounts are the Trainium chips of the Trn sizes, for when the price list's gpu
+// attribute doesn't count them
+var awsAcceleratorCounts = map[string]int{
+	"trn1.2xlarge":   1,
+	"trn1.32xlarge":  16,
+	"trn1n.32xlarge": 16,
+	"trn2.48xlarge":  16,
+}
+
+// awsAccelerators returns the accelerator type of an instance type's family and how many it
+// has, counted by the price list's gpu attribute. Instance types outside the accelerated
+// computing families, and those whose accelerators can't be counted, have none.
+func awsAccelerators(instanceType, gpuAttribute string) (string, int) {
+	family, _, _ := strings.Cut(instanceType, ".")
+	gpuType, ok := awsAcceleratorFamilies[family]
+	if !ok {
+		return "", 0
+	}
+	if count, err := strconv.Atoi(gpuAttribute); err == nil && count > 0 {
+		return gpuType, count
+	}
+	if count, ok := awsAcceleratorCounts[instanceType]; ok {
+		return gpuType, count
+	}
+	slog.Debug("failed to count the accelerators of AWS instance type", "instance_type", instanceType, "gpu", gpuAttribute)
+	return "", 0
+}
@@ -0,0 +1,63 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// GraphiteSink sends the pricing gauges to a Carbon plaintext receiver, named with their label
+// values appended, such as cloud_vm_total_cost_per_hour.aws.us-east-1.m5_large.false.false._.0
+type GraphiteSink struct {
+	address string
+	prefix  string
+}
+
+// NewGraphiteSink sends to a Carbon plaintext receiver at a TCP host:port, usually port 2003
+func NewGraphiteSink(address, prefix string) (*GraphiteSink, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return nil, fmt.Errorf("invalid Graphite address %q: %w", address, err)
+	}
+	return &GraphiteSink{address: address, prefix: prefix}, nil
+}
+
+func (s *GraphiteSink) Name() string {
+	return "graphite"
+}
+
+// Push sends every sample timestamped with when its price was published over a new
+// connection, so a restarted Carbon receiver doesn't need a reconnect
+func (s *GraphiteSink) Push(ctx context.Context, samples []SinkSample) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Graphite at %s: %w", s.address, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetWriteDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	w := bufio.NewWriter(conn)
+	for _, sample := range samples {
+		w.WriteString(sinkPath(s.prefix, sample))
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatFloat(sample.Value, 'f', -1, 64))
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatInt(sample.Time.Unix(), 10))
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to send to Graphite: %w", err)
+	}
+	return nil
+}
+
+func (s *GraphiteSink) Close() error {
+	return nil
+}
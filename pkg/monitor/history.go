@@ -0,0 +1,141 @@
+package monitor
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// PriceRecord is a single point in the price history of a series. Costs are stored as JSON
+// numbers, which round-trip a decimal price through its shortest float representation, and
+// memory is always in GB so records stay comparable whatever unit memory is published in.
+type PriceRecord struct {
+	Time         time.Time `json:"time"`
+	Provider     string    `json:"provider"`
+	Region       string    `json:"region"`
+	InstanceType string    `json:"instance_type"`
+	Confidential bool      `json:"confidential,omitempty"`
+	TotalCost    float64   `json:"total_cost"`
+	MemoryGB     float64   `json:"memory_gb"`
+	VCPUs        int       `json:"vcpus"`
+	GPUType      string    `json:"gpu_type,omitempty"`
+	GPUCount     int       `json:"gpu_count,omitempty"`
+
+	// Source is where the price came from: "live", or the price list version it was backfilled from
+	Source string `json:"source"`
+}
+
+// NewPriceRecord returns the history record of a price published at t
+func NewPriceRecord(p VMPricing, t time.Time, source string) PriceRecord {
+	return PriceRecord{
+		Time:         t,
+		Provider:     p.Provider,
+		Region:       p.Region,
+		InstanceType: p.InstanceType,
+		Confidential: p.Confidential,
+		TotalCost:    p.TotalCost.InexactFloat64(),
+		MemoryGB:     p.MemoryGB,
+		VCPUs:        p.VCPUs,
+		GPUType:      p.GPUType,
+		GPUCount:     p.GPUCount,
+		Source:       source,
+	}
+}
+
+// Key returns the series key of the record
+func (r PriceRecord) Key() PriceKey {
+	return PriceKey{
+		Provider:     r.Provider,
+		Region:       r.Region,
+		InstanceType: r.InstanceType,
+		Confidential: r.Confidential,
+	}
+}
+
+// HistoryStore persists price changes so trends outlive the process and the Prometheus retention
+type HistoryStore interface {
+	Append(records ...PriceRecord) error
+	Records() ([]PriceRecord, error)
+	Close() error
+}
+
+// FileHistoryStore keeps price history as JSON lines in a local file
+type FileHistoryStore struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileHistoryStore(path string) (*FileHistoryStore, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+
+	return &FileHistoryStore{
+		path: path,
+		file: file,
+	}, nil
+}
+
+// Append writes records to the end of the history file
+func (s *FileHistoryStore) Append(records ...PriceRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := bufio.NewWriter(s.file)
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("failed to encode price record: %w", err)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	return nil
+}
+
+// Records reads back every record in the history file in the order they were written
+func (s *FileHistoryStore) Records() ([]PriceRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer file.Close()
+
+	return readPriceRecords(file)
+}
+
+func (s *FileHistoryStore) Close() error {
+	return s.file.Close()
+}
+
+// readPriceRecords decodes price records written as JSON lines
+func readPriceRecords(r io.Reader) ([]PriceRecord, error) {
+	var records []PriceRecord
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for dec.More() {
+		var rec PriceRecord
+		if err := dec.Decode(&rec); err != nil {
+			return nil, fmt.Errorf("failed to read price records: %w", err)
+		}
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,326 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+// hookTimeout bounds a run of the hook script, so a script stuck in a loop doesn't hold up the
+// next poll
+const hookTimeout = 30 * time.Second
+
+// reservedMetricPrefixes are the prefixes of the metrics the monitor and the Prometheus client
+// export, which a hook script's metrics can't take
+var reservedMetricPrefixes = []string{"cloud_", "go_", "process_", "promhttp_"}
+
+// HookScript is a Starlark script run after every poll with the published prices, for logic
+// specific to an organization. The script defines on_poll(prices, state), which is called with
+// a list of prices and a dict it keeps across polls, and can call metric() to export a gauge and
+// notify() to open an issue in the configured issue trackers. print() logs.
+type HookScript struct {
+	file      string
+	onPoll    starlark.Callable
+	collector *hookCollector
+
+	// mu serializes runs, which share the state
+	mu    sync.Mutex
+	state *starlark.Dict
+}
+
+// hookBuiltins are the functions a hook script can call besides Starlark's own
+var hookBuiltins = starlark.StringDict{
+	"metric": starlark.NewBuiltin("metric", hookMetric),
+	"notify": starlark.NewBuiltin("notify", hookNotify),
+}
+
+// LoadHookScript reads and runs the top level of a Starlark hook script, which must define
+// on_poll
+func LoadHookScript(file string) (*HookScript, error) {
+	thread := &starlark.Thread{Name: "load", Print: hookPrint}
+	opts := &syntax.FileOptions{Set: true, While: true, TopLevelControl: true}
+	globals, err := starlark.ExecFileOptions(opts, thread, file, nil, hookBuiltins)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load hook script: %w", err)
+	}
+
+	onPoll, ok := globals["on_poll"].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("hook script %s must define on_poll(prices, state)", file)
+	}
+	return &HookScript{
+		file:      file,
+		onPoll:    onPoll,
+		collector: &hookCollector{},
+		state:     starlark.NewDict(0),
+	}, nil
+}
+
+// hookRunKey is the thread-local key of the run a hook script's builtins record into
+const hookRunKey = "hook_run"
+
+// hookRun is what a run of the hook script exported and asked for
+type hookRun struct {
+	ctx     context.Context
+	samples []hookSample
+	notify  func(ctx context.Context, title, body string)
+}
+
+// Run calls on_poll with published prices. The metrics the run exports replace those of the
+// previous run once it succeeds, so a failed run leaves the previous run's metrics exported.
+func (h *HookScript) Run(ctx context.Context, entries []PriceEntry, rounding PriceRounding, memoryUnit MemoryUnit, notify func(ctx context.Context, title, body string)) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, hookTimeout)
+	defer cancel()
+
+	run := &hookRun{ctx: ctx, notify: notify}
+	thread := &starlark.Thread{Name: "on_poll", Print: hookPrint}
+	thread.SetLocal(hookRunKey, run)
+	stop := context.AfterFunc(ctx, func() { thread.Cancel(context.Cause(ctx).Error()) })
+	defer stop()
+
+	prices := make([]starlark.Value, len(entries))
+	for i, entry := range entries {
+		prices[i] = hookPrice(entry, rounding, memoryUnit)
+	}
+
+	if _, err := starlark.Call(thread, h.onPoll, starlark.Tuple{starlark.NewList(prices), h.state}, nil); err != nil {
+		var evalErr *starlark.EvalError
+		if errors.As(err, &evalErr) {
+			return fmt.Errorf("hook script failed: %s", evalErr.Backtrace())
+		}
+		return fmt.Errorf("hook script failed: %w", err)
+	}
+	h.collector.replace(run.samples)
+	return nil
+}
+
+// hookPrice is a published price as a hook script sees it: a struct with costs rounded like the
+// metrics, memory in the memory unit, and None for what isn't known
+func hookPrice(entry PriceEntry, rounding PriceRounding, memoryUnit MemoryUnit) starlark.Value {
+	p := entry.Pricing
+	fields := starlark.StringDict{
+		"provider":        starlark.String(p.Provider),
+		"region":          starlark.String(p.Region),
+		"instance_type":   starlark.String(p.InstanceType),
+		"confidential":    starlark.Bool(p.Confidential),
+		"overridden":      starlark.Bool(p.Overridden),
+		"total_cost":      starlark.Float(rounding.Float(p.TotalCost)),
+		"vcpus":           starlark.MakeInt(p.VCPUs),
+		"memory":          starlark.None,
+		"cost_per_vcpu":   starlark.None,
+		"cost_per_memory": starlark.None,
+		"updated_at":      starlark.MakeInt64(entry.UpdatedAt.Unix()),
+		"previous_cost":   starlark.None,
+		"changed_at":      starlark.None,
+	}
+	if p.MemoryGB > 0 {
+		fields["memory"] = starlark.Float(memoryUnit.FromGB(p.MemoryGB).InexactFloat64())
+	}
+	if cost, ok := p.CostPerVCPU(); ok {
+		fields["cost_per_vcpu"] = starlark.Float(rounding.Float(cost))
+	}
+	if cost, ok := p.CostPerMemory(memoryUnit); ok {
+		fields["cost_per_memory"] = starlark.Float(rounding.Float(cost))
+	}
+	if !entry.ChangedAt.IsZero() {
+		fields["previous_cost"] = starlark.Float(rounding.Float(entry.PreviousCost))
+		fields["changed_at"] = starlark.MakeInt64(entry.ChangedAt.Unix())
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, fields)
+}
+
+func hookPrint(thread *starlark.Thread, msg string) {
+	slog.Info("hook script", "message", msg)
+}
+
+// currentHookRun returns the run a builtin was called from, which is nil at the top level of
+// the script
+func currentHookRun(thread *starlark.Thread) (*hookRun, error) {
+	run, _ := thread.Local(hookRunKey).(*hookRun)
+	if run == nil {
+		return nil, errors.New("can only be called from on_poll")
+	}
+	return run, nil
+}
+
+// hookMetric implements metric(name, value, labels={}, help=""), which exports a gauge sample
+// until the next run
+func hookMetric(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		name   string
+		value  starlark.Value
+		labels *starlark.Dict
+		help   string
+	)
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "name", &name, "value", &value, "labels?", &labels, "help?", &help); err != nil {
+		return nil, err
+	}
+	run, err := currentHookRun(thread)
+	if err != nil {
+		return nil, err
+	}
+
+	if !metricNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("%q is not a valid Prometheus metric name", name)
+	}
+	if slices.ContainsFunc(reservedMetricPrefixes, func(prefix string) bool { return strings.HasPrefix(name, prefix) }) {
+		return nil, fmt.Errorf("%q takes a prefix reserved for the monitor's metrics", name)
+	}
+	v, ok := starlark.AsFloat(value)
+	if !ok {
+		return nil, fmt.Errorf("value must be a number, not %s", value.Type())
+	}
+
+	sample := hookSample{name: name, help: help, value: v}
+	if sample.help == "" {
+		sample.help = "Exported by the hook script"
+	}
+	if labels != nil {
+		for _, item := range labels.Items() {
+			key, ok := starlark.AsString(item[0])
+			if !ok || !labelNamePattern.MatchString(key) || strings.HasPrefix(key, "__") {
+				return nil, fmt.Errorf("%s is not a valid label name", item[0])
+			}
+			label, ok := starlark.AsString(item[1])
+			if !ok {
+				label = item[1].String()
+			}
+			sample.labelNames = append(sample.labelNames, key)
+			sample.labelValues = append(sample.labelValues, label)
+		}
+	}
+
+	// Prometheus rejects a scrape whose samples of a metric disagree on its help or labels
+	for i, other := range run.samples {
+		if other.name != name {
+			continue
+		}
+		if other.help != sample.help || !slices.Equal(other.labelNames, sample.labelNames) {
+			return nil, fmt.Errorf("%s was exported with other labels or help earlier in the run", name)
+		}
+		if slices.Equal(other.labelValues, sample.labelValues) {
+			run.samples[i] = sample
+			return starlark.None, nil
+		}
+	}
+	run.samples = append(run.samples, sample)
+	return starlark.None, nil
+}
+
+// hookNotify implements notify(title, body=""), which opens an issue in every configured issue
+// tracker
+func hookNotify(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var title, body string
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "title", &title, "body?", &body); err != nil {
+		return nil, err
+	}
+	run, err := currentHookRun(thread)
+	if err != nil {
+		return nil, err
+	}
+	if title == "" {
+		return nil, errors.New("title must not be empty")
+	}
+	run.notify(run.ctx, title, body)
+	return starlark.None, nil
+}
+
+// hookSample is a gauge sample a hook script exported
+type hookSample struct {
+	name        string
+	help        string
+	labelNames  []string
+	labelValues []string
+	value       float64
+}
+
+// hookCollector exports the samples of the last successful run of the hook script. Their names
+// are only known once the script runs, so the collector is unchecked.
+type hookCollector struct {
+	mu      sync.Mutex
+	samples []hookSample
+}
+
+func (c *hookCollector) replace(samples []hookSample) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.samples = samples
+}
+
+func (c *hookCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (c *hookCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.samples {
+		desc := prometheus.NewDesc(s.name, s.help, s.labelNames, nil)
+		m, err := prometheus.NewConstMetric(desc, prometheus.GaugeValue, s.value, s.labelValues...)
+		if err != nil {
+			slog.Warn("skipping hook script metric", "metric", s.name, "error", err)
+			continue
+		}
+		ch <- m
+	}
+}
+
+// RegisterHookScript exports the metrics of a hook script's runs
+func (m *Metrics) RegisterHookScript(h *HookScript) error {
+	if m.registerer == nil {
+		return nil
+	}
+	if err := m.registerer.Register(h.collector); err != nil {
+		return fmt.Errorf("failed to register hook script metrics: %w", err)
+	}
+	return nil
+}
+
+// runHook runs the hook script with the published prices after a poll
+func (m *Monitor) runHook(ctx context.Context) {
+	start := time.Now()
+	err := m.hook.Run(ctx, m.snapshot.Entries(), m.rounding, m.metrics.memoryUnit, m.openHookIssue)
+
+	result := "success"
+	if err != nil {
+		result = "error"
+		slog.Error("hook script failed", "hook_script", m.hook.file, "error", err)
+	}
+	m.metrics.HookRuns.With(prometheus.Labels{"result": result}).Inc()
+	slog.Debug("ran hook script", "hook_script", m.hook.file, "duration", time.Since(start))
+}
+
+// openHookIssue opens an issue the hook script asked for in every configured issue tracker
+func (m *Monitor) openHookIssue(ctx context.Context, title, body string) {
+	slog.Warn("hook script notification", "title", title)
+
+	for _, tracker := range m.issueTrackers {
+		result := "opened"
+		err := tracker.CreateIssue(ctx, title, body)
+		if err != nil {
+			result = "failed"
+		}
+		m.metrics.HookIssues.With(prometheus.Labels{
+			"tracker": tracker.Name(),
+			"result":  result,
+		}).Inc()
+		if err != nil {
+			slog.Error("failed to open hook script issue",
+				"tracker", tracker.Name(),
+				"title", title,
+				"error", err,
+			)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// influxDBBatchSize is the number of lines written per request, as recommended by InfluxDB
+const influxDBBatchSize = 5000
+
+// influxDBEscaper escapes the characters that delimit tags in line protocol
+var influxDBEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// InfluxDBSink writes the pricing gauges to InfluxDB in line protocol, with a measurement per
+// gauge, the labels as tags, and the price in a value field
+type InfluxDBSink struct {
+	writeURL string
+	token    *Secret
+	client   *http.Client
+}
+
+// NewInfluxDBSink writes to a bucket through the /api/v2/write endpoint of InfluxDB 2 and later.
+// InfluxDB 1.8 serves the same endpoint with the bucket given as database/retention-policy and
+// the token as username:password.
+func NewInfluxDBSink(baseURL, org, bucket string, token *Secret) (*InfluxDBSink, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("an InfluxDB bucket is required")
+	}
+
+	query := url.Values{}
+	query.Set("bucket", bucket)
+	query.Set("precision", "s")
+	if org != "" {
+		query.Set("org", org)
+	}
+
+	return &InfluxDBSink{
+		writeURL: strings.TrimSuffix(baseURL, "/") + "/api/v2/write?" + query.Encode(),
+		token:    token,
+		client:   http.DefaultClient,
+	}, nil
+}
+
+func (s *InfluxDBSink) Name() string {
+	return "influxdb"
+}
+
+// Push writes every sample as a point timestamped with when its price was published, so pushing
+// an unchanged price again overwrites the same point
+func (s *InfluxDBSink) Push(ctx context.Context, samples []SinkSample) error {
+	for start := 0; start < len(samples); start += influxDBBatchSize {
+		var body bytes.Buffer
+		for _, sample := range samples[start:min(start+influxDBBatchSize, len(samples))] {
+			writeInfluxDBLine(&body, sample)
+		}
+		if err := s.write(ctx, &body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *InfluxDBSink) write(ctx context.Context, body io.Reader) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.writeURL, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if token := s.token.Value(); token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s writing to InfluxDB: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func (s *InfluxDBSink) Close() error {
+	return nil
+}
+
+// writeInfluxDBLine formats a sample as a line of line protocol
+func writeInfluxDBLine(b *bytes.Buffer, sample SinkSample) {
+	b.WriteString(influxDBEscaper.Replace(sample.Name))
+	for _, label := range sample.Labels {
+		// Line protocol can't represent an empty tag value, so the tag is left out
+		if label.Value == "" {
+			continue
+		}
+		b.WriteByte(',')
+		b.WriteString(influxDBEscaper.Replace(label.Name))
+		b.WriteByte('=')
+		b.WriteString(influxDBEscaper.Replace(label.Value))
+	}
+	b.WriteString(" value=")
+	b.WriteString(strconv.FormatFloat(sample.Value, 'f', -1, 64))
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatInt(sample.Time.Unix(), 10))
+	b.WriteByte('\n')
+}
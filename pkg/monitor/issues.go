@@ -0,0 +1,151 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// IssueTracker opens tickets for price regressions and alerts
+type IssueTracker interface {
+	Name() string
+	CreateIssue(ctx context.Context, title, body string) error
+}
+
+// GitHubIssues opens issues in a GitHub repository
+type GitHubIssues struct {
+	apiURL string
+	repo   string
+	token  *Secret
+	labels []string
+	client *http.Client
+}
+
+// NewGitHubIssues creates a tracker for a repository given as owner/name. apiURL is the
+// REST API root, which differs from https://api.github.com on GitHub Enterprise Server.
+func NewGitHubIssues(apiURL, repo string, token *Secret, labels []string) (*GitHubIssues, error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("GitHub repository %q must be owner/name", repo)
+	}
+	if token.Value() == "" {
+		return nil, fmt.Errorf("a GitHub token is required to open issues")
+	}
+
+	return &GitHubIssues{
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+		repo:   repo,
+		token:  token,
+		labels: labels,
+		client: http.DefaultClient,
+	}, nil
+}
+
+func (g *GitHubIssues) Name() string {
+	return "github"
+}
+
+func (g *GitHubIssues) CreateIssue(ctx context.Context, title, body string) error {
+	payload := map[string]any{
+		"title": title,
+		"body":  body,
+	}
+	if len(g.labels) > 0 {
+		payload["labels"] = g.labels
+	}
+
+	headers := map[string]string{
+		"Authorization":        "Bearer " + g.token.Value(),
+		"Accept":               "application/vnd.github+json",
+		"X-GitHub-Api-Version": "2022-11-28",
+	}
+	return postIssue(ctx, g.client, g.apiURL+"/repos/"+g.repo+"/issues", headers, payload)
+}
+
+// JiraIssues opens issues in a Jira project
+type JiraIssues struct {
+	baseURL   string
+	project   string
+	issueType string
+	user      string
+	token     *Secret
+	client    *http.Client
+}
+
+// NewJiraIssues creates a tracker for a Jira project. Jira Cloud authenticates with an account
+// email and API token; Jira Data Center accepts a personal access token without a user.
+func NewJiraIssues(baseURL, project, issueType, user string, token *Secret) (*JiraIssues, error) {
+	if project == "" {
+		return nil, fmt.Errorf("a Jira project key is required to open issues")
+	}
+	if token.Value() == "" {
+		return nil, fmt.Errorf("a Jira API token is required to open issues")
+	}
+
+	return &JiraIssues{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		project:   project,
+		issueType: issueType,
+		user:      user,
+		token:     token,
+		client:    http.DefaultClient,
+	}, nil
+}
+
+func (j *JiraIssues) Name() string {
+	return "jira"
+}
+
+func (j *JiraIssues) CreateIssue(ctx context.Context, title, body string) error {
+	// API version 2 takes a plain-text description, unlike version 3's document format
+	payload := map[string]any{
+		"fields": map[string]any{
+			"project":     map[string]string{"key": j.project},
+			"issuetype":   map[string]string{"name": j.issueType},
+			"summary":     title,
+			"description": body,
+		},
+	}
+
+	token := j.token.Value()
+	auth := "Bearer " + token
+	if j.user != "" {
+		auth = "Basic " + base64.StdEncoding.EncodeToString([]byte(j.user+":"+token))
+	}
+	headers := map[string]string{"Authorization": auth}
+	return postIssue(ctx, j.client, j.baseURL+"/rest/api/2/issue", headers, payload)
+}
+
+// postIssue sends an issue as JSON and fails on any response other than 201 Created
+func postIssue(ctx context.Context, client *http.Client, url string, headers map[string]string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s creating issue: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
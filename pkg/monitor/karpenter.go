@@ -0,0 +1,81 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// karpenterPrices returns the published AWS on-demand prices keyed by region and instance
+// type, the shape of Karpenter's on-demand pricing tables
+func karpenterPrices(snapshot *PriceSnapshot, region string, rounding PriceRounding) map[string]map[string]float64 {
+	prices := make(map[string]map[string]float64)
+	for _, entry := range snapshot.Entries() {
+		p := entry.Pricing
+		if p.Provider != "aws" || p.Confidential || (region != "" && p.Region != region) {
+			continue
+		}
+
+		if prices[p.Region] == nil {
+			prices[p.Region] = make(map[string]float64)
+		}
+		prices[p.Region][p.InstanceType] = rounding.Float(p.TotalCost)
+	}
+	return prices
+}
+
+// karpenterPricingHandler serves the on-demand prices for Karpenter, as JSON by default or,
+// with ?format=go, as a replacement for the generated initial pricing file of the Karpenter
+// AWS provider (pkg/providers/pricing/zz_generated.pricing_aws.go). ?region= limits the
+// output to one region.
+func karpenterPricingHandler(snapshot *PriceSnapshot, rounding PriceRounding) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prices := karpenterPrices(snapshot, r.URL.Query().Get("region"), rounding)
+
+		switch format := r.URL.Query().Get("format"); format {
+		case "", "json":
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(prices); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+		case "go":
+			w.Header().Set("Content-Type", "text/x-go; charset=utf-8")
+			writeKarpenterPricingGo(w, prices, time.Now())
+		default:
+			http.Error(w, fmt.Sprintf("unknown format %q (expected json or go)", format), http.StatusBadRequest)
+		}
+	})
+}
+
+// writeKarpenterPricingGo writes prices in the layout of Karpenter's generated pricing file,
+// grouped by instance family within each region
+func writeKarpenterPricingGo(w io.Writer, prices map[string]map[string]float64, generatedAt time.Time) {
+	fmt.Fprintf(w, "//go:build !ignore_autogenerated\n\n")
+	fmt.Fprintf(w, "package pricing\n\n")
+	fmt.Fprintf(w, "// generated at %s by cloud-pricing-monitor\n\n", generatedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(w, "import ec2types \"github.com/aws/aws-sdk-go-v2/service/ec2/types\"\n\n")
+	fmt.Fprintf(w, "var InitialOnDemandPricesAWS = map[string]map[ec2types.InstanceType]float64{\n")
+
+	for _, region := range slices.Sorted(maps.Keys(prices)) {
+		fmt.Fprintf(w, "\t// %s\n", region)
+		fmt.Fprintf(w, "\t%q: {\n", region)
+
+		family := ""
+		for _, instanceType := range slices.Sorted(maps.Keys(prices[region])) {
+			if f, _, _ := strings.Cut(instanceType, "."); f != family {
+				family = f
+				fmt.Fprintf(w, "\t\t// %s family\n", family)
+			}
+			fmt.Fprintf(w, "\t\t%q: %f,\n", instanceType, prices[region][instanceType])
+		}
+
+		fmt.Fprintf(w, "\t},\n")
+	}
+
+	fmt.Fprintf(w, "}\n")
+}
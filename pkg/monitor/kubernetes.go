@@ -0,0 +1,264 @@
+package monitor
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const kubernetesServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// KubernetesDiscoverer discovers nodes and pod resource requests from the Kubernetes API
+type KubernetesDiscoverer struct {
+	baseURL string
+	// token is nil without authentication, and rotated by the kubelet otherwise
+	token  *Secret
+	client *http.Client
+}
+
+// NewKubernetesDiscoverer connects to the API server at apiURL without authentication (for
+// example through kubectl proxy), or to the in-cluster API server with the pod's service
+// account when apiURL is empty
+func NewKubernetesDiscoverer(apiURL string) (*KubernetesDiscoverer, error) {
+	if apiURL != "" {
+		return &KubernetesDiscoverer{
+			baseURL: strings.TrimSuffix(apiURL, "/"),
+			client:  http.DefaultClient,
+		}, nil
+	}
+
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a Kubernetes cluster and no API server URL given")
+	}
+
+	token, err := NewSecret("", kubernetesServiceAccountDir+"/token")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+
+	ca, err := os.ReadFile(kubernetesServiceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account CA: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("failed to parse service account CA")
+	}
+
+	return &KubernetesDiscoverer{
+		baseURL: "https://" + net.JoinHostPort(host, port),
+		token:   token,
+		client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: pool},
+			},
+		},
+	}, nil
+}
+
+func (d *KubernetesDiscoverer) Scheduler() string {
+	return "kubernetes"
+}
+
+type kubeObjectMeta struct {
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace"`
+	Labels          map[string]string `json:"labels"`
+	OwnerReferences []struct {
+		Kind       string `json:"kind"`
+		Name       string `json:"name"`
+		Controller bool   `json:"controller"`
+	} `json:"ownerReferences"`
+}
+
+type kubeNode struct {
+	Metadata kubeObjectMeta `json:"metadata"`
+	Spec     struct {
+		ProviderID string `json:"providerID"`
+	} `json:"spec"`
+	Status struct {
+		Allocatable map[string]string `json:"allocatable"`
+	} `json:"status"`
+}
+
+type kubePod struct {
+	Metadata kubeObjectMeta `json:"metadata"`
+	Spec     struct {
+		NodeName   string `json:"nodeName"`
+		Containers []struct {
+			Resources struct {
+				Requests map[string]string `json:"requests"`
+			} `json:"resources"`
+		} `json:"containers"`
+	} `json:"spec"`
+}
+
+func (d *KubernetesDiscoverer) Discover(ctx context.Context) (*ClusterState, error) {
+	nodes, err := kubeList[kubeNode](ctx, d, "/api/v1/nodes", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Kubernetes nodes: %w", err)
+	}
+
+	query := url.Values{"fieldSelector": {"status.phase=Running"}}
+	pods, err := kubeList[kubePod](ctx, d, "/api/v1/pods", query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Kubernetes pods: %w", err)
+	}
+
+	state := &ClusterState{}
+	for _, n := range nodes {
+		node, ok := kubeClusterNode(n)
+		if ok {
+			state.Nodes = append(state.Nodes, node)
+		}
+	}
+
+	for _, p := range pods {
+		if p.Spec.NodeName == "" {
+			continue
+		}
+
+		kind, name := kubeWorkload(p.Metadata)
+		alloc := ClusterAllocation{
+			Node:         p.Spec.NodeName,
+			Namespace:    p.Metadata.Namespace,
+			WorkloadKind: kind,
+			Workload:     name,
+		}
+
+		for _, c := range p.Spec.Containers {
+			cpu, _ := parseQuantity(c.Resources.Requests["cpu"])
+			memory, _ := parseQuantity(c.Resources.Requests["memory"])
+			alloc.VCPUs += cpu
+			alloc.MemoryGB += memory / 1e9
+		}
+
+		state.Allocations = append(state.Allocations, alloc)
+	}
+
+	return state, nil
+}
+
+// kubeList fetches every page of a Kubernetes list endpoint
+func kubeList[T any](ctx context.Context, d *KubernetesDiscoverer, path string, query url.Values) ([]T, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("limit", "500")
+
+	var items []T
+	for {
+		var page struct {
+			Metadata struct {
+				Continue string `json:"continue"`
+			} `json:"metadata"`
+			Items []T `json:"items"`
+		}
+		if err := d.get(ctx, path+"?"+query.Encode(), &page); err != nil {
+			return nil, err
+		}
+
+		items = append(items, page.Items...)
+		if page.Metadata.Continue == "" {
+			return items, nil
+		}
+		query.Set("continue", page.Metadata.Continue)
+	}
+}
+
+func (d *KubernetesDiscoverer) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if token := d.token.Value(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, path)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// kubeProviderIDSchemes are the schemes of the node provider IDs set by the cloud providers'
+// Kubernetes integrations, such as azure:///subscriptions/... on AKS, by provider
+var kubeProviderIDSchemes = map[string]string{
+	"aws://":   "aws",
+	"gce://":   "gcp",
+	"azure://": "azure",
+}
+
+// kubeProvider returns the provider of a node from its provider ID
+func kubeProvider(providerID string) (string, bool) {
+	for scheme, provider := range kubeProviderIDSchemes {
+		if strings.HasPrefix(providerID, scheme) {
+			return provider, true
+		}
+	}
+	return "", false
+}
+
+// kubeClusterNode maps a Kubernetes node to its cloud instance using the well-known topology
+// labels and the provider ID. Nodes on other providers are skipped.
+func kubeClusterNode(n kubeNode) (ClusterNode, bool) {
+	provider, ok := kubeProvider(n.Spec.ProviderID)
+	if !ok {
+		return ClusterNode{}, false
+	}
+
+	labels := n.Metadata.Labels
+	instanceType := cmp.Or(labels["node.kubernetes.io/instance-type"], labels["beta.kubernetes.io/instance-type"])
+	region := cmp.Or(labels["topology.kubernetes.io/region"], labels["failure-domain.beta.kubernetes.io/region"])
+	if instanceType == "" || region == "" {
+		return ClusterNode{}, false
+	}
+
+	cpu, _ := parseQuantity(n.Status.Allocatable["cpu"])
+	memory, _ := parseQuantity(n.Status.Allocatable["memory"])
+
+	return ClusterNode{
+		Name:         n.Metadata.Name,
+		Provider:     provider,
+		Region:       region,
+		InstanceType: instanceType,
+		VCPUs:        cpu,
+		MemoryGB:     memory / 1e9,
+	}, true
+}
+
+// kubeWorkload returns the controller a pod belongs to. Pods of a Deployment are owned by a
+// ReplicaSet named after the Deployment plus the pod template hash, so the hash is stripped
+// to attribute them to the Deployment.
+func kubeWorkload(meta kubeObjectMeta) (kind, name string) {
+	for _, owner := range meta.OwnerReferences {
+		if !owner.Controller {
+			continue
+		}
+
+		if hash := meta.Labels["pod-template-hash"]; owner.Kind == "ReplicaSet" && hash != "" {
+			return "Deployment", strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+		return owner.Kind, owner.Name
+	}
+
+	return "Pod", meta.Name
+}
@@ -0,0 +1,215 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/providers"
+	cli "github.com/urfave/cli/v2"
+)
+
+// defaultMaxConcurrentFetches is how many prices of a provider are fetched at the same time
+// unless --max-concurrent-fetches says otherwise
+const defaultMaxConcurrentFetches = 16
+
+// parseProviderLimits parses limits given as provider=value, such as aws=4, or as a bare value
+// for every provider not given one of its own. Values must be positive.
+func parseProviderLimits(flag string, values []string) (map[string]float64, error) {
+	limits := make(map[string]float64)
+	for _, value := range values {
+		provider, limit, ok := strings.Cut(value, "=")
+		if !ok {
+			provider, limit = "", value
+		}
+		n, err := strconv.ParseFloat(limit, 64)
+		if err != nil || n <= 0 || math.IsInf(n, 0) {
+			return nil, fmt.Errorf("invalid %s %q: must be a positive number", flag, value)
+		}
+		limits[provider] = n
+	}
+	return limits, nil
+}
+
+// providerLimit returns the limit of a provider, or the limit of every provider when it has none
+func providerLimit(limits map[string]float64, provider string) (float64, bool) {
+	if n, ok := limits[provider]; ok {
+		return n, true
+	}
+	n, ok := limits[""]
+	return n, ok
+}
+
+// FetchLimits bound the price fetches sent to each provider's API, so polls of many regions
+// and instance types stay within the provider's throttling limits
+type FetchLimits map[string]*fetchLimiter
+
+// NewFetchLimits creates the limiter of each named provider. A provider without a concurrency
+// limit fetches defaultMaxConcurrentFetches prices at a time, and one without a rate is
+// unlimited. A rate is a number of fetches per second, in bursts of up to a second's worth.
+func NewFetchLimits(names []string, concurrency, rates map[string]float64, metrics *Metrics) (FetchLimits, error) {
+	for _, limits := range []map[string]float64{concurrency, rates} {
+		for provider := range limits {
+			if provider != "" && !slices.Contains(names, provider) {
+				return nil, fmt.Errorf("invalid fetch limit: unknown provider %q", provider)
+			}
+		}
+	}
+
+	limits := make(FetchLimits)
+	for _, provider := range names {
+		n, ok := providerLimit(concurrency, provider)
+		if !ok {
+			n = defaultMaxConcurrentFetches
+		}
+		l := &fetchLimiter{
+			provider: provider,
+			slots:    make(chan struct{}, max(1, int(n))),
+			metrics:  metrics,
+		}
+		if rate, ok := providerLimit(rates, provider); ok {
+			l.bucket = newTokenBucket(rate, max(1, math.Floor(rate)))
+		}
+		limits[provider] = l
+	}
+	return limits, nil
+}
+
+// fetchLimitFlags creates the limits of the max-concurrent-fetches and fetch-rate-limit flags
+func fetchLimitFlags(cctx *cli.Context, names []string, metrics *Metrics) (FetchLimits, error) {
+	concurrency, err := parseProviderLimits("max-concurrent-fetches", cctx.StringSlice("max-concurrent-fetches"))
+	if err != nil {
+		return nil, err
+	}
+	rates, err := parseProviderLimits("fetch-rate-limit", cctx.StringSlice("fetch-rate-limit"))
+	if err != nil {
+		return nil, err
+	}
+	return NewFetchLimits(names, concurrency, rates, metrics)
+}
+
+// concurrency returns how many prices of a provider are fetched at the same time
+func (l FetchLimits) concurrency(provider string) int {
+	if limiter, ok := l[provider]; ok {
+		return cap(limiter.slots)
+	}
+	return defaultMaxConcurrentFetches
+}
+
+// acquire waits for a provider's limits to allow another fetch and returns the function that
+// releases its slot. Providers without a limiter, and nil limits, fetch right away.
+func (l FetchLimits) acquire(ctx context.Context, provider string) (func(), error) {
+	limiter, ok := l[provider]
+	if !ok {
+		return func() {}, nil
+	}
+	return limiter.acquire(ctx)
+}
+
+// fetchLimiter bounds the fetches of a provider in flight, and their rate when it has a bucket
+type fetchLimiter struct {
+	provider string
+	slots    chan struct{}
+	bucket   *tokenBucket
+	metrics  *Metrics
+}
+
+func (l *fetchLimiter) acquire(ctx context.Context) (func(), error) {
+	start := time.Now()
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	l.metrics.RecordFetchWait(l.provider, "concurrency", time.Since(start))
+	release := func() { <-l.slots }
+
+	if l.bucket != nil {
+		start := time.Now()
+		if err := l.bucket.wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+		l.metrics.RecordFetchWait(l.provider, "rate", time.Since(start))
+	}
+	return release, nil
+}
+
+// tokenBucket allows a number of events per second, in bursts of up to its capacity. A waiter
+// takes its token up front, leaving the bucket in debt, so waiters are served in order.
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64
+	capacity float64
+	tokens   float64
+	updated  time.Time
+}
+
+func newTokenBucket(rate, capacity float64) *tokenBucket {
+	return &tokenBucket{rate: rate, capacity: capacity, tokens: capacity, updated: time.Now()}
+}
+
+// wait takes a token, waiting until the bucket has refilled enough to pay for it
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.capacity, b.tokens+now.Sub(b.updated).Seconds()*b.rate)
+	b.updated = now
+	b.tokens--
+	delay := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	if err := sleepContext(ctx, delay); err != nil {
+		// The token was never used, so it goes back
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// fetchUpstream fetches the current price of a series from its provider, within the provider's
+// fetch limits. A fetch that failed for a reason that may not last is retried after a backoff,
+// during which it gives up its slot.
+func (m *Monitor) fetchUpstream(ctx context.Context, p providers.PricingProvider, region, instanceType string) (*VMPricing, error) {
+	for retry := 0; ; retry++ {
+		release, err := m.limits.acquire(ctx, p.Name())
+		if err != nil {
+			return nil, err
+		}
+		pricing, err := p.FetchPricing(ctx, region, instanceType)
+		release()
+		if err == nil {
+			return (*VMPricing)(pricing), nil
+		}
+
+		reason, retryable := retryReason(err)
+		if !retryable || retry >= m.retries.retries {
+			return nil, err
+		}
+		m.metrics.RecordRetry(p.Name(), reason)
+
+		delay := m.retries.delay(retry)
+		slog.Debug("retrying price fetch",
+			"provider", p.Name(),
+			"region", region,
+			"instance_type", instanceType,
+			"reason", reason,
+			"delay", delay,
+			"error", err,
+		)
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/providers"
+)
+
+// matchReport is how a price was resolved from a provider's catalog
+type matchReport struct {
+	Provider     string   `json:"provider"`
+	Region       string   `json:"region"`
+	InstanceType string   `json:"instance_type"`
+	TotalCost    *float64 `json:"total_cost,omitempty"`
+	Error        string   `json:"error,omitempty"`
+	ErrorReason  string   `json:"error_reason,omitempty"`
+
+	Queries   []string                  `json:"queries"`
+	Decisions []providers.MatchDecision `json:"decisions"`
+}
+
+// traceFetch fetches a price from its provider with how it was resolved traced. The shared
+// cache and concurrent fetches of the series are bypassed, so the provider resolves the price
+// again, but the provider's fetch limits still apply.
+func (m *Monitor) traceFetch(ctx context.Context, provider, region, instanceType string) (*VMPricing, *providers.MatchTrace, error) {
+	p := m.provider(provider)
+	if p == nil {
+		return nil, nil, fmt.Errorf("%w: %s", errProviderNotMonitored, provider)
+	}
+
+	release, err := m.limits.acquire(ctx, provider)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	trace := &providers.MatchTrace{}
+	pricing, err := p.FetchPricing(providers.WithMatchTrace(ctx, trace), region, instanceType)
+	return (*VMPricing)(pricing), trace, err
+}
+
+// matchDebugHandler serves how the price of a series is resolved: the catalog queries sent,
+// and every SKU or price list product considered, whether it was matched, and why. A failed
+// fetch is reported with the decisions that led to it rather than as an error response.
+func matchDebugHandler(trace func(ctx context.Context, provider, region, instanceType string) (*VMPricing, *providers.MatchTrace, error), rounding PriceRounding) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		report := matchReport{
+			Provider:     query.Get("provider"),
+			Region:       query.Get("region"),
+			InstanceType: query.Get("instance_type"),
+		}
+		if report.Provider == "" || report.Region == "" || report.InstanceType == "" {
+			http.Error(w, "provider, region, and instance_type are required", http.StatusBadRequest)
+			return
+		}
+
+		pricing, matches, err := trace(r.Context(), report.Provider, report.Region, report.InstanceType)
+		switch {
+		case errors.Is(err, errProviderNotMonitored):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			report.Error = err.Error()
+			report.ErrorReason = errorReason(err)
+		default:
+			cost := rounding.Float(pricing.TotalCost)
+			report.TotalCost = &cost
+		}
+		if matches != nil {
+			report.Queries, report.Decisions = matches.Queries, matches.Decisions
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
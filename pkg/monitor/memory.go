@@ -0,0 +1,45 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// MemoryUnit is the unit memory sizes and per-memory costs are published in. Providers size
+// memory in GiB, though GCP labels it GB, so every provider's memory is normalized to GB
+// internally and converted to the configured unit where it is published.
+type MemoryUnit string
+
+const (
+	memoryUnitGB  MemoryUnit = "GB"
+	memoryUnitGiB MemoryUnit = "GiB"
+)
+
+// gbPerGiB is the size of a GiB (2^30 bytes) in GB (10^9 bytes)
+var gbPerGiB = decimal.RequireFromString("1.073741824")
+
+// ParseMemoryUnit parses a memory unit case-insensitively
+func ParseMemoryUnit(s string) (MemoryUnit, error) {
+	switch {
+	case strings.EqualFold(s, string(memoryUnitGB)):
+		return memoryUnitGB, nil
+	case strings.EqualFold(s, string(memoryUnitGiB)):
+		return memoryUnitGiB, nil
+	}
+	return "", fmt.Errorf("unknown memory unit %q (expected %s or %s)", s, memoryUnitGB, memoryUnitGiB)
+}
+
+// FromGB converts a memory size in GB to the unit
+func (u MemoryUnit) FromGB(gb float64) decimal.Decimal {
+	if u == memoryUnitGiB {
+		return decimal.NewFromFloat(gb).Div(gbPerGiB)
+	}
+	return decimal.NewFromFloat(gb)
+}
+
+// gibToGB converts a memory size in GiB to GB
+func gibToGB(gib float64) float64 {
+	return decimal.NewFromFloat(gib).Mul(gbPerGiB).InexactFloat64()
+}
@@ -0,0 +1,1212 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/providers"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shopspring/decimal"
+)
+
+// costPerGBOpts describes the per-memory cost gauge, whose name keeps "gb" whichever unit
+// memory is published in
+func costPerGBOpts(unit MemoryUnit) prometheus.GaugeOpts {
+	return prometheus.GaugeOpts{
+		Name: "cloud_vm_cost_per_gb_hour",
+		Help: fmt.Sprintf("Cost per %s of RAM per hour in USD", unit),
+	}
+}
+
+// vmPriceLabels are the labels of the per-instance price gauges
+var vmPriceLabels = []string{"provider", "region", "instance_type", "confidential"}
+
+// vmCostLabels are the labels of the current price gauges, which tell overridden prices apart
+// and carry the GPUs of the instance type
+var vmCostLabels = []string{"provider", "region", "instance_type", "confidential", "overridden", "gpu_type", "gpu_count"}
+
+// commitmentLabels are the labels of the Reserved Instance and Savings Plan rates
+var commitmentLabels = []string{"provider", "region", "instance_type", "term", "payment_option"}
+
+var (
+	totalCostOpts = prometheus.GaugeOpts{
+		Name: "cloud_vm_total_cost_per_hour",
+		Help: "Total cost per hour for the instance type in USD",
+	}
+	costPerVCPUOpts = prometheus.GaugeOpts{
+		Name: "cloud_vm_cost_per_vcpu_hour",
+		Help: "Cost per vCPU per hour in USD",
+	}
+)
+
+type Metrics struct {
+	TotalCostPerHour    *prometheus.GaugeVec
+	PreviousCostPerHour *prometheus.GaugeVec
+	PriceChangePercent  *prometheus.GaugeVec
+	PriceLastChanged    *prometheus.GaugeVec
+	CostPerGBPerHour    *prometheus.GaugeVec
+	CostPerVCPUPerHour  *prometheus.GaugeVec
+	ConfidentialPremium *prometheus.GaugeVec
+	PricingErrors       *prometheus.CounterVec
+	ResolutionFailed    *prometheus.GaugeVec
+	Stale               *prometheus.GaugeVec
+	PriceChangesHeld    *prometheus.CounterVec
+	NewerGeneration     *prometheus.GaugeVec
+	GenerationsLaunched *prometheus.CounterVec
+
+	BlendedCostPerVCPU *prometheus.GaugeVec
+	NodeCost           *prometheus.GaugeVec
+	NamespaceCost      *prometheus.GaugeVec
+	WorkloadCost       *prometheus.GaugeVec
+	BaselineRatio      *prometheus.GaugeVec
+	AboveBaseline      *prometheus.GaugeVec
+	EffectiveCost      *prometheus.GaugeVec
+	OverprovisionRatio *prometheus.GaugeVec
+	NormalizedCost     *prometheus.GaugeVec
+	ScenarioCost       *prometheus.GaugeVec
+	FleetCost          *prometheus.GaugeVec
+	SpotCostPerHour    *prometheus.GaugeVec
+	CapacityBlockCost  *prometheus.GaugeVec
+	RICostPerHour      *prometheus.GaugeVec
+	SavingsPlanCost    *prometheus.GaugeVec
+	SoftwareCost       *prometheus.GaugeVec
+	AccountCost        *prometheus.GaugeVec
+	TemplateCost       *prometheus.GaugeVec
+	GPUCost            *prometheus.GaugeVec
+	InstanceGroupCost  *prometheus.GaugeVec
+	ComparisonCost     *prometheus.GaugeVec
+	ComparisonDelta    *prometheus.GaugeVec
+	ComparisonUnpriced *prometheus.GaugeVec
+	SizeStepCost       *prometheus.GaugeVec
+	TypeAvailable      *prometheus.GaugeVec
+	ServiceAvailable   *prometheus.GaugeVec
+	BillingInfo        *prometheus.GaugeVec
+	BillingAccountInfo *prometheus.GaugeVec
+	VCPUQuota          *prometheus.GaugeVec
+	QuotaCostCeiling   *prometheus.GaugeVec
+	RegressionIssues   *prometheus.CounterVec
+	AlertFiring        *prometheus.GaugeVec
+	AlertIssues        *prometheus.CounterVec
+	HookRuns           *prometheus.CounterVec
+	HookIssues         *prometheus.CounterVec
+	CanaryChanged      *prometheus.GaugeVec
+	CanaryHeld         *prometheus.GaugeVec
+	CanaryIssues       *prometheus.CounterVec
+	CoalescedFetches   *prometheus.CounterVec
+	FeaturePermitted   *prometheus.GaugeVec
+	SinkPushes         *prometheus.CounterVec
+	MQTTMessages       *prometheus.CounterVec
+	APICalls           *prometheus.CounterVec
+	APICost            *prometheus.CounterVec
+	CacheRequests      *prometheus.CounterVec
+	FetchWait          *prometheus.CounterVec
+	FetchRetries       *prometheus.CounterVec
+
+	PriceListVersionTime       *prometheus.GaugeVec
+	PriceListVersionsPublished *prometheus.CounterVec
+	LastUpdateTime             *prometheus.GaugeVec
+
+	registerer prometheus.Registerer
+	rounding   PriceRounding
+	memoryUnit MemoryUnit
+	derived    []derivedGauge
+	// resources are the gauges of each resource kind, by kind name
+	resources map[string]resourceGauge
+}
+
+// metricsFactory creates collectors and registers them with a registerer, keeping the first
+// registration error so the metrics can be created in a single expression
+type metricsFactory struct {
+	registerer prometheus.Registerer
+	registered []prometheus.Collector
+	err        error
+}
+
+func (f *metricsFactory) register(c prometheus.Collector) {
+	if f.registerer == nil || f.err != nil {
+		return
+	}
+	if err := f.registerer.Register(c); err != nil {
+		f.err = err
+		return
+	}
+	f.registered = append(f.registered, c)
+}
+
+// unregister removes every collector the factory registered, so that a failed NewMetrics leaves
+// the registerer as it found it
+func (f *metricsFactory) unregister() {
+	for _, c := range f.registered {
+		f.registerer.Unregister(c)
+	}
+}
+
+func (f *metricsFactory) gauge(opts prometheus.GaugeOpts) prometheus.Gauge {
+	g := prometheus.NewGauge(opts)
+	f.register(g)
+	return g
+}
+
+func (f *metricsFactory) gaugeVec(opts prometheus.GaugeOpts, labels []string) *prometheus.GaugeVec {
+	g := prometheus.NewGaugeVec(opts, labels)
+	f.register(g)
+	return g
+}
+
+func (f *metricsFactory) counterVec(opts prometheus.CounterOpts, labels []string) *prometheus.CounterVec {
+	c := prometheus.NewCounterVec(opts, labels)
+	f.register(c)
+	return c
+}
+
+// NewMetrics creates the metrics, publishing per-memory costs per the memory unit, and registers
+// them with a registerer. A nil registerer leaves them unregistered. Registering metrics that
+// are already registered, such as by a second monitor in the same process, returns an error.
+func NewMetrics(registerer prometheus.Registerer, memoryUnit MemoryUnit) (*Metrics, error) {
+	f := &metricsFactory{registerer: registerer}
+	m := &Metrics{
+		registerer: registerer,
+		memoryUnit: memoryUnit,
+		resources:  newResourceGauges(f),
+
+		TotalCostPerHour: f.gaugeVec(totalCostOpts, vmCostLabels),
+		PreviousCostPerHour: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_previous_cost_per_hour",
+				Help: "Total cost per hour in USD before the most recent price change",
+			},
+			vmPriceLabels,
+		),
+		PriceChangePercent: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_price_change_percent",
+				Help: "Percentage change of the total cost per hour at the most recent price change",
+			},
+			vmPriceLabels,
+		),
+		PriceLastChanged: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_price_last_changed_timestamp_seconds",
+				Help: "Unix timestamp of the most recent price change",
+			},
+			vmPriceLabels,
+		),
+		CostPerGBPerHour:   f.gaugeVec(costPerGBOpts(memoryUnit), vmCostLabels),
+		CostPerVCPUPerHour: f.gaugeVec(costPerVCPUOpts, vmCostLabels),
+		ConfidentialPremium: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_confidential_premium_per_hour",
+				Help: "Additional cost per hour of the confidential computing variant over the standard instance in USD",
+			},
+			[]string{"provider", "region", "instance_type"},
+		),
+		PricingErrors: f.counterVec(
+			prometheus.CounterOpts{
+				Name: "cloud_vm_pricing_errors_total",
+				Help: "Total number of errors encountered while fetching pricing, by the reason the fetch failed",
+			},
+			[]string{"provider", "region", "reason"},
+		),
+		ResolutionFailed: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_pricing_resolution_failed",
+				Help: "Whether the provider catalog had no matching price for the region and instance type (1) or it resolved (0)",
+			},
+			[]string{"provider", "region", "instance_type"},
+		),
+		Stale: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_pricing_stale",
+				Help: "Whether the published price hasn't been fetched successfully within the staleness window (1) or has (0)",
+			},
+			[]string{"provider", "region", "instance_type", "confidential"},
+		),
+		PriceChangesHeld: f.counterVec(
+			prometheus.CounterOpts{
+				Name: "cloud_vm_price_changes_held_total",
+				Help: "Total number of fetched price changes held back pending confirmation",
+			},
+			[]string{"provider", "region"},
+		),
+		NewerGeneration: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_newer_generation_available",
+				Help: "Set to 1 when the provider catalog offers a newer generation of a monitored instance type",
+			},
+			[]string{"provider", "instance_type", "newer_instance_type"},
+		),
+		GenerationsLaunched: f.counterVec(
+			prometheus.CounterOpts{
+				Name: "cloud_vm_new_generations_detected_total",
+				Help: "Total number of newer instance generations that appeared in a provider catalog while running",
+			},
+			[]string{"provider"},
+		),
+		BlendedCostPerVCPU: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_blended_cost_per_vcpu_hour",
+				Help: "Usage-weighted average cost per vCPU per hour across the fleet's instance types in USD",
+			},
+			[]string{"provider", "region"},
+		),
+		NodeCost: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_node_cost_per_hour",
+				Help: "Cost per hour of a discovered cluster node in USD",
+			},
+			[]string{"scheduler", "node", "provider", "region", "instance_type"},
+		),
+		NamespaceCost: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_namespace_cost_per_hour",
+				Help: "Estimated cost per hour of the resources requested in a cluster namespace in USD",
+			},
+			[]string{"scheduler", "namespace"},
+		),
+		WorkloadCost: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_workload_cost_per_hour",
+				Help: "Estimated cost per hour of the resources requested by a cluster workload in USD",
+			},
+			[]string{"scheduler", "namespace", "workload_kind", "workload"},
+		),
+		BaselineRatio: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_price_vs_baseline_ratio",
+				Help: "Ratio of the current total cost per hour to the baseline price",
+			},
+			vmPriceLabels,
+		),
+		AboveBaseline: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_price_above_baseline",
+				Help: "Set to 1 when the current price exceeds the baseline price by more than the configured margin",
+			},
+			vmPriceLabels,
+		),
+		EffectiveCost: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_effective_cost_per_hour",
+				Help: "Total cost per hour in USD including the over-provisioning the series' availability assumptions require",
+			},
+			vmPriceLabels,
+		),
+		OverprovisionRatio: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_overprovision_factor",
+				Help: "Capacity that has to be bought per unit of usable capacity under the series' availability assumptions",
+			},
+			vmPriceLabels,
+		),
+		NormalizedCost: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_normalized_cost_score",
+				Help: "Cost per hour in USD of a normalized unit of the instance type, its vCPUs and memory averaged by their weights, to compare similar shapes across providers",
+			},
+			vmPriceLabels,
+		),
+		ScenarioCost: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_scenario_cost_per_hour",
+				Help: "Total cost per hour in USD under a hypothetical price scenario",
+			},
+			append([]string{"scenario"}, vmPriceLabels...),
+		),
+		FleetCost: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_fleet_cost_per_unit_hour",
+				Help: "Cost per hour of one unit of a mixed-instances fleet's capacity in USD, on demand, on spot, or blended by the fleet's purchase option split",
+			},
+			[]string{"fleet", "region", "purchase_option"},
+		),
+		SpotCostPerHour: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_spot_cost_per_hour",
+				Help: "Current spot price per hour for the instance type in USD, by availability zone where spot is priced per zone",
+			},
+			[]string{"provider", "region", "az", "instance_type"},
+		),
+		CapacityBlockCost: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_capacity_block_cost_per_hour",
+				Help: "Lowest effective price per instance hour in USD of an EC2 Capacity Block for ML of the instance type, by availability zone, its upfront fee spread over the block",
+			},
+			[]string{"provider", "region", "az", "instance_type"},
+		),
+		RICostPerHour: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_ri_cost_per_hour",
+				Help: "Effective cost per hour in USD of a standard Reserved Instance of the instance type, upfront payment included, by term and payment option",
+			},
+			commitmentLabels,
+		),
+		SavingsPlanCost: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_savings_plan_cost_per_hour",
+				Help: "Cost per hour in USD of the instance type under a Compute Savings Plan, by term and payment option",
+			},
+			commitmentLabels,
+		),
+		SoftwareCost: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_software_cost_per_hour",
+				Help: "Hourly fee in USD of paid software, such as Windows or a Marketplace product, on top of an instance type's Linux price",
+			},
+			[]string{"provider", "region", "instance_type", "product_code"},
+		),
+		AccountCost: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_account_cost_per_hour",
+				Help: "Cost per hour in USD of the instance type to an AWS account, after the account's negotiated discount off the list price",
+			},
+			[]string{"provider", "account_id", "account_name", "region", "instance_type"},
+		),
+		GPUCost: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_gpu_cost_per_hour",
+				Help: "Cost per hour of one GPU in USD, by component: the GPU itself, the virtual workstation license of workstation GPUs, and their total",
+			},
+			[]string{"provider", "region", "gpu_type", "component"},
+		),
+		TemplateCost: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_instance_template_cost_per_hour",
+				Help: "Cost per hour of an instance created from a GCP instance template in USD, by machine, disk, and GPU component",
+			},
+			[]string{"name", "region", "machine_type", "component"},
+		),
+		InstanceGroupCost: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_instance_group_cost_per_hour",
+				Help: "Cost per hour of a GCP managed instance group at its target size in USD",
+			},
+			[]string{"name", "region"},
+		),
+		ComparisonCost: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_fleet_comparison_cost_per_hour",
+				Help: "Cost per hour of the current or proposed fleet of a comparison in USD, leaving out items without a published price",
+			},
+			[]string{"fleet"},
+		),
+		ComparisonDelta: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_fleet_comparison_delta_per_hour",
+				Help: "Cost per hour of the proposed fleet minus the current fleet in USD, negative when the proposed fleet is cheaper, while both fleets are fully priced",
+			},
+			nil,
+		),
+		ComparisonUnpriced: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_fleet_comparison_unpriced_items",
+				Help: "Number of items of the current or proposed fleet of a comparison without a published price",
+			},
+			[]string{"fleet"},
+		),
+		SizeStepCost: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_size_step_cost_per_hour",
+				Help: "Total cost per hour of the next smaller or larger size in the same family as a monitored instance type in USD",
+			},
+			[]string{"provider", "region", "instance_type", "direction", "step_instance_type"},
+		),
+		TypeAvailable: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_type_available",
+				Help: "Whether a zone offers the instance type (1) or not (0)",
+			},
+			[]string{"provider", "region", "zone", "instance_type"},
+		),
+		ServiceAvailable: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_region_service_available",
+				Help: "Whether the region's catalog offers a monitored instance family, GPU type, or disk type (1) or not (0)",
+			},
+			[]string{"provider", "region", "kind", "service"},
+		),
+		BillingInfo: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_region_billing_info",
+				Help: "Always 1, labeled with the entity that bills a monitored region and the currency it bills in",
+			},
+			[]string{"provider", "region", "billing_entity", "currency"},
+		),
+		BillingAccountInfo: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_pricing_billing_account_info",
+				Help: "Always 1, labeled with the billing account whose contract prices a provider's prices are",
+			},
+			[]string{"provider", "billing_account"},
+		),
+		VCPUQuota: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_vcpu_quota",
+				Help: "vCPU limit of a regional on-demand compute quota",
+			},
+			[]string{"provider", "region", "quota"},
+		),
+		QuotaCostCeiling: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_quota_cost_ceiling_per_hour",
+				Help: "Highest cost per hour in USD a vCPU quota permits, with every vCPU running the monitored instance type with the highest cost per vCPU",
+			},
+			[]string{"provider", "region", "quota", "instance_type"},
+		),
+		RegressionIssues: f.counterVec(
+			prometheus.CounterOpts{
+				Name: "cloud_vm_price_regression_issues_total",
+				Help: "Total number of issues opened, or failed to open, for sustained price increases",
+			},
+			[]string{"provider", "region", "tracker", "result"},
+		),
+		AlertFiring: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_pricing_alert_firing",
+				Help: "Set to 1 while an alert rule is firing for a series, and to 0 once it resolves",
+			},
+			append([]string{"alert"}, vmPriceLabels...),
+		),
+		AlertIssues: f.counterVec(
+			prometheus.CounterOpts{
+				Name: "cloud_vm_pricing_alert_issues_total",
+				Help: "Total number of issues opened, or failed to open, for alerts that started firing",
+			},
+			[]string{"alert", "tracker", "result"},
+		),
+		HookRuns: f.counterVec(
+			prometheus.CounterOpts{
+				Name: "cloud_vm_pricing_hook_runs_total",
+				Help: "Total number of runs of the hook script after a poll, by whether it succeeded",
+			},
+			[]string{"result"},
+		),
+		HookIssues: f.counterVec(
+			prometheus.CounterOpts{
+				Name: "cloud_vm_pricing_hook_issues_total",
+				Help: "Total number of issues opened, or failed to open, for notifications of the hook script",
+			},
+			[]string{"tracker", "result"},
+		),
+		CanaryChanged: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_pricing_canary_changed_percent",
+				Help: "Percent of the published series of a provider that changed or failed to resolve in the last poll under the canary check",
+			},
+			[]string{"provider"},
+		),
+		CanaryHeld: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_pricing_canary_held",
+				Help: "Set to 1 while the canary check holds back the prices of a provider, and to 0 once they pass it",
+			},
+			[]string{"provider"},
+		),
+		CanaryIssues: f.counterVec(
+			prometheus.CounterOpts{
+				Name: "cloud_vm_pricing_canary_issues_total",
+				Help: "Total number of issues opened, or failed to open, for providers whose prices the canary check held back",
+			},
+			[]string{"provider", "tracker", "result"},
+		),
+		CoalescedFetches: f.counterVec(
+			prometheus.CounterOpts{
+				Name: "cloud_vm_pricing_fetches_coalesced_total",
+				Help: "Total number of price fetches that shared an upstream call with a concurrent fetch of the same series",
+			},
+			[]string{"provider"},
+		),
+		FeaturePermitted: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_pricing_feature_permitted",
+				Help: "Set to 1 when the startup permission check found every permission of an enabled feature, and to 0 when one is denied",
+			},
+			[]string{"provider", "feature"},
+		),
+		SinkPushes: f.counterVec(
+			prometheus.CounterOpts{
+				Name: "cloud_vm_pricing_sink_pushes_total",
+				Help: "Total number of pushes of the published prices to each push sink, by result",
+			},
+			[]string{"sink", "result"},
+		),
+		MQTTMessages: f.counterVec(
+			prometheus.CounterOpts{
+				Name: "cloud_vm_pricing_mqtt_messages_total",
+				Help: "Total number of price updates published to the MQTT broker, by result",
+			},
+			[]string{"provider", "result"},
+		),
+		APICalls: f.counterVec(
+			prometheus.CounterOpts{
+				Name: "cloud_vm_pricing_api_calls_total",
+				Help: "Total number of requests the monitor sent to each cloud API, retries included",
+			},
+			[]string{"provider", "service"},
+		),
+		APICost: f.counterVec(
+			prometheus.CounterOpts{
+				Name: "cloud_vm_pricing_api_cost_total",
+				Help: "Estimated total cost in USD of the requests the monitor sent to each cloud API",
+			},
+			[]string{"provider", "service"},
+		),
+		CacheRequests: f.counterVec(
+			prometheus.CounterOpts{
+				Name: "cloud_vm_pricing_shared_cache_requests_total",
+				Help: "Total number of lookups in the shared cache, by kind of value and result",
+			},
+			[]string{"kind", "result"},
+		),
+		FetchWait: f.counterVec(
+			prometheus.CounterOpts{
+				Name: "cloud_vm_pricing_fetch_wait_seconds_total",
+				Help: "Total time in seconds price fetches waited for a provider's concurrency or rate limit",
+			},
+			[]string{"provider", "limit"},
+		),
+		FetchRetries: f.counterVec(
+			prometheus.CounterOpts{
+				Name: "cloud_vm_pricing_retries_total",
+				Help: "Total number of price fetches retried after failing for a reason that may not last",
+			},
+			[]string{"provider", "reason"},
+		),
+		PriceListVersionTime: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_price_list_version_timestamp_seconds",
+				Help: "Unix timestamp at which the current provider price list version was published",
+			},
+			[]string{"provider", "region"},
+		),
+		PriceListVersionsPublished: f.counterVec(
+			prometheus.CounterOpts{
+				Name: "cloud_vm_price_list_versions_published_total",
+				Help: "Total number of new provider price list versions observed while running",
+			},
+			[]string{"provider", "region"},
+		),
+		LastUpdateTime: f.gaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloud_vm_pricing_last_update_timestamp_seconds",
+				Help: "Unix timestamp of the last successful pricing update",
+			},
+			[]string{"provider", "region"},
+		),
+	}
+	if f.err != nil {
+		f.unregister()
+		return nil, fmt.Errorf("failed to register metrics: %w", f.err)
+	}
+	return m, nil
+}
+
+// VMPricing is the price of an instance type as a provider returns it, with the costs the
+// metrics are derived from
+type VMPricing providers.Pricing
+
+// CostPerMemory returns the cost per unit of memory per hour, if the memory is known
+func (p VMPricing) CostPerMemory(unit MemoryUnit) (decimal.Decimal, bool) {
+	if p.MemoryGB <= 0 {
+		return decimal.Zero, false
+	}
+	return p.TotalCost.Div(unit.FromGB(p.MemoryGB)), true
+}
+
+// CostPerVCPU returns the cost per vCPU per hour, if the vCPU count is known
+func (p VMPricing) CostPerVCPU() (decimal.Decimal, bool) {
+	if p.VCPUs <= 0 {
+		return decimal.Zero, false
+	}
+	return p.TotalCost.Div(decimal.NewFromInt(int64(p.VCPUs))), true
+}
+
+func (m *Metrics) RecordPricing(p VMPricing) {
+	labels := prometheus.Labels{
+		"provider":      p.Provider,
+		"region":        p.Region,
+		"instance_type": p.InstanceType,
+		"confidential":  strconv.FormatBool(p.Confidential),
+		"overridden":    strconv.FormatBool(!p.Overridden),
+		"gpu_type":      p.GPUType,
+		"gpu_count":     strconv.Itoa(p.GPUCount),
+	}
+
+	// The series of the other overridden value goes, so a price that starts or stops being
+	// overridden isn't exported twice
+	for _, vec := range []*prometheus.GaugeVec{m.TotalCostPerHour, m.CostPerGBPerHour, m.CostPerVCPUPerHour} {
+		vec.Delete(labels)
+	}
+	labels["overridden"] = strconv.FormatBool(p.Overridden)
+
+	m.TotalCostPerHour.With(labels).Set(m.rounding.Float(p.TotalCost))
+
+	if cost, ok := p.CostPerMemory(m.memoryUnit); ok {
+		m.CostPerGBPerHour.With(labels).Set(m.rounding.Float(cost))
+	}
+
+	if cost, ok := p.CostPerVCPU(); ok {
+		m.CostPerVCPUPerHour.With(labels).Set(m.rounding.Float(cost))
+	}
+}
+
+// DeletePricing removes every series of an instance type in a region, such as when it is no
+// longer monitored
+func (m *Metrics) DeletePricing(provider, region, instanceType string) {
+	m.deleteSeries(prometheus.Labels{"provider": provider, "region": region, "instance_type": instanceType})
+}
+
+// DeletePrice removes the series of a published price, leaving those of the instance type's
+// other variant and those that don't tell the variants apart, such as resolution failures
+func (m *Metrics) DeletePrice(key PriceKey) {
+	m.deleteSeries(prometheus.Labels{
+		"provider":      key.Provider,
+		"region":        key.Region,
+		"instance_type": key.InstanceType,
+		"confidential":  strconv.FormatBool(key.Confidential),
+	})
+}
+
+// deleteSeries removes the series of every per-instance-type gauge having all the labels
+func (m *Metrics) deleteSeries(labels prometheus.Labels) {
+	for _, vec := range []*prometheus.GaugeVec{
+		m.TotalCostPerHour,
+		m.PreviousCostPerHour,
+		m.PriceChangePercent,
+		m.PriceLastChanged,
+		m.CostPerGBPerHour,
+		m.CostPerVCPUPerHour,
+		m.ConfidentialPremium,
+		m.ResolutionFailed,
+		m.Stale,
+		m.BaselineRatio,
+		m.AboveBaseline,
+		m.EffectiveCost,
+		m.OverprovisionRatio,
+		m.NormalizedCost,
+		m.ScenarioCost,
+		m.SpotCostPerHour,
+		m.CapacityBlockCost,
+		m.RICostPerHour,
+		m.SavingsPlanCost,
+		m.SoftwareCost,
+		m.AccountCost,
+		m.SizeStepCost,
+		m.TypeAvailable,
+		m.QuotaCostCeiling,
+		m.AlertFiring,
+	} {
+		vec.DeletePartialMatch(labels)
+	}
+	for _, d := range m.derived {
+		d.gauge.DeletePartialMatch(labels)
+	}
+}
+
+// RecordPriceChange records the most recent change of a series' price: the price before it,
+// by how much it changed, and when
+func (m *Metrics) RecordPriceChange(entry PriceEntry) {
+	p := entry.Pricing
+	labels := prometheus.Labels{
+		"provider":      p.Provider,
+		"region":        p.Region,
+		"instance_type": p.InstanceType,
+		"confidential":  strconv.FormatBool(p.Confidential),
+	}
+
+	m.PreviousCostPerHour.With(labels).Set(m.rounding.Float(entry.PreviousCost))
+	if !entry.PreviousCost.IsZero() {
+		m.PriceChangePercent.With(labels).Set(priceChangePercent(entry.PreviousCost, p.TotalCost))
+	}
+	m.PriceLastChanged.With(labels).Set(float64(entry.ChangedAt.Unix()))
+}
+
+// priceChangePercent returns the change from a previous cost to the current one as a
+// percentage of the previous cost
+func priceChangePercent(previous, current decimal.Decimal) float64 {
+	return current.Sub(previous).Div(previous).Mul(decimal.NewFromInt(100)).InexactFloat64()
+}
+
+// RecordBlendedCostPerVCPU records the usage-weighted cost per vCPU of a region
+func (m *Metrics) RecordBlendedCostPerVCPU(provider, region string, cost decimal.Decimal) {
+	m.BlendedCostPerVCPU.With(prometheus.Labels{
+		"provider": provider,
+		"region":   region,
+	}).Set(m.rounding.Float(cost))
+}
+
+// RecordFleetCost records the per-unit cost of a fleet. The spot cost is dropped for fleets
+// that run entirely on demand.
+func (m *Metrics) RecordFleetCost(fleet FleetConfig, cost FleetCost) {
+	labels := func(option string) prometheus.Labels {
+		return prometheus.Labels{"fleet": fleet.Name, "region": fleet.Region, "purchase_option": option}
+	}
+
+	m.FleetCost.With(labels("on_demand")).Set(m.rounding.Float(cost.OnDemand))
+	m.FleetCost.With(labels("blended")).Set(m.rounding.Float(cost.Blended))
+	if cost.Spot.Valid {
+		m.FleetCost.With(labels("spot")).Set(m.rounding.Float(cost.Spot.Decimal))
+	} else {
+		m.FleetCost.Delete(labels("spot"))
+	}
+}
+
+// RecordSpotPrices replaces the spot prices of an instance type in a region, dropping zones
+// that no longer offer it
+func (m *Metrics) RecordSpotPrices(provider, region, instanceType string, prices map[string]decimal.Decimal) {
+	m.SpotCostPerHour.DeletePartialMatch(prometheus.Labels{"provider": provider, "region": region, "instance_type": instanceType})
+
+	for zone, price := range prices {
+		m.SpotCostPerHour.With(prometheus.Labels{
+			"provider":      provider,
+			"region":        region,
+			"az":            zone,
+			"instance_type": instanceType,
+		}).Set(m.rounding.Float(price))
+	}
+}
+
+// RecordCapacityBlockPrices replaces the Capacity Block prices of an instance type in a region,
+// dropping zones without offerings
+func (m *Metrics) RecordCapacityBlockPrices(provider, region, instanceType string, prices map[string]decimal.Decimal) {
+	m.CapacityBlockCost.DeletePartialMatch(prometheus.Labels{"provider": provider, "region": region, "instance_type": instanceType})
+
+	for zone, price := range prices {
+		m.CapacityBlockCost.With(prometheus.Labels{
+			"provider":      provider,
+			"region":        region,
+			"az":            zone,
+			"instance_type": instanceType,
+		}).Set(m.rounding.Float(price))
+	}
+}
+
+// RecordGPUCosts records the cost of the GPU types priced in a region. The license component
+// is only exported for workstation GPUs.
+func (m *Metrics) RecordGPUCosts(provider, region string, costs map[string]GPUCost) {
+	for gpuType, cost := range costs {
+		labels := func(component string) prometheus.Labels {
+			return prometheus.Labels{"provider": provider, "region": region, "gpu_type": gpuType, "component": component}
+		}
+
+		m.GPUCost.With(labels("gpu")).Set(m.rounding.Float(cost.GPU))
+		if strings.HasSuffix(gpuType, gcpWorkstationSuffix) {
+			m.GPUCost.With(labels("license")).Set(m.rounding.Float(cost.License))
+		}
+		m.GPUCost.With(labels("total")).Set(m.rounding.Float(cost.Total()))
+	}
+}
+
+// RecordReservedRates replaces the Reserved Instance rates of an instance type
+func (m *Metrics) RecordReservedRates(p VMPricing, rates []CommitmentRate) {
+	m.recordCommitmentRates(m.RICostPerHour, p, rates)
+}
+
+// RecordSavingsPlanRates replaces the Savings Plan rates of an instance type
+func (m *Metrics) RecordSavingsPlanRates(p VMPricing, rates []CommitmentRate) {
+	m.recordCommitmentRates(m.SavingsPlanCost, p, rates)
+}
+
+func (m *Metrics) recordCommitmentRates(vec *prometheus.GaugeVec, p VMPricing, rates []CommitmentRate) {
+	vec.DeletePartialMatch(prometheus.Labels{"provider": p.Provider, "region": p.Region, "instance_type": p.InstanceType})
+
+	for _, rate := range rates {
+		vec.With(prometheus.Labels{
+			"provider":       p.Provider,
+			"region":         p.Region,
+			"instance_type":  p.InstanceType,
+			"term":           rate.Term,
+			"payment_option": rate.PaymentOption,
+		}).Set(m.rounding.Float(rate.Cost))
+	}
+}
+
+// RecordSoftwareFee records the hourly fee of a software product on an instance type
+func (m *Metrics) RecordSoftwareFee(p VMPricing, productCode string, fee decimal.Decimal) {
+	m.SoftwareCost.With(softwareLabels(p, productCode)).Set(m.rounding.Float(fee))
+}
+
+// DeleteSoftwareFee drops the fee of a software product on an instance type that can no
+// longer be priced
+func (m *Metrics) DeleteSoftwareFee(p VMPricing, productCode string) {
+	m.SoftwareCost.Delete(softwareLabels(p, productCode))
+}
+
+func softwareLabels(p VMPricing, productCode string) prometheus.Labels {
+	return prometheus.Labels{
+		"provider":      p.Provider,
+		"region":        p.Region,
+		"instance_type": p.InstanceType,
+		"product_code":  productCode,
+	}
+}
+
+// RecordAccountCost records what an instance type costs an AWS account
+func (m *Metrics) RecordAccountCost(p VMPricing, account AWSAccount, cost decimal.Decimal) {
+	m.AccountCost.With(accountLabels(p, account)).Set(m.rounding.Float(cost))
+}
+
+func accountLabels(p VMPricing, account AWSAccount) prometheus.Labels {
+	return prometheus.Labels{
+		"provider":      p.Provider,
+		"account_id":    account.ID,
+		"account_name":  account.Name,
+		"region":        p.Region,
+		"instance_type": p.InstanceType,
+	}
+}
+
+// RecordEffectiveCost records the over-provisioning factor of a series and its cost including it
+func (m *Metrics) RecordEffectiveCost(p VMPricing, factor, cost decimal.Decimal) {
+	labels := prometheus.Labels{
+		"provider":      p.Provider,
+		"region":        p.Region,
+		"instance_type": p.InstanceType,
+		"confidential":  strconv.FormatBool(p.Confidential),
+	}
+
+	m.OverprovisionRatio.With(labels).Set(factor.InexactFloat64())
+	m.EffectiveCost.With(labels).Set(m.rounding.Float(cost))
+}
+
+// RecordNormalizedCost records the cost of a normalized unit of a series
+func (m *Metrics) RecordNormalizedCost(p VMPricing, cost decimal.Decimal) {
+	m.NormalizedCost.With(prometheus.Labels{
+		"provider":      p.Provider,
+		"region":        p.Region,
+		"instance_type": p.InstanceType,
+		"confidential":  strconv.FormatBool(p.Confidential),
+	}).Set(m.rounding.Float(cost))
+}
+
+// RecordScenarioCosts records the price of a series under each scenario, as of a time
+func (m *Metrics) RecordScenarioCosts(p VMPricing, scenarios PriceScenarios, at time.Time) {
+	for _, s := range scenarios {
+		m.ScenarioCost.With(prometheus.Labels{
+			"scenario":      s.Name,
+			"provider":      p.Provider,
+			"region":        p.Region,
+			"instance_type": p.InstanceType,
+			"confidential":  strconv.FormatBool(p.Confidential),
+		}).Set(m.rounding.Float(s.Cost(p, at)))
+	}
+}
+
+// RecordComparison records the cost of both fleets of a comparison and the difference between
+// them. The difference of partial costs is meaningless, so it's deleted while either fleet has
+// unpriced items.
+func (m *Metrics) RecordComparison(current, proposed ComparisonCost) {
+	for fleet, cost := range map[string]ComparisonCost{"current": current, "proposed": proposed} {
+		m.ComparisonCost.WithLabelValues(fleet).Set(m.rounding.Float(cost.Hourly))
+		m.ComparisonUnpriced.WithLabelValues(fleet).Set(float64(cost.Unpriced))
+	}
+	if current.Unpriced > 0 || proposed.Unpriced > 0 {
+		m.ComparisonDelta.Reset()
+		return
+	}
+	m.ComparisonDelta.WithLabelValues().Set(m.rounding.Float(proposed.Hourly.Sub(current.Hourly)))
+}
+
+// RecordTemplateCost records the cost of an instance template by component, and of its
+// managed instance group when it was resolved through one
+func (m *Metrics) RecordTemplateCost(t *GCPTemplate, cost *GCPTemplateCost) {
+	m.TemplateCost.DeletePartialMatch(prometheus.Labels{"name": t.Name})
+
+	components := map[string]decimal.Decimal{
+		"machine":     cost.Machine,
+		"disk":        cost.Disks,
+		"gpu":         cost.GPUs,
+		"gpu_license": cost.GPULicenses,
+		"total":       cost.Total(),
+	}
+	for component, value := range components {
+		m.TemplateCost.With(prometheus.Labels{
+			"name":         t.Name,
+			"region":       t.Region,
+			"machine_type": t.MachineType,
+			"component":    component,
+		}).Set(m.rounding.Float(value))
+	}
+
+	if t.Instances > 0 {
+		m.InstanceGroupCost.With(prometheus.Labels{
+			"name":   t.Name,
+			"region": t.Region,
+		}).Set(m.rounding.Float(cost.Total().Mul(decimal.NewFromInt(int64(t.Instances)))))
+	}
+}
+
+// RecordSizeSteps replaces the exported prices of every size step
+func (m *Metrics) RecordSizeSteps(steps []sizeStep) {
+	m.SizeStepCost.Reset()
+	for _, step := range steps {
+		m.SizeStepCost.With(prometheus.Labels{
+			"provider":           step.provider,
+			"region":             step.region,
+			"instance_type":      step.instanceType,
+			"direction":          step.direction,
+			"step_instance_type": step.stepType,
+		}).Set(m.rounding.Float(step.cost))
+	}
+}
+
+// RecordAvailability replaces the availability of a region's zones, dropping zones and
+// instance types that are no longer checked
+func (m *Metrics) RecordAvailability(provider, region string, instanceTypes []string, offerings ZoneOfferings) {
+	m.TypeAvailable.DeletePartialMatch(prometheus.Labels{"provider": provider, "region": region})
+
+	for zone, offered := range offerings {
+		for _, instanceType := range instanceTypes {
+			value := 0.0
+			if offered[instanceType] {
+				value = 1
+			}
+			m.TypeAvailable.With(prometheus.Labels{
+				"provider":      provider,
+				"region":        region,
+				"zone":          zone,
+				"instance_type": instanceType,
+			}).Set(value)
+		}
+	}
+}
+
+// RecordServiceAvailability replaces whether a region offers each service of a kind, dropping
+// services that are no longer looked up
+func (m *Metrics) RecordServiceAvailability(provider, region, kind string, offered map[string]bool) {
+	m.ServiceAvailable.DeletePartialMatch(prometheus.Labels{"provider": provider, "region": region, "kind": kind})
+
+	for service, available := range offered {
+		value := 0.0
+		if available {
+			value = 1
+		}
+		m.ServiceAvailable.With(prometheus.Labels{
+			"provider": provider,
+			"region":   region,
+			"kind":     kind,
+			"service":  service,
+		}).Set(value)
+	}
+}
+
+// RecordBillingEntities replaces the billing entity and currency of every region, dropping
+// regions that are no longer monitored
+func (m *Metrics) RecordBillingEntities(entities map[regionKey]BillingEntityRule) {
+	m.BillingInfo.Reset()
+	for key, rule := range entities {
+		m.BillingInfo.With(prometheus.Labels{
+			"provider":       key.Provider,
+			"region":         key.Region,
+			"billing_entity": rule.Entity,
+			"currency":       rule.Currency,
+		}).Set(1)
+	}
+}
+
+// RecordQuotaCeilings replaces the quotas and cost ceilings of a region
+func (m *Metrics) RecordQuotaCeilings(provider, region string, ceilings []QuotaCeiling) {
+	labels := prometheus.Labels{"provider": provider, "region": region}
+	m.VCPUQuota.DeletePartialMatch(labels)
+	m.QuotaCostCeiling.DeletePartialMatch(labels)
+
+	for _, c := range ceilings {
+		m.VCPUQuota.With(prometheus.Labels{
+			"provider": provider,
+			"region":   region,
+			"quota":    c.Quota,
+		}).Set(c.VCPUs)
+		m.QuotaCostCeiling.With(prometheus.Labels{
+			"provider":      provider,
+			"region":        region,
+			"quota":         c.Quota,
+			"instance_type": c.InstanceType,
+		}).Set(m.rounding.Float(c.CostPerHour))
+	}
+}
+
+// RecordClusterCosts replaces the exported costs of a scheduler's cluster, dropping nodes and
+// workloads that have gone away
+func (m *Metrics) RecordClusterCosts(scheduler string, state *ClusterState, costs *ClusterCosts) {
+	m.NodeCost.DeletePartialMatch(prometheus.Labels{"scheduler": scheduler})
+	m.NamespaceCost.DeletePartialMatch(prometheus.Labels{"scheduler": scheduler})
+	m.WorkloadCost.DeletePartialMatch(prometheus.Labels{"scheduler": scheduler})
+
+	for _, node := range state.Nodes {
+		cost, ok := costs.Nodes[node.Name]
+		if !ok {
+			continue
+		}
+		m.NodeCost.With(prometheus.Labels{
+			"scheduler":     scheduler,
+			"node":          node.Name,
+			"provider":      node.Provider,
+			"region":        node.Region,
+			"instance_type": node.InstanceType,
+		}).Set(m.rounding.Float(cost))
+	}
+
+	for namespace, cost := range costs.Namespaces {
+		m.NamespaceCost.With(prometheus.Labels{
+			"scheduler": scheduler,
+			"namespace": namespace,
+		}).Set(m.rounding.Float(cost))
+	}
+
+	for workload, cost := range costs.Workloads {
+		m.WorkloadCost.With(prometheus.Labels{
+			"scheduler":     scheduler,
+			"namespace":     workload.Namespace,
+			"workload_kind": workload.Kind,
+			"workload":      workload.Name,
+		}).Set(m.rounding.Float(cost))
+	}
+}
+
+// RecordBaselineComparison records how a price compares to its baseline
+func (m *Metrics) RecordBaselineComparison(p VMPricing, ratio float64, exceeded bool) {
+	labels := prometheus.Labels{
+		"provider":      p.Provider,
+		"region":        p.Region,
+		"instance_type": p.InstanceType,
+		"confidential":  strconv.FormatBool(p.Confidential),
+	}
+
+	m.BaselineRatio.With(labels).Set(ratio)
+
+	above := 0.0
+	if exceeded {
+		above = 1
+	}
+	m.AboveBaseline.With(labels).Set(above)
+}
+
+// RecordConfidentialPremium records the cost delta between a confidential variant and its standard instance
+func (m *Metrics) RecordConfidentialPremium(standard, confidential VMPricing) {
+	m.ConfidentialPremium.With(prometheus.Labels{
+		"provider":      standard.Provider,
+		"region":        standard.Region,
+		"instance_type": standard.InstanceType,
+	}).Set(m.rounding.Float(confidential.TotalCost.Sub(standard.TotalCost)))
+}
+
+// RecordPricingError counts a failed fetch of a price by the reason it failed
+func (m *Metrics) RecordPricingError(provider, region string, err error) {
+	m.PricingErrors.With(prometheus.Labels{
+		"provider": provider,
+		"region":   region,
+		"reason":   errorReason(err),
+	}).Inc()
+}
+
+// RecordAPICall counts a request sent to a cloud API and adds its estimated cost. Free APIs
+// still get a cost series, which stays at zero.
+func (m *Metrics) RecordAPICall(provider, service string) {
+	labels := prometheus.Labels{
+		"provider": provider,
+		"service":  service,
+	}
+	m.APICalls.With(labels).Inc()
+	m.APICost.With(labels).Add(apiCallPrice(provider, service).InexactFloat64())
+}
+
+// RecordCacheRequest counts a lookup in the shared cache, which is a hit, a miss, or an error
+func (m *Metrics) RecordCacheRequest(kind, result string) {
+	m.CacheRequests.With(prometheus.Labels{"kind": kind, "result": result}).Inc()
+}
+
+// RecordFetchWait adds the time a fetch waited for a provider's fetch limit
+func (m *Metrics) RecordFetchWait(provider, limit string, wait time.Duration) {
+	m.FetchWait.With(prometheus.Labels{"provider": provider, "limit": limit}).Add(wait.Seconds())
+}
+
+// RecordRetry counts a retry of a price fetch, by why the attempt before it failed
+func (m *Metrics) RecordRetry(provider, reason string) {
+	m.FetchRetries.With(prometheus.Labels{"provider": provider, "reason": reason}).Inc()
+}
+
+// RecordResolution tracks whether a price could be resolved from the provider catalog.
+// Errors other than a missing price leave the previous state untouched.
+func (m *Metrics) RecordResolution(provider, region, instanceType string, err error) {
+	labels := prometheus.Labels{
+		"provider":      provider,
+		"region":        region,
+		"instance_type": instanceType,
+	}
+
+	switch {
+	case err == nil:
+		m.ResolutionFailed.With(labels).Set(0)
+	case errors.Is(err, errNoPricingFound):
+		m.ResolutionFailed.With(labels).Set(1)
+	}
+}
+
+// RecordStale records whether a published price has gone unrefreshed for longer than the
+// staleness window
+func (m *Metrics) RecordStale(key PriceKey, stale bool) {
+	value := 0.0
+	if stale {
+		value = 1
+	}
+	m.Stale.With(prometheus.Labels{
+		"provider":      key.Provider,
+		"region":        key.Region,
+		"instance_type": key.InstanceType,
+		"confidential":  strconv.FormatBool(key.Confidential),
+	}).Set(value)
+}
+
+// RecordAlerts exports which alert rules are firing for which series after a poll
+func (m *Metrics) RecordAlerts(results AlertResults) {
+	labels := func(a Alert) prometheus.Labels {
+		return prometheus.Labels{
+			"alert":         a.Rule.Name,
+			"provider":      a.Pricing.Provider,
+			"region":        a.Pricing.Region,
+			"instance_type": a.Pricing.InstanceType,
+			"confidential":  strconv.FormatBool(a.Pricing.Confidential),
+		}
+	}
+	for _, alert := range results.Resolved {
+		m.AlertFiring.With(labels(alert)).Set(0)
+	}
+	for _, alert := range results.Firing {
+		m.AlertFiring.With(labels(alert)).Set(1)
+	}
+}
+
+// SetPriceRounding sets the precision every price gauge is exported at
+func (m *Metrics) SetPriceRounding(rounding PriceRounding) {
+	m.rounding = rounding
+}
+
+// replaceCollectors unregisters collectors and registers one that exports their series instead
+func (m *Metrics) replaceCollectors(replacement prometheus.Collector, replaced ...prometheus.Collector) error {
+	if m.registerer == nil {
+		return nil
+	}
+	for _, c := range replaced {
+		m.registerer.Unregister(c)
+	}
+	if err := m.registerer.Register(replacement); err != nil {
+		return fmt.Errorf("failed to register price collector: %w", err)
+	}
+	return nil
+}
+
+// ExportWithTimestamps replaces the price gauges with a collector that stamps each sample
+// with the time its price was fetched
+func (m *Metrics) ExportWithTimestamps(snapshot *PriceSnapshot) error {
+	return m.replaceCollectors(NewTimestampedCollector(snapshot, m.rounding, m.memoryUnit),
+		m.TotalCostPerHour, m.CostPerGBPerHour, m.CostPerVCPUPerHour)
+}
+
+// ExportWithNaming replaces the per-series price gauges with a collector that names them with
+// templates, stamping samples like ExportWithTimestamps when timestamps is set
+func (m *Metrics) ExportWithNaming(snapshot *PriceSnapshot, naming *MetricNaming, timestamps bool) error {
+	return m.replaceCollectors(NewNamedCollector(snapshot, naming, m.rounding, m.memoryUnit, timestamps),
+		m.TotalCostPerHour, m.PreviousCostPerHour, m.CostPerGBPerHour, m.CostPerVCPUPerHour)
+}
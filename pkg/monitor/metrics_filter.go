@@ -0,0 +1,64 @@
+package monitor
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// filteredMetricsHandler serves the pricing series of a single provider, optionally narrowed to
+// one region with ?region=, so scrapers that only care about a subset don't pull everything
+func filteredMetricsHandler(gatherer prometheus.Gatherer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filter := map[string]string{"provider": r.PathValue("provider")}
+		if region := r.URL.Query().Get("region"); region != "" {
+			filter["region"] = region
+		}
+
+		filtered := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			families, err := gatherer.Gather()
+			return filterMetricFamilies(families, filter), err
+		})
+		promhttp.HandlerFor(filtered, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
+
+// filterMetricFamilies keeps the series labeled with the filtered provider. Series without a
+// provider label are dropped, and series without one of the other filter labels are kept since
+// they apply across that dimension.
+func filterMetricFamilies(families []*dto.MetricFamily, filter map[string]string) []*dto.MetricFamily {
+	var kept []*dto.MetricFamily
+	for _, family := range families {
+		var metrics []*dto.Metric
+		for _, metric := range family.Metric {
+			if metricMatches(metric, filter) {
+				metrics = append(metrics, metric)
+			}
+		}
+
+		if len(metrics) > 0 {
+			family.Metric = metrics
+			kept = append(kept, family)
+		}
+	}
+	return kept
+}
+
+func metricMatches(metric *dto.Metric, filter map[string]string) bool {
+	hasProvider := false
+	for _, label := range metric.Label {
+		want, ok := filter[label.GetName()]
+		if !ok {
+			continue
+		}
+		if label.GetValue() != want {
+			return false
+		}
+		if label.GetName() == "provider" {
+			hasProvider = true
+		}
+	}
+	return hasProvider
+}
@@ -0,0 +1,77 @@
+package monitor
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	cli "github.com/urfave/cli/v2"
+	"google.golang.org/protobuf/proto"
+)
+
+// MetricHelp replaces the help text of a metric, or adds a link to documentation of the
+// organization's own, such as a page on the pricing assumptions its series are built on
+type MetricHelp struct {
+	Help    string `yaml:"help"`
+	DocsURL string `yaml:"docs_url"`
+}
+
+// checkMetricHelp rejects overrides of invalid metric names and links that aren't absolute
+// http or https URLs
+func checkMetricHelp(overrides map[string]MetricHelp) error {
+	for name, o := range overrides {
+		if !metricNamePattern.MatchString(name) {
+			return fmt.Errorf("metric help of %q: invalid metric name", name)
+		}
+		if o.Help == "" && o.DocsURL == "" {
+			return fmt.Errorf("metric help of %s must set help, docs_url, or both", name)
+		}
+		if o.DocsURL == "" {
+			continue
+		}
+		u, err := url.Parse(o.DocsURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("metric help of %s has an invalid docs_url %q: must be an http or https URL", name, o.DocsURL)
+		}
+	}
+	return nil
+}
+
+// apply returns the help text of a metric with the override
+func (o MetricHelp) apply(help string) string {
+	if o.Help != "" {
+		help = o.Help
+	}
+	if o.DocsURL != "" {
+		help = strings.TrimSuffix(help, ".") + ". See " + o.DocsURL
+	}
+	return help
+}
+
+// metricHelpKey is the app metadata key of the metric help overrides of the --config file
+const metricHelpKey = "metric-help"
+
+// configMetricHelp returns the metric help overrides ApplyConfigFile read from the --config file
+func configMetricHelp(cctx *cli.Context) map[string]MetricHelp {
+	overrides, _ := cctx.App.Metadata[metricHelpKey].(map[string]MetricHelp)
+	return overrides
+}
+
+// helpGatherer replaces the help text of the metric families a gatherer gathers with their
+// overrides
+type helpGatherer struct {
+	prometheus.Gatherer
+	overrides map[string]MetricHelp
+}
+
+func (g helpGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.Gatherer.Gather()
+	for _, family := range families {
+		if o, ok := g.overrides[family.GetName()]; ok {
+			family.Help = proto.String(o.apply(family.GetHelp()))
+		}
+	}
+	return families, err
+}
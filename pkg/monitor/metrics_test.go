@@ -0,0 +1,38 @@
+package monitor
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestVMPricingUnitCosts(t *testing.T) {
+	tests := []struct {
+		totalCost     string
+		vcpus         int
+		memoryGB      float64
+		unit          MemoryUnit
+		costPerVCPU   string
+		costPerMemory string
+	}{
+		{"0.192", 4, 16, memoryUnitGB, "0.048", "0.012"},
+		{"0.3", 3, 3, memoryUnitGB, "0.1", "0.1"},
+		// 16 GiB is 17.179869184 GB
+		{"0.194236", 4, 17.179869184, memoryUnitGiB, "0.048559", "0.01213975"},
+		{"0.1", 0, 0, memoryUnitGB, "", ""},
+	}
+
+	for _, tt := range tests {
+		p := VMPricing{TotalCost: decimal.RequireFromString(tt.totalCost), VCPUs: tt.vcpus, MemoryGB: tt.memoryGB}
+
+		cost, ok := p.CostPerVCPU()
+		if ok != (tt.costPerVCPU != "") || ok && !cost.Equal(decimal.RequireFromString(tt.costPerVCPU)) {
+			t.Errorf("CostPerVCPU of %s for %d vCPUs = (%s, %t), want %q", tt.totalCost, tt.vcpus, cost, ok, tt.costPerVCPU)
+		}
+
+		cost, ok = p.CostPerMemory(tt.unit)
+		if ok != (tt.costPerMemory != "") || ok && !cost.Equal(decimal.RequireFromString(tt.costPerMemory)) {
+			t.Errorf("CostPerMemory of %s for %v GB in %s = (%s, %t), want %q", tt.totalCost, tt.memoryGB, tt.unit, cost, ok, tt.costPerMemory)
+		}
+	}
+}
@@ -0,0 +1,1090 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/providers"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shopspring/decimal"
+	"golang.org/x/sync/singleflight"
+)
+
+type Monitor struct {
+	gcpGPUTypes      []string
+	awsVolumeTypes   []string
+	gcpDiskTypes     []string
+	fileStorage      bool
+	backupPricing    bool
+	platformServices bool
+	awsSpotPricing   bool
+	gcpSpotPricing   bool
+	awsCommitments   bool
+	autoAddNewGens   bool
+	sizeSteps        bool
+	availability     bool
+	quotaCeilings    bool
+	gcpProject       string
+	pollInterval     time.Duration
+	schedule         *AdaptiveSchedule
+	metrics          *Metrics
+	snapshot         *PriceSnapshot
+	consensus        *PriceConsensus
+	history          HistoryStore
+	baseline         *Baseline
+	comparison       *FleetComparison
+	slaAssumptions   SLAAssumptions
+	billingEntities  BillingEntities
+	weights          UsageWeights
+	regressions      *RegressionTracker
+	issueTrackers    []IssueTracker
+	alerts           *AlertEvaluator
+	discoverers      []ClusterDiscoverer
+	clusters         map[string]*ClusterState
+	fleets           []FleetConfig
+	software         []SoftwareProduct
+	gcpTemplates     []GCPTemplateConfig
+	generations      *GenerationTracker
+	bundle           *PriceBundle
+	rounding         PriceRounding
+	naming           *MetricNaming
+	legacyMetrics    []metricMigration
+	sinks            []Sink
+	mqtt             *MQTTPublisher
+	pushInterval     time.Duration
+
+	awsPriceListPin        *PriceListPin
+	priceListCheckInterval time.Duration
+	refresh                chan struct{}
+
+	// awsCapacityBlockDuration is the length of the Capacity Blocks priced, and zero when they
+	// aren't
+	awsCapacityBlockDuration time.Duration
+
+	// awsAccounts are priced at their discounts off every published AWS price
+	awsAccounts []AWSAccount
+
+	// gcpBillingAccount is the Cloud Billing account whose contract prices GCP is priced at
+	gcpBillingAccount string
+
+	// priorityTypes are fetched before the other instance types on the first fetch, which
+	// warmedUp records has completed
+	priorityTypes []string
+	warmedUp      bool
+
+	// window restricts polling to the times it is open, when set
+	window *PollWindow
+
+	// watching is what is priced with each registered provider, by provider name, including
+	// what discovery, fleets, and templates added. confidential lists the providers whose
+	// confidential variants are priced too.
+	watching     map[string]*watchedTypes
+	confidential map[string]bool
+
+	// watched is the configured watch list a reload replaces, sent to the poll loop on reload
+	watched watchList
+	reload  chan watchList
+
+	// instanceTypeGlobs are the patterns of each provider's instance types, which every poll
+	// expands into the types of its catalog they match, recorded in expandedTypes
+	instanceTypeGlobs map[string][]string
+	expandedTypes     map[string][]string
+
+	// allRegions are the providers monitored in every region they offer, which every poll lists
+	// into listedRegions, keeping those of the shard when sharded
+	allRegions    map[string]bool
+	listedRegions map[string][]string
+	shards        *ShardConfig
+
+	// statuses records the outcome of the latest fetch of every series, when set
+	statuses *FetchStatuses
+
+	// fetcherInitTimeout bounds how long each provider's fetcher may take to be created
+	fetcherInitTimeout time.Duration
+
+	// registry creates the providers VM prices are fetched from
+	registry *providers.Registry
+
+	// fetchersMu guards the configured providers and the fetchers of the features only AWS or
+	// GCP has, which are created lazily and read by API requests
+	fetchersMu sync.RWMutex
+	providers  map[string]providers.PricingProvider
+	awsFetcher *AWSPricingFetcher
+	gcpFetcher *GCPPricingFetcher
+	fetches    singleflight.Group
+
+	// cache shares fetched prices with other replicas, when set
+	cache *sharedCache
+	// limits bound the price fetches sent to each provider
+	limits FetchLimits
+	// retries retries the price fetches that failed for a reason that may not last
+	retries retryPolicy
+
+	// syncFrom is the base URL of the peer whose prices are published on startup, when set
+	syncFrom string
+
+	// stale is when a price that can no longer be fetched stops being published as current
+	stale stalePolicy
+
+	// overrides replace the fetched prices of the series they match
+	overrides PriceOverrides
+	// scenarios are the hypothetical price changes prices are also exported under
+	scenarios PriceScenarios
+	// hook is the script run after every poll, when set
+	hook *HookScript
+	// normalization prices a normalized unit of every series, to compare shapes across providers
+	normalization CostNormalization
+	// canary holds back the prices of a poll that change too many series at once, when set
+	canary *CanaryCheck
+}
+
+func (m *Monitor) Start(ctx context.Context) error {
+	// An offline bundle never changes, so there is nothing to fetch or poll
+	if m.bundle != nil {
+		m.publishBundle()
+		m.startSinks(ctx)
+		return nil
+	}
+
+	// Discovery, fleets, and templates add to the lists, which a reload must leave alone
+	m.watched = m.currentWatchList()
+
+	// A replica syncing from a peer serves the peer's prices until its own are fetched
+	if m.syncFrom != "" {
+		m.syncFromPeer(ctx)
+	}
+
+	// Perform initial fetch, which initializes the fetchers
+	if err := m.fetchAllPricing(ctx); err != nil {
+		slog.Error("initial pricing fetch failed", "error", err)
+	}
+
+	// Start polling goroutine
+	m.refresh = make(chan struct{}, 1)
+	m.reload = make(chan watchList, 1)
+	go m.pollPricing(ctx)
+	m.startSinks(ctx)
+
+	// A pinned price list never changes, so there is nothing to watch for
+	if m.awsFetcher != nil && m.awsPriceListPin == nil && m.priceListCheckInterval > 0 {
+		watcher := NewPriceListWatcher(m.awsFetcher, m.watch("aws").regions, m.priceListCheckInterval, m.metrics, m.requestRefresh)
+		go watcher.Run(ctx)
+	}
+
+	return nil
+}
+
+// initFetchers configures a provider from the registry for every pricing target with regions
+// to monitor. Providers are configured concurrently, each within the fetcher init timeout, so a
+// slow or failing provider doesn't hold up the others; its error is returned and it is retried
+// on the next call. Regions can be added by cluster discovery after startup, so it is safe to
+// call again.
+func (m *Monitor) initFetchers(ctx context.Context) error {
+	return m.initFetchersThen(ctx, nil)
+}
+
+// initFetchersThen is initFetchers, also calling ready from each provider's own init goroutine
+// once the provider is configured, so work on its prices can begin without waiting on the
+// others. It returns once every provider is configured and every call to ready has returned.
+func (m *Monitor) initFetchersThen(ctx context.Context, ready func(provider string)) error {
+	var (
+		wg   sync.WaitGroup
+		errs []error
+	)
+
+	for _, t := range m.pricingTargets() {
+		if (len(t.regions) == 0 && !m.allRegions[t.name]) || m.provider(t.name) != nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p, err := initFetcher(ctx, t.name, m.fetcherInitTimeout, func(ctx context.Context) (providers.PricingProvider, error) {
+				p, err := m.registry.New(t.name)
+				if err != nil {
+					return nil, err
+				}
+				cfg := providers.Config{
+					Regions:       t.regions,
+					InstanceTypes: t.instanceTypes,
+					HTTPClient:    egressFrom(ctx).Client(t.name),
+				}
+				if err := p.Configure(ctx, cfg); err != nil {
+					return nil, err
+				}
+				warnUnsupportedRegions(ctx, p, t.regions)
+				return p, nil
+			})
+
+			m.fetchersMu.Lock()
+			if err != nil {
+				errs = append(errs, err)
+				m.fetchersMu.Unlock()
+				return
+			}
+			m.providers[t.name] = p
+			if a, ok := p.(fetcherAttacher); ok {
+				a.attach(m)
+			}
+			m.fetchersMu.Unlock()
+
+			if ready != nil {
+				ready(t.name)
+			}
+		}()
+	}
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// initFetcher creates a provider's fetcher, giving up on it once the timeout has passed. A
+// timeout of zero waits indefinitely. Clients keep the context they are created with to refresh
+// credentials, so the timeout only bounds the wait and an abandoned fetcher is left to finish
+// in the background.
+func initFetcher[F any](ctx context.Context, provider string, timeout time.Duration, create func(context.Context) (F, error)) (F, error) {
+	type result struct {
+		fetcher F
+		err     error
+	}
+	done := make(chan result, 1)
+	start := time.Now()
+	go func() {
+		fetcher, err := create(context.WithoutCancel(ctx))
+		done <- result{fetcher, err}
+	}()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	var zero F
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return zero, fmt.Errorf("failed to initialize %s fetcher: %w", provider, r.err)
+		}
+		slog.Info("initialized fetcher", "provider", provider, "duration", time.Since(start))
+		return r.fetcher, nil
+	case <-expired:
+		return zero, fmt.Errorf("failed to initialize %s fetcher: timed out after %s", provider, timeout)
+	case <-ctx.Done():
+		return zero, fmt.Errorf("failed to initialize %s fetcher: %w", provider, ctx.Err())
+	}
+}
+
+func (m *Monitor) pollPricing(ctx context.Context) {
+	ticker := time.NewTicker(m.pollInterval)
+	defer ticker.Stop()
+
+	// Series an adaptive schedule has due are also fetched between full polls
+	var due <-chan time.Time
+	if m.schedule != nil {
+		dueTicker := time.NewTicker(m.schedule.minInterval)
+		defer dueTicker.Stop()
+		due = dueTicker.C
+	}
+
+	// Polls that fall outside the poll window are skipped, and made up for when it opens
+	var opens <-chan time.Time
+	outsideWindow := func() bool {
+		if m.window == nil || m.window.Contains(time.Now()) {
+			return false
+		}
+		if opens == nil {
+			if next := m.window.NextOpen(time.Now()); !next.IsZero() {
+				slog.Info("deferring poll until the poll window opens", "window", m.window, "opens", next)
+				opens = time.After(time.Until(next))
+			}
+		}
+		return true
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("stopping pricing monitor")
+			return
+		case <-due:
+			if outsideWindow() {
+				continue
+			}
+			m.fetchDuePricing(ctx)
+		case <-ticker.C:
+			if outsideWindow() {
+				continue
+			}
+			if err := m.fetchAllPricing(ctx); err != nil {
+				slog.Error("pricing fetch failed", "error", err)
+			}
+		case <-opens:
+			opens = nil
+			slog.Info("poll window opened, fetching the deferred pricing")
+			if err := m.fetchAllPricing(ctx); err != nil {
+				slog.Error("pricing fetch failed", "error", err)
+			}
+			ticker.Reset(m.pollInterval)
+		case list := <-m.reload:
+			added := m.applyWatchList(ctx, list)
+			if len(added) > 0 && !outsideWindow() {
+				m.runVMFetches(ctx, added)
+			}
+		case <-m.refresh:
+			if outsideWindow() {
+				continue
+			}
+			slog.Info("refreshing pricing ahead of schedule")
+			if err := m.fetchAllPricing(ctx); err != nil {
+				slog.Error("pricing fetch failed", "error", err)
+			}
+			ticker.Reset(m.pollInterval)
+		}
+	}
+}
+
+// requestRefresh asks the poll loop to fetch all pricing now. Requests made while a refresh
+// is already pending are coalesced.
+func (m *Monitor) requestRefresh() {
+	select {
+	case m.refresh <- struct{}{}:
+	default:
+	}
+}
+
+func (m *Monitor) fetchAllPricing(ctx context.Context) error {
+	slog.Info("fetching pricing data")
+
+	// Catalogs are listed once per poll, and a provider configured by this poll starts empty
+	m.resetCatalogs()
+
+	// Every price fetched by the poll waits on the canary check, including those published
+	// before the rest of the poll
+	m.canary.start()
+
+	// A provider whose fetcher failed to initialize is retried on every poll. The prices of a
+	// provider configured now are fetched and checked as soon as it's ready, so a slow provider
+	// doesn't hold up the first prices of the others, and aren't fetched again later in the poll.
+	var (
+		readyMu sync.Mutex
+		fetched = make(map[vmFetch]bool)
+	)
+	err := m.initFetchersThen(ctx, func(provider string) {
+		fetches := slices.DeleteFunc(m.vmFetches(), func(f vmFetch) bool {
+			return f.provider != provider
+		})
+		priority, rest := splitPriorityFetches(fetches, m.priorityTypes)
+		m.runVMFetches(ctx, priority)
+		m.runVMFetches(ctx, rest)
+		slog.Info("fetched pricing of initialized provider", "provider", provider, "series", len(fetches))
+
+		// The canary check's state is shared by every provider
+		readyMu.Lock()
+		defer readyMu.Unlock()
+		m.releaseCanaryOf(ctx, provider)
+		for _, f := range fetches {
+			fetched[f] = true
+		}
+	})
+	if err != nil {
+		slog.Error("failed to initialize fetchers", "error", err)
+	}
+
+	if len(m.allRegions) > 0 || len(m.instanceTypeGlobs) > 0 {
+		before := m.watchedPairs()
+		m.listAllRegions(ctx)
+		m.expandInstanceTypeGlobs(ctx)
+		m.forgetPairs(before, m.watchedPairs())
+	}
+
+	// On the first fetch, the priority types are published before discovery and the rest
+	if !m.warmedUp && len(m.priorityTypes) > 0 {
+		priority, _ := splitPriorityFetches(m.vmFetches(), m.priorityTypes)
+		priority = slices.DeleteFunc(priority, func(f vmFetch) bool {
+			return fetched[f]
+		})
+		start := time.Now()
+		m.runVMFetches(ctx, priority)
+		for _, provider := range m.registry.Names() {
+			m.releaseCanaryOf(ctx, provider)
+		}
+		for _, f := range priority {
+			fetched[f] = true
+		}
+		slog.Info("fetched priority pricing", "series", len(priority), "duration", time.Since(start))
+	}
+
+	for _, d := range m.discoverers {
+		m.discoverCluster(ctx, d)
+	}
+
+	fleets := m.resolveFleets(ctx)
+	templates := m.resolveGCPTemplates(ctx)
+	if m.comparison != nil {
+		m.watchComparison(ctx)
+	}
+
+	if m.generations != nil {
+		m.checkGenerations(ctx)
+	}
+
+	fetches := slices.DeleteFunc(m.vmFetches(), func(f vmFetch) bool {
+		return fetched[f]
+	})
+	m.runVMFetches(ctx, fetches)
+	m.releaseCanary(ctx)
+	m.warmedUp = true
+	m.expireStalePrices()
+	m.recordFamilyAvailability()
+	m.recordBillingEntities()
+
+	if m.weights != nil {
+		m.recordBlendedPrices()
+	}
+
+	if m.comparison != nil {
+		m.metrics.RecordComparison(m.comparison.Costs(m.snapshot))
+	}
+
+	for scheduler, state := range m.clusters {
+		costs := AttributeCosts(state, m.snapshot)
+		m.metrics.RecordClusterCosts(scheduler, state, costs)
+	}
+
+	for _, fleet := range fleets {
+		m.recordFleetCost(ctx, fleet)
+	}
+
+	for _, template := range templates {
+		m.recordTemplateCost(ctx, template)
+	}
+
+	if len(m.gcpGPUTypes) > 0 && m.gcpFetcher != nil {
+		m.recordGPUCosts(ctx)
+	}
+
+	m.recordResourcePrices(ctx)
+	m.recordSpotPrices(ctx)
+
+	if len(m.software) > 0 && m.awsFetcher != nil {
+		m.recordSoftwareFees(ctx)
+	}
+
+	if m.awsCommitments && m.awsFetcher != nil {
+		m.recordCommitmentPrices(ctx)
+	}
+
+	if m.awsCapacityBlockDuration > 0 && m.awsFetcher != nil {
+		m.recordCapacityBlockPrices(ctx)
+	}
+
+	if m.sizeSteps {
+		m.recordSizeSteps(ctx)
+	}
+
+	if m.availability {
+		m.recordAvailability(ctx)
+	}
+
+	if m.quotaCeilings {
+		m.recordQuotaCeilings(ctx)
+	}
+
+	if m.alerts != nil {
+		m.evaluateAlerts(ctx)
+	}
+
+	if m.hook != nil {
+		m.runHook(ctx)
+	}
+
+	slog.Info("pricing data fetch complete")
+	return nil
+}
+
+// resetCatalogs makes the fetchers that keep a catalog for the length of a poll look it up
+// again: the SKU catalog on GCP, and the price list versions on AWS with --aws-bulk-pricing
+func (m *Monitor) resetCatalogs() {
+	if m.awsFetcher != nil {
+		m.awsFetcher.ResetPriceLists()
+	}
+	if m.gcpFetcher != nil {
+		m.gcpFetcher.ResetCatalog()
+	}
+}
+
+// vmFetch is the price of an instance type in a region to fetch
+type vmFetch struct {
+	provider, region, instanceType string
+	confidential                   bool
+}
+
+// vmFetches returns every instance type in every region of the configured providers
+func (m *Monitor) vmFetches() []vmFetch {
+	var fetches []vmFetch
+	for _, t := range m.pricingTargets() {
+		if m.provider(t.name) == nil {
+			continue
+		}
+		for _, region := range t.regions {
+			for _, instanceType := range t.instanceTypes {
+				fetches = append(fetches, vmFetch{t.name, region, instanceType, t.confidential})
+			}
+		}
+	}
+	return fetches
+}
+
+// splitPriorityFetches separates the fetches of priority instance types from the rest
+func splitPriorityFetches(fetches []vmFetch, priorityTypes []string) (priority, rest []vmFetch) {
+	for _, f := range fetches {
+		if slices.Contains(priorityTypes, f.instanceType) {
+			priority = append(priority, f)
+		} else {
+			rest = append(rest, f)
+		}
+	}
+	return priority, rest
+}
+
+// runVMFetches fetches and publishes the prices of instance types, skipping those an adaptive
+// schedule doesn't have due. Each provider's prices are fetched by a pool of as many workers as
+// the provider's concurrency limit.
+func (m *Monitor) runVMFetches(ctx context.Context, fetches []vmFetch) {
+	queues := make(map[string]chan vmFetch)
+	for _, f := range fetches {
+		if !m.due(scheduledSeries{seriesOnDemand, f.provider, f.region, f.instanceType}) {
+			continue
+		}
+		if queues[f.provider] == nil {
+			queues[f.provider] = make(chan vmFetch, len(fetches))
+		}
+		queues[f.provider] <- f
+	}
+
+	var wg sync.WaitGroup
+	for provider, queue := range queues {
+		close(queue)
+		for range min(m.limits.concurrency(provider), len(queue)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for f := range queue {
+					m.fetchVMPricing(ctx, f)
+
+					// A failed fetch leaves the published price, and backs off like an unchanged one
+					var value string
+					if entry, ok := m.snapshot.Get(PriceKey{Provider: f.provider, Region: f.region, InstanceType: f.instanceType}); ok {
+						value = entry.Pricing.TotalCost.String()
+					}
+					m.observe(scheduledSeries{seriesOnDemand, f.provider, f.region, f.instanceType}, value)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+// fetchVMPricing fetches and publishes the price of an instance type, and of its confidential
+// computing variant when the provider can price one
+func (m *Monitor) fetchVMPricing(ctx context.Context, f vmFetch) {
+	pricing, err := m.fetchPricing(ctx, f.provider, f.region, f.instanceType)
+	m.metrics.RecordResolution(f.provider, f.region, f.instanceType, err)
+
+	// Under a canary check, the provider's prices are published once they pass it
+	if m.canary.stage(f, pricing, err) {
+		return
+	}
+	m.publishVMFetch(ctx, f, pricing, err)
+}
+
+// publishVMFetch publishes a fetched price, or records why it couldn't be fetched
+func (m *Monitor) publishVMFetch(ctx context.Context, f vmFetch, pricing *VMPricing, err error) {
+	if err != nil {
+		m.statuses.Record(PriceKey{Provider: f.provider, Region: f.region, InstanceType: f.instanceType}, err)
+		slog.Error("failed to fetch pricing",
+			"provider", f.provider,
+			"region", f.region,
+			"instance_type", f.instanceType,
+			"reason", errorReason(err),
+			"error", err,
+		)
+		m.metrics.RecordPricingError(f.provider, f.region, err)
+		return
+	}
+
+	// A confirming fetch must not share a call with the fetch it confirms
+	p := m.provider(f.provider)
+	refetch := func(ctx context.Context) (*VMPricing, error) {
+		return m.fetchUpstream(ctx, p, f.region, f.instanceType)
+	}
+	if !m.publishPricing(ctx, *pricing, refetch) {
+		return
+	}
+
+	slog.Info("updated pricing",
+		"provider", f.provider,
+		"region", f.region,
+		"instance_type", f.instanceType,
+		"cost_per_hour", pricing.TotalCost,
+	)
+
+	if fetcher, ok := p.(confidentialFetcher); ok && f.confidential {
+		m.fetchConfidentialPricing(ctx, fetcher, *pricing)
+	}
+}
+
+// discoverCluster refreshes the cluster state and starts pricing the regions and instance
+// types of any nodes that aren't monitored yet
+func (m *Monitor) discoverCluster(ctx context.Context, d ClusterDiscoverer) {
+	state, err := d.Discover(ctx)
+	if err != nil {
+		slog.Error("failed to discover cluster", "scheduler", d.Scheduler(), "error", err)
+		return
+	}
+
+	if m.clusters == nil {
+		m.clusters = make(map[string]*ClusterState)
+	}
+	m.clusters[d.Scheduler()] = state
+
+	// Another shard prices the nodes in the regions it owns
+	registered := m.registry.Names()
+	for _, node := range state.Nodes {
+		if !slices.Contains(registered, node.Provider) || len(m.ownedRegions(node.Provider, []string{node.Region})) == 0 {
+			continue
+		}
+		watched := m.watch(node.Provider)
+		watched.regions = appendMissing(watched.regions, node.Region)
+		watched.instanceTypes = appendMissing(watched.instanceTypes, node.InstanceType)
+	}
+
+	if err := m.initFetchers(ctx); err != nil {
+		slog.Error("failed to initialize fetchers for discovered nodes", "error", err)
+	}
+
+	slog.Info("discovered cluster",
+		"scheduler", d.Scheduler(),
+		"nodes", len(state.Nodes),
+		"allocations", len(state.Allocations),
+	)
+}
+
+// resolveFleets reads the current configuration of fleets backed by an Auto Scaling group and
+// starts pricing the regions and instance types of every fleet that aren't monitored yet
+func (m *Monitor) resolveFleets(ctx context.Context) []FleetConfig {
+	if len(m.fleets) == 0 {
+		return nil
+	}
+
+	watched := m.watch("aws")
+	for _, fleet := range m.fleets {
+		watched.regions = appendMissing(watched.regions, fleet.Region)
+	}
+	if err := m.initFetchers(ctx); err != nil {
+		slog.Error("failed to initialize fetchers for fleets", "error", err)
+	}
+	if m.awsFetcher == nil {
+		return nil
+	}
+
+	fleets := make([]FleetConfig, 0, len(m.fleets))
+	for _, fleet := range m.fleets {
+		if fleet.ASG != "" {
+			resolved, err := m.awsFetcher.ResolveAutoScalingGroup(ctx, fleet)
+			if err != nil {
+				slog.Error("failed to resolve fleet", "fleet", fleet.Name, "error", err)
+				continue
+			}
+			fleet = resolved
+		}
+
+		watched.instanceTypes = appendMissing(watched.instanceTypes, fleet.InstanceTypes()...)
+		fleets = append(fleets, fleet)
+	}
+	return fleets
+}
+
+// recordFleetCost exports the per-unit cost of a fleet from the published on-demand prices
+// and the current spot prices of its instance types
+func (m *Monitor) recordFleetCost(ctx context.Context, fleet FleetConfig) {
+	onDemand := make(map[string]decimal.Decimal)
+	spot := make(map[string]decimal.Decimal)
+	for _, instanceType := range fleet.InstanceTypes() {
+		key := PriceKey{Provider: "aws", Region: fleet.Region, InstanceType: instanceType}
+		if entry, ok := m.snapshot.Get(key); ok {
+			onDemand[instanceType] = entry.Pricing.TotalCost
+		}
+
+		if fleet.OnDemandFraction() >= 1 {
+			continue
+		}
+		price, err := m.awsFetcher.SpotPrice(ctx, fleet.Region, instanceType)
+		if err != nil {
+			slog.Warn("failed to fetch spot price",
+				"fleet", fleet.Name,
+				"region", fleet.Region,
+				"instance_type", instanceType,
+				"reason", errorReason(err),
+				"error", err,
+			)
+			continue
+		}
+		spot[instanceType] = price
+	}
+
+	cost, err := EstimateFleetCost(fleet, onDemand, spot)
+	if err != nil {
+		slog.Error("failed to estimate fleet cost", "fleet", fleet.Name, "error", err)
+		return
+	}
+	m.metrics.RecordFleetCost(fleet, cost)
+
+	slog.Info("updated fleet cost",
+		"fleet", fleet.Name,
+		"region", fleet.Region,
+		"on_demand_fraction", fleet.OnDemandFraction(),
+		"blended_cost_per_unit_hour", cost.Blended,
+	)
+}
+
+// resolveGCPTemplates reads the current instance templates of the configured templates and
+// managed instance groups and starts pricing their regions and machine types
+func (m *Monitor) resolveGCPTemplates(ctx context.Context) []*GCPTemplate {
+	if len(m.gcpTemplates) == 0 {
+		return nil
+	}
+
+	watched := m.watch("gcp")
+	for _, config := range m.gcpTemplates {
+		watched.regions = appendMissing(watched.regions, config.PricingRegion())
+	}
+	if err := m.initFetchers(ctx); err != nil {
+		slog.Error("failed to initialize fetchers for GCP templates", "error", err)
+	}
+	if m.gcpFetcher == nil {
+		return nil
+	}
+
+	templates := make([]*GCPTemplate, 0, len(m.gcpTemplates))
+	for _, config := range m.gcpTemplates {
+		template, err := m.gcpFetcher.ResolveTemplate(ctx, config)
+		if err != nil {
+			slog.Error("failed to resolve GCP template", "name", config.Name, "error", err)
+			continue
+		}
+
+		watched.instanceTypes = appendMissing(watched.instanceTypes, template.MachineType)
+		templates = append(templates, template)
+	}
+	return templates
+}
+
+// recordTemplateCost exports the all-in cost of an instance template from the published
+// price of its machine type and the live disk and GPU SKUs
+func (m *Monitor) recordTemplateCost(ctx context.Context, t *GCPTemplate) {
+	entry, ok := m.snapshot.Get(PriceKey{Provider: "gcp", Region: t.Region, InstanceType: t.MachineType})
+	if !ok {
+		slog.Error("no price for GCP template machine type",
+			"name", t.Name,
+			"region", t.Region,
+			"machine_type", t.MachineType,
+		)
+		return
+	}
+
+	cost, err := m.gcpFetcher.EstimateTemplateCost(ctx, t, entry.Pricing.TotalCost)
+	if err != nil {
+		slog.Error("failed to estimate GCP template cost", "name", t.Name, "reason", errorReason(err), "error", err)
+		m.metrics.RecordPricingError("gcp", t.Region, err)
+		return
+	}
+	m.metrics.RecordTemplateCost(t, cost)
+
+	slog.Info("updated GCP template cost",
+		"name", t.Name,
+		"region", t.Region,
+		"machine_type", t.MachineType,
+		"cost_per_hour", cost.Total(),
+		"instances", t.Instances,
+	)
+}
+
+// appendMissing appends the values not already in s
+func appendMissing(s []string, values ...string) []string {
+	for _, v := range values {
+		if !slices.Contains(s, v) {
+			s = append(s, v)
+		}
+	}
+	return s
+}
+
+// recordBlendedPrices exports the usage-weighted cost per vCPU of every region from the
+// published prices
+func (m *Monitor) recordBlendedPrices() {
+	for key, blended := range m.weights.BlendedCostPerVCPU(m.snapshot.Entries()) {
+		m.metrics.RecordBlendedCostPerVCPU(key.Provider, key.Region, blended)
+	}
+}
+
+// confidentialFetcher is implemented by fetchers that can price confidential computing variants
+type confidentialFetcher interface {
+	FetchConfidentialPricing(ctx context.Context, standard VMPricing) (*VMPricing, error)
+}
+
+func (m *Monitor) fetchConfidentialPricing(ctx context.Context, fetcher confidentialFetcher, standard VMPricing) {
+	confidential, err := fetcher.FetchConfidentialPricing(ctx, standard)
+	if errors.Is(err, errConfidentialUnsupported) {
+		slog.Debug("instance type has no confidential variant",
+			"provider", standard.Provider,
+			"region", standard.Region,
+			"instance_type", standard.InstanceType,
+		)
+		return
+	}
+	if err != nil {
+		m.statuses.Record(PriceKey{Provider: standard.Provider, Region: standard.Region, InstanceType: standard.InstanceType, Confidential: true}, err)
+		slog.Error("failed to fetch confidential pricing",
+			"provider", standard.Provider,
+			"region", standard.Region,
+			"instance_type", standard.InstanceType,
+			"reason", errorReason(err),
+			"error", err,
+		)
+		m.metrics.RecordPricingError(standard.Provider, standard.Region, err)
+		return
+	}
+
+	refetch := func(ctx context.Context) (*VMPricing, error) {
+		return fetcher.FetchConfidentialPricing(ctx, standard)
+	}
+	if !m.publishPricing(ctx, *confidential, refetch) {
+		return
+	}
+	m.metrics.RecordConfidentialPremium(standard, *confidential)
+
+	slog.Info("updated confidential pricing",
+		"provider", standard.Provider,
+		"region", standard.Region,
+		"instance_type", standard.InstanceType,
+		"cost_per_hour", confidential.TotalCost,
+		"premium_per_hour", confidential.TotalCost.Sub(standard.TotalCost),
+	)
+}
+
+// publishPricing exports a fetched price and stores it in the snapshot, unless the change
+// from the last published price still needs to be confirmed
+func (m *Monitor) publishPricing(ctx context.Context, p VMPricing, refetch func(context.Context) (*VMPricing, error)) bool {
+	// An override replaces both fetches, so a corrected price isn't held back as a change the
+	// provider never made
+	p = m.overrides.Apply(p)
+	if m.consensus != nil {
+		upstream := refetch
+		refetch = func(ctx context.Context) (*VMPricing, error) {
+			pricing, err := upstream(ctx)
+			if err != nil {
+				return nil, err
+			}
+			overridden := m.overrides.Apply(*pricing)
+			return &overridden, nil
+		}
+
+		var previous *PriceEntry
+		if entry, ok := m.snapshot.Get(p.Key()); ok {
+			previous = &entry
+		}
+
+		if !m.consensus.Confirm(ctx, p, previous, refetch) {
+			slog.Warn("holding back price change pending confirmation",
+				"provider", p.Provider,
+				"region", p.Region,
+				"instance_type", p.InstanceType,
+				"published_cost_per_hour", previous.Pricing.TotalCost,
+				"fetched_cost_per_hour", p.TotalCost,
+			)
+			m.metrics.PriceChangesHeld.With(prometheus.Labels{
+				"provider": p.Provider,
+				"region":   p.Region,
+			}).Inc()
+			m.statuses.Hold(p.Key())
+			return false
+		}
+	}
+
+	now := time.Now()
+	m.statuses.Record(p.Key(), nil)
+	m.metrics.RecordPricing(p)
+	m.metrics.RecordDerived(p)
+	_, seen := m.snapshot.Get(p.Key())
+	entry := m.snapshot.Set(p, now)
+	if !entry.ChangedAt.IsZero() {
+		m.metrics.RecordPriceChange(entry)
+	}
+	changed := !seen || entry.ChangedAt.Equal(now)
+
+	if seen && changed {
+		slog.Info("price changed",
+			"provider", p.Provider,
+			"region", p.Region,
+			"instance_type", p.InstanceType,
+			"confidential", p.Confidential,
+			"previous_cost_per_hour", entry.PreviousCost,
+			"cost_per_hour", p.TotalCost,
+		)
+	}
+
+	if m.history != nil && changed {
+		if err := m.history.Append(NewPriceRecord(m.rounding.Pricing(p), now, "live")); err != nil {
+			slog.Error("failed to record price history", "error", err)
+		}
+	}
+
+	if m.mqtt != nil && changed {
+		m.mqtt.Publish(NewPriceRecord(m.rounding.Pricing(p), now, "live"))
+	}
+
+	if m.regressions != nil {
+		if regression, ok := m.regressions.Observe(p); ok {
+			regression.suggestAlternatives(m.snapshot.Entries())
+			m.reportRegression(ctx, regression)
+		}
+	}
+
+	if m.baseline != nil {
+		if ratio, exceeded, ok := m.baseline.Compare(p); ok {
+			m.metrics.RecordBaselineComparison(p, ratio, exceeded)
+			if exceeded && changed {
+				slog.Warn("price exceeds baseline",
+					"provider", p.Provider,
+					"region", p.Region,
+					"instance_type", p.InstanceType,
+					"confidential", p.Confidential,
+					"cost_per_hour", p.TotalCost,
+					"baseline_ratio", ratio,
+				)
+			}
+		}
+	}
+	if factor, cost, ok := m.slaAssumptions.EffectiveCost(p); ok {
+		m.metrics.RecordEffectiveCost(p, factor, cost)
+	}
+	if cost, ok := m.normalization.Cost(p); ok {
+		m.metrics.RecordNormalizedCost(p, cost)
+	}
+	m.metrics.RecordScenarioCosts(p, m.scenarios, now)
+	m.recordAccountCosts(p)
+
+	m.metrics.LastUpdateTime.With(prometheus.Labels{
+		"provider": p.Provider,
+		"region":   p.Region,
+	}).Set(float64(now.Unix()))
+
+	return true
+}
+
+// reportRegression logs a sustained price increase and opens an issue for it in every
+// configured tracker
+func (m *Monitor) reportRegression(ctx context.Context, r PriceRegression) {
+	p := r.Pricing
+	slog.Warn("sustained price increase",
+		"provider", p.Provider,
+		"region", p.Region,
+		"instance_type", p.InstanceType,
+		"confidential", p.Confidential,
+		"previous_cost_per_hour", r.ReferenceCost,
+		"cost_per_hour", p.TotalCost,
+		"polls", r.Polls,
+	)
+
+	for _, tracker := range m.issueTrackers {
+		result := "opened"
+		err := tracker.CreateIssue(ctx, r.Title(), r.Body())
+		if err != nil {
+			result = "failed"
+		}
+		m.metrics.RegressionIssues.With(prometheus.Labels{
+			"provider": p.Provider,
+			"region":   p.Region,
+			"tracker":  tracker.Name(),
+			"result":   result,
+		}).Inc()
+		if err != nil {
+			slog.Error("failed to open price regression issue",
+				"tracker", tracker.Name(),
+				"provider", p.Provider,
+				"region", p.Region,
+				"instance_type", p.InstanceType,
+				"error", err,
+			)
+		}
+	}
+}
+
+// checkGenerations looks for newer generations of the monitored instance types and, when
+// enabled, starts tracking the ones launched since the monitor started
+func (m *Monitor) checkGenerations(ctx context.Context) {
+	if m.awsFetcher != nil {
+		catalog, err := m.awsFetcher.ListInstanceTypes(ctx)
+		if err != nil {
+			slog.Error("failed to check for new AWS instance generations", "error", err)
+		} else {
+			watched := m.watch("aws")
+			newer := m.generations.Update("aws", catalog, watched.instanceTypes, parseAWSGeneration)
+			watched.instanceTypes = m.handleNewerGenerations(newer, watched.instanceTypes)
+		}
+	}
+
+	if m.gcpFetcher != nil {
+		families, err := m.gcpFetcher.ListMachineFamilies(ctx)
+		if err != nil {
+			slog.Error("failed to check for new GCP machine generations", "error", err)
+		} else {
+			watched := m.watch("gcp")
+			catalog := gcpCatalogTypes(families, watched.instanceTypes)
+			newer := m.generations.Update("gcp", catalog, watched.instanceTypes, parseGCPGeneration)
+			watched.instanceTypes = m.handleNewerGenerations(newer, watched.instanceTypes)
+		}
+	}
+}
+
+func (m *Monitor) handleNewerGenerations(newer []NewerGeneration, instanceTypes []string) []string {
+	for _, n := range newer {
+		m.metrics.NewerGeneration.With(prometheus.Labels{
+			"provider":            n.Provider,
+			"instance_type":       n.InstanceType,
+			"newer_instance_type": n.NewerType,
+		}).Set(1)
+
+		if !n.Launched {
+			continue
+		}
+
+		slog.Warn("new instance generation launched",
+			"provider", n.Provider,
+			"instance_type", n.InstanceType,
+			"new_instance_type", n.NewerType,
+		)
+		m.metrics.GenerationsLaunched.With(prometheus.Labels{
+			"provider": n.Provider,
+		}).Inc()
+
+		if m.autoAddNewGens && !slices.Contains(instanceTypes, n.NewerType) {
+			slog.Info("tracking new instance generation",
+				"provider", n.Provider,
+				"instance_type", n.NewerType,
+			)
+			instanceTypes = append(instanceTypes, n.NewerType)
+		}
+	}
+
+	return instanceTypes
+}
@@ -0,0 +1,128 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/providers"
+	"github.com/shopspring/decimal"
+)
+
+// fakeProvider prices every instance type at the same price. Its Configure waits on
+// configured when set, which fails the provider if it returns an error.
+type fakeProvider struct {
+	name       string
+	price      decimal.Decimal
+	configured func(ctx context.Context) error
+}
+
+func (p *fakeProvider) Name() string {
+	return p.name
+}
+
+func (p *fakeProvider) Configure(ctx context.Context, cfg providers.Config) error {
+	if p.configured == nil {
+		return nil
+	}
+	return p.configured(ctx)
+}
+
+func (p *fakeProvider) FetchPricing(ctx context.Context, region, instanceType string) (*providers.Pricing, error) {
+	return &providers.Pricing{Provider: p.name, Region: region, InstanceType: instanceType, TotalCost: p.price}, nil
+}
+
+func (p *fakeProvider) ListSupportedRegions(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+// newFakeMonitor returns a monitor pricing one instance type in one region of each provider
+func newFakeMonitor(t *testing.T, fakes ...*fakeProvider) *Monitor {
+	t.Helper()
+
+	metrics, err := NewMetrics(nil, memoryUnitGB)
+	if err != nil {
+		t.Fatalf("NewMetrics failed: %v", err)
+	}
+	m := &Monitor{
+		metrics:   metrics,
+		snapshot:  NewPriceSnapshot(),
+		registry:  providers.NewRegistry(),
+		providers: make(map[string]providers.PricingProvider),
+		watching:  make(map[string]*watchedTypes),
+	}
+	for _, fake := range fakes {
+		if err := m.registry.Register(fake.name, func() providers.PricingProvider { return fake }); err != nil {
+			t.Fatalf("Register(%q) failed: %v", fake.name, err)
+		}
+		m.watching[fake.name] = &watchedTypes{[]string{"region-1"}, []string{"type-1"}}
+	}
+	return m
+}
+
+// publishedPrice returns the published price of a fake provider's instance type
+func publishedPrice(m *Monitor, provider string) (decimal.Decimal, bool) {
+	entry, ok := m.snapshot.Get(PriceKey{Provider: provider, Region: "region-1", InstanceType: "type-1"})
+	return entry.Pricing.TotalCost, ok
+}
+
+func TestFetchAllPricingPublishesReadyProviders(t *testing.T) {
+	fast := &fakeProvider{name: "fast", price: decimal.RequireFromString("0.1")}
+	slow := &fakeProvider{name: "slow", price: decimal.RequireFromString("0.2")}
+	m := newFakeMonitor(t, fast, slow)
+
+	// The slow provider is only configured once the fast one's price is published
+	slow.configured = func(ctx context.Context) error {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if _, ok := publishedPrice(m, "fast"); ok {
+				return nil
+			}
+			time.Sleep(time.Millisecond)
+		}
+		return fmt.Errorf("fast provider's price was not published while slow was configuring")
+	}
+
+	if err := m.fetchAllPricing(context.Background()); err != nil {
+		t.Fatalf("fetchAllPricing failed: %v", err)
+	}
+	for _, fake := range []*fakeProvider{fast, slow} {
+		if got, ok := publishedPrice(m, fake.name); !ok || !got.Equal(fake.price) {
+			t.Errorf("published %s price = %s (%t), want %s", fake.name, got, ok, fake.price)
+		}
+	}
+}
+
+func TestFetchAllPricingChecksReadyProvidersWithCanary(t *testing.T) {
+	tests := []struct {
+		price string
+		want  string
+	}{
+		{"0.1", "0.1"},
+		{"0.3", "0.1"},
+	}
+
+	for _, tt := range tests {
+		fake := &fakeProvider{name: "fake", price: decimal.RequireFromString(tt.price)}
+		m := newFakeMonitor(t, fake)
+		canary, err := NewCanaryCheck(50)
+		if err != nil {
+			t.Fatalf("NewCanaryCheck failed: %v", err)
+		}
+		m.canary = canary
+
+		// A price served before the provider is configured, such as one synced from a peer
+		m.snapshot.Set(VMPricing{Provider: "fake", Region: "region-1", InstanceType: "type-1", TotalCost: decimal.RequireFromString("0.1")}, time.Now())
+
+		if err := m.fetchAllPricing(context.Background()); err != nil {
+			t.Fatalf("fetchAllPricing failed: %v", err)
+		}
+		if got, _ := publishedPrice(m, "fake"); !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("fetched %s, published price = %s, want %s", tt.price, got, tt.want)
+		}
+		if held := m.canary.held["fake"]; held != (tt.price != tt.want) {
+			t.Errorf("fetched %s, canary held = %t", tt.price, held)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// mqttPublishTimeout bounds how long a price update waits for the broker to acknowledge it
+const mqttPublishTimeout = 10 * time.Second
+
+// MQTTConfig configures publishing price updates to an MQTT broker
+type MQTTConfig struct {
+	// Broker is the broker URL, such as tcp://broker:1883, ssl://broker:8883, or ws://broker/mqtt
+	Broker string
+	// ClientID defaults to cloud-pricing-monitor-<hostname>, so shards don't take over each
+	// other's sessions
+	ClientID    string
+	Username    string
+	Password    *Secret
+	TopicPrefix string
+	QoS         byte
+	Retain      bool
+}
+
+// MQTTPublisher publishes each price change as a price record to a topic per series, named
+// <prefix>/<provider>/<region>/<instance type>, with /confidential appended for confidential
+// variants. The last message of every topic is sent again after reconnecting, so consumers of
+// a broker that lost its retained messages catch up.
+type MQTTPublisher struct {
+	client      mqtt.Client
+	topicPrefix string
+	qos         byte
+	retain      bool
+	metrics     *Metrics
+
+	mu   sync.Mutex
+	last map[string][]byte
+}
+
+// NewMQTTPublisher connects to a broker in the background. Updates published while the broker
+// is unreachable are sent once it connects.
+func NewMQTTPublisher(cfg MQTTConfig, metrics *Metrics) (*MQTTPublisher, error) {
+	if cfg.QoS > 2 {
+		return nil, fmt.Errorf("MQTT QoS must be 0, 1, or 2")
+	}
+	if strings.ContainsAny(cfg.TopicPrefix, "+#") {
+		return nil, fmt.Errorf("MQTT topic prefix %q can't contain wildcards", cfg.TopicPrefix)
+	}
+
+	clientID := cfg.ClientID
+	if clientID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get hostname for MQTT client ID: %w", err)
+		}
+		clientID = "cloud-pricing-monitor-" + hostname
+	}
+
+	p := &MQTTPublisher{
+		topicPrefix: strings.TrimSuffix(cfg.TopicPrefix, "/"),
+		qos:         cfg.QoS,
+		retain:      cfg.Retain,
+		metrics:     metrics,
+		last:        make(map[string][]byte),
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(clientID).
+		SetConnectRetry(true).
+		SetAutoReconnect(true).
+		SetOnConnectHandler(func(mqtt.Client) { p.republish() }).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			slog.Warn("lost connection to MQTT broker", "broker", cfg.Broker, "error", err)
+		})
+	if cfg.Username != "" {
+		// The password is read on every connect, so a rotated one is used after reconnecting
+		opts.SetCredentialsProvider(func() (string, string) {
+			return cfg.Username, cfg.Password.Value()
+		})
+	}
+
+	p.client = mqtt.NewClient(opts)
+	p.client.Connect()
+	return p, nil
+}
+
+// Topic returns the topic a series is published to
+func (p *MQTTPublisher) Topic(key PriceKey) string {
+	levels := []string{mqttTopicLevel(key.Provider), mqttTopicLevel(key.Region), mqttTopicLevel(key.InstanceType)}
+	if key.Confidential {
+		levels = append(levels, "confidential")
+	}
+	if p.topicPrefix != "" {
+		levels = append([]string{p.topicPrefix}, levels...)
+	}
+	return strings.Join(levels, "/")
+}
+
+// Publish sends a price record to its series' topic
+func (p *MQTTPublisher) Publish(record PriceRecord) {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		slog.Error("failed to encode MQTT price update", "error", err)
+		return
+	}
+
+	topic := p.Topic(record.Key())
+	p.mu.Lock()
+	p.last[topic] = payload
+	p.mu.Unlock()
+
+	// Updates are sent again on connect, so there's nothing to queue while disconnected
+	if !p.client.IsConnectionOpen() {
+		p.count(record.Provider, "deferred")
+		return
+	}
+
+	token := p.client.Publish(topic, p.qos, p.retain, payload)
+	result := "published"
+	if !token.WaitTimeout(mqttPublishTimeout) {
+		err = fmt.Errorf("timed out waiting for the broker")
+	} else {
+		err = token.Error()
+	}
+	if err != nil {
+		result = "failed"
+		slog.Error("failed to publish MQTT price update", "topic", topic, "error", err)
+	}
+	p.count(record.Provider, result)
+}
+
+// republish sends the last message of every topic after connecting
+func (p *MQTTPublisher) republish() {
+	p.mu.Lock()
+	messages := make(map[string][]byte, len(p.last))
+	for topic, payload := range p.last {
+		messages[topic] = payload
+	}
+	p.mu.Unlock()
+
+	slog.Info("connected to MQTT broker", "republished_topics", len(messages))
+	for topic, payload := range messages {
+		p.client.Publish(topic, p.qos, p.retain, payload)
+	}
+}
+
+func (p *MQTTPublisher) count(provider, result string) {
+	p.metrics.MQTTMessages.With(prometheus.Labels{
+		"provider": provider,
+		"result":   result,
+	}).Inc()
+}
+
+// Close disconnects from the broker, waiting briefly for in-flight messages
+func (p *MQTTPublisher) Close() {
+	p.client.Disconnect(250)
+}
+
+// mqttTopicLevel replaces the characters that are wildcards or separators in topic names
+func mqttTopicLevel(s string) string {
+	return strings.NewReplacer("/", "_", "+", "_", "#", "_").Replace(s)
+}
@@ -0,0 +1,342 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shopspring/decimal"
+)
+
+// labelNamePattern is the Prometheus label name syntax. Names starting with __ are reserved.
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// namedPriceMetrics are the per-series price gauges that can be renamed, with their help
+var namedPriceMetrics = map[string]string{
+	"cloud_vm_total_cost_per_hour":    totalCostOpts.Help,
+	"cloud_vm_previous_cost_per_hour": "Total cost per hour in USD before the most recent price change",
+	"cloud_vm_cost_per_gb_hour":       "Cost per unit of RAM per hour in USD",
+	"cloud_vm_cost_per_vcpu_hour":     costPerVCPUOpts.Help,
+}
+
+// metricNameFuncs are the functions available to metric name and label templates
+var metricNameFuncs = template.FuncMap{
+	"sanitize":   sanitizeMetricName,
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+}
+
+// metricNameData is what metric name and label templates are executed with
+type metricNameData struct {
+	// Metric is the default name of the metric, such as cloud_vm_total_cost_per_hour
+	Metric       string
+	Provider     string
+	Region       string
+	InstanceType string
+	Confidential bool
+	// Overridden is whether the price is a configured override of the fetched one
+	Overridden bool
+	VCPUs      int
+	MemoryGB   float64
+}
+
+// metricTemplate renames one price gauge
+type metricTemplate struct {
+	Name string `json:"name"`
+	// Labels replace the default labels when set, mapping each label name to a value template
+	Labels map[string]string `json:"labels,omitempty"`
+
+	name       *template.Template
+	labelNames []string
+	labels     []*template.Template
+}
+
+// MetricNaming renames the per-series price gauges with templates over the fields of each
+// price, for organizations with their own metric naming conventions
+type MetricNaming struct {
+	templates map[string]*metricTemplate
+	warned    sync.Map
+}
+
+// LoadMetricNaming reads metric name templates from a JSON file mapping default metric names to
+// templates, such as {"cloud_vm_total_cost_per_hour": {"name": "acme_{{.Provider}}_vm_price"}}
+func LoadMetricNaming(file string) (*MetricNaming, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metric naming file: %w", err)
+	}
+
+	var templates map[string]*metricTemplate
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("failed to parse metric naming file: %w", err)
+	}
+
+	// Files written for a legacy name rename the metric it became
+	for metric, t := range templates {
+		current, ok := migratedMetricName(metric)
+		if !ok {
+			continue
+		}
+		if _, ok := templates[current]; ok {
+			return nil, fmt.Errorf("metric naming: %s is renamed by both its name and its legacy name %s", current, metric)
+		}
+		slog.Warn("metric naming file uses a deprecated metric name", "metric", metric, "renamed_to", current)
+		delete(templates, metric)
+		templates[current] = t
+	}
+
+	for metric, t := range templates {
+		if _, ok := namedPriceMetrics[metric]; !ok {
+			return nil, fmt.Errorf("metric naming: unknown metric %s (only the per-series price gauges can be renamed)", metric)
+		}
+
+		t.name, err = template.New(metric).Funcs(metricNameFuncs).Option("missingkey=error").Parse(t.Name)
+		if err != nil {
+			return nil, fmt.Errorf("metric naming: invalid name template of %s: %w", metric, err)
+		}
+
+		for name := range t.Labels {
+			if !labelNamePattern.MatchString(name) || strings.HasPrefix(name, "__") {
+				return nil, fmt.Errorf("metric naming: invalid Prometheus label name %q for %s", name, metric)
+			}
+			t.labelNames = append(t.labelNames, name)
+		}
+		slices.Sort(t.labelNames)
+		for _, name := range t.labelNames {
+			label, err := template.New(name).Funcs(metricNameFuncs).Option("missingkey=error").Parse(t.Labels[name])
+			if err != nil {
+				return nil, fmt.Errorf("metric naming: invalid template of label %s of %s: %w", name, metric, err)
+			}
+			t.labels = append(t.labels, label)
+		}
+	}
+
+	return &MetricNaming{templates: templates}, nil
+}
+
+// Name returns the name and labels a price gauge is exported with for a price. A nil naming
+// keeps the default name and labels.
+func (n *MetricNaming) Name(metric string, p VMPricing) (string, []SinkLabel, error) {
+	var t *metricTemplate
+	if n != nil {
+		t = n.templates[metric]
+	}
+	if t == nil {
+		labels := []SinkLabel{
+			{"provider", p.Provider},
+			{"region", p.Region},
+			{"instance_type", p.InstanceType},
+			{"confidential", strconv.FormatBool(p.Confidential)},
+		}
+		// The previous price was published before the current one was overridden, if it was, and
+		// is labeled like its gauge on the metrics endpoint, without GPUs
+		if metric != "cloud_vm_previous_cost_per_hour" {
+			labels = append(labels,
+				SinkLabel{"overridden", strconv.FormatBool(p.Overridden)},
+				SinkLabel{"gpu_type", p.GPUType},
+				SinkLabel{"gpu_count", strconv.Itoa(p.GPUCount)},
+			)
+		}
+		return metric, labels, nil
+	}
+
+	data := metricNameData{
+		Metric:       metric,
+		Provider:     p.Provider,
+		Region:       p.Region,
+		InstanceType: p.InstanceType,
+		Confidential: p.Confidential,
+		Overridden:   p.Overridden,
+		VCPUs:        p.VCPUs,
+		MemoryGB:     p.MemoryGB,
+	}
+
+	var b strings.Builder
+	if err := t.name.Execute(&b, data); err != nil {
+		return "", nil, fmt.Errorf("failed to name %s: %w", metric, err)
+	}
+	name := b.String()
+	if !metricNamePattern.MatchString(name) {
+		return "", nil, fmt.Errorf("%s is named %q, which is not a valid Prometheus metric name", metric, name)
+	}
+
+	labels := make([]SinkLabel, len(t.labels))
+	for i, label := range t.labels {
+		b.Reset()
+		if err := label.Execute(&b, data); err != nil {
+			return "", nil, fmt.Errorf("failed to label %s: %w", metric, err)
+		}
+		labels[i] = SinkLabel{t.labelNames[i], b.String()}
+	}
+	return name, labels, nil
+}
+
+// Validate checks that every template gives a valid metric name for each series, that no two
+// gauges share a name, and that no two series are given the same name and labels
+func (n *MetricNaming) Validate(keys []PriceKey) error {
+	names := make(map[string]string)
+	series := make(map[string]string)
+	for _, key := range keys {
+		p := VMPricing{Provider: key.Provider, Region: key.Region, InstanceType: key.InstanceType, Confidential: key.Confidential}
+		desc := fmt.Sprintf("%s/%s/%s (confidential=%t)", key.Provider, key.Region, key.InstanceType, key.Confidential)
+		for metric := range namedPriceMetrics {
+			name, labels, err := n.Name(metric, p)
+			if err != nil {
+				return fmt.Errorf("metric naming of %s: %w", desc, err)
+			}
+
+			if other, ok := names[name]; ok && other != metric {
+				return fmt.Errorf("metric naming gives %s and %s the same name %s", other, metric, name)
+			}
+			names[name] = metric
+
+			id := seriesID(name, labels)
+			if other, ok := series[id]; ok {
+				return fmt.Errorf("metric naming gives %s of %s and %s the same name and labels", metric, other, desc)
+			}
+			series[id] = desc
+		}
+	}
+	return nil
+}
+
+// configuredPriceKeys returns the series of the configured regions and instance types of each
+// provider, and their confidential variants where enabled. Discovered instance types are only
+// known once running.
+func configuredPriceKeys(providers []string, list watchList, confidential map[string]bool) []PriceKey {
+	var keys []PriceKey
+	for _, provider := range providers {
+		for _, region := range list[provider].regions {
+			for _, instanceType := range list[provider].instanceTypes {
+				// The regions and types these stand for are only known once they are listed
+				if region == allRegions || isInstanceTypeGlob(instanceType) {
+					continue
+				}
+				keys = append(keys, PriceKey{provider, region, instanceType, false})
+				if confidential[provider] {
+					keys = append(keys, PriceKey{provider, region, instanceType, true})
+				}
+			}
+		}
+	}
+	return keys
+}
+
+// warnOnce logs a problem naming a series the first time it happens, since it would otherwise
+// be logged on every scrape
+func (n *MetricNaming) warnOnce(msg string, err error) {
+	if _, warned := n.warned.LoadOrStore(err.Error(), true); !warned {
+		slog.Warn(msg, "error", err)
+	}
+}
+
+// seriesID identifies a series by its name and labels
+func seriesID(name string, labels []SinkLabel) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, label := range labels {
+		b.WriteString("\xff" + label.Name + "=" + label.Value)
+	}
+	return b.String()
+}
+
+// sanitizeMetricName replaces characters that aren't allowed in metric names, such as the
+// hyphens of us-east-1, with underscores
+func sanitizeMetricName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// NamedCollector exports the price gauges from the snapshot under templated names and labels,
+// optionally with explicit sample timestamps like TimestampedCollector
+type NamedCollector struct {
+	snapshot   *PriceSnapshot
+	naming     *MetricNaming
+	rounding   PriceRounding
+	memoryUnit MemoryUnit
+	timestamps bool
+}
+
+func NewNamedCollector(snapshot *PriceSnapshot, naming *MetricNaming, rounding PriceRounding, memoryUnit MemoryUnit, timestamps bool) *NamedCollector {
+	return &NamedCollector{
+		snapshot:   snapshot,
+		naming:     naming,
+		rounding:   rounding,
+		memoryUnit: memoryUnit,
+		timestamps: timestamps,
+	}
+}
+
+// Describe sends nothing, since metric names depend on the series, which makes the collector
+// unchecked
+func (c *NamedCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (c *NamedCollector) Collect(ch chan<- prometheus.Metric) {
+	seen := make(map[string]bool)
+	for _, entry := range c.snapshot.Entries() {
+		p := entry.Pricing
+		emit := func(metric string, value decimal.Decimal) {
+			name, labels, err := c.naming.Name(metric, p)
+			if err != nil {
+				c.naming.warnOnce("skipping series that can't be named", err)
+				return
+			}
+
+			// A duplicate would fail the whole scrape
+			id := seriesID(name, labels)
+			if seen[id] {
+				c.naming.warnOnce("skipping series named like another", fmt.Errorf("%s of %s/%s/%s is a duplicate of another series", name, p.Provider, p.Region, p.InstanceType))
+				return
+			}
+			seen[id] = true
+
+			names := make([]string, len(labels))
+			values := make([]string, len(labels))
+			for i, label := range labels {
+				names[i], values[i] = label.Name, label.Value
+			}
+			help := namedPriceMetrics[metric]
+			if metric == "cloud_vm_cost_per_gb_hour" {
+				help = costPerGBOpts(c.memoryUnit).Help
+			}
+
+			m, err := prometheus.NewConstMetric(prometheus.NewDesc(name, help, names, nil), prometheus.GaugeValue, c.rounding.Float(value), values...)
+			if err != nil {
+				c.naming.warnOnce("skipping series that can't be exported", err)
+				return
+			}
+			if c.timestamps {
+				m = prometheus.NewMetricWithTimestamp(entry.UpdatedAt, m)
+			}
+			ch <- m
+		}
+
+		emit("cloud_vm_total_cost_per_hour", p.TotalCost)
+		if !entry.ChangedAt.IsZero() {
+			emit("cloud_vm_previous_cost_per_hour", entry.PreviousCost)
+		}
+		if cost, ok := p.CostPerMemory(c.memoryUnit); ok {
+			emit("cloud_vm_cost_per_gb_hour", cost)
+		}
+		if cost, ok := p.CostPerVCPU(); ok {
+			emit("cloud_vm_cost_per_vcpu_hour", cost)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// NomadDiscoverer discovers client nodes and allocations from the Nomad HTTP API
+type NomadDiscoverer struct {
+	address string
+	token   *Secret
+	client  *http.Client
+}
+
+func NewNomadDiscoverer(address string, token *Secret) *NomadDiscoverer {
+	return &NomadDiscoverer{
+		address: strings.TrimSuffix(address, "/"),
+		token:   token,
+		client:  http.DefaultClient,
+	}
+}
+
+func (d *NomadDiscoverer) Scheduler() string {
+	return "nomad"
+}
+
+type nomadNodeStub struct {
+	ID     string
+	Name   string
+	Status string
+}
+
+type nomadNode struct {
+	ID            string
+	Name          string
+	Attributes    map[string]string
+	NodeResources struct {
+		Cpu struct {
+			CpuShares int64
+		}
+		Memory struct {
+			MemoryMB int64
+		}
+	}
+}
+
+type nomadAllocation struct {
+	NodeID             string
+	Namespace          string
+	JobID              string
+	ClientStatus       string
+	AllocatedResources struct {
+		Tasks map[string]struct {
+			Cpu struct {
+				CpuShares int64
+			}
+			Memory struct {
+				MemoryMB int64
+			}
+		}
+	}
+}
+
+func (d *NomadDiscoverer) Discover(ctx context.Context) (*ClusterState, error) {
+	var stubs []nomadNodeStub
+	if err := d.get(ctx, "/v1/nodes", nil, &stubs); err != nil {
+		return nil, fmt.Errorf("failed to list Nomad nodes: %w", err)
+	}
+
+	// CPU is allocated in MHz, so remember each node's MHz per core to convert to vCPUs
+	mhzPerCore := make(map[string]float64)
+	names := make(map[string]string)
+
+	state := &ClusterState{}
+	for _, stub := range stubs {
+		if stub.Status != "ready" {
+			continue
+		}
+
+		var n nomadNode
+		if err := d.get(ctx, "/v1/node/"+url.PathEscape(stub.ID), nil, &n); err != nil {
+			return nil, fmt.Errorf("failed to read Nomad node %s: %w", stub.Name, err)
+		}
+
+		node, ok := nomadClusterNode(n)
+		if !ok {
+			continue
+		}
+		state.Nodes = append(state.Nodes, node)
+		names[n.ID] = n.Name
+		if node.VCPUs > 0 {
+			mhzPerCore[n.ID] = float64(n.NodeResources.Cpu.CpuShares) / node.VCPUs
+		}
+	}
+
+	var allocs []nomadAllocation
+	query := url.Values{"namespace": {"*"}, "resources": {"true"}}
+	if err := d.get(ctx, "/v1/allocations", query, &allocs); err != nil {
+		return nil, fmt.Errorf("failed to list Nomad allocations: %w", err)
+	}
+
+	for _, a := range allocs {
+		name, ok := names[a.NodeID]
+		if !ok || a.ClientStatus != "running" {
+			continue
+		}
+
+		alloc := ClusterAllocation{
+			Node:         name,
+			Namespace:    a.Namespace,
+			WorkloadKind: "Job",
+			Workload:     a.JobID,
+		}
+		for _, task := range a.AllocatedResources.Tasks {
+			if mhz := mhzPerCore[a.NodeID]; mhz > 0 {
+				alloc.VCPUs += float64(task.Cpu.CpuShares) / mhz
+			}
+			alloc.MemoryGB += float64(task.Memory.MemoryMB) * 1024 * 1024 / 1e9
+		}
+		state.Allocations = append(state.Allocations, alloc)
+	}
+
+	return state, nil
+}
+
+func (d *NomadDiscoverer) get(ctx context.Context, path string, query url.Values, out any) error {
+	u := d.address + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	if token := d.token.Value(); token != "" {
+		req.Header.Set("X-Nomad-Token", token)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, path)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// nomadClusterNode maps a Nomad client to its cloud instance using the attributes set by
+// Nomad's AWS, GCE, and Azure environment fingerprinters. Nodes elsewhere are skipped.
+func nomadClusterNode(n nomadNode) (ClusterNode, bool) {
+	attrs := n.Attributes
+
+	var provider, instanceType, region string
+	switch {
+	case attrs["platform.aws.instance-type"] != "":
+		provider = "aws"
+		instanceType = attrs["platform.aws.instance-type"]
+		region = awsRegionFromZone(attrs["platform.aws.placement.availability-zone"])
+	case attrs["platform.gce.machine-type"] != "":
+		provider = "gcp"
+		instanceType = attrs["platform.gce.machine-type"]
+		region = gcpRegionFromZone(attrs["platform.gce.zone"])
+	case attrs["platform.azure.vm-size"] != "":
+		provider = "azure"
+		instanceType = attrs["platform.azure.vm-size"]
+		region = attrs["platform.azure.location"]
+	default:
+		return ClusterNode{}, false
+	}
+
+	if region == "" {
+		return ClusterNode{}, false
+	}
+
+	cores, _ := strconv.Atoi(attrs["cpu.numcores"])
+	return ClusterNode{
+		Name:         n.Name,
+		Provider:     provider,
+		Region:       region,
+		InstanceType: instanceType,
+		VCPUs:        float64(cores),
+		MemoryGB:     float64(n.NodeResources.Memory.MemoryMB) * 1024 * 1024 / 1e9,
+	}, true
+}
+
+// awsRegionFromZone returns the region of an availability zone like us-east-1a
+func awsRegionFromZone(zone string) string {
+	if len(zone) < 2 {
+		return ""
+	}
+	return strings.TrimRight(zone, "abcdefghijklmnopqrstuvwxyz")
+}
+
+// gcpRegionFromZone returns the region of a zone like us-central1-a
+func gcpRegionFromZone(zone string) string {
+	i := strings.LastIndex(zone, "-")
+	if i <= 0 {
+		return ""
+	}
+	return zone[:i]
+}
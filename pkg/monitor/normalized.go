@@ -0,0 +1,60 @@
+package monitor
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// Defaults of the normalized unit: half vCPU, half memory, at the 1:4 vCPU to memory ratio of
+// general purpose instance types
+const (
+	defaultNormalizedVCPUWeight    = 0.5
+	defaultNormalizedMemoryPerVCPU = 4
+)
+
+// CostNormalization turns the shape of an instance type into a number of normalized units, so
+// the prices of types whose shapes differ slightly, such as m5.2xlarge and n2-standard-8,
+// compare across providers. A unit is a vCPU and MemoryPerVCPU of memory, in the memory unit,
+// with the vCPUs counting for VCPUWeight of it and the memory for the rest.
+type CostNormalization struct {
+	VCPUWeight    float64
+	MemoryPerVCPU float64
+	MemoryUnit    MemoryUnit
+}
+
+// NewCostNormalization checks the weight of vCPUs, from 0 to 1, and the memory of a unit
+func NewCostNormalization(vcpuWeight, memoryPerVCPU float64, unit MemoryUnit) (CostNormalization, error) {
+	if vcpuWeight < 0 || vcpuWeight > 1 {
+		return CostNormalization{}, fmt.Errorf("normalized-vcpu-weight must be between 0 and 1")
+	}
+	if memoryPerVCPU <= 0 {
+		return CostNormalization{}, fmt.Errorf("normalized-memory-per-vcpu must be positive")
+	}
+	return CostNormalization{VCPUWeight: vcpuWeight, MemoryPerVCPU: memoryPerVCPU, MemoryUnit: unit}, nil
+}
+
+// Units returns how many normalized units an instance type is, averaging its vCPUs and its
+// memory in units by their weights. It is false when the type's vCPUs or memory are unknown
+// and carry weight.
+func (n CostNormalization) Units(p VMPricing) (decimal.Decimal, bool) {
+	vcpuWeight := decimal.NewFromFloat(n.VCPUWeight)
+	memoryWeight := decimal.NewFromInt(1).Sub(vcpuWeight)
+	if (p.VCPUs <= 0 && vcpuWeight.IsPositive()) || (p.MemoryGB <= 0 && memoryWeight.IsPositive()) {
+		return decimal.Zero, false
+	}
+
+	vcpus := decimal.NewFromInt(int64(p.VCPUs)).Mul(vcpuWeight)
+	memory := n.MemoryUnit.FromGB(p.MemoryGB).Div(decimal.NewFromFloat(n.MemoryPerVCPU)).Mul(memoryWeight)
+	units := vcpus.Add(memory)
+	return units, units.IsPositive()
+}
+
+// Cost returns the cost per hour in USD of a normalized unit of an instance type
+func (n CostNormalization) Cost(p VMPricing) (decimal.Decimal, bool) {
+	units, ok := n.Units(p)
+	if !ok {
+		return decimal.Zero, false
+	}
+	return p.TotalCost.Div(units), true
+}
@@ -0,0 +1,116 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/shopspring/decimal"
+)
+
+// PriceOverride replaces the fetched price of the series it matches, for when a provider's
+// catalog is wrong or lags an announced change. Empty provider, region, and instance type
+// fields match anything, and the instance type may be a glob such as m5.*. A rule matches the
+// standard price of an instance type, or its confidential variant when Confidential is set.
+type PriceOverride struct {
+	Provider     string `json:"provider,omitempty"`
+	Region       string `json:"region,omitempty"`
+	InstanceType string `json:"instance_type,omitempty"`
+	Confidential bool   `json:"confidential,omitempty"`
+
+	// TotalCost is the price per hour in USD to publish instead of the fetched one
+	TotalCost *decimal.Decimal `json:"total_cost,omitempty"`
+	// Multiplier scales the fetched price instead, such as 0.9 for a 10% cut
+	Multiplier *decimal.Decimal `json:"multiplier,omitempty"`
+	// Reason says why the price is overridden, such as the announcement it reflects
+	Reason string `json:"reason,omitempty"`
+}
+
+// PriceOverrides are the overrides of fetched prices. The first matching rule applies.
+type PriceOverrides []PriceOverride
+
+// LoadPriceOverrides reads price overrides from a JSON file holding a list of rules, each
+// setting either a total cost or a multiplier
+func LoadPriceOverrides(file string) (PriceOverrides, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price overrides file: %w", err)
+	}
+
+	var rules PriceOverrides
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse price overrides file: %w", err)
+	}
+
+	for i, r := range rules {
+		if _, err := path.Match(r.InstanceType, ""); err != nil {
+			return nil, fmt.Errorf("price override %d has an invalid instance type pattern %q: %w", i, r.InstanceType, err)
+		}
+		switch {
+		case (r.TotalCost == nil) == (r.Multiplier == nil):
+			return nil, fmt.Errorf("price override %d must set either total_cost or multiplier", i)
+		case r.TotalCost != nil && r.TotalCost.IsNegative():
+			return nil, fmt.Errorf("price override %d total cost must not be negative", i)
+		case r.Multiplier != nil && !r.Multiplier.IsPositive():
+			return nil, fmt.Errorf("price override %d multiplier must be positive", i)
+		}
+	}
+
+	return rules, nil
+}
+
+// matches reports whether a rule applies to a series
+func (r PriceOverride) matches(p VMPricing) bool {
+	if r.Provider != "" && r.Provider != p.Provider {
+		return false
+	}
+	if r.Region != "" && r.Region != p.Region {
+		return false
+	}
+	if r.Confidential != p.Confidential {
+		return false
+	}
+	if r.InstanceType == "" {
+		return true
+	}
+	matched, _ := path.Match(r.InstanceType, p.InstanceType)
+	return matched
+}
+
+// match returns the rule that applies to a series, if any
+func (o PriceOverrides) match(p VMPricing) (PriceOverride, bool) {
+	for _, r := range o {
+		if r.matches(p) {
+			return r, true
+		}
+	}
+	return PriceOverride{}, false
+}
+
+// Apply returns a fetched price with the override of its series applied, marked as overridden,
+// or the price as it is when no rule matches it
+func (o PriceOverrides) Apply(p VMPricing) VMPricing {
+	r, ok := o.match(p)
+	if !ok {
+		return p
+	}
+	if r.TotalCost != nil {
+		p.TotalCost = *r.TotalCost
+	} else {
+		p.TotalCost = p.TotalCost.Mul(*r.Multiplier)
+	}
+	p.Overridden = true
+	return p
+}
+
+// fetchOverridden fetches the price of a series like fetchPricing, with its override applied
+func (m *Monitor) fetchOverridden(ctx context.Context, provider, region, instanceType string) (*VMPricing, error) {
+	pricing, err := m.fetchPricing(ctx, provider, region, instanceType)
+	if err != nil {
+		return nil, err
+	}
+	overridden := m.overrides.Apply(*pricing)
+	return &overridden, nil
+}
@@ -0,0 +1,432 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	cli "github.com/urfave/cli/v2"
+	"google.golang.org/api/cloudresourcemanager/v1"
+)
+
+// cloudFeature is a feature of the monitor and the cloud permissions it needs: IAM actions on
+// AWS and IAM permissions on GCP. The pricing catalogs of GCP only require authentication, so
+// GCP features that only read prices need no permissions.
+type cloudFeature struct {
+	provider    string
+	name        string
+	enabled     func(cctx *cli.Context) bool
+	permissions []string
+}
+
+func awsRegionsSet(cctx *cli.Context) bool { return len(cctx.StringSlice("aws-regions")) > 0 }
+func gcpRegionsSet(cctx *cli.Context) bool { return len(cctx.StringSlice("gcp-regions")) > 0 }
+
+// cloudFeatures lists every feature that calls a cloud API with the permissions of those calls
+var cloudFeatures = []cloudFeature{
+	{"aws", "pricing", awsRegionsSet, []string{"pricing:GetProducts"}},
+	{"aws", "confidential", func(cctx *cli.Context) bool {
+		return awsRegionsSet(cctx) && cctx.Bool("aws-confidential")
+	}, []string{"ec2:DescribeInstanceTypes"}},
+	{"aws", "price-lists", func(cctx *cli.Context) bool {
+		pinned := cctx.String("aws-price-list-version") != "" || cctx.String("aws-price-list-date") != ""
+		return awsRegionsSet(cctx) && (pinned || cctx.Bool("aws-bulk-pricing") || cctx.Duration("price-list-check-interval") > 0)
+	}, []string{"pricing:ListPriceLists", "pricing:GetPriceListFileUrl"}},
+	{"aws", "instance-catalog", func(cctx *cli.Context) bool {
+		return awsRegionsSet(cctx) && (cctx.Bool("track-new-generations") || cctx.Bool("export-size-steps"))
+	}, []string{"pricing:GetAttributeValues"}},
+	{"aws", "availability", func(cctx *cli.Context) bool {
+		return awsRegionsSet(cctx) && cctx.Bool("track-availability")
+	}, []string{"ec2:DescribeAvailabilityZones", "ec2:DescribeInstanceTypeOfferings"}},
+	{"aws", "all-regions", func(cctx *cli.Context) bool {
+		return slices.Contains(cctx.StringSlice("aws-regions"), allRegions)
+	}, []string{"ec2:DescribeRegions"}},
+	{"aws", "instance-type-patterns", func(cctx *cli.Context) bool {
+		return awsRegionsSet(cctx) && slices.ContainsFunc(cctx.StringSlice("aws-instance-types"), isInstanceTypeGlob)
+	}, []string{"ec2:DescribeInstanceTypes"}},
+	{"aws", "quota-ceilings", func(cctx *cli.Context) bool {
+		return awsRegionsSet(cctx) && cctx.Bool("export-quota-ceilings")
+	}, []string{"servicequotas:GetServiceQuota"}},
+	{"aws", "fleets", func(cctx *cli.Context) bool {
+		return cctx.String("fleet-config-file") != ""
+	}, []string{"autoscaling:DescribeAutoScalingGroups", "ec2:DescribeSpotPriceHistory"}},
+	{"aws", "spot-pricing", func(cctx *cli.Context) bool {
+		return awsRegionsSet(cctx) && cctx.Bool("aws-spot-pricing")
+	}, []string{"ec2:DescribeSpotPriceHistory"}},
+	{"aws", "capacity-blocks", func(cctx *cli.Context) bool {
+		return awsRegionsSet(cctx) && cctx.Bool("aws-capacity-block-pricing")
+	}, []string{"ec2:DescribeCapacityBlockOfferings"}},
+	{"aws", "spot-alerts", func(cctx *cli.Context) bool {
+		return alertRulesUseSpot(cctx.String("alert-rules-file"))
+	}, []string{"ec2:DescribeSpotPriceHistory"}},
+	{"aws", "ecs-discovery", func(cctx *cli.Context) bool {
+		return len(cctx.StringSlice("ecs-discovery-regions")) > 0
+	}, []string{"ecs:ListClusters", "ecs:ListContainerInstances", "ecs:DescribeContainerInstances", "ecs:ListTasks", "ecs:DescribeTasks"}},
+	{"gcp", "availability", func(cctx *cli.Context) bool {
+		return gcpRegionsSet(cctx) && cctx.Bool("track-availability")
+	}, []string{"compute.zones.list", "compute.machineTypes.list"}},
+	{"gcp", "all-regions", func(cctx *cli.Context) bool {
+		return slices.Contains(cctx.StringSlice("gcp-regions"), allRegions)
+	}, []string{"compute.regions.list"}},
+	{"gcp", "instance-type-patterns", func(cctx *cli.Context) bool {
+		return gcpRegionsSet(cctx) && slices.ContainsFunc(cctx.StringSlice("gcp-instance-types"), isInstanceTypeGlob)
+	}, []string{"compute.machineTypes.list"}},
+	{"gcp", "size-steps", func(cctx *cli.Context) bool {
+		return gcpRegionsSet(cctx) && cctx.Bool("export-size-steps")
+	}, []string{"compute.machineTypes.list"}},
+	{"gcp", "quota-ceilings", func(cctx *cli.Context) bool {
+		return gcpRegionsSet(cctx) && cctx.Bool("export-quota-ceilings")
+	}, []string{"compute.regions.get"}},
+	{"gcp", "templates", func(cctx *cli.Context) bool {
+		return cctx.String("gcp-template-config-file") != ""
+	}, []string{"compute.instanceTemplates.get", "compute.instanceGroupManagers.get"}},
+}
+
+// enabledFeatures returns the features of a provider the flags enable
+func enabledFeatures(cctx *cli.Context, provider string) []cloudFeature {
+	var features []cloudFeature
+	for _, f := range cloudFeatures {
+		if f.provider == provider && f.enabled(cctx) {
+			features = append(features, f)
+		}
+	}
+	return features
+}
+
+// readOnlyOperation reports whether an AWS operation only reads. Every call the monitor makes is
+// a Describe, List, or Get.
+func readOnlyOperation(name string) bool {
+	return strings.HasPrefix(name, "Describe") || strings.HasPrefix(name, "List") || strings.HasPrefix(name, "Get")
+}
+
+// enforceReadOnly refuses AWS operations that could change anything before they are sent, so
+// broad credentials shared with other tools can't be used to write by mistake
+func enforceReadOnly(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("EnforceReadOnly", func(
+		ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler,
+	) (middleware.InitializeOutput, middleware.Metadata, error) {
+		if op := awsmiddleware.GetOperationName(ctx); !readOnlyOperation(op) {
+			return middleware.InitializeOutput{}, middleware.Metadata{}, fmt.Errorf("refusing to call %s %s: the monitor only makes read-only calls", awsmiddleware.GetServiceID(ctx), op)
+		}
+		return next.HandleInitialize(ctx, in)
+	}), middleware.After)
+}
+
+// loadAWSConfig loads the default AWS configuration with read-only calls enforced, API calls
+// counted, requests routed through the AWS egress, and credentials reloaded when rotated
+func loadAWSConfig(ctx context.Context, optFns ...func(*config.LoadOptions) error) (aws.Config, error) {
+	// The route also applies to the credentials loaded again when rotated
+	if route, ok := egressFrom(ctx)["aws"]; ok {
+		optFns = append(optFns, config.WithHTTPClient(awshttp.NewBuildableClient().WithTransportOptions(route.configure)))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, optFns...)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	cfg.APIOptions = append(cfg.APIOptions, enforceReadOnly)
+	if metrics := apiCallMetrics(ctx); metrics != nil {
+		cfg.APIOptions = append(cfg.APIOptions, countAWSAPICalls(metrics))
+	}
+	cfg.Credentials = newReloadingAWSCredentials(cfg.Credentials, optFns)
+	return cfg, nil
+}
+
+// IAMPolicyCommand prints the permissions the monitor needs for the features its flags enable
+var IAMPolicyCommand = &cli.Command{
+	Name:  "iam-policy",
+	Usage: "Print the minimal AWS IAM policy or GCP custom role for the features the monitor's flags enable",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "provider",
+			Usage:    "Provider to print the policy for: aws prints an IAM policy document, gcp a custom role definition for gcloud iam roles create --file",
+			Required: true,
+		},
+	},
+	Action: printIAMPolicy,
+}
+
+// awsPolicy is an AWS IAM policy document
+type awsPolicy struct {
+	Version   string               `json:"Version"`
+	Statement []awsPolicyStatement `json:"Statement"`
+}
+
+// awsPolicyStatement is a statement of an AWS IAM policy document
+type awsPolicyStatement struct {
+	Sid      string   `json:"Sid"`
+	Effect   string   `json:"Effect"`
+	Action   []string `json:"Action"`
+	Resource string   `json:"Resource"`
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+func printIAMPolicy(cctx *cli.Context) error {
+	provider := cctx.String("provider")
+	if provider != "aws" && provider != "gcp" {
+		return fmt.Errorf("unknown provider %q (expected aws or gcp)", provider)
+	}
+	features := enabledFeatures(cctx, provider)
+
+	if provider == "aws" {
+		if len(features) == 0 {
+			return fmt.Errorf("no AWS features are enabled; pass the monitor's flags before iam-policy")
+		}
+
+		// Pricing reads aren't scoped to resources, and the rest only describe, so every
+		// statement applies to all resources
+		statements := make([]awsPolicyStatement, 0, len(features))
+		for _, f := range features {
+			sid := "CloudPricingMonitor"
+			for _, word := range nonAlphanumeric.Split(f.name, -1) {
+				sid += strings.ToUpper(word[:1]) + word[1:]
+			}
+			statements = append(statements, awsPolicyStatement{Sid: sid, Effect: "Allow", Action: f.permissions, Resource: "*"})
+		}
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(awsPolicy{Version: "2012-10-17", Statement: statements})
+	}
+
+	var permissions []string
+	for _, f := range features {
+		permissions = append(permissions, f.permissions...)
+	}
+	slices.Sort(permissions)
+
+	fmt.Println("# Cloud Billing Catalog prices only require authentication and the API to be enabled")
+	fmt.Println("title: Cloud Pricing Monitor")
+	fmt.Println("description: Read-only access for cloud-pricing-monitor")
+	fmt.Println("stage: GA")
+	if len(permissions) == 0 {
+		fmt.Println("includedPermissions: []")
+		return nil
+	}
+	fmt.Println("includedPermissions:")
+	for _, p := range slices.Compact(permissions) {
+		fmt.Printf("- %s\n", p)
+	}
+	return nil
+}
+
+// permissionStatus is the outcome of checking a permission
+type permissionStatus int
+
+const (
+	permissionUnverified permissionStatus = iota
+	permissionGranted
+	permissionDenied
+)
+
+// awsProbe makes the cheapest call that needs an AWS action in a region
+type awsProbe func(ctx context.Context, cfg aws.Config, region string) error
+
+// awsProbes are the calls used to check AWS actions. EC2 calls are dry runs. Actions that can
+// only be called on existing resources, such as a price list file, aren't probed.
+var awsProbes = map[string]awsProbe{
+	"pricing:GetProducts": func(ctx context.Context, cfg aws.Config, region string) error {
+		_, err := pricing.NewFromConfig(cfg).GetProducts(ctx, &pricing.GetProductsInput{ServiceCode: aws.String("AmazonEC2"), MaxResults: aws.Int32(1)})
+		return err
+	},
+	"pricing:ListPriceLists": func(ctx context.Context, cfg aws.Config, region string) error {
+		_, err := pricing.NewFromConfig(cfg).ListPriceLists(ctx, &pricing.ListPriceListsInput{
+			ServiceCode:   aws.String("AmazonEC2"),
+			CurrencyCode:  aws.String("USD"),
+			EffectiveDate: aws.Time(time.Now()),
+			RegionCode:    aws.String(region),
+			MaxResults:    aws.Int32(1),
+		})
+		return err
+	},
+	"pricing:GetAttributeValues": func(ctx context.Context, cfg aws.Config, region string) error {
+		_, err := pricing.NewFromConfig(cfg).GetAttributeValues(ctx, &pricing.GetAttributeValuesInput{ServiceCode: aws.String("AmazonEC2"), AttributeName: aws.String("instanceType"), MaxResults: aws.Int32(1)})
+		return err
+	},
+	"ec2:DescribeInstanceTypes": func(ctx context.Context, cfg aws.Config, region string) error {
+		_, err := ec2Client(cfg, region).DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{DryRun: aws.Bool(true)})
+		return err
+	},
+	"ec2:DescribeRegions": func(ctx context.Context, cfg aws.Config, region string) error {
+		_, err := ec2Client(cfg, region).DescribeRegions(ctx, &ec2.DescribeRegionsInput{DryRun: aws.Bool(true)})
+		return err
+	},
+	"ec2:DescribeAvailabilityZones": func(ctx context.Context, cfg aws.Config, region string) error {
+		_, err := ec2Client(cfg, region).DescribeAvailabilityZones(ctx, &ec2.DescribeAvailabilityZonesInput{DryRun: aws.Bool(true)})
+		return err
+	},
+	"ec2:DescribeInstanceTypeOfferings": func(ctx context.Context, cfg aws.Config, region string) error {
+		_, err := ec2Client(cfg, region).DescribeInstanceTypeOfferings(ctx, &ec2.DescribeInstanceTypeOfferingsInput{DryRun: aws.Bool(true)})
+		return err
+	},
+	"ec2:DescribeSpotPriceHistory": func(ctx context.Context, cfg aws.Config, region string) error {
+		_, err := ec2Client(cfg, region).DescribeSpotPriceHistory(ctx, &ec2.DescribeSpotPriceHistoryInput{DryRun: aws.Bool(true)})
+		return err
+	},
+	"servicequotas:GetServiceQuota": func(ctx context.Context, cfg aws.Config, region string) error {
+		client := servicequotas.NewFromConfig(cfg, func(o *servicequotas.Options) { o.Region = region })
+		_, err := client.GetServiceQuota(ctx, &servicequotas.GetServiceQuotaInput{ServiceCode: aws.String("ec2"), QuotaCode: aws.String(awsStandardVCPUQuota)})
+		return err
+	},
+	"autoscaling:DescribeAutoScalingGroups": func(ctx context.Context, cfg aws.Config, region string) error {
+		client := autoscaling.NewFromConfig(cfg, func(o *autoscaling.Options) { o.Region = region })
+		_, err := client.DescribeAutoScalingGroups(ctx, &autoscaling.DescribeAutoScalingGroupsInput{MaxRecords: aws.Int32(1)})
+		return err
+	},
+	"ecs:ListClusters": func(ctx context.Context, cfg aws.Config, region string) error {
+		client := ecs.NewFromConfig(cfg, func(o *ecs.Options) { o.Region = region })
+		_, err := client.ListClusters(ctx, &ecs.ListClustersInput{MaxResults: aws.Int32(1)})
+		return err
+	},
+}
+
+func ec2Client(cfg aws.Config, region string) *ec2.Client {
+	return ec2.NewFromConfig(cfg, func(o *ec2.Options) { o.Region = region })
+}
+
+// awsProbeStatus classifies the error of a probe. Dry runs that would have succeeded fail with
+// DryRunOperation.
+func awsProbeStatus(err error) (permissionStatus, error) {
+	if err == nil {
+		return permissionGranted, nil
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "DryRunOperation":
+			return permissionGranted, nil
+		case "AccessDenied", "AccessDeniedException", "UnauthorizedOperation", "UnauthorizedException":
+			return permissionDenied, nil
+		}
+	}
+	return permissionUnverified, err
+}
+
+// CheckPermissions verifies that the monitor's credentials allow the calls of every enabled
+// feature, logs the features that are unavailable, and exports the outcome per feature.
+// Permissions that can't be verified are logged and left out of the metric.
+func CheckPermissions(ctx context.Context, cctx *cli.Context, metrics *Metrics) {
+	if features := enabledFeatures(cctx, "aws"); len(features) > 0 {
+		region := "us-east-1"
+		for _, flag := range []string{"aws-regions", "ecs-discovery-regions"} {
+			if regions := cctx.StringSlice(flag); len(regions) > 0 && regions[0] != allRegions {
+				region = regions[0]
+				break
+			}
+		}
+
+		cfg, err := loadAWSConfig(ctx, config.WithRegion("us-east-1"))
+		if err != nil {
+			slog.Warn("failed to check AWS permissions", "error", err)
+		} else {
+			reportFeatures(features, metrics, func(action string) (permissionStatus, error) {
+				probe, ok := awsProbes[action]
+				if !ok {
+					return permissionUnverified, nil
+				}
+				return awsProbeStatus(probe(ctx, cfg, region))
+			})
+		}
+	}
+
+	if features := enabledFeatures(cctx, "gcp"); len(features) > 0 {
+		project := cctx.String("gcp-project")
+		granted, err := gcpGrantedPermissions(ctx, project, features)
+		if err != nil {
+			slog.Warn("failed to check GCP permissions", "gcp_project", project, "error", err)
+			return
+		}
+		reportFeatures(features, metrics, func(permission string) (permissionStatus, error) {
+			if slices.Contains(granted, permission) {
+				return permissionGranted, nil
+			}
+			return permissionDenied, nil
+		})
+	}
+}
+
+// gcpGrantedPermissions returns which of the features' permissions the credentials hold on the
+// project
+func gcpGrantedPermissions(ctx context.Context, project string, features []cloudFeature) ([]string, error) {
+	if project == "" {
+		return nil, fmt.Errorf("--gcp-project is required to check permissions")
+	}
+
+	opts, err := gcpClientOptions(ctx, cloudresourcemanager.CloudPlatformReadOnlyScope)
+	if err != nil {
+		return nil, err
+	}
+	service, err := cloudresourcemanager.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCP resource manager service: %w", err)
+	}
+
+	var permissions []string
+	for _, f := range features {
+		permissions = append(permissions, f.permissions...)
+	}
+	resp, err := service.Projects.TestIamPermissions(project, &cloudresourcemanager.TestIamPermissionsRequest{
+		Permissions: permissions,
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to test GCP permissions: %w", err)
+	}
+	return resp.Permissions, nil
+}
+
+// reportFeatures checks the permissions of features, logging and exporting whether each is
+// available
+func reportFeatures(features []cloudFeature, metrics *Metrics, check func(permission string) (permissionStatus, error)) {
+	for _, f := range features {
+		var denied, unverified []string
+		for _, permission := range f.permissions {
+			status, err := check(permission)
+			switch status {
+			case permissionDenied:
+				denied = append(denied, permission)
+			case permissionUnverified:
+				unverified = append(unverified, permission)
+				if err != nil {
+					slog.Debug("failed to verify permission", "provider", f.provider, "permission", permission, "error", err)
+				}
+			}
+		}
+
+		labels := prometheus.Labels{"provider": f.provider, "feature": f.name}
+		switch {
+		case len(denied) > 0:
+			slog.Warn("feature unavailable: missing cloud permissions",
+				"provider", f.provider,
+				"feature", f.name,
+				"denied", denied,
+			)
+			metrics.FeaturePermitted.With(labels).Set(0)
+		case len(unverified) == len(f.permissions):
+			slog.Info("could not verify feature permissions", "provider", f.provider, "feature", f.name, "unverified", unverified)
+		default:
+			slog.Info("feature permissions verified", "provider", f.provider, "feature", f.name, "unverified", unverified)
+			metrics.FeaturePermitted.With(labels).Set(1)
+		}
+	}
+}
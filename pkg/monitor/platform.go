@@ -0,0 +1,161 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// The units platform services are priced per
+const (
+	serviceUnitKeyMonth    = "key_month"
+	serviceUnitSecretMonth = "secret_month"
+	serviceUnit10kRequests = "10k_requests"
+	serviceUnitGB          = "gb"
+	serviceUnitGBMonth     = "gb_month"
+)
+
+// platformSku is a dimension of a platform service, and the usage type or SKU description it is
+// priced by
+type platformSku struct {
+	match     string
+	dimension string
+	unit      string
+}
+
+// platformService is a platform service whose unit prices are exported, with the service code
+// or display name its prices are listed under
+type platformService struct {
+	service string
+	id      string
+	skus    []platformSku
+}
+
+// awsPlatformServices are matched by the usage types of their products, without the region
+// prefix
+var awsPlatformServices = []platformService{
+	{"kms", "awskms", []platformSku{
+		{"KMS-Keys", "keys", serviceUnitKeyMonth},
+		{"KMS-Requests", "requests", serviceUnit10kRequests},
+	}},
+	{"secrets_manager", "AWSSecretsManager", []platformSku{
+		{"AWSSecretsManager-Secrets", "secrets", serviceUnitSecretMonth},
+		{"AWSSecretsManager-APIRequest", "requests", serviceUnit10kRequests},
+	}},
+	{"cloudwatch_logs", "AmazonCloudWatch", []platformSku{
+		{"DataProcessing-Bytes", "ingestion", serviceUnitGB},
+		{"TimedStorage-ByteHrs", "storage", serviceUnitGBMonth},
+	}},
+}
+
+// gcpPlatformServices are matched by the start of their SKUs' normalized descriptions
+var gcpPlatformServices = []platformService{
+	{"kms", "Cloud Key Management Service (KMS)", []platformSku{
+		{"active software symmetric key versions", "keys", serviceUnitKeyMonth},
+		{"cryptographic operations with a symmetric key", "requests", serviceUnit10kRequests},
+	}},
+	{"secret_manager", "Secret Manager", []platformSku{
+		{"secret version replica storage", "secrets", serviceUnitSecretMonth},
+		{"secret access operations", "requests", serviceUnit10kRequests},
+	}},
+	{"cloud_logging", "Cloud Logging", []platformSku{
+		{"log volume", "ingestion", serviceUnitGB},
+		{"log storage", "storage", serviceUnitGBMonth},
+	}},
+}
+
+// FetchPlatformPricing returns the unit prices of KMS, Secrets Manager, and CloudWatch Logs in a
+// region. Services without prices in the region are left out.
+func (f *AWSPricingFetcher) FetchPlatformPricing(ctx context.Context, region string) ([]ResourcePrice, error) {
+	dimensions := make(map[string][]ResourceDimension)
+	for _, service := range awsPlatformServices {
+		items, err := f.getProducts(ctx, service.id, map[string]string{"regionCode": region})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, sku := range service.skus {
+			for _, item := range items {
+				usageType := item.Product.Attributes["usagetype"]
+				if usageType != sku.match && !strings.HasSuffix(usageType, "-"+sku.match) {
+					continue
+				}
+				price, _, ok := item.firstTier()
+				if !ok {
+					continue
+				}
+				// Requests are listed per request
+				if sku.unit == serviceUnit10kRequests {
+					price = price.Mul(decimal.NewFromInt(10000))
+				}
+				dimensions[service.service] = append(dimensions[service.service], ResourceDimension{Dimension: sku.dimension, Unit: sku.unit, Price: price})
+				break
+			}
+		}
+	}
+
+	if len(dimensions) == 0 {
+		return nil, fmt.Errorf("%w for platform services in region %s", errNoPricingFound, region)
+	}
+	return resourcePrices(serviceKind.Name, "aws", region, dimensions), nil
+}
+
+// FetchPlatformPricing returns the unit prices of Cloud KMS, Secret Manager, and Cloud Logging
+// in a region. Most of their SKUs are global, and are used where a region has none of its own.
+func (f *GCPPricingFetcher) FetchPlatformPricing(ctx context.Context, region string) ([]ResourcePrice, error) {
+	dimensions := make(map[string][]ResourceDimension)
+	for _, service := range gcpPlatformServices {
+		serviceId, err := f.serviceID(ctx, service.id)
+		if err != nil {
+			return nil, err
+		}
+
+		match := func(product string) (string, bool) {
+			for _, sku := range service.skus {
+				if strings.HasPrefix(product, sku.match) {
+					return sku.match, true
+				}
+			}
+			return "", false
+		}
+		rates, err := f.findSkuRates(ctx, serviceId, region, match)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s pricing: %w", service.id, err)
+		}
+		if len(rates) < len(service.skus) {
+			global, err := f.findSkuRates(ctx, serviceId, "global", match)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get %s pricing: %w", service.id, err)
+			}
+			for key, rate := range global {
+				if _, ok := rates[key]; !ok {
+					rates[key] = rate
+				}
+			}
+		}
+
+		for _, sku := range service.skus {
+			rate, ok := rates[sku.match]
+			if !ok {
+				slog.Debug("skipping platform service dimension", "region", region, "service", service.service, "dimension", sku.dimension)
+				continue
+			}
+			price := rate.Price
+			switch sku.unit {
+			case serviceUnit10kRequests:
+				price = price.Mul(decimal.NewFromInt(10000))
+			case serviceUnitKeyMonth, serviceUnitSecretMonth, serviceUnitGBMonth:
+				price = rate.Monthly()
+			}
+			dimensions[service.service] = append(dimensions[service.service], ResourceDimension{Dimension: sku.dimension, Unit: sku.unit, Price: price})
+		}
+	}
+
+	if len(dimensions) == 0 {
+		return nil, fmt.Errorf("%w for platform services in region %s", errNoPricingFound, region)
+	}
+	return resourcePrices(serviceKind.Name, "gcp", region, dimensions), nil
+}
@@ -0,0 +1,85 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PriceListWatcher polls the AWS price list version for each monitored region and triggers
+// an immediate refresh when a new version is published, instead of waiting for the next poll
+type PriceListWatcher struct {
+	fetcher   *AWSPricingFetcher
+	regions   []string
+	interval  time.Duration
+	metrics   *Metrics
+	onPublish func()
+
+	versions map[string]string
+}
+
+func NewPriceListWatcher(fetcher *AWSPricingFetcher, regions []string, interval time.Duration, metrics *Metrics, onPublish func()) *PriceListWatcher {
+	return &PriceListWatcher{
+		fetcher:   fetcher,
+		regions:   regions,
+		interval:  interval,
+		metrics:   metrics,
+		onPublish: onPublish,
+		versions:  make(map[string]string),
+	}
+}
+
+func (w *PriceListWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.check(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check(ctx)
+		}
+	}
+}
+
+func (w *PriceListWatcher) check(ctx context.Context) {
+	published := false
+
+	for _, region := range w.regions {
+		version, err := w.fetcher.PriceListVersion(ctx, region, time.Now())
+		if err != nil {
+			slog.Error("failed to check AWS price list version", "region", region, "error", err)
+			continue
+		}
+
+		w.metrics.PriceListVersionTime.With(prometheus.Labels{
+			"provider": "aws",
+			"region":   region,
+		}).Set(float64(version.PublishedAt.Unix()))
+
+		previous, seen := w.versions[region]
+		w.versions[region] = version.Version
+		if !seen || previous == version.Version {
+			continue
+		}
+
+		slog.Info("new AWS price list version published",
+			"region", region,
+			"previous_version", previous,
+			"version", version.Version,
+		)
+		w.metrics.PriceListVersionsPublished.With(prometheus.Labels{
+			"provider": "aws",
+			"region":   region,
+		}).Inc()
+		published = true
+	}
+
+	if published {
+		w.onPublish()
+	}
+}
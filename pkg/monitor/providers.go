@@ -0,0 +1,221 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/providers"
+)
+
+// awsProvider prices AWS instance types for the provider registry. Its fetcher is also used
+// directly by the features only AWS has, such as fleets and spot prices.
+type awsProvider struct {
+	pin     *PriceListPin
+	bulk    bool
+	fetcher *AWSPricingFetcher
+}
+
+func (p *awsProvider) Name() string {
+	return "aws"
+}
+
+func (p *awsProvider) Configure(ctx context.Context, cfg providers.Config) error {
+	fetcher, err := NewAWSPricingFetcher(ctx)
+	if err != nil {
+		return err
+	}
+	switch {
+	case p.pin != nil:
+		fetcher.PinPriceList(p.pin)
+	case p.bulk:
+		fetcher.UseBulkPriceLists()
+	}
+	p.fetcher = fetcher
+	return nil
+}
+
+func (p *awsProvider) FetchPricing(ctx context.Context, region, instanceType string) (*providers.Pricing, error) {
+	pricing, err := p.fetcher.FetchPricing(ctx, region, instanceType)
+	return (*providers.Pricing)(pricing), classifyError(err)
+}
+
+func (p *awsProvider) ListSupportedRegions(ctx context.Context) ([]string, error) {
+	return p.fetcher.ListRegions(ctx)
+}
+
+func (p *awsProvider) FetchConfidentialPricing(ctx context.Context, standard VMPricing) (*VMPricing, error) {
+	pricing, err := p.fetcher.FetchConfidentialPricing(ctx, standard)
+	return pricing, classifyError(err)
+}
+
+// gcpProvider prices GCP machine types for the provider registry. Its fetcher is also used
+// directly by the features only GCP has, such as instance templates and GPUs.
+type gcpProvider struct {
+	billingAccount string
+	fetcher        *GCPPricingFetcher
+}
+
+func (p *gcpProvider) Name() string {
+	return "gcp"
+}
+
+func (p *gcpProvider) Configure(ctx context.Context, cfg providers.Config) error {
+	fetcher, err := NewGCPPricingFetcher(ctx)
+	if err != nil {
+		return err
+	}
+	if p.billingAccount != "" {
+		if err := fetcher.UseBillingAccount(ctx, p.billingAccount); err != nil {
+			return err
+		}
+	}
+	p.fetcher = fetcher
+	return nil
+}
+
+func (p *gcpProvider) FetchPricing(ctx context.Context, region, machineType string) (*providers.Pricing, error) {
+	pricing, err := p.fetcher.FetchPricing(ctx, region, machineType)
+	return (*providers.Pricing)(pricing), classifyError(err)
+}
+
+// ListSupportedRegions returns the GA regions whose SKUs can be matched by location
+func (p *gcpProvider) ListSupportedRegions(ctx context.Context) ([]string, error) {
+	return slices.Sorted(maps.Keys(gcpRegionLocations)), nil
+}
+
+func (p *gcpProvider) FetchConfidentialPricing(ctx context.Context, standard VMPricing) (*VMPricing, error) {
+	pricing, err := p.fetcher.FetchConfidentialPricing(ctx, standard)
+	return pricing, classifyError(err)
+}
+
+// azureProvider prices Azure VM sizes for the provider registry, along with the confidential
+// variants of the sizes that have one
+type azureProvider struct {
+	*providers.AzureProvider
+}
+
+// FetchConfidentialPricing returns the price of a VM size's confidential counterpart as its
+// confidential variant. Confidential sizes are offered in fewer regions than the sizes they are
+// named after, so a region without one is unsupported rather than failing.
+func (p *azureProvider) FetchConfidentialPricing(ctx context.Context, standard VMPricing) (*VMPricing, error) {
+	size, ok := providers.AzureConfidentialVMSize(standard.InstanceType)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errConfidentialUnsupported, standard.InstanceType)
+	}
+
+	pricing, err := p.FetchPricing(ctx, standard.Region, size)
+	if errors.Is(err, providers.ErrNoPricingFound) {
+		return nil, fmt.Errorf("%w: %s has no price in %s", errConfidentialUnsupported, size, standard.Region)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	confidential := standard
+	confidential.TotalCost = pricing.TotalCost
+	confidential.Confidential = true
+	return &confidential, nil
+}
+
+// newProviderRegistry registers every provider the monitor can price VMs with. AWS prices come
+// from the pinned price list when there is a pin, and from the current price list files with
+// awsBulk. GCP prices are the contract prices of gcpBillingAccount when it is set.
+func newProviderRegistry(pin *PriceListPin, awsBulk bool, gcpBillingAccount string) (*providers.Registry, error) {
+	if err := checkGCPBillingAccount(gcpBillingAccount); err != nil {
+		return nil, err
+	}
+
+	registry := providers.NewRegistry()
+	factories := []struct {
+		name    string
+		factory providers.Factory
+	}{
+		{"aws", func() providers.PricingProvider { return &awsProvider{pin: pin, bulk: awsBulk} }},
+		{"gcp", func() providers.PricingProvider { return &gcpProvider{billingAccount: gcpBillingAccount} }},
+		{"azure", func() providers.PricingProvider { return &azureProvider{providers.NewAzureProvider()} }},
+	}
+	for _, f := range factories {
+		if err := registry.Register(f.name, f.factory); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// pricingTarget is a registered provider and what the monitor prices with it
+type pricingTarget struct {
+	name          string
+	regions       []string
+	instanceTypes []string
+	confidential  bool
+}
+
+// pricingTargets returns the registered providers VM prices are fetched from, with the regions
+// and instance types of each. Discovery, fleets, and templates add to them, so they are read
+// afresh on every call.
+func (m *Monitor) pricingTargets() []pricingTarget {
+	names := m.registry.Names()
+	targets := make([]pricingTarget, 0, len(names))
+	for _, name := range names {
+		t := pricingTarget{name: name, confidential: m.confidential[name]}
+		if watched := m.watching[name]; watched != nil {
+			t.regions, t.instanceTypes = watched.regions, watched.instanceTypes
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
+
+// watch returns the regions and instance types priced with a provider, for discovery, fleets,
+// and templates to add to
+func (m *Monitor) watch(provider string) *watchedTypes {
+	if m.watching == nil {
+		m.watching = make(map[string]*watchedTypes)
+	}
+	watched, ok := m.watching[provider]
+	if !ok {
+		watched = &watchedTypes{}
+		m.watching[provider] = watched
+	}
+	return watched
+}
+
+// fetcherAttacher is a provider whose fetcher is also used directly by the features only it
+// has. attach is called with fetchersMu held once the provider is configured.
+type fetcherAttacher interface {
+	attach(m *Monitor)
+}
+
+func (p *awsProvider) attach(m *Monitor) {
+	m.awsFetcher = p.fetcher
+}
+
+func (p *gcpProvider) attach(m *Monitor) {
+	m.gcpFetcher = p.fetcher
+}
+
+// provider returns the configured provider of a name, or nil if it isn't configured yet
+func (m *Monitor) provider(name string) providers.PricingProvider {
+	m.fetchersMu.RLock()
+	defer m.fetchersMu.RUnlock()
+	return m.providers[name]
+}
+
+// warnUnsupportedRegions logs the configured regions a provider can't price. Not every
+// credential can list regions, so failing to list them is only logged at debug level.
+func warnUnsupportedRegions(ctx context.Context, p providers.PricingProvider, regions []string) {
+	supported, err := p.ListSupportedRegions(ctx)
+	if err != nil {
+		slog.Debug("failed to list supported regions", "provider", p.Name(), "error", err)
+		return
+	}
+	for _, region := range regions {
+		if !slices.Contains(supported, region) {
+			slog.Warn("region is not supported by provider", "provider", p.Name(), "region", region)
+		}
+	}
+}
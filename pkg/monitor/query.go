@@ -0,0 +1,237 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/client"
+)
+
+const (
+	// queryTimeout bounds how long a SQL query may run
+	queryTimeout = 10 * time.Second
+	// maxQueryRows is the most rows a SQL query returns
+	maxQueryRows = 10000
+)
+
+var (
+	// errQueryUnsupported is returned by monitors built without cgo, which SQLite needs
+	errQueryUnsupported = errors.New("SQL queries are not supported by this build of the monitor (it must be built with cgo)")
+	// errInvalidQuery wraps errors in the query itself, as opposed to loading the data
+	errInvalidQuery = errors.New("invalid query")
+)
+
+// queryTables creates the tables SQL queries read. Costs are rounded as in the metrics, memory of
+// current prices is in the memory unit, and times are RFC 3339 in UTC, which SQLite's date
+// functions accept.
+const queryTables = `
+CREATE TABLE prices (
+	provider TEXT NOT NULL,
+	region TEXT NOT NULL,
+	instance_type TEXT NOT NULL,
+	confidential INTEGER NOT NULL,
+	vcpus INTEGER NOT NULL,
+	memory REAL NOT NULL,
+	memory_unit TEXT NOT NULL,
+	total_cost REAL NOT NULL,
+	cost_per_vcpu REAL,
+	cost_per_memory REAL,
+	previous_cost REAL,
+	updated_at TEXT NOT NULL,
+	changed_at TEXT
+);
+CREATE TABLE history (
+	time TEXT NOT NULL,
+	provider TEXT NOT NULL,
+	region TEXT NOT NULL,
+	instance_type TEXT NOT NULL,
+	confidential INTEGER NOT NULL,
+	total_cost REAL NOT NULL,
+	memory_gb REAL NOT NULL,
+	vcpus INTEGER NOT NULL,
+	source TEXT NOT NULL
+);
+`
+
+// queryData is what SQL queries run over
+type queryData struct {
+	entries    []PriceEntry
+	history    []PriceRecord
+	rounding   PriceRounding
+	memoryUnit MemoryUnit
+}
+
+// runQuery loads the published prices and their history into a new in-memory database, and runs
+// a query over it that may only read. Each query gets its own copy of the data, so nothing it
+// does outlives it.
+func runQuery(ctx context.Context, query string, data queryData) (*client.QueryResult, error) {
+	db, err := openQueryDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	// Each connection to an in-memory database has its own database
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open query database: %w", err)
+	}
+	defer conn.Close()
+
+	if err := loadQueryTables(ctx, conn, data); err != nil {
+		return nil, fmt.Errorf("failed to load query tables: %w", err)
+	}
+	if err := restrictToReads(conn); err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidQuery, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := &client.QueryResult{Columns: columns, Rows: [][]any{}}
+	for rows.Next() {
+		if len(result.Rows) == maxQueryRows {
+			result.Truncated = true
+			break
+		}
+
+		row := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range row {
+			ptrs[i] = &row[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		for i, v := range row {
+			if b, ok := v.([]byte); ok {
+				row[i] = string(b)
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidQuery, err)
+	}
+	return result, nil
+}
+
+// loadQueryTables creates the query tables and fills them
+func loadQueryTables(ctx context.Context, conn *sql.Conn, data queryData) error {
+	if _, err := conn.ExecContext(ctx, queryTables); err != nil {
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	prices, err := tx.PrepareContext(ctx, `INSERT INTO prices VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	for _, entry := range data.entries {
+		price := newAPIPrice(entry, time.Time{}, data.rounding, data.memoryUnit)
+		var costPerVCPU, costPerMemory, previousCost, changedAt any
+		if _, ok := entry.Pricing.CostPerVCPU(); ok {
+			costPerVCPU = price.CostPerVCPU
+		}
+		if _, ok := entry.Pricing.CostPerMemory(data.memoryUnit); ok {
+			costPerMemory = price.CostPerGB
+		}
+		if price.ChangedAt != nil {
+			previousCost = price.PreviousCost
+			changedAt = queryTime(*price.ChangedAt)
+		}
+
+		if _, err := prices.ExecContext(ctx,
+			price.Provider, price.Region, price.InstanceType, price.Confidential, price.VCPUs, price.MemoryGB, price.MemoryUnit,
+			price.TotalCost, costPerVCPU, costPerMemory, previousCost, queryTime(price.UpdatedAt), changedAt,
+		); err != nil {
+			return err
+		}
+	}
+
+	history, err := tx.PrepareContext(ctx, `INSERT INTO history VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	for _, r := range data.history {
+		if _, err := history.ExecContext(ctx,
+			queryTime(r.Time), r.Provider, r.Region, r.InstanceType, r.Confidential, r.TotalCost, r.MemoryGB, r.VCPUs, r.Source,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// queryTime formats a time for the query tables
+func queryTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// queryHandler runs the SQL query in the sql parameter over the published prices and, when
+// enabled, the price history
+func queryHandler(snapshot *PriceSnapshot, history HistoryStore, rounding PriceRounding, memoryUnit MemoryUnit) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("sql")
+		if query == "" {
+			http.Error(w, "the sql parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), queryTimeout)
+		defer cancel()
+
+		data := queryData{
+			entries:    snapshot.Entries(),
+			rounding:   rounding,
+			memoryUnit: memoryUnit,
+		}
+		if history != nil {
+			records, err := history.Records()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			data.history = records
+		}
+
+		result, err := runQuery(ctx, query, data)
+		switch {
+		case errors.Is(err, errQueryUnsupported):
+			http.Error(w, err.Error(), http.StatusNotImplemented)
+			return
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			http.Error(w, fmt.Sprintf("query did not finish within %s", queryTimeout), http.StatusServiceUnavailable)
+			return
+		case errors.Is(err, errInvalidQuery):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,13 @@
+//go:build !cgo
+
+package monitor
+
+import "database/sql"
+
+func openQueryDB() (*sql.DB, error) {
+	return nil, errQueryUnsupported
+}
+
+func restrictToReads(*sql.Conn) error {
+	return errQueryUnsupported
+}
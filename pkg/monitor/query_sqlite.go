@@ -0,0 +1,41 @@
+//go:build cgo
+
+package monitor
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteRecursive is SQLITE_RECURSIVE, which the driver doesn't export
+const sqliteRecursive = 33
+
+func openQueryDB() (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open query database: %w", err)
+	}
+	return db, nil
+}
+
+// restrictToReads denies every statement on a connection other than reading tables and calling
+// functions, which also rules out ATTACH, PRAGMA, and writes
+func restrictToReads(conn *sql.Conn) error {
+	return conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unexpected query database connection %T", driverConn)
+		}
+		c.RegisterAuthorizer(func(action int, _, _, _ string) int {
+			switch action {
+			case sqlite3.SQLITE_SELECT, sqlite3.SQLITE_READ, sqlite3.SQLITE_FUNCTION, sqliteRecursive:
+				return sqlite3.SQLITE_OK
+			default:
+				return sqlite3.SQLITE_DENY
+			}
+		})
+		return nil
+	})
+}
@@ -0,0 +1,195 @@
+package monitor
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	"github.com/shopspring/decimal"
+)
+
+// awsVCPUQuotaCodes maps the series of EC2 instance types to the service quota code of the
+// on-demand vCPU limit that covers them. Series without a code share the standard quota.
+var awsVCPUQuotaCodes = map[string]string{
+	"dl":  "L-6E869C2A",
+	"f":   "L-74FC7D96",
+	"g":   "L-DB2E81BA",
+	"vt":  "L-DB2E81BA",
+	"hpc": "L-F7808C92",
+	"inf": "L-1945791B",
+	"p":   "L-417A185B",
+	"trn": "L-2C3B7624",
+	"u":   "L-43DA4232",
+	"x":   "L-7295265B",
+}
+
+// awsStandardVCPUQuota is the code of the Running On-Demand Standard (A, C, D, H, I, M, R, T, Z)
+// instances quota
+const awsStandardVCPUQuota = "L-1216C47A"
+
+// awsVCPUQuotaCode returns the code of the on-demand vCPU quota that covers an instance type,
+// or "" for types such as Mac instances that only run on dedicated hosts
+func awsVCPUQuotaCode(instanceType string) string {
+	// The series is the leading letters: m for m6i.large, hpc for hpc7g.4xlarge, u for u-6tb1.metal
+	series := instanceType
+	if i := strings.IndexFunc(instanceType, func(r rune) bool { return r < 'a' || r > 'z' }); i >= 0 {
+		series = instanceType[:i]
+	}
+
+	if code, ok := awsVCPUQuotaCodes[series]; ok {
+		return code
+	}
+	if len(series) == 1 && strings.Contains("acdhimrtz", series) {
+		return awsStandardVCPUQuota
+	}
+	return ""
+}
+
+// gcpCPUQuotaMetric returns the regional quota metric that limits the vCPUs of a machine type.
+// Families with a quota of their own (N2_CPUS, C3_CPUS, ...) use it, and the rest, such as N1
+// and E2, count against CPUS.
+func gcpCPUQuotaMetric(machineType string, limits map[string]float64) string {
+	family, _, _ := strings.Cut(machineType, "-")
+	if isCustomMachineType(machineType) {
+		if custom, err := parseCustomMachineType(machineType); err == nil {
+			family = custom.family
+		}
+	}
+
+	metric := strings.ToUpper(family) + "_CPUS"
+	if _, ok := limits[metric]; ok {
+		return metric
+	}
+	return "CPUS"
+}
+
+// VCPUQuota returns the on-demand vCPU limit of a service quota in a region
+func (f *AWSPricingFetcher) VCPUQuota(ctx context.Context, region, code string) (float64, error) {
+	// Service Quotas is regional, unlike the Pricing API
+	client := servicequotas.NewFromConfig(f.cfg, func(o *servicequotas.Options) {
+		o.Region = region
+	})
+
+	output, err := client.GetServiceQuota(ctx, &servicequotas.GetServiceQuotaInput{
+		ServiceCode: aws.String("ec2"),
+		QuotaCode:   aws.String(code),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get AWS service quota %s: %w", code, err)
+	}
+	if output.Quota == nil || output.Quota.Value == nil {
+		return 0, fmt.Errorf("AWS service quota %s has no value in %s", code, region)
+	}
+
+	return *output.Quota.Value, nil
+}
+
+// RegionQuotas returns the limit of every quota metric of a region in a project
+func (f *GCPPricingFetcher) RegionQuotas(ctx context.Context, project, region string) (map[string]float64, error) {
+	r, err := f.compute.Regions.Get(project, region).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GCP region %s: %w", region, err)
+	}
+
+	limits := make(map[string]float64, len(r.Quotas))
+	for _, q := range r.Quotas {
+		limits[q.Metric] = q.Limit
+	}
+	return limits, nil
+}
+
+// QuotaCeiling is the most a vCPU quota lets a region spend per hour on the monitored
+// instance types: every vCPU of the quota running the type with the highest cost per vCPU
+type QuotaCeiling struct {
+	Quota        string
+	VCPUs        float64
+	InstanceType string
+	CostPerHour  decimal.Decimal
+}
+
+// quotaCeilings combines the vCPU limits of a region with its published prices. Quotas that
+// cover none of the priced types are left out.
+func quotaCeilings(entries []PriceEntry, provider, region string, quotaOf func(instanceType string) string, limits map[string]float64) []QuotaCeiling {
+	ceilings := make(map[string]*QuotaCeiling)
+	for _, entry := range entries {
+		p := entry.Pricing
+		if p.Provider != provider || p.Region != region || p.Confidential || p.VCPUs == 0 {
+			continue
+		}
+
+		quota := quotaOf(p.InstanceType)
+		vcpus, ok := limits[quota]
+		if !ok {
+			continue
+		}
+
+		cost := p.TotalCost.Mul(decimal.NewFromFloat(vcpus)).Div(decimal.NewFromInt(int64(p.VCPUs)))
+		if c, ok := ceilings[quota]; !ok || cost.GreaterThan(c.CostPerHour) {
+			ceilings[quota] = &QuotaCeiling{
+				Quota:        quota,
+				VCPUs:        vcpus,
+				InstanceType: p.InstanceType,
+				CostPerHour:  cost,
+			}
+		}
+	}
+
+	var result []QuotaCeiling
+	for _, c := range ceilings {
+		result = append(result, *c)
+	}
+	slices.SortFunc(result, func(a, b QuotaCeiling) int {
+		return cmp.Compare(a.Quota, b.Quota)
+	})
+	return result
+}
+
+// recordQuotaCeilings exports the vCPU quotas of each region and the hourly spend they permit
+// at the current prices
+func (m *Monitor) recordQuotaCeilings(ctx context.Context) {
+	entries := m.snapshot.Entries()
+
+	if m.awsFetcher != nil {
+		for _, region := range m.watch("aws").regions {
+			limits := make(map[string]float64)
+			for _, instanceType := range m.watch("aws").instanceTypes {
+				code := awsVCPUQuotaCode(instanceType)
+				if _, ok := limits[code]; ok || code == "" {
+					continue
+				}
+
+				vcpus, err := m.awsFetcher.VCPUQuota(ctx, region, code)
+				if err != nil {
+					slog.Error("failed to get AWS vCPU quota", "region", region, "quota", code, "error", err)
+					continue
+				}
+				limits[code] = vcpus
+			}
+
+			ceilings := quotaCeilings(entries, "aws", region, awsVCPUQuotaCode, limits)
+			m.metrics.RecordQuotaCeilings("aws", region, ceilings)
+		}
+	}
+
+	// GCP regions can be added by discovery without a project configured
+	if m.gcpFetcher != nil && m.gcpProject != "" {
+		for _, region := range m.watch("gcp").regions {
+			limits, err := m.gcpFetcher.RegionQuotas(ctx, m.gcpProject, region)
+			if err != nil {
+				slog.Error("failed to get GCP CPU quotas", "region", region, "error", err)
+				continue
+			}
+
+			quotaOf := func(machineType string) string {
+				return gcpCPUQuotaMetric(machineType, limits)
+			}
+			ceilings := quotaCeilings(entries, "gcp", region, quotaOf, limits)
+			m.metrics.RecordQuotaCeilings("gcp", region, ceilings)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisTimeout bounds a command sent without a context deadline, so an unreachable server
+// delays a fetch instead of hanging it
+const redisTimeout = 30 * time.Second
+
+// RedisCache is a cache backend on a Redis or Valkey server. It keeps a single connection,
+// which is dialed again after a failed command.
+type RedisCache struct {
+	address  string
+	tls      *tls.Config
+	username string
+	password string
+	db       int
+
+	mu   sync.Mutex
+	conn net.Conn
+	rw   *bufio.ReadWriter
+}
+
+// redisError is an error reply of the server
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// NewRedisCache connects to the server at a redis:// URL, or rediss:// for TLS, of the form
+// redis://[user:password@]host[:port][/db]. The valkey:// and valkeys:// schemes are accepted
+// too. The server is reached on the first command, so the monitor starts while it is down.
+func NewRedisCache(rawURL string) (*RedisCache, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache URL: %w", err)
+	}
+
+	c := &RedisCache{}
+	switch u.Scheme {
+	case "redis", "valkey":
+	case "rediss", "valkeys":
+		c.tls = &tls.Config{ServerName: u.Hostname()}
+	default:
+		return nil, fmt.Errorf("invalid cache URL %s: scheme must be redis, rediss, valkey, or valkeys", u.Redacted())
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid cache URL %s: missing host", u.Redacted())
+	}
+	c.address = u.Host
+	if u.Port() == "" {
+		c.address = net.JoinHostPort(u.Hostname(), "6379")
+	}
+
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		c.db, err = strconv.Atoi(db)
+		if err != nil || c.db < 0 {
+			return nil, fmt.Errorf("invalid cache URL %s: database must be a number", u.Redacted())
+		}
+	}
+	return c, nil
+}
+
+// Get returns the value of a key, and whether the key exists
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := c.do(ctx, "GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis: unexpected reply to GET: %v", reply)
+	}
+	return value, true, nil
+}
+
+// Set stores the value of a key, which expires after ttl
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := c.do(ctx, "SET", key, value, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+func (c *RedisCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn, c.rw = nil, nil
+	return err
+}
+
+// do sends a command and reads its reply. A connection that failed mid-command may have a
+// reply left to read, so it is closed and the next command dials again.
+func (c *RedisCache) do(ctx context.Context, args ...any) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	deadline, ok := ctx.Deadline()
+	if !ok || time.Until(deadline) > redisTimeout {
+		deadline = time.Now().Add(redisTimeout)
+	}
+
+	if c.conn == nil {
+		if err := c.connect(ctx, deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := c.roundTrip(deadline, args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		c.conn.Close()
+		c.conn, c.rw = nil, nil
+	}
+	return reply, err
+}
+
+// connect dials the server, then authenticates and selects the database
+func (c *RedisCache) connect(ctx context.Context, deadline time.Time) error {
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	var conn net.Conn
+	var err error
+	if c.tls != nil {
+		dialer := tls.Dialer{Config: c.tls}
+		conn, err = dialer.DialContext(ctx, "tcp", c.address)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", c.address)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to cache at %s: %w", c.address, err)
+	}
+	c.conn = conn
+	c.rw = bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	var setup [][]any
+	switch {
+	case c.username != "" && c.password != "":
+		setup = append(setup, []any{"AUTH", c.username, c.password})
+	case c.password != "":
+		setup = append(setup, []any{"AUTH", c.password})
+	}
+	if c.db != 0 {
+		setup = append(setup, []any{"SELECT", strconv.Itoa(c.db)})
+	}
+	for _, args := range setup {
+		if _, err := c.roundTrip(deadline, args...); err != nil {
+			conn.Close()
+			c.conn, c.rw = nil, nil
+			return fmt.Errorf("failed to set up cache connection: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *RedisCache) roundTrip(deadline time.Time, args ...any) (any, error) {
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	// Commands are sent as arrays of bulk strings
+	fmt.Fprintf(c.rw, "*%d\r\n", len(args))
+	for _, arg := range args {
+		var value []byte
+		switch arg := arg.(type) {
+		case string:
+			value = []byte(arg)
+		case []byte:
+			value = arg
+		}
+		fmt.Fprintf(c.rw, "$%d\r\n", len(value))
+		c.rw.Write(value)
+		c.rw.WriteString("\r\n")
+	}
+	if err := c.rw.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to send cache command: %w", err)
+	}
+
+	reply, err := readRedisReply(c.rw.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if replyErr, ok := reply.(redisError); ok {
+		return nil, replyErr
+	}
+	return reply, nil
+}
+
+// readRedisReply reads a RESP2 reply: a status string, an error, an integer, a bulk string as
+// []byte, or an array. Nil bulk strings and arrays are nil.
+func readRedisReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache reply: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("failed to read cache reply: empty line")
+	}
+
+	kind, rest := line[0], line[1:]
+	switch kind {
+	case '+':
+		return rest, nil
+	case '-':
+		return redisError(rest), nil
+	case ':':
+		n, err := strconv.ParseInt(rest, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache reply %q", line)
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		value := make([]byte, n+2)
+		if _, err := io.ReadFull(r, value); err != nil {
+			return nil, fmt.Errorf("failed to read cache reply: %w", err)
+		}
+		return value[:n], nil
+	case '*':
+		n, err := strconv.Atoi(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		values := make([]any, n)
+		for i := range values {
+			if values[i], err = readRedisReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("invalid cache reply %q", line)
+	}
+}
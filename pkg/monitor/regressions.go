@@ -0,0 +1,166 @@
+package monitor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// maxRegressionAlternatives is how many alternatives of each kind a regression suggests
+const maxRegressionAlternatives = 3
+
+// RegressionTracker detects price increases that persist. Each series is compared against a
+// reference price: the lowest price seen since the last reported regression. An increase over
+// the reference of more than the threshold must hold for a number of consecutive polls before
+// it is reported, and it then becomes the new reference, so each increase is reported once.
+type RegressionTracker struct {
+	threshold float64
+	polls     int
+
+	mu        sync.Mutex
+	reference map[PriceKey]decimal.Decimal
+	streak    map[PriceKey]int
+}
+
+// NewRegressionTracker creates a tracker for increases of more than threshold percent that
+// last for polls consecutive polls
+func NewRegressionTracker(threshold float64, polls int) (*RegressionTracker, error) {
+	if threshold <= 0 {
+		return nil, fmt.Errorf("regression threshold must be positive")
+	}
+	if polls < 1 {
+		return nil, fmt.Errorf("regression polls must be at least 1")
+	}
+
+	return &RegressionTracker{
+		threshold: threshold,
+		polls:     polls,
+		reference: make(map[PriceKey]decimal.Decimal),
+		streak:    make(map[PriceKey]int),
+	}, nil
+}
+
+// PriceRegression is a sustained price increase of a series
+type PriceRegression struct {
+	Pricing       VMPricing
+	ReferenceCost decimal.Decimal
+	Polls         int
+
+	// CheaperTypes are cheaper types in the same region with at least as many vCPUs and as
+	// much memory, and CheaperRegions are regions where the same type costs less
+	CheaperTypes   []VMPricing
+	CheaperRegions []VMPricing
+}
+
+// Increase returns the price increase in percent
+func (r PriceRegression) Increase() float64 {
+	return percentIncrease(r.ReferenceCost, r.Pricing.TotalCost).InexactFloat64()
+}
+
+// percentIncrease returns how much a price rose from a reference in percent, negative for a drop
+func percentIncrease(reference, price decimal.Decimal) decimal.Decimal {
+	return price.Sub(reference).Div(reference).Shift(2)
+}
+
+// Observe records a published price and returns a regression when an increase has just
+// lasted long enough to report
+func (t *RegressionTracker) Observe(p VMPricing) (PriceRegression, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := p.Key()
+	reference, ok := t.reference[key]
+	if !ok || p.TotalCost.LessThanOrEqual(reference) {
+		t.reference[key] = p.TotalCost
+		t.streak[key] = 0
+		return PriceRegression{}, false
+	}
+
+	if percentIncrease(reference, p.TotalCost).LessThanOrEqual(decimal.NewFromFloat(t.threshold)) {
+		t.streak[key] = 0
+		return PriceRegression{}, false
+	}
+
+	t.streak[key]++
+	if t.streak[key] < t.polls {
+		return PriceRegression{}, false
+	}
+
+	t.reference[key] = p.TotalCost
+	t.streak[key] = 0
+	return PriceRegression{Pricing: p, ReferenceCost: reference, Polls: t.polls}, true
+}
+
+// suggestAlternatives fills in the cheapest alternatives to a regressed series from the
+// published prices
+func (r *PriceRegression) suggestAlternatives(entries []PriceEntry) {
+	p := r.Pricing
+	for _, entry := range entries {
+		alt := entry.Pricing
+		if alt.Provider != p.Provider || alt.Confidential != p.Confidential || alt.TotalCost.GreaterThanOrEqual(p.TotalCost) {
+			continue
+		}
+
+		switch {
+		case alt.Region == p.Region && alt.InstanceType != p.InstanceType:
+			if alt.VCPUs >= p.VCPUs && alt.MemoryGB >= p.MemoryGB {
+				r.CheaperTypes = append(r.CheaperTypes, alt)
+			}
+		case alt.Region != p.Region && alt.InstanceType == p.InstanceType:
+			r.CheaperRegions = append(r.CheaperRegions, alt)
+		}
+	}
+
+	byCost := func(a, b VMPricing) int {
+		return a.TotalCost.Cmp(b.TotalCost)
+	}
+	slices.SortFunc(r.CheaperTypes, byCost)
+	slices.SortFunc(r.CheaperRegions, byCost)
+	r.CheaperTypes = r.CheaperTypes[:min(len(r.CheaperTypes), maxRegressionAlternatives)]
+	r.CheaperRegions = r.CheaperRegions[:min(len(r.CheaperRegions), maxRegressionAlternatives)]
+}
+
+// Title returns the summary line of the regression's issue
+func (r PriceRegression) Title() string {
+	p := r.Pricing
+	variant := ""
+	if p.Confidential {
+		variant = " (confidential)"
+	}
+	return fmt.Sprintf("%s %s%s in %s price up %.1f%% to $%s/hr", strings.ToUpper(p.Provider), p.InstanceType, variant, p.Region, r.Increase(), p.TotalCost.StringFixed(4))
+}
+
+// Body returns a plain-text description of the regression and its alternatives
+func (r PriceRegression) Body() string {
+	p := r.Pricing
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "The price of a tracked instance type increased by %.1f%% and has stayed there for %d consecutive polls.\n\n", r.Increase(), r.Polls)
+	fmt.Fprintf(&b, "Series: provider=%s region=%s instance_type=%s confidential=%t\n", p.Provider, p.Region, p.InstanceType, p.Confidential)
+	fmt.Fprintf(&b, "Previous price: $%s/hr\n", r.ReferenceCost.StringFixed(4))
+	fmt.Fprintf(&b, "Current price: $%s/hr ($%s/month)\n", p.TotalCost.StringFixed(4), monthlyCost(p.TotalCost).StringFixed(2))
+
+	if len(r.CheaperTypes) == 0 && len(r.CheaperRegions) == 0 {
+		b.WriteString("\nNo cheaper alternatives are monitored.\n")
+		return b.String()
+	}
+
+	if len(r.CheaperTypes) > 0 {
+		fmt.Fprintf(&b, "\nCheaper instance types in %s with at least %d vCPUs and %.1f GB of memory:\n", p.Region, p.VCPUs, p.MemoryGB)
+		for _, alt := range r.CheaperTypes {
+			fmt.Fprintf(&b, "- %s: $%s/hr (%d vCPUs, %.1f GB)\n", alt.InstanceType, alt.TotalCost.StringFixed(4), alt.VCPUs, alt.MemoryGB)
+		}
+	}
+
+	if len(r.CheaperRegions) > 0 {
+		fmt.Fprintf(&b, "\nRegions where %s costs less:\n", p.InstanceType)
+		for _, alt := range r.CheaperRegions {
+			fmt.Fprintf(&b, "- %s: $%s/hr\n", alt.Region, alt.TotalCost.StringFixed(4))
+		}
+	}
+
+	return b.String()
+}
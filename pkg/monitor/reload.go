@@ -0,0 +1,230 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+// watchListFlags are the flags of the regions and instance types priced with each provider
+var watchListFlags = map[string]struct{ regions, instanceTypes string }{
+	"aws":   {"aws-regions", "aws-instance-types"},
+	"gcp":   {"gcp-regions", "gcp-instance-types"},
+	"azure": {"azure-regions", "azure-vm-sizes"},
+}
+
+// watchedTypes are the regions and instance types priced with a provider
+type watchedTypes struct {
+	regions, instanceTypes []string
+}
+
+// watchList is what is priced with each provider, by provider name
+type watchList map[string]watchedTypes
+
+// confidentialFlags are the flags pricing the confidential variants of a provider's types too
+var confidentialFlags = map[string]string{
+	"aws":   "aws-confidential",
+	"gcp":   "gcp-confidential",
+	"azure": "azure-confidential",
+}
+
+// flagWatchList returns the regions and instance types of each provider set by the flags
+func flagWatchList(cctx *cli.Context) watchList {
+	list := make(watchList)
+	for provider, flags := range watchListFlags {
+		list[provider] = watchedTypes{cctx.StringSlice(flags.regions), cctx.StringSlice(flags.instanceTypes)}
+	}
+	return list
+}
+
+// flagConfidential returns the providers whose confidential variants the flags price
+func flagConfidential(cctx *cli.Context) map[string]bool {
+	confidential := make(map[string]bool)
+	for provider, flag := range confidentialFlags {
+		confidential[provider] = cctx.Bool(flag)
+	}
+	return confidential
+}
+
+// watching returns a copy of the list for a monitor to price and add to
+func (l watchList) watching() map[string]*watchedTypes {
+	watching := make(map[string]*watchedTypes, len(l))
+	for provider, watched := range l {
+		watching[provider] = &watchedTypes{slices.Clone(watched.regions), slices.Clone(watched.instanceTypes)}
+	}
+	return watching
+}
+
+// hasRegions reports whether any provider has regions to price
+func (l watchList) hasRegions() bool {
+	for _, watched := range l {
+		if len(watched.regions) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// regions returns the regions of each provider
+func (l watchList) regions() map[string][]string {
+	regions := make(map[string][]string, len(l))
+	for provider, watched := range l {
+		regions[provider] = watched.regions
+	}
+	return regions
+}
+
+// loadWatchList reads the regions and instance types of each provider from the --config file
+// again. A list set by a flag or environment variable keeps its value, since it takes
+// precedence over the file.
+func loadWatchList(cctx *cli.Context) (watchList, error) {
+	cfg, err := LoadConfigFile(cctx.String("config"))
+	if err != nil {
+		return nil, err
+	}
+
+	values := cfg.flagValues()
+	fromFile := configFileFlags(cctx)
+	value := func(name string) []string {
+		if cctx.IsSet(name) && !slices.Contains(fromFile, name) {
+			return cctx.StringSlice(name)
+		}
+		return values[name]
+	}
+
+	list := make(watchList)
+	for provider, flags := range watchListFlags {
+		watched := watchedTypes{value(flags.regions), value(flags.instanceTypes)}
+		if len(watched.regions) > 0 && len(watched.instanceTypes) == 0 {
+			return nil, fmt.Errorf("%s specified but no %s provided", flags.regions, flags.instanceTypes)
+		}
+		if _, _, err := splitAllRegions(provider, watched.regions); err != nil {
+			return nil, err
+		}
+		if _, globs := splitInstanceTypeGlobs(watched.instanceTypes); len(globs) > 0 {
+			if err := checkInstanceTypeGlobs(provider, globs); err != nil {
+				return nil, err
+			}
+		}
+		list[provider] = watched
+	}
+	return list, nil
+}
+
+// currentWatchList returns the regions and instance types each provider is configured with
+func (m *Monitor) currentWatchList() watchList {
+	list := make(watchList)
+	for provider, watched := range m.watching {
+		list[provider] = watchedTypes{slices.Clone(watched.regions), slices.Clone(watched.instanceTypes)}
+	}
+	return list
+}
+
+// watchedPairs returns every region and instance type pair the monitor prices
+func (m *Monitor) watchedPairs() map[vmFetch]bool {
+	pairs := make(map[vmFetch]bool)
+	for _, t := range m.pricingTargets() {
+		for _, region := range t.regions {
+			for _, instanceType := range t.instanceTypes {
+				pairs[vmFetch{t.name, region, instanceType, t.confidential}] = true
+			}
+		}
+	}
+	return pairs
+}
+
+// replaceWatched swaps the configured values of a list for the reloaded ones, keeping the values
+// added by discovery, fleets, or templates
+func replaceWatched(current, configured, reloaded []string) []string {
+	current = slices.DeleteFunc(slices.Clone(current), func(v string) bool {
+		return slices.Contains(configured, v) && !slices.Contains(reloaded, v)
+	})
+	return appendMissing(current, reloaded...)
+}
+
+// requestReload asks the poll loop to price a reloaded watch list. Only the latest of the lists
+// sent while one is pending is applied.
+func (m *Monitor) requestReload(list watchList) {
+	if m.reload == nil {
+		slog.Warn("ignoring reloaded config, pricing is not being polled")
+		return
+	}
+
+	select {
+	case <-m.reload:
+	default:
+	}
+	m.reload <- list
+}
+
+// applyWatchList replaces the configured regions and instance types with a reloaded list,
+// deleting the series of the pairs that are no longer monitored, and returns the pairs to
+// start fetching. Pairs that discovery, fleets, or templates still need come back on the next
+// poll.
+func (m *Monitor) applyWatchList(ctx context.Context, list watchList) []vmFetch {
+	if m.instanceTypeGlobs == nil {
+		m.instanceTypeGlobs = make(map[string][]string)
+	}
+	if m.allRegions == nil {
+		m.allRegions = make(map[string]bool)
+	}
+
+	before := m.watchedPairs()
+	for provider, watched := range list {
+		// The list was checked when it was loaded, and a shard keeps only the regions it owns as
+		// it did at startup
+		watched.regions, m.allRegions[provider], _ = splitAllRegions(provider, m.ownedRegions(provider, watched.regions))
+		watched.instanceTypes, m.instanceTypeGlobs[provider] = splitInstanceTypeGlobs(watched.instanceTypes)
+		list[provider] = watched
+	}
+	for _, provider := range m.registry.Names() {
+		watched, configured, reloaded := m.watch(provider), m.watched[provider], list[provider]
+		watched.regions = replaceWatched(watched.regions, configured.regions, reloaded.regions)
+		watched.instanceTypes = replaceWatched(watched.instanceTypes, configured.instanceTypes, reloaded.instanceTypes)
+	}
+	m.watched = list
+
+	// A provider that wasn't monitored before needs its fetcher, which also lists its regions
+	// and the types its patterns match
+	if err := m.initFetchers(ctx); err != nil {
+		slog.Error("failed to initialize fetchers for the reloaded config", "error", err)
+	}
+	m.listAllRegions(ctx)
+	m.expandInstanceTypeGlobs(ctx)
+
+	after := m.watchedPairs()
+	removed := m.forgetPairs(before, after)
+
+	var added []vmFetch
+	for f := range after {
+		if !before[f] && m.provider(f.provider) != nil {
+			added = append(added, f)
+		}
+	}
+
+	slog.Info("reloaded watch list", "added", len(added), "removed", removed)
+	return added
+}
+
+// forgetPairs deletes the series and fetch state of the pairs priced before that no longer are,
+// and returns how many there were
+func (m *Monitor) forgetPairs(before, after map[vmFetch]bool) int {
+	removed := 0
+	for f := range before {
+		if after[f] {
+			continue
+		}
+		m.metrics.DeletePricing(f.provider, f.region, f.instanceType)
+		m.snapshot.Delete(f.provider, f.region, f.instanceType)
+		m.statuses.Delete(f.provider, f.region, f.instanceType)
+		if m.schedule != nil {
+			m.schedule.Forget(scheduledSeries{seriesOnDemand, f.provider, f.region, f.instanceType})
+			m.schedule.Forget(scheduledSeries{seriesSpot, f.provider, f.region, f.instanceType})
+		}
+		removed++
+	}
+	return removed
+}
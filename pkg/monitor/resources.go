@@ -0,0 +1,215 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shopspring/decimal"
+)
+
+// ResourceKind is a kind of resource other than VMs whose unit prices are exported, such as
+// storage. Each kind is exported as one gauge, labelled by provider, region, the resource within
+// the kind, and the dimension and unit its price is for.
+type ResourceKind struct {
+	Name   string
+	Metric string
+	Help   string
+	// Label is the label naming the resource within the kind, such as storage_type
+	Label string
+}
+
+var (
+	storageKind = ResourceKind{
+		Name:   "storage",
+		Metric: "cloud_storage_unit_cost",
+		Help:   "On-demand price in USD of one unit of a dimension a storage type is billed by, such as a GB-month of capacity, a provisioned IOPS-month, or a GB transferred",
+		Label:  "storage_type",
+	}
+	serviceKind = ResourceKind{
+		Name:   "service",
+		Metric: "cloud_service_unit_cost",
+		Help:   "On-demand price in USD of one unit of a dimension a platform service is billed by, such as 10,000 KMS requests, a secret-month, or a GB of logs ingested",
+		Label:  "service",
+	}
+)
+
+// resourceKinds are the kinds of resources that are priced, each registered as a gauge
+var resourceKinds = []ResourceKind{storageKind, serviceKind}
+
+// ResourceDimension is the on-demand price in USD of one unit of a dimension a resource is
+// billed by, such as a GB-month of capacity or 10,000 requests
+type ResourceDimension struct {
+	Dimension string
+	Unit      string
+	Price     decimal.Decimal
+}
+
+// ResourcePrice is the unit prices of a resource in a region, by the dimensions it is billed by
+type ResourcePrice struct {
+	Kind     string
+	Provider string
+	Region   string
+	// Name identifies the resource within its kind, such as the gp3 volume type
+	Name       string
+	Dimensions []ResourceDimension
+}
+
+// resourceFetcher prices resources of one kind in each region of a provider
+type resourceFetcher struct {
+	provider string
+	// name describes the resources in logs
+	name    string
+	enabled func(m *Monitor) bool
+	fetch   func(ctx context.Context, m *Monitor, region string) ([]ResourcePrice, error)
+}
+
+// resourceFetchers are the fetchers of every resource the monitor can price. A new resource is
+// priced by adding a fetcher, and a kind to resourceKinds if it needs its own gauge.
+var resourceFetchers = []resourceFetcher{
+	{"aws", "EBS volumes", func(m *Monitor) bool {
+		return len(m.awsVolumeTypes) > 0
+	}, func(ctx context.Context, m *Monitor, region string) ([]ResourcePrice, error) {
+		prices, err := m.awsFetcher.FetchVolumePricing(ctx, region, m.awsVolumeTypes)
+		if err == nil {
+			m.recordDiskAvailability("aws", region, m.awsVolumeTypes, prices)
+		}
+		return prices, err
+	}},
+	{"gcp", "persistent disks", func(m *Monitor) bool {
+		return len(m.gcpDiskTypes) > 0
+	}, func(ctx context.Context, m *Monitor, region string) ([]ResourcePrice, error) {
+		prices, err := m.gcpFetcher.FetchDiskPricing(ctx, region, m.gcpDiskTypes)
+		if err == nil {
+			m.recordDiskAvailability("gcp", region, m.gcpDiskTypes, prices)
+		}
+		return prices, err
+	}},
+	{"aws", "EFS", func(m *Monitor) bool {
+		return m.fileStorage
+	}, func(ctx context.Context, m *Monitor, region string) ([]ResourcePrice, error) {
+		return m.awsFetcher.FetchEFSPricing(ctx, region)
+	}},
+	{"gcp", "Filestore", func(m *Monitor) bool {
+		return m.fileStorage
+	}, func(ctx context.Context, m *Monitor, region string) ([]ResourcePrice, error) {
+		return m.gcpFetcher.FetchFilestorePricing(ctx, region)
+	}},
+	{"aws", "AWS Backup", func(m *Monitor) bool {
+		return m.backupPricing
+	}, func(ctx context.Context, m *Monitor, region string) ([]ResourcePrice, error) {
+		return m.awsFetcher.FetchBackupPricing(ctx, region)
+	}},
+	{"gcp", "Backup and DR", func(m *Monitor) bool {
+		return m.backupPricing
+	}, func(ctx context.Context, m *Monitor, region string) ([]ResourcePrice, error) {
+		return m.gcpFetcher.FetchBackupPricing(ctx, region)
+	}},
+	{"aws", "platform services", func(m *Monitor) bool {
+		return m.platformServices
+	}, func(ctx context.Context, m *Monitor, region string) ([]ResourcePrice, error) {
+		return m.awsFetcher.FetchPlatformPricing(ctx, region)
+	}},
+	{"gcp", "platform services", func(m *Monitor) bool {
+		return m.platformServices
+	}, func(ctx context.Context, m *Monitor, region string) ([]ResourcePrice, error) {
+		return m.gcpFetcher.FetchPlatformPricing(ctx, region)
+	}},
+}
+
+// resourcePrices returns the prices of resources of a kind in a region, from the dimensions of
+// each resource by name
+func resourcePrices(kind, provider, region string, dimensions map[string][]ResourceDimension) []ResourcePrice {
+	prices := make([]ResourcePrice, 0, len(dimensions))
+	for _, name := range slices.Sorted(maps.Keys(dimensions)) {
+		prices = append(prices, ResourcePrice{
+			Kind:       kind,
+			Provider:   provider,
+			Region:     region,
+			Name:       name,
+			Dimensions: dimensions[name],
+		})
+	}
+	return prices
+}
+
+// resourceGauge is the gauge a resource kind is exported as
+type resourceGauge struct {
+	kind  ResourceKind
+	gauge *prometheus.GaugeVec
+}
+
+// newResourceGauges creates a gauge for each resource kind
+func newResourceGauges(f *metricsFactory) map[string]resourceGauge {
+	gauges := make(map[string]resourceGauge, len(resourceKinds))
+	for _, kind := range resourceKinds {
+		gauges[kind.Name] = resourceGauge{kind, f.gaugeVec(
+			prometheus.GaugeOpts{Name: kind.Metric, Help: kind.Help},
+			[]string{"provider", "region", kind.Label, "dimension", "unit"},
+		)}
+	}
+	return gauges
+}
+
+// RecordResourcePrices records the unit prices of resources, each to the gauge of its kind
+func (m *Metrics) RecordResourcePrices(prices []ResourcePrice) {
+	for _, p := range prices {
+		g, ok := m.resources[p.Kind]
+		if !ok {
+			slog.Warn("skipping price of unknown resource kind", "kind", p.Kind, "name", p.Name)
+			continue
+		}
+		for _, d := range p.Dimensions {
+			g.gauge.With(prometheus.Labels{
+				"provider":   p.Provider,
+				"region":     p.Region,
+				g.kind.Label: p.Name,
+				"dimension":  d.Dimension,
+				"unit":       d.Unit,
+			}).Set(m.rounding.Float(d.Price))
+		}
+	}
+}
+
+// recordResourcePrices exports the prices of every enabled resource in every region of its
+// provider
+func (m *Monitor) recordResourcePrices(ctx context.Context) {
+	for _, f := range resourceFetchers {
+		if !f.enabled(m) {
+			continue
+		}
+
+		var regions []string
+		switch {
+		case f.provider == "aws" && m.awsFetcher != nil:
+			regions = m.watch("aws").regions
+		case f.provider == "gcp" && m.gcpFetcher != nil:
+			regions = m.watch("gcp").regions
+		}
+
+		for _, region := range regions {
+			prices, err := f.fetch(ctx, m, region)
+			if err != nil {
+				slog.Error("failed to fetch resource pricing",
+					"provider", f.provider,
+					"resources", f.name,
+					"region", region,
+					"reason", errorReason(err),
+					"error", err,
+				)
+				continue
+			}
+			m.metrics.RecordResourcePrices(prices)
+		}
+	}
+}
+
+// recordDiskAvailability exports which of the requested disk types a region offers, which are
+// those the region's catalog priced
+func (m *Monitor) recordDiskAvailability(provider, region string, diskTypes []string, prices []ResourcePrice) {
+	m.recordServiceAvailability(provider, region, serviceDiskType, diskTypes, func(diskType string) bool {
+		return slices.ContainsFunc(prices, func(p ResourcePrice) bool { return p.Name == diskType })
+	})
+}
@@ -0,0 +1,67 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+)
+
+// maxFetchRetryBackoff caps the backoff between retries of a price fetch
+const maxFetchRetryBackoff = 30 * time.Second
+
+// retryPolicy retries the price fetches that failed for a reason that may not last. The zero
+// policy doesn't retry.
+type retryPolicy struct {
+	// retries is how many times a fetch is retried after its first attempt
+	retries int
+	// backoff is the backoff before the first retry, doubled on every retry after it
+	backoff time.Duration
+}
+
+// delay returns the backoff before a retry, counting from 0, with half of it jittered so that
+// fetches throttled together don't retry together
+func (p retryPolicy) delay(retry int) time.Duration {
+	d := min(p.backoff<<min(retry, 16), maxFetchRetryBackoff)
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryReason tells whether a failed fetch is worth retrying, and why: the provider throttled
+// it, its API failed with a server error, or the network failed on the way. A missing price,
+// rejected credentials, and an invalid response fail the same way when retried.
+func retryReason(err error) (string, bool) {
+	err = classifyError(err)
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "", false
+	case errors.Is(err, errThrottled):
+		return "throttled", true
+	case errors.Is(err, errUnavailable):
+		return "unavailable", true
+	case errors.As(err, &netErr), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED):
+		return "network", true
+	default:
+		return "", false
+	}
+}
+
+// sleepContext waits for a duration, or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// roundingHalfEven rounds ties to the nearest even digit (banker's rounding)
+	roundingHalfEven = "half-even"
+	// roundingHalfUp rounds ties away from zero
+	roundingHalfUp = "half-up"
+)
+
+// PriceRounding is the precision prices are published at, in the metrics, the API, and
+// exported records. Prices are kept at full precision internally and derived prices, such as
+// the cost per GB, are computed before rounding. The zero value leaves prices unrounded.
+type PriceRounding struct {
+	decimalPlaces     int
+	significantDigits int
+	mode              string
+}
+
+// NewPriceRounding creates a rounding policy to a number of decimal places or of significant
+// digits, at most one of which may be set. Zero for both leaves prices unrounded.
+func NewPriceRounding(decimalPlaces, significantDigits int, mode string) (PriceRounding, error) {
+	if decimalPlaces < 0 || significantDigits < 0 {
+		return PriceRounding{}, fmt.Errorf("price decimal places and significant digits must not be negative")
+	}
+	if decimalPlaces > 0 && significantDigits > 0 {
+		return PriceRounding{}, fmt.Errorf("price decimal places and significant digits can't both be set")
+	}
+
+	if mode != roundingHalfEven && mode != roundingHalfUp {
+		return PriceRounding{}, fmt.Errorf("unknown price rounding mode %q (expected %q or %q)", mode, roundingHalfEven, roundingHalfUp)
+	}
+
+	return PriceRounding{
+		decimalPlaces:     decimalPlaces,
+		significantDigits: significantDigits,
+		mode:              mode,
+	}, nil
+}
+
+// Round rounds a price to the policy's precision
+func (r PriceRounding) Round(v decimal.Decimal) decimal.Decimal {
+	if r.decimalPlaces == 0 && r.significantDigits == 0 || v.IsZero() {
+		return v
+	}
+
+	places := int32(r.decimalPlaces)
+	if r.significantDigits > 0 {
+		// The most significant digit of coefficient × 10^exponent is at 10^(digits-1+exponent)
+		places = int32(r.significantDigits-v.NumDigits()) - v.Exponent()
+	}
+
+	if r.mode == roundingHalfUp {
+		return v.Round(places)
+	}
+	return v.RoundBank(places)
+}
+
+// Float rounds a price and converts it to the float64 published in metrics and APIs
+func (r PriceRounding) Float(v decimal.Decimal) float64 {
+	return r.Round(v).InexactFloat64()
+}
+
+// Pricing returns a copy of a price with its cost rounded
+func (r PriceRounding) Pricing(p VMPricing) VMPricing {
+	p.TotalCost = r.Round(p.TotalCost)
+	return p
+}
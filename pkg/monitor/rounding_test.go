@@ -0,0 +1,80 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPriceRoundingRound(t *testing.T) {
+	tests := []struct {
+		decimalPlaces     int
+		significantDigits int
+		mode              string
+		price             string
+		want              string
+	}{
+		{0, 0, roundingHalfEven, "0.123456789", "0.123456789"},
+		{2, 0, roundingHalfEven, "0.125", "0.12"},
+		{2, 0, roundingHalfUp, "0.125", "0.13"},
+		{3, 0, roundingHalfEven, "0.0965", "0.096"},
+		{3, 0, roundingHalfUp, "0.0965", "0.097"},
+		{4, 0, roundingHalfEven, "0.0965", "0.0965"},
+		{2, 0, roundingHalfUp, "-0.125", "-0.13"},
+		{0, 3, roundingHalfEven, "0.0012345", "0.00123"},
+		{0, 2, roundingHalfEven, "0.0125", "0.012"},
+		{0, 2, roundingHalfUp, "0.0125", "0.013"},
+		{0, 2, roundingHalfUp, "1234.5", "1200"},
+		{0, 3, roundingHalfEven, "0", "0"},
+		// 0.1 + 0.2 rounds to 0.3 exactly
+		{4, 0, roundingHalfEven, "0.30000000000000004", "0.3"},
+	}
+
+	for _, tt := range tests {
+		rounding, err := NewPriceRounding(tt.decimalPlaces, tt.significantDigits, tt.mode)
+		if err != nil {
+			t.Errorf("NewPriceRounding(%d, %d, %q) failed: %v", tt.decimalPlaces, tt.significantDigits, tt.mode, err)
+			continue
+		}
+		got := rounding.Round(decimal.RequireFromString(tt.price))
+		if !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("Round(%s) to %d places, %d digits, %s = %s, want %s",
+				tt.price, tt.decimalPlaces, tt.significantDigits, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestPriceRoundingFloat(t *testing.T) {
+	rounding, err := NewPriceRounding(2, 0, roundingHalfUp)
+	if err != nil {
+		t.Fatalf("NewPriceRounding failed: %v", err)
+	}
+	if got := rounding.Float(decimal.RequireFromString("0.125")); got != 0.13 {
+		t.Errorf("Float(0.125) = %v, want 0.13", got)
+	}
+	if got := (PriceRounding{}).Float(decimal.RequireFromString("0.1").Add(decimal.RequireFromString("0.2"))); got != 0.3 {
+		t.Errorf("unrounded Float(0.1 + 0.2) = %v, want 0.3", got)
+	}
+}
+
+func TestNewPriceRoundingErrors(t *testing.T) {
+	tests := []struct {
+		decimalPlaces     int
+		significantDigits int
+		mode              string
+		err               string
+	}{
+		{-1, 0, roundingHalfEven, "must not be negative"},
+		{0, -1, roundingHalfEven, "must not be negative"},
+		{2, 3, roundingHalfEven, "can't both be set"},
+		{2, 0, "half-down", `unknown price rounding mode "half-down"`},
+	}
+
+	for _, tt := range tests {
+		_, err := NewPriceRounding(tt.decimalPlaces, tt.significantDigits, tt.mode)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("NewPriceRounding(%d, %d, %q) error = %v, want %q", tt.decimalPlaces, tt.significantDigits, tt.mode, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PriceScenario is a set of hypothetical price changes, such as +5% on AWS compute from Q3,
+// that every published price is exported under next to its actual value
+type PriceScenario struct {
+	Name    string           `json:"name"`
+	Changes []ScenarioChange `json:"changes"`
+}
+
+// ScenarioChange changes the prices of the series it matches by a percentage. Empty provider,
+// region, and instance type fields match anything, and the instance type may be a glob such as
+// m5.*. Changes apply to both variants of an instance type.
+type ScenarioChange struct {
+	Provider     string `json:"provider,omitempty"`
+	Region       string `json:"region,omitempty"`
+	InstanceType string `json:"instance_type,omitempty"`
+
+	// ChangePercent is by how much the price changes, negative for a cut
+	ChangePercent float64 `json:"change_percent"`
+	// Effective is the date (YYYY-MM-DD, UTC) the change takes effect on. A change without one
+	// is in effect already.
+	Effective string `json:"effective,omitempty"`
+
+	effective time.Time
+}
+
+// PriceScenarios are the scenarios prices are exported under
+type PriceScenarios []PriceScenario
+
+// LoadPriceScenarios reads scenarios from a JSON file holding a list of named scenarios, each
+// with a list of changes
+func LoadPriceScenarios(file string) (PriceScenarios, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenarios file: %w", err)
+	}
+
+	var scenarios PriceScenarios
+	if err := json.Unmarshal(data, &scenarios); err != nil {
+		return nil, fmt.Errorf("failed to parse scenarios file: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for i := range scenarios {
+		s := &scenarios[i]
+		if s.Name == "" {
+			return nil, fmt.Errorf("scenario %d has no name", i)
+		}
+		if names[s.Name] {
+			return nil, fmt.Errorf("scenario %q is defined more than once", s.Name)
+		}
+		names[s.Name] = true
+		if len(s.Changes) == 0 {
+			return nil, fmt.Errorf("scenario %q has no changes", s.Name)
+		}
+
+		for j := range s.Changes {
+			c := &s.Changes[j]
+			if _, err := path.Match(c.InstanceType, ""); err != nil {
+				return nil, fmt.Errorf("scenario %q change %d has an invalid instance type pattern %q: %w", s.Name, j, c.InstanceType, err)
+			}
+			if c.ChangePercent <= -100 {
+				return nil, fmt.Errorf("scenario %q change %d must leave a price above zero", s.Name, j)
+			}
+			if c.Effective != "" {
+				if c.effective, err = time.Parse(time.DateOnly, c.Effective); err != nil {
+					return nil, fmt.Errorf("scenario %q change %d has an invalid effective date: %w", s.Name, j, err)
+				}
+			}
+		}
+	}
+
+	return scenarios, nil
+}
+
+// matches reports whether a change applies to a series
+func (c ScenarioChange) matches(p VMPricing) bool {
+	if c.Provider != "" && c.Provider != p.Provider {
+		return false
+	}
+	if c.Region != "" && c.Region != p.Region {
+		return false
+	}
+	if c.InstanceType == "" {
+		return true
+	}
+	matched, _ := path.Match(c.InstanceType, p.InstanceType)
+	return matched
+}
+
+// Cost returns the price of a series under the scenario at a time. Every matching change in
+// effect by then applies, each on top of the ones before it.
+func (s PriceScenario) Cost(p VMPricing, at time.Time) decimal.Decimal {
+	cost := p.TotalCost
+	hundred := decimal.NewFromInt(100)
+	for _, c := range s.Changes {
+		if !c.matches(p) || at.Before(c.effective) {
+			continue
+		}
+		cost = cost.Mul(hundred.Add(decimal.NewFromFloat(c.ChangePercent)).Div(hundred))
+	}
+	return cost
+}
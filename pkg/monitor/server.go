@@ -0,0 +1,85 @@
+package monitor
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/go-util/pkg/telemetry"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	cli "github.com/urfave/cli/v2"
+)
+
+// Server is where RunWith mounts the monitor's metrics and API. Its zero value is what Run
+// uses: the default mux and registry, served on --metrics-listen-address.
+type Server struct {
+	// Mux receives the monitor's handlers. A program that passes its own serves it itself, so
+	// --metrics-listen-address is ignored.
+	Mux *http.ServeMux
+
+	// Registerer is what the monitor's metrics are registered with, and Gatherer what they
+	// are exposed from. Gatherer defaults to Registerer when it is a *prometheus.Registry.
+	Registerer prometheus.Registerer
+	Gatherer   prometheus.Gatherer
+}
+
+// withDefaults fills in the default mux and registry for what a program didn't set
+func (s Server) withDefaults() Server {
+	if s.Registerer == nil {
+		s.Registerer = prometheus.DefaultRegisterer
+	}
+	if s.Gatherer == nil {
+		if gatherer, ok := s.Registerer.(prometheus.Gatherer); ok {
+			s.Gatherer = gatherer
+		} else {
+			s.Gatherer = prometheus.DefaultGatherer
+		}
+	}
+	return s
+}
+
+// serveMetrics exposes the gathered metrics at --metrics-path, and those of each provider
+// below it, with the help text of the --config file and the metrics as they were before their
+// migrations when --legacy-metrics is set. Without a mux of its own, the default mux is served on
+// --metrics-listen-address.
+func (s Server) serveMetrics(cctx *cli.Context) (*http.ServeMux, error) {
+	path := cctx.String("metrics-path")
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("invalid metrics-path %q: must start with /", path)
+	}
+
+	gatherer := s.Gatherer
+	if overrides := configMetricHelp(cctx); len(overrides) > 0 {
+		gatherer = helpGatherer{gatherer, overrides}
+	}
+	if cctx.Bool("legacy-metrics") {
+		gatherer = legacyGatherer{gatherer, activeMigrations(time.Now())}
+	}
+
+	mux := s.Mux
+	switch {
+	case mux != nil:
+		mux.Handle("GET "+path, promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
+	case gatherer == prometheus.DefaultGatherer:
+		telemetry.StartMetrics(cctx, telemetry.WithPath(path))
+		mux = http.DefaultServeMux
+	default:
+		// telemetry only serves the default registry as it is, so anything else needs a listener
+		// of its own
+		mux = http.DefaultServeMux
+		mux.Handle("GET "+path, promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
+		if addr := cctx.String("metrics-listen-address"); addr != "" {
+			go func() {
+				if err := http.ListenAndServe(addr, mux); err != nil {
+					slog.Error("metrics server failed", "error", err)
+				}
+			}()
+		}
+	}
+
+	mux.Handle("GET "+strings.TrimSuffix(path, "/")+"/{provider}", filteredMetricsHandler(gatherer))
+	return mux, nil
+}
@@ -0,0 +1,150 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ShardConfig splits the monitored provider regions across several monitor instances. Every
+// shard computes the same assignment from the full configuration, so any of them can describe
+// the whole deployment for service discovery.
+type ShardConfig struct {
+	Count int
+	Index int
+
+	// Peers are the scrape addresses of every shard, in shard index order
+	Peers []string
+}
+
+func NewShardConfig(count, index int, peers []string) (*ShardConfig, error) {
+	if count < 1 {
+		return nil, fmt.Errorf("shard count must be at least 1")
+	}
+
+	if index < 0 || index >= count {
+		return nil, fmt.Errorf("shard index %d out of range for %d shards", index, count)
+	}
+
+	if len(peers) > 0 && len(peers) != count {
+		return nil, fmt.Errorf("expected %d shard peers, got %d", count, len(peers))
+	}
+
+	return &ShardConfig{
+		Count: count,
+		Index: index,
+		Peers: peers,
+	}, nil
+}
+
+// shardOf returns the shard that monitors a provider region
+func (c *ShardConfig) shardOf(provider, region string) int {
+	h := fnv.New32a()
+	h.Write([]byte(provider + "/" + region))
+	return int(h.Sum32() % uint32(c.Count))
+}
+
+// Filter returns the regions of a provider owned by this shard. The all keyword is kept by every
+// shard, which filters the regions it lists in turn.
+func (c *ShardConfig) Filter(provider string, regions []string) []string {
+	var owned []string
+	for _, region := range regions {
+		if region == allRegions || c.shardOf(provider, region) == c.Index {
+			owned = append(owned, region)
+		}
+	}
+	return owned
+}
+
+// ownedRegions returns the regions of a provider this replica's shard owns, which are all of
+// them when the monitor isn't sharded
+func (m *Monitor) ownedRegions(provider string, regions []string) []string {
+	if m.shards == nil {
+		return regions
+	}
+	return m.shards.Filter(provider, regions)
+}
+
+// TargetGroup is a Prometheus HTTP and file service discovery target group
+type TargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// TargetGroups describes one scrape target per shard and provider it monitors. Each target
+// scrapes the provider's filtered path below metricsPath so shards never export overlapping
+// series. Which regions a provider monitored in all of them has is only known at runtime, so it
+// gets a target on every shard.
+func (c *ShardConfig) TargetGroups(metricsPath string, providers []string, regions map[string][]string) []TargetGroup {
+	groups := []TargetGroup{}
+	for shard, peer := range c.Peers {
+		for _, provider := range providers {
+			owned := false
+			for _, region := range regions[provider] {
+				if region == allRegions || c.shardOf(provider, region) == shard {
+					owned = true
+					break
+				}
+			}
+			if !owned {
+				continue
+			}
+
+			groups = append(groups, TargetGroup{
+				Targets: []string{peer},
+				Labels: map[string]string{
+					"__metrics_path__": strings.TrimSuffix(metricsPath, "/") + "/" + provider,
+					"shard":            strconv.Itoa(shard),
+				},
+			})
+		}
+	}
+	return groups
+}
+
+// sdHandler serves the target groups in the Prometheus HTTP SD format
+func sdHandler(groups []TargetGroup) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(groups); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// writeSDFile writes the target groups in the Prometheus file SD format. The file is replaced
+// atomically since Prometheus watches it for changes.
+func writeSDFile(path string, groups []TargetGroup) error {
+	data, err := json.MarshalIndent(groups, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode service discovery targets: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".sd-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write service discovery file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write service discovery file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write service discovery file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write service discovery file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write service discovery file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/jazware/cloud-pricing-monitor/pkg/client"
+	"github.com/shopspring/decimal"
+)
+
+// hoursPerMonth is the average month used to turn hourly prices into monthly costs
+const hoursPerMonth = 730
+
+// maxSimulationBodyBytes bounds the size of a simulation request
+const maxSimulationBodyBytes = 1 << 20
+
+// Simulate prices a hypothetical fleet at the published prices. Items whose series isn't
+// monitored are reported as unpriced and left out of the totals, so the result is only
+// complete when Unpriced is empty. Costs are summed exactly and only rounded for the result.
+func Simulate(req client.SimulationRequest, snapshot *PriceSnapshot, rounding PriceRounding) *client.SimulationResult {
+	result := &client.SimulationResult{
+		Items: []client.SimulationLine{},
+	}
+
+	var hourly decimal.Decimal
+	providers := make(map[string]decimal.Decimal)
+	regions := make(map[string]decimal.Decimal)
+	for _, item := range req.Items {
+		if item.PricingModel == "" {
+			item.PricingModel = client.PricingOnDemand
+		}
+
+		entry, ok := snapshot.Get(simulationKey(item))
+		if !ok {
+			result.Unpriced = append(result.Unpriced, client.UnpricedItem{
+				SimulationItem: item,
+				Error:          "no published price for this instance type, region, and pricing model",
+			})
+			continue
+		}
+
+		lineHourly := entry.Pricing.TotalCost.Mul(decimal.NewFromFloat(item.Count))
+		result.Items = append(result.Items, client.SimulationLine{
+			SimulationItem:  item,
+			UnitCostPerHour: rounding.Float(entry.Pricing.TotalCost),
+			HourlyCost:      rounding.Float(lineHourly),
+			MonthlyCost:     rounding.Float(monthlyCost(lineHourly)),
+		})
+
+		hourly = hourly.Add(lineHourly)
+		providers[item.Provider] = providers[item.Provider].Add(lineHourly)
+		region := item.Provider + "/" + item.Region
+		regions[region] = regions[region].Add(lineHourly)
+	}
+
+	result.HourlyCost = rounding.Float(hourly)
+	result.MonthlyCost = rounding.Float(monthlyCost(hourly))
+	result.Providers = simulationCosts(providers, rounding)
+	result.Regions = simulationCosts(regions, rounding)
+	return result
+}
+
+// simulationKey returns the series a simulation item is priced from
+func simulationKey(item client.SimulationItem) PriceKey {
+	return PriceKey{
+		Provider:     item.Provider,
+		Region:       item.Region,
+		InstanceType: item.InstanceType,
+		Confidential: item.PricingModel == client.PricingConfidential,
+	}
+}
+
+// monthlyCost returns the cost of running for an average month at an hourly cost
+func monthlyCost(hourly decimal.Decimal) decimal.Decimal {
+	return hourly.Mul(decimal.NewFromInt(hoursPerMonth))
+}
+
+// simulationCosts converts exact hourly subtotals to the rounded costs of a result
+func simulationCosts(hourly map[string]decimal.Decimal, rounding PriceRounding) map[string]*client.SimulationCost {
+	costs := make(map[string]*client.SimulationCost, len(hourly))
+	for name, cost := range hourly {
+		costs[name] = &client.SimulationCost{
+			HourlyCost:  rounding.Float(cost),
+			MonthlyCost: rounding.Float(monthlyCost(cost)),
+		}
+	}
+	return costs
+}
+
+// validateSimulation checks that every item names a series and a supported pricing model
+func validateSimulation(r client.SimulationRequest) error {
+	if len(r.Items) == 0 {
+		return fmt.Errorf("no items to simulate")
+	}
+
+	for i, item := range r.Items {
+		if item.Provider == "" || item.Region == "" || item.InstanceType == "" {
+			return fmt.Errorf("item %d must have a provider, region, and instance_type", i)
+		}
+		if item.Count < 0 {
+			return fmt.Errorf("item %d count must not be negative", i)
+		}
+		switch item.PricingModel {
+		case "", client.PricingOnDemand, client.PricingConfidential:
+		default:
+			return fmt.Errorf("item %d has unknown pricing_model %q (expected %s or %s)", i, item.PricingModel, client.PricingOnDemand, client.PricingConfidential)
+		}
+	}
+	return nil
+}
+
+// simulateHandler serves what-if cost simulations of hypothetical fleets
+func simulateHandler(snapshot *PriceSnapshot, rounding PriceRounding) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req client.SimulationRequest
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSimulationBodyBytes))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid simulation request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if err := validateSimulation(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(Simulate(req, snapshot, rounding)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
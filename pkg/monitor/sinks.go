@@ -0,0 +1,151 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// sinkPushTimeout bounds a single push so a stalled sink can't hold up the others
+const sinkPushTimeout = 30 * time.Second
+
+// Sink pushes the published prices to a monitoring system that doesn't scrape the metrics
+// endpoint. Sinks run alongside the metrics endpoint, and any number of them can be enabled.
+type Sink interface {
+	Name() string
+	Push(ctx context.Context, samples []SinkSample) error
+	Close() error
+}
+
+// SinkLabel is a label of a pushed sample, in the order the metrics endpoint exports them
+type SinkLabel struct {
+	Name  string
+	Value string
+}
+
+// SinkSample is one value of a pricing gauge pushed to a sink
+type SinkSample struct {
+	// Name is the name the gauge is exported under by the metrics endpoint
+	Name   string
+	Labels []SinkLabel
+	Value  float64
+	// Time is when the price was last published
+	Time time.Time
+}
+
+// sinkPath names a sample for systems without labels, by appending the label values to its
+// name, such as cloud_vm_total_cost_per_hour.aws.us-east-1.m5_large.false
+func sinkPath(prefix string, sample SinkSample) string {
+	var b strings.Builder
+	b.WriteString(prefix)
+	b.WriteString(sample.Name)
+	for _, label := range sample.Labels {
+		b.WriteByte('.')
+		b.WriteString(sinkPathComponent(label.Value))
+	}
+	return b.String()
+}
+
+// sinkPathComponent replaces the characters that would split a path component, such as the
+// dot in m5.large, with underscores. An empty value is an underscore too, since an empty
+// component would be collapsed and shift the ones after it.
+func sinkPathComponent(s string) string {
+	if s == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// sinkSamples returns the per-series pricing gauges of published prices, as exported by the
+// metrics endpoint
+func sinkSamples(entries []PriceEntry, rounding PriceRounding, memoryUnit MemoryUnit, naming *MetricNaming) []SinkSample {
+	var samples []SinkSample
+	for _, entry := range entries {
+		p := entry.Pricing
+		add := func(metric string, v float64) {
+			name, labels, err := naming.Name(metric, p)
+			if err != nil {
+				naming.warnOnce("skipping series that can't be named", err)
+				return
+			}
+			samples = append(samples, SinkSample{Name: name, Labels: labels, Value: v, Time: entry.UpdatedAt})
+		}
+
+		add("cloud_vm_total_cost_per_hour", rounding.Float(p.TotalCost))
+		if !entry.ChangedAt.IsZero() {
+			add("cloud_vm_previous_cost_per_hour", rounding.Float(entry.PreviousCost))
+		}
+		if cost, ok := p.CostPerMemory(memoryUnit); ok {
+			add("cloud_vm_cost_per_gb_hour", rounding.Float(cost))
+		}
+		if cost, ok := p.CostPerVCPU(); ok {
+			add("cloud_vm_cost_per_vcpu_hour", rounding.Float(cost))
+		}
+	}
+	return samples
+}
+
+// startSinks pushes the published prices to every sink now and then at each push interval,
+// until the context is cancelled
+func (m *Monitor) startSinks(ctx context.Context) {
+	if len(m.sinks) == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(m.pushInterval)
+		defer ticker.Stop()
+
+		for {
+			m.pushSinks(ctx)
+
+			select {
+			case <-ctx.Done():
+				for _, sink := range m.sinks {
+					if err := sink.Close(); err != nil {
+						slog.Warn("failed to close sink", "sink", sink.Name(), "error", err)
+					}
+				}
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// pushSinks pushes the current snapshot to every sink
+func (m *Monitor) pushSinks(ctx context.Context) {
+	samples := sinkSamples(m.snapshot.Entries(), m.rounding, m.metrics.memoryUnit, m.naming)
+	if len(m.legacyMetrics) > 0 {
+		samples = legacySamples(samples, m.legacyMetrics)
+	}
+	if len(samples) == 0 {
+		return
+	}
+
+	for _, sink := range m.sinks {
+		pushCtx, cancel := context.WithTimeout(ctx, sinkPushTimeout)
+		err := sink.Push(pushCtx, samples)
+		cancel()
+
+		result := "pushed"
+		if err != nil {
+			result = "failed"
+			slog.Error("failed to push prices to sink", "sink", sink.Name(), "samples", len(samples), "error", err)
+		}
+		m.metrics.SinkPushes.With(prometheus.Labels{
+			"sink":   sink.Name(),
+			"result": result,
+		}).Inc()
+	}
+}
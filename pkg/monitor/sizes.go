@@ -0,0 +1,267 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// awsNamedSizes are the EC2 sizes below xlarge, in xlarge units
+var awsNamedSizes = map[string]float64{
+	"nano":   1.0 / 32,
+	"micro":  1.0 / 16,
+	"small":  1.0 / 8,
+	"medium": 1.0 / 4,
+	"large":  1.0 / 2,
+	"xlarge": 1,
+}
+
+// awsSizeUnits returns the size of an EC2 size name like 4xlarge in xlarge units. Metal and
+// other sizes without a fixed place in the ladder are not sized.
+func awsSizeUnits(size string) (float64, bool) {
+	if units, ok := awsNamedSizes[size]; ok {
+		return units, true
+	}
+
+	n, ok := strings.CutSuffix(size, "xlarge")
+	if !ok {
+		return 0, false
+	}
+	units, err := strconv.Atoi(n)
+	if err != nil || units <= 0 {
+		return 0, false
+	}
+	return float64(units), true
+}
+
+// awsSizeSteps returns the next smaller and larger instance types of the same family in the
+// catalog, or empty strings at either end of the family
+func awsSizeSteps(instanceType string, catalog []string) (down, up string) {
+	family, size, ok := strings.Cut(instanceType, ".")
+	if !ok {
+		return "", ""
+	}
+	units, ok := awsSizeUnits(size)
+	if !ok {
+		return "", ""
+	}
+
+	downUnits, upUnits := 0.0, 0.0
+	for _, candidate := range catalog {
+		f, s, ok := strings.Cut(candidate, ".")
+		if !ok || f != family {
+			continue
+		}
+		u, ok := awsSizeUnits(s)
+		if !ok {
+			continue
+		}
+
+		if u < units && u > downUnits {
+			down, downUnits = candidate, u
+		}
+		if u > units && (upUnits == 0 || u < upUnits) {
+			up, upUnits = candidate, u
+		}
+	}
+	return down, up
+}
+
+// gcpSizeSteps returns the machine types of the same family and class in the catalog with the
+// next smaller and larger vCPU counts, or empty strings at either end. Families skip counts
+// (n2-standard starts at 2, c2-standard at 4), so steps only come from the catalog. Shared-core
+// e2 types step through micro, small, and medium; other shared-core, custom, and
+// accelerator-optimized types, whose sizes scale with their GPUs, have no steps.
+func gcpSizeSteps(machineType string, catalog []string) (down, up string) {
+	if i := slices.Index(gcpSharedCoreE2, machineType); i >= 0 {
+		if i > 0 {
+			down = gcpSharedCoreE2[i-1]
+		}
+		if i+1 < len(gcpSharedCoreE2) {
+			up = gcpSharedCoreE2[i+1]
+		}
+		return down, up
+	}
+
+	prefix, vcpus, ok := gcpSizedMachineType(machineType)
+	if !ok {
+		return "", ""
+	}
+
+	downVCPUs, upVCPUs := 0, 0
+	for _, candidate := range catalog {
+		p, n, ok := gcpSizedMachineType(candidate)
+		if !ok || p != prefix {
+			continue
+		}
+
+		if n < vcpus && n > downVCPUs {
+			down, downVCPUs = candidate, n
+		}
+		if n > vcpus && (upVCPUs == 0 || n < upVCPUs) {
+			up, upVCPUs = candidate, n
+		}
+	}
+	return down, up
+}
+
+// gcpSizedMachineType splits a predefined machine type like n2-standard-8 into its family and
+// class prefix and its vCPU count
+func gcpSizedMachineType(machineType string) (prefix string, vcpus int, ok bool) {
+	if _, ok := gcpAcceleratorMachineTypes[machineType]; ok || isCustomMachineType(machineType) {
+		return "", 0, false
+	}
+
+	parts := strings.Split(machineType, "-")
+	if len(parts) != 3 {
+		return "", 0, false
+	}
+	vcpus, err := strconv.Atoi(parts[2])
+	if err != nil || vcpus <= 0 {
+		return "", 0, false
+	}
+	return parts[0] + "-" + parts[1] + "-", vcpus, true
+}
+
+// gcpSizeStepGlobs returns a pattern for the family and class of every monitored machine type
+// with size steps, to list their sizes with
+func gcpSizeStepGlobs(machineTypes []string) []string {
+	var globs []string
+	for _, machineType := range machineTypes {
+		if prefix, _, ok := gcpSizedMachineType(machineType); ok {
+			globs = appendMissing(globs, prefix+"*")
+		}
+	}
+	return globs
+}
+
+// gcpSharedCoreE2 are the shared-core e2 types from smallest to largest
+var gcpSharedCoreE2 = []string{"e2-micro", "e2-small", "e2-medium"}
+
+// sizeStep is the price of the next size down or up from a monitored instance type
+type sizeStep struct {
+	provider     string
+	region       string
+	instanceType string
+	direction    string
+	stepType     string
+	cost         decimal.Decimal
+}
+
+// recordSizeSteps exports the price of the next size down and up from every monitored
+// instance type. Steps that are monitored themselves use the published price, and others are
+// fetched without being published as series of their own.
+func (m *Monitor) recordSizeSteps(ctx context.Context) {
+	var steps []sizeStep
+
+	if m.awsFetcher != nil {
+		catalog, err := m.awsFetcher.ListInstanceTypes(ctx)
+		if err != nil {
+			slog.Error("failed to list AWS instance types for size steps", "error", err)
+		} else {
+			watched := m.watch("aws")
+			steps = append(steps, m.priceSizeSteps(ctx, "aws", watched.regions, watched.instanceTypes, func(instanceType string) (string, string) {
+				return awsSizeSteps(instanceType, catalog)
+			})...)
+		}
+	}
+
+	// The sizes of each family are listed through a project, and regions can be added by
+	// discovery without one configured
+	if m.gcpFetcher != nil && m.gcpProject != "" {
+		watched := m.watch("gcp")
+		var catalog []string
+		var err error
+		if globs := gcpSizeStepGlobs(watched.instanceTypes); len(globs) > 0 {
+			catalog, err = m.gcpFetcher.MatchMachineTypes(ctx, m.gcpProject, watched.regions, globs)
+		}
+		if err != nil {
+			slog.Error("failed to list GCP machine types for size steps", "error", err)
+		} else {
+			steps = append(steps, m.priceSizeSteps(ctx, "gcp", watched.regions, watched.instanceTypes, func(machineType string) (string, string) {
+				return gcpSizeSteps(machineType, catalog)
+			})...)
+		}
+	}
+
+	// Replace the series only once every step is priced, so scrapes never see a partial ladder
+	m.metrics.RecordSizeSteps(steps)
+}
+
+func (m *Monitor) priceSizeSteps(
+	ctx context.Context,
+	provider string,
+	regions, instanceTypes []string,
+	stepsOf func(instanceType string) (down, up string),
+) []sizeStep {
+	var mu sync.Mutex
+	var steps []sizeStep
+
+	var wg sync.WaitGroup
+	for _, region := range regions {
+		for _, instanceType := range instanceTypes {
+			if _, ok := m.snapshot.Get(PriceKey{Provider: provider, Region: region, InstanceType: instanceType}); !ok {
+				continue
+			}
+
+			down, up := stepsOf(instanceType)
+			for direction, stepType := range map[string]string{"down": down, "up": up} {
+				if stepType == "" {
+					continue
+				}
+
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					step := sizeStep{
+						provider:     provider,
+						region:       region,
+						instanceType: instanceType,
+						direction:    direction,
+						stepType:     stepType,
+					}
+					if !m.priceSizeStep(ctx, &step) {
+						return
+					}
+
+					mu.Lock()
+					steps = append(steps, step)
+					mu.Unlock()
+				}()
+			}
+		}
+	}
+	wg.Wait()
+
+	return steps
+}
+
+// priceSizeStep prices a step from the snapshot, or fetches it. Neighboring types often share
+// a step (m5.large up and m5.2xlarge down are both m5.xlarge), and such fetches are coalesced.
+func (m *Monitor) priceSizeStep(ctx context.Context, step *sizeStep) bool {
+	if entry, ok := m.snapshot.Get(PriceKey{Provider: step.provider, Region: step.region, InstanceType: step.stepType}); ok {
+		step.cost = entry.Pricing.TotalCost
+		return true
+	}
+
+	pricing, err := m.fetchPricing(ctx, step.provider, step.region, step.stepType)
+	if err != nil {
+		slog.Debug("no price for size step",
+			"provider", step.provider,
+			"region", step.region,
+			"instance_type", step.instanceType,
+			"step_instance_type", step.stepType,
+			"error", err,
+		)
+		return false
+	}
+
+	step.cost = pricing.TotalCost
+	return true
+}
@@ -0,0 +1,138 @@
+package monitor
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PriceKey identifies a single priced series
+type PriceKey struct {
+	Provider     string
+	Region       string
+	InstanceType string
+	Confidential bool
+}
+
+// Key returns the series key for the pricing
+func (p VMPricing) Key() PriceKey {
+	return PriceKey{
+		Provider:     p.Provider,
+		Region:       p.Region,
+		InstanceType: p.InstanceType,
+		Confidential: p.Confidential,
+	}
+}
+
+// PriceEntry is the most recently published price for a series
+type PriceEntry struct {
+	Pricing   VMPricing
+	UpdatedAt time.Time
+
+	// PreviousCost is the total cost published before the last price change, if any
+	PreviousCost decimal.Decimal
+	ChangedAt    time.Time
+}
+
+// PriceSnapshot holds the latest published price for every series
+type PriceSnapshot struct {
+	mu      sync.RWMutex
+	entries map[PriceKey]PriceEntry
+}
+
+func NewPriceSnapshot() *PriceSnapshot {
+	return &PriceSnapshot{
+		entries: make(map[PriceKey]PriceEntry),
+	}
+}
+
+// Get returns the published price for a series
+func (s *PriceSnapshot) Get(key PriceKey) (PriceEntry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.entries[key]
+	return entry, ok
+}
+
+// Set publishes a price for a series and returns the stored entry
+func (s *PriceSnapshot) Set(p VMPricing, updatedAt time.Time) PriceEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := PriceEntry{
+		Pricing:   p,
+		UpdatedAt: updatedAt,
+	}
+
+	if prev, ok := s.entries[p.Key()]; ok {
+		entry.PreviousCost = prev.PreviousCost
+		entry.ChangedAt = prev.ChangedAt
+		if !prev.Pricing.TotalCost.Equal(p.TotalCost) {
+			entry.PreviousCost = prev.Pricing.TotalCost
+			entry.ChangedAt = updatedAt
+		}
+	}
+
+	s.entries[p.Key()] = entry
+	return entry
+}
+
+// Delete removes the published prices of an instance type in a region, confidential variant
+// included
+func (s *PriceSnapshot) Delete(provider, region, instanceType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, confidential := range []bool{false, true} {
+		delete(s.entries, PriceKey{Provider: provider, Region: region, InstanceType: instanceType, Confidential: confidential})
+	}
+}
+
+// Expire removes the published price of a series if it was fetched before a time, and reports
+// whether it did. A price published again in the meantime is kept.
+func (s *PriceSnapshot) Expire(key PriceKey, before time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok || !entry.UpdatedAt.Before(before) {
+		return false
+	}
+	delete(s.entries, key)
+	return true
+}
+
+// Entries returns every published price ordered by provider, region, and instance type
+func (s *PriceSnapshot) Entries() []PriceEntry {
+	s.mu.RLock()
+	entries := make([]PriceEntry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, entry)
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(entries, func(a, b PriceEntry) int {
+		return cmp.Or(
+			cmp.Compare(a.Pricing.Provider, b.Pricing.Provider),
+			cmp.Compare(a.Pricing.Region, b.Pricing.Region),
+			cmp.Compare(a.Pricing.InstanceType, b.Pricing.InstanceType),
+			compareBool(a.Pricing.Confidential, b.Pricing.Confidential),
+		)
+	})
+	return entries
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,138 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// softwareFeeDefault is the key of an hourly fee table that applies to every other instance type
+const softwareFeeDefault = "*"
+
+// SoftwareProduct is paid software running on AWS instances, whose hourly fee comes on top of
+// the Linux price. Operating systems and pre-installed software the EC2 price list has products
+// for, such as Windows or SQL Server, are priced from it. Marketplace products, whose fees the
+// Price List API doesn't expose, are given a table of hourly fees by instance type instead.
+type SoftwareProduct struct {
+	ProductCode string `json:"product_code"`
+
+	// OperatingSystem and PreInstalledSw are the operatingSystem and preInstalledSw attributes of
+	// the price list, defaulting to Linux and NA
+	OperatingSystem string `json:"operating_system,omitempty"`
+	PreInstalledSw  string `json:"pre_installed_sw,omitempty"`
+
+	// HourlyFees maps instance types, or * for any other type, to a fee in USD per hour
+	HourlyFees map[string]float64 `json:"hourly_fees,omitempty"`
+}
+
+// LoadSoftwareProducts reads a JSON list of software products
+func LoadSoftwareProducts(path string) ([]SoftwareProduct, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read software config file: %w", err)
+	}
+
+	var products []SoftwareProduct
+	if err := json.Unmarshal(data, &products); err != nil {
+		return nil, fmt.Errorf("failed to parse software config file: %w", err)
+	}
+
+	codes := make(map[string]bool)
+	for i := range products {
+		p := &products[i]
+		if p.ProductCode == "" {
+			return nil, fmt.Errorf("software product %d must have a product code", i)
+		}
+		if codes[p.ProductCode] {
+			return nil, fmt.Errorf("software product %s is defined more than once", p.ProductCode)
+		}
+		codes[p.ProductCode] = true
+
+		listed := p.OperatingSystem != "" || p.PreInstalledSw != ""
+		if listed == (len(p.HourlyFees) > 0) {
+			return nil, fmt.Errorf("software product %s must either name a price list operating system or pre-installed software, or list hourly fees", p.ProductCode)
+		}
+		for instanceType, fee := range p.HourlyFees {
+			if fee < 0 {
+				return nil, fmt.Errorf("hourly fee of software product %s on %s must not be negative", p.ProductCode, instanceType)
+			}
+		}
+
+		if listed {
+			if p.OperatingSystem == "" {
+				p.OperatingSystem = "Linux"
+			}
+			if p.PreInstalledSw == "" {
+				p.PreInstalledSw = "NA"
+			}
+			if p.OperatingSystem == "Linux" && p.PreInstalledSw == "NA" {
+				return nil, fmt.Errorf("software product %s is plain Linux, which has no fee", p.ProductCode)
+			}
+		}
+	}
+
+	return products, nil
+}
+
+// FetchSoftwareFee returns the hourly fee of a software product on an instance type, given the
+// instance type's published Linux price
+func (f *AWSPricingFetcher) FetchSoftwareFee(ctx context.Context, product SoftwareProduct, linux VMPricing) (decimal.Decimal, error) {
+	if len(product.HourlyFees) > 0 {
+		fee, ok := product.HourlyFees[linux.InstanceType]
+		if !ok {
+			fee, ok = product.HourlyFees[softwareFeeDefault]
+		}
+		if !ok {
+			return decimal.Zero, fmt.Errorf("%w for software product %s on instance type %s", errNoPricingFound, product.ProductCode, linux.InstanceType)
+		}
+		return decimal.NewFromFloat(fee), nil
+	}
+
+	price, _, err := f.getOnDemandProduct(ctx, linux.Region, linux.InstanceType, product.OperatingSystem, product.PreInstalledSw)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	fee := price.Sub(linux.TotalCost)
+	if fee.IsNegative() {
+		return decimal.Zero, fmt.Errorf("software product %s costs less than Linux on instance type %s in region %s", product.ProductCode, linux.InstanceType, linux.Region)
+	}
+	return fee, nil
+}
+
+// recordSoftwareFees exports the fee of every software product on every published AWS instance
+// type
+func (m *Monitor) recordSoftwareFees(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, entry := range m.snapshot.Entries() {
+		p := entry.Pricing
+		if p.Provider != "aws" || p.Confidential {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, product := range m.software {
+				fee, err := m.awsFetcher.FetchSoftwareFee(ctx, product, p)
+				if err != nil {
+					slog.Warn("failed to price software",
+						"product_code", product.ProductCode,
+						"region", p.Region,
+						"instance_type", p.InstanceType,
+						"error", err,
+					)
+					m.metrics.DeleteSoftwareFee(p, product.ProductCode)
+					continue
+				}
+				m.metrics.RecordSoftwareFee(p, product.ProductCode, fee)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,72 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// recordSpotPrices exports the current spot price of every monitored instance type in each
+// availability zone of its region. GCP prices spot per region, so its prices have no zone.
+func (m *Monitor) recordSpotPrices(ctx context.Context) {
+	if m.awsSpotPricing && m.awsFetcher != nil {
+		for _, region := range m.watch("aws").regions {
+			for _, instanceType := range m.watch("aws").instanceTypes {
+				series := scheduledSeries{seriesSpot, "aws", region, instanceType}
+				if !m.due(series) {
+					continue
+				}
+				prices, err := m.awsFetcher.SpotPricesByZone(ctx, region, instanceType)
+				m.observe(series, spotValue(prices))
+				if err != nil {
+					slog.Error("failed to fetch AWS spot pricing",
+						"region", region,
+						"instance_type", instanceType,
+						"reason", errorReason(err),
+						"error", err,
+					)
+					continue
+				}
+				m.metrics.RecordSpotPrices("aws", region, instanceType, prices)
+			}
+		}
+	}
+
+	if m.gcpSpotPricing && m.gcpFetcher != nil {
+		for _, region := range m.watch("gcp").regions {
+			for _, machineType := range m.watch("gcp").instanceTypes {
+				series := scheduledSeries{seriesSpot, "gcp", region, machineType}
+				if isCustomMachineType(machineType) || hasGPUs(machineType) || !m.due(series) {
+					continue
+				}
+				pricing, err := m.gcpFetcher.FetchSpotPricing(ctx, region, machineType)
+				if err != nil {
+					m.observe(series, "")
+					slog.Error("failed to fetch GCP spot pricing",
+						"region", region,
+						"machine_type", machineType,
+						"reason", errorReason(err),
+						"error", err,
+					)
+					continue
+				}
+				m.observe(series, pricing.TotalCost.String())
+				m.metrics.RecordSpotPrices("gcp", region, machineType, map[string]decimal.Decimal{"": pricing.TotalCost})
+			}
+		}
+	}
+}
+
+// spotValue summarizes the prices of a series in every zone, to tell when any of them changes
+func spotValue(prices map[string]decimal.Decimal) string {
+	var b strings.Builder
+	for _, zone := range slices.Sorted(maps.Keys(prices)) {
+		fmt.Fprintf(&b, "%s=%s;", zone, prices[zone])
+	}
+	return b.String()
+}
@@ -0,0 +1,82 @@
+package monitor
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// The ways a stale price is handled
+const (
+	// staleFlag keeps publishing a stale price, flagged by cloud_vm_pricing_stale
+	staleFlag = "flag"
+	// staleDelete stops publishing a stale price until it is fetched again
+	staleDelete = "delete"
+)
+
+// stalePolicy is when a published price that can no longer be fetched is considered stale,
+// and what is done with it then
+type stalePolicy struct {
+	// polls is how many poll intervals a price may go without a successful fetch, or zero to
+	// keep prices however old they get
+	polls  int
+	action string
+}
+
+func newStalePolicy(polls int, action string) (stalePolicy, error) {
+	if polls < 0 {
+		return stalePolicy{}, fmt.Errorf("stale-after-polls must not be negative")
+	}
+	if action != staleFlag && action != staleDelete {
+		return stalePolicy{}, fmt.Errorf("unknown stale-series-action %q (expected %q or %q)", action, staleFlag, staleDelete)
+	}
+	return stalePolicy{polls: polls, action: action}, nil
+}
+
+// staleWindow returns how long a price may go without a successful fetch before it is stale.
+// With an adaptive schedule, a series that rarely changes is fetched as seldom as the maximum
+// interval, so the window counts those.
+func (m *Monitor) staleWindow() time.Duration {
+	interval := m.pollInterval
+	if m.schedule != nil {
+		interval = max(interval, m.schedule.maxInterval)
+	}
+	return time.Duration(m.stale.polls) * interval
+}
+
+// expireStalePrices flags, or with the delete action stops publishing, the prices that
+// haven't been fetched successfully within the staleness window, such as those of an instance
+// type the provider retired. Deleted prices keep their stale flag until they are published
+// again.
+func (m *Monitor) expireStalePrices() {
+	if m.stale.polls == 0 || m.bundle != nil {
+		return
+	}
+
+	window := m.staleWindow()
+	cutoff := time.Now().Add(-window)
+	for _, entry := range m.snapshot.Entries() {
+		key := entry.Pricing.Key()
+		if !entry.UpdatedAt.Before(cutoff) {
+			m.metrics.RecordStale(key, false)
+			continue
+		}
+
+		if m.stale.action == staleDelete {
+			if !m.snapshot.Expire(key, cutoff) {
+				continue
+			}
+			m.metrics.DeletePrice(key)
+		}
+		m.metrics.RecordStale(key, true)
+		slog.Warn("price is stale",
+			"provider", key.Provider,
+			"region", key.Region,
+			"instance_type", key.InstanceType,
+			"confidential", key.Confidential,
+			"updated_at", entry.UpdatedAt,
+			"window", window,
+			"action", m.stale.action,
+		)
+	}
+}
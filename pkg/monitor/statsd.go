@@ -0,0 +1,164 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	// statsdUDPPacketSize keeps UDP datagrams within a typical MTU, so they aren't fragmented
+	statsdUDPPacketSize = 1432
+	// statsdUnixPacketSize is the datagram size the Datadog agent reads from its Unix socket
+	statsdUnixPacketSize = 8192
+)
+
+// StatsDSink pushes the pricing gauges to a StatsD server, or to a Datadog agent in the
+// DogStatsD format with the labels as tags
+type StatsDSink struct {
+	name       string
+	conn       net.Conn
+	packetSize int
+	prefix     string
+	dogstatsd  bool
+	tags       []string
+}
+
+// NewStatsDSink sends gauges to a StatsD server at a UDP host:port. Plain StatsD has no tags, so
+// label values are appended to the metric name, such as
+// cloud_vm_total_cost_per_hour.aws.us-east-1.m5_large.false.false._.0.
+func NewStatsDSink(address, prefix string) (*StatsDSink, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to StatsD at %s: %w", address, err)
+	}
+	return &StatsDSink{
+		name:       "statsd",
+		conn:       conn,
+		packetSize: statsdUDPPacketSize,
+		prefix:     prefix,
+	}, nil
+}
+
+// NewDogStatsDSink sends tagged gauges to a Datadog agent at a UDP host:port, or at a Unix
+// socket given as unix:///path. Tags are added to every gauge on top of its labels.
+func NewDogStatsDSink(address, prefix string, tags []string) (*StatsDSink, error) {
+	network, packetSize := "udp", statsdUDPPacketSize
+	if path, ok := strings.CutPrefix(address, "unix://"); ok {
+		network, packetSize, address = "unixgram", statsdUnixPacketSize, path
+	}
+
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to DogStatsD at %s: %w", address, err)
+	}
+	return &StatsDSink{
+		name:       "dogstatsd",
+		conn:       conn,
+		packetSize: packetSize,
+		prefix:     prefix,
+		dogstatsd:  true,
+		tags:       tags,
+	}, nil
+}
+
+func (s *StatsDSink) Name() string {
+	return s.name
+}
+
+// Push sends every sample as a gauge, batching as many lines into each datagram as fit
+func (s *StatsDSink) Push(ctx context.Context, samples []SinkSample) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := s.conn.SetWriteDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	var packet []byte
+	flush := func() error {
+		if len(packet) == 0 {
+			return nil
+		}
+		_, err := s.conn.Write(packet)
+		packet = packet[:0]
+		return err
+	}
+
+	for _, sample := range samples {
+		line := s.line(sample)
+		if len(packet) > 0 && len(packet)+1+len(line) > s.packetSize {
+			if err := flush(); err != nil {
+				return fmt.Errorf("failed to send to %s: %w", s.name, err)
+			}
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+	}
+
+	if err := flush(); err != nil {
+		return fmt.Errorf("failed to send to %s: %w", s.name, err)
+	}
+	return nil
+}
+
+// line formats a sample as a StatsD gauge
+func (s *StatsDSink) line(sample SinkSample) string {
+	var b strings.Builder
+	if s.dogstatsd {
+		b.WriteString(s.prefix)
+		b.WriteString(sample.Name)
+	} else {
+		b.WriteString(sinkPath(s.prefix, sample))
+	}
+
+	b.WriteByte(':')
+	b.WriteString(strconv.FormatFloat(sample.Value, 'f', -1, 64))
+	b.WriteString("|g")
+
+	if s.dogstatsd {
+		b.WriteString("|#")
+		first := true
+		for _, label := range sample.Labels {
+			// A tag without a value would be a different tag, named with the colon, so it's
+			// left out like InfluxDB's
+			if label.Value == "" {
+				continue
+			}
+			if !first {
+				b.WriteByte(',')
+			}
+			first = false
+			b.WriteString(label.Name)
+			b.WriteByte(':')
+			b.WriteString(dogstatsdTagValue(label.Value))
+		}
+		for _, tag := range s.tags {
+			if !first {
+				b.WriteByte(',')
+			}
+			first = false
+			b.WriteString(dogstatsdTagValue(tag))
+		}
+	}
+	return b.String()
+}
+
+func (s *StatsDSink) Close() error {
+	return s.conn.Close()
+}
+
+// dogstatsdTagValue replaces the characters that separate DogStatsD fields and tags
+func dogstatsdTagValue(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ',', '|', '#', '\n':
+			return '_'
+		default:
+			return r
+		}
+	}, s)
+}
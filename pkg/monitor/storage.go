@@ -0,0 +1,228 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/aws-sdk-go-v2/service/pricing/types"
+	"github.com/shopspring/decimal"
+)
+
+// The billed dimensions of storage, and the units they are priced per
+const (
+	storageCapacity   = "capacity"
+	storageIOPS       = "iops"
+	storageThroughput = "throughput"
+	storageAccess     = "access"
+	storageRead       = "read"
+	storageWrite      = "write"
+	storageRestore    = "restore"
+	storageProtected  = "protected"
+
+	storageUnitGB         = "gb"
+	storageUnitGBMonth    = "gb_month"
+	storageUnitIOPSMonth  = "iops_month"
+	storageUnitMiBpsMonth = "mibps_month"
+)
+
+// gcpDiskIOPSSkuProducts maps disk types to the product of their provisioned IOPS SKU
+var gcpDiskIOPSSkuProducts = map[string]string{
+	"pd-extreme":         "extreme pd iops",
+	"hyperdisk-balanced": "hyperdisk balanced iops",
+	"hyperdisk-extreme":  "hyperdisk extreme iops",
+}
+
+// gcpDiskThroughputSkuProducts maps disk types to the product of their provisioned throughput SKU
+var gcpDiskThroughputSkuProducts = map[string]string{
+	"hyperdisk-balanced":   "hyperdisk balanced throughput",
+	"hyperdisk-throughput": "hyperdisk throughput throughput capacity",
+}
+
+// gcpDiskDimensions returns the products of the SKUs a disk type is billed by, for each dimension
+func gcpDiskDimensions(diskType string) []storageSku {
+	skus := []storageSku{{storageCapacity, storageUnitGBMonth, gcpDiskSkuProducts[diskType]}}
+	if product, ok := gcpDiskIOPSSkuProducts[diskType]; ok {
+		skus = append(skus, storageSku{storageIOPS, storageUnitIOPSMonth, product})
+	}
+	if product, ok := gcpDiskThroughputSkuProducts[diskType]; ok {
+		skus = append(skus, storageSku{storageThroughput, storageUnitMiBpsMonth, product})
+	}
+	return skus
+}
+
+// storageSku is the SKU product a dimension of a storage type is billed by
+type storageSku struct {
+	dimension, unit, product string
+}
+
+// FetchDiskPricing returns the monthly price of the capacity of each persistent disk type in a
+// region, and of the IOPS and throughput provisioned on top of it for types that bill them, in
+// a single pass over the catalog. Hyperdisk Balanced includes a baseline of IOPS and throughput
+// that isn't billed. Types that aren't offered in the region are left out.
+func (f *GCPPricingFetcher) FetchDiskPricing(ctx context.Context, region string, diskTypes []string) ([]ResourcePrice, error) {
+	products := make(map[string]bool)
+	for _, t := range diskTypes {
+		for _, sku := range gcpDiskDimensions(t) {
+			products[sku.product] = true
+		}
+	}
+
+	rates, err := f.getSkuRates(ctx, gcpComputeServiceID, region, products)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disk pricing: %w", err)
+	}
+
+	dimensions := make(map[string][]ResourceDimension)
+	for _, t := range diskTypes {
+		for _, sku := range gcpDiskDimensions(t) {
+			rate, ok := rates[sku.product]
+			if !ok {
+				slog.Debug("skipping disk dimension", "region", region, "disk_type", t, "dimension", sku.dimension)
+				if sku.dimension == storageCapacity {
+					break
+				}
+				continue
+			}
+			dimensions[t] = append(dimensions[t], ResourceDimension{Dimension: sku.dimension, Unit: sku.unit, Price: rate.Monthly()})
+		}
+	}
+	return resourcePrices(storageKind.Name, "gcp", region, dimensions), nil
+}
+
+// awsPriceListItem is the part of a price list product that storage is priced from
+type awsPriceListItem struct {
+	Product struct {
+		ProductFamily string            `json:"productFamily"`
+		Attributes    map[string]string `json:"attributes"`
+	} `json:"product"`
+	Terms struct {
+		OnDemand map[string]struct {
+			PriceDimensions map[string]awsPriceDimension `json:"priceDimensions"`
+		} `json:"OnDemand"`
+	} `json:"terms"`
+}
+
+// awsPriceDimension is one tier of an on-demand price
+type awsPriceDimension struct {
+	Unit         string            `json:"unit"`
+	BeginRange   string            `json:"beginRange"`
+	PricePerUnit map[string]string `json:"pricePerUnit"`
+}
+
+// firstTier returns the on-demand price of the first tier of a product and the unit it is per
+func (item awsPriceListItem) firstTier() (decimal.Decimal, string, bool) {
+	for _, term := range item.Terms.OnDemand {
+		for _, dimension := range term.PriceDimensions {
+			if dimension.BeginRange != "" && dimension.BeginRange != "0" {
+				continue
+			}
+			price, err := decimal.NewFromString(dimension.PricePerUnit["USD"])
+			if err != nil {
+				continue
+			}
+			return price, dimension.Unit, true
+		}
+	}
+	return decimal.Zero, "", false
+}
+
+// getProducts returns every product of a service in the price list matching the attributes
+func (f *AWSPricingFetcher) getProducts(ctx context.Context, serviceCode string, attributes map[string]string) ([]awsPriceListItem, error) {
+	var filters []types.Filter
+	for _, field := range slices.Sorted(maps.Keys(attributes)) {
+		filters = append(filters, types.Filter{
+			Type:  types.FilterTypeTermMatch,
+			Field: aws.String(field),
+			Value: aws.String(attributes[field]),
+		})
+	}
+
+	paginator := pricing.NewGetProductsPaginator(f.client, &pricing.GetProductsInput{
+		ServiceCode: aws.String(serviceCode),
+		Filters:     filters,
+		MaxResults:  aws.Int32(100),
+	})
+
+	var items []awsPriceListItem
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get AWS pricing: %w", err)
+		}
+		for _, data := range page.PriceList {
+			var item awsPriceListItem
+			if err := json.Unmarshal([]byte(data), &item); err != nil {
+				return nil, fmt.Errorf("failed to parse pricing data: %w", err)
+			}
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// FetchVolumePricing returns the monthly price of the capacity of each EBS volume type in a
+// region, and of the IOPS and throughput provisioned on top of it for types that bill them. gp3
+// includes a baseline of IOPS and throughput that isn't billed, and io2 IOPS are priced at their
+// first tier, which covers the first 32,000 IOPS of a volume. Types that aren't offered in the
+// region are left out.
+func (f *AWSPricingFetcher) FetchVolumePricing(ctx context.Context, region string, volumeTypes []string) ([]ResourcePrice, error) {
+	dimensions := make(map[string][]ResourceDimension)
+	for _, volumeType := range volumeTypes {
+		items, err := f.getProducts(ctx, "AmazonEC2", map[string]string{
+			"regionCode":    region,
+			"volumeApiName": volumeType,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		found := make(map[string]ResourceDimension)
+		for _, item := range items {
+			var dimension, unit string
+			switch item.Product.ProductFamily {
+			case "Storage":
+				dimension, unit = storageCapacity, storageUnitGBMonth
+			case "System Operation":
+				if item.Product.Attributes["group"] != "EBS IOPS" {
+					continue
+				}
+				dimension, unit = storageIOPS, storageUnitIOPSMonth
+			case "Provisioned Throughput":
+				dimension, unit = storageThroughput, storageUnitMiBpsMonth
+			default:
+				continue
+			}
+			if _, ok := found[dimension]; ok {
+				continue
+			}
+
+			price, priceUnit, ok := item.firstTier()
+			if !ok {
+				continue
+			}
+			// Throughput is listed per GiBps-month
+			if strings.HasPrefix(priceUnit, "GiBps") {
+				price = price.Div(decimal.NewFromInt(1024))
+			}
+			found[dimension] = ResourceDimension{Dimension: dimension, Unit: unit, Price: price}
+		}
+
+		if _, ok := found[storageCapacity]; !ok {
+			slog.Debug("skipping volume type", "region", region, "volume_type", volumeType, "error", errNoPricingFound)
+			continue
+		}
+		for _, dimension := range []string{storageCapacity, storageIOPS, storageThroughput} {
+			if d, ok := found[dimension]; ok {
+				dimensions[volumeType] = append(dimensions[volumeType], d)
+			}
+		}
+	}
+	return resourcePrices(storageKind.Name, "aws", region, dimensions), nil
+}
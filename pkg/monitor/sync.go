@@ -0,0 +1,121 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// peerSyncTimeout bounds the pull of a peer's snapshot, so an unreachable peer only delays
+// the first fetch
+const peerSyncTimeout = 30 * time.Second
+
+// snapshotHandler serves every published price in the bundle format, with when each was
+// fetched, for replicas to sync their snapshot from
+func snapshotHandler(snapshot *PriceSnapshot, version string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entries := snapshot.Entries()
+		bundle := PriceBundle{
+			FormatVersion:  bundleFormatVersion,
+			CreatedAt:      time.Now().UTC(),
+			MonitorVersion: version,
+			Prices:         make([]PriceRecord, 0, len(entries)),
+		}
+		for _, entry := range entries {
+			bundle.Prices = append(bundle.Prices, NewPriceRecord(entry.Pricing, entry.UpdatedAt, "live"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(bundle); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// pullSnapshot fetches the published prices of the monitor at a base URL
+func pullSnapshot(ctx context.Context, peer string) (*PriceBundle, error) {
+	endpoint, err := url.JoinPath(peer, "/api/v1/snapshot")
+	if err != nil {
+		return nil, fmt.Errorf("invalid sync peer URL: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull snapshot from %s: %w", peer, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to pull snapshot from %s: %s", peer, resp.Status)
+	}
+
+	var bundle PriceBundle
+	if err := json.NewDecoder(resp.Body).Decode(&bundle); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot from %s: %w", peer, err)
+	}
+	if bundle.FormatVersion != bundleFormatVersion {
+		return nil, fmt.Errorf("unsupported snapshot format version %d from %s (expected %d)", bundle.FormatVersion, peer, bundleFormatVersion)
+	}
+	return &bundle, nil
+}
+
+// syncFromPeer publishes the prices a peer has published for the series this monitor is
+// configured with, so a replica that just started serves them before its first fetch
+// completes. The fetch then replaces them like any published price. A peer that can't be
+// reached is logged and left out.
+func (m *Monitor) syncFromPeer(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, peerSyncTimeout)
+	defer cancel()
+
+	start := time.Now()
+	bundle, err := pullSnapshot(ctx, m.syncFrom)
+	if err != nil {
+		slog.Warn("failed to sync prices from peer, fetching them instead", "peer", m.syncFrom, "error", err)
+		return
+	}
+
+	records := slices.DeleteFunc(bundle.Prices, func(r PriceRecord) bool {
+		return !m.configured(r)
+	})
+	for _, record := range records {
+		m.publishRecord(record, nil)
+	}
+	m.recordAggregates()
+
+	slog.Info("synced prices from peer",
+		"peer", m.syncFrom,
+		"series", len(records),
+		"skipped", len(bundle.Prices)-len(records),
+		"duration", time.Since(start),
+	)
+}
+
+// configured tells whether a price record is of a series of the configured regions and
+// instance types
+func (m *Monitor) configured(r PriceRecord) bool {
+	for _, t := range m.pricingTargets() {
+		if t.name == r.Provider {
+			return slices.Contains(t.regions, r.Region) &&
+				slices.Contains(t.instanceTypes, r.InstanceType) &&
+				(!r.Confidential || t.confidential)
+		}
+	}
+	return false
+}
+
+// syncPeerURL checks the base URL of the peer to sync from
+func syncPeerURL(peer string) (string, error) {
+	u, err := url.Parse(peer)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid sync-from %q: must be an http or https URL", peer)
+	}
+	return strings.TrimSuffix(peer, "/"), nil
+}
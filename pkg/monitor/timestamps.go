@@ -0,0 +1,62 @@
+package monitor
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shopspring/decimal"
+)
+
+// TimestampedCollector exports the price gauges from the snapshot with explicit sample
+// timestamps equal to each price's fetch time, so consumers can tell a fresh scrape of
+// old data from freshly fetched data.
+//
+// Prometheus does not write staleness markers for samples with explicit timestamps and
+// only looks back 5 minutes by default, so with a long poll interval queries need to use
+// last_over_time() or similar to see the latest price between polls.
+type TimestampedCollector struct {
+	snapshot    *PriceSnapshot
+	rounding    PriceRounding
+	memoryUnit  MemoryUnit
+	totalCost   *prometheus.Desc
+	costPerGB   *prometheus.Desc
+	costPerVCPU *prometheus.Desc
+}
+
+func NewTimestampedCollector(snapshot *PriceSnapshot, rounding PriceRounding, memoryUnit MemoryUnit) *TimestampedCollector {
+	costPerGBOpts := costPerGBOpts(memoryUnit)
+	return &TimestampedCollector{
+		snapshot:    snapshot,
+		rounding:    rounding,
+		memoryUnit:  memoryUnit,
+		totalCost:   prometheus.NewDesc(totalCostOpts.Name, totalCostOpts.Help, vmCostLabels, nil),
+		costPerGB:   prometheus.NewDesc(costPerGBOpts.Name, costPerGBOpts.Help, vmCostLabels, nil),
+		costPerVCPU: prometheus.NewDesc(costPerVCPUOpts.Name, costPerVCPUOpts.Help, vmCostLabels, nil),
+	}
+}
+
+func (c *TimestampedCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.totalCost
+	ch <- c.costPerGB
+	ch <- c.costPerVCPU
+}
+
+func (c *TimestampedCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, entry := range c.snapshot.Entries() {
+		p := entry.Pricing
+		labels := []string{p.Provider, p.Region, p.InstanceType, strconv.FormatBool(p.Confidential), strconv.FormatBool(p.Overridden), p.GPUType, strconv.Itoa(p.GPUCount)}
+
+		emit := func(desc *prometheus.Desc, value decimal.Decimal) {
+			ch <- prometheus.NewMetricWithTimestamp(entry.UpdatedAt,
+				prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, c.rounding.Float(value), labels...))
+		}
+
+		emit(c.totalCost, p.TotalCost)
+		if cost, ok := p.CostPerMemory(c.memoryUnit); ok {
+			emit(c.costPerGB, cost)
+		}
+		if cost, ok := p.CostPerVCPU(); ok {
+			emit(c.costPerVCPU, cost)
+		}
+	}
+}
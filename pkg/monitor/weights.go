@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/shopspring/decimal"
+)
+
+// UsageWeights is the share of the fleet running each instance type, by provider
+type UsageWeights map[string]map[string]float64
+
+// LoadUsageWeights reads usage weights from a JSON file like
+// {"aws": {"m5.large": 0.7, "c5.xlarge": 0.3}}. Weights only need to be relative to each
+// other within a provider, so instance counts work as well as fractions.
+func LoadUsageWeights(path string) (UsageWeights, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage weights file: %w", err)
+	}
+
+	var weights UsageWeights
+	if err := json.Unmarshal(data, &weights); err != nil {
+		return nil, fmt.Errorf("failed to parse usage weights file: %w", err)
+	}
+
+	for provider, types := range weights {
+		for instanceType, weight := range types {
+			if weight < 0 {
+				return nil, fmt.Errorf("usage weight for %s %s must not be negative", provider, instanceType)
+			}
+		}
+	}
+
+	return weights, nil
+}
+
+// regionKey identifies a provider region
+type regionKey struct {
+	Provider string
+	Region   string
+}
+
+// BlendedCostPerVCPU returns the usage-weighted cost per vCPU-hour of each provider region:
+// the cost of the weighted fleet divided by its vCPUs. Instance types without a price in a
+// region are left out of that region's blend.
+func (w UsageWeights) BlendedCostPerVCPU(entries []PriceEntry) map[regionKey]decimal.Decimal {
+	cost := make(map[regionKey]decimal.Decimal)
+	vcpus := make(map[regionKey]decimal.Decimal)
+
+	for _, entry := range entries {
+		p := entry.Pricing
+		if p.Confidential || p.VCPUs == 0 {
+			continue
+		}
+
+		weight := w[p.Provider][p.InstanceType]
+		if weight == 0 {
+			continue
+		}
+
+		key := regionKey{Provider: p.Provider, Region: p.Region}
+		cost[key] = cost[key].Add(p.TotalCost.Mul(decimal.NewFromFloat(weight)))
+		vcpus[key] = vcpus[key].Add(decimal.NewFromFloat(weight * float64(p.VCPUs)))
+	}
+
+	blended := make(map[regionKey]decimal.Decimal, len(cost))
+	for key, c := range cost {
+		blended[key] = c.Div(vcpus[key])
+	}
+	return blended
+}
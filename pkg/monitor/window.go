@@ -0,0 +1,185 @@
+package monitor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// The runtime image has no zoneinfo database, so the poll window's time zone is embedded
+	_ "time/tzdata"
+)
+
+// pollWindowSearchLimit bounds the search for the next opening of a poll window, so that an
+// expression no date matches, such as February 30th, can't search forever
+const pollWindowSearchLimit = 5 * 366 * 24 * time.Hour
+
+// cronField is a field of a cron expression: its bounds and the names its values may be
+// written as
+type cronField struct {
+	name   string
+	lo, hi int
+	names  map[string]int
+}
+
+var (
+	cronMinute = cronField{name: "minute", lo: 0, hi: 59}
+	cronHour   = cronField{name: "hour", lo: 0, hi: 23}
+	cronDay    = cronField{name: "day of month", lo: 1, hi: 31}
+	cronMonth  = cronField{name: "month", lo: 1, hi: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Sunday is both 0 and 7, as in most cron implementations
+	cronWeekday = cronField{name: "day of week", lo: 0, hi: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// parse returns the set of values a field matches as a bitmask. Fields are lists of values,
+// ranges, and *, each optionally stepped with /n.
+func (f cronField) parse(field string) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		expr, stepText, stepped := strings.Cut(part, "/")
+		step := 1
+		if stepped {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepText, f.name)
+			}
+			step = n
+		}
+
+		lo, hi := f.lo, f.hi
+		if expr != "*" {
+			first, last, isRange := strings.Cut(expr, "-")
+			var err error
+			if lo, err = f.value(first); err != nil {
+				return 0, err
+			}
+			switch {
+			case isRange:
+				if hi, err = f.value(last); err != nil {
+					return 0, err
+				}
+			case !stepped:
+				hi = lo
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q in %s field", expr, f.name)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// value parses a single value of a field, by number or name
+func (f cronField) value(text string) (int, error) {
+	if v, ok := f.names[strings.ToLower(text)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(text)
+	if err != nil || v < f.lo || v > f.hi {
+		return 0, fmt.Errorf("invalid value %q in %s field (expected %d-%d)", text, f.name, f.lo, f.hi)
+	}
+	return v, nil
+}
+
+// PollWindow is when the monitor may poll: the minutes a cron expression matches in a time
+// zone. A window such as "* 8-18 * * mon-fri" only polls during business hours.
+type PollWindow struct {
+	expr     string
+	location *time.Location
+
+	minutes, hours, days, months, weekdays uint64
+
+	// As in cron, a date matches either day field when both are restricted
+	daysRestricted, weekdaysRestricted bool
+}
+
+// ParsePollWindow parses a five-field cron expression (minute, hour, day of month, month, and
+// day of week) evaluated in an IANA time zone such as Europe/Berlin
+func ParsePollWindow(expr, timezone string) (*PollWindow, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid poll window time zone %q: %w", timezone, err)
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid poll window %q: expected 5 fields (minute hour day-of-month month day-of-week), got %d", expr, len(fields))
+	}
+
+	w := &PollWindow{
+		expr:               expr,
+		location:           location,
+		daysRestricted:     !strings.HasPrefix(fields[2], "*"),
+		weekdaysRestricted: !strings.HasPrefix(fields[4], "*"),
+	}
+	sets := []*uint64{&w.minutes, &w.hours, &w.days, &w.months, &w.weekdays}
+	for i, field := range []cronField{cronMinute, cronHour, cronDay, cronMonth, cronWeekday} {
+		if *sets[i], err = field.parse(fields[i]); err != nil {
+			return nil, fmt.Errorf("invalid poll window %q: %w", expr, err)
+		}
+	}
+	if w.weekdays&(1<<7) != 0 {
+		w.weekdays |= 1
+	}
+	if w.NextOpen(time.Now()).IsZero() {
+		return nil, fmt.Errorf("invalid poll window %q: it never opens", expr)
+	}
+	return w, nil
+}
+
+func (w *PollWindow) String() string {
+	return fmt.Sprintf("%s (%s)", w.expr, w.location)
+}
+
+// dateMatches reports whether a date is one of the window's days
+func (w *PollWindow) dateMatches(t time.Time) bool {
+	if w.months&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	day := w.days&(1<<t.Day()) != 0
+	weekday := w.weekdays&(1<<int(t.Weekday())) != 0
+	if w.daysRestricted && w.weekdaysRestricted {
+		return day || weekday
+	}
+	return day && weekday
+}
+
+// Contains reports whether the window is open at a time
+func (w *PollWindow) Contains(t time.Time) bool {
+	t = t.In(w.location)
+	return w.dateMatches(t) && w.hours&(1<<t.Hour()) != 0 && w.minutes&(1<<t.Minute()) != 0
+}
+
+// NextOpen returns the start of the first minute at or after a time that the window is open,
+// or the zero time if it never opens
+func (w *PollWindow) NextOpen(t time.Time) time.Time {
+	limit := t.Add(pollWindowSearchLimit)
+	t = t.In(w.location)
+	if t.Second() != 0 || t.Nanosecond() != 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, w.location)
+	}
+
+	// Skip whole days and hours that don't match, rather than every minute in them
+	for t.Before(limit) {
+		switch {
+		case !w.dateMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, w.location)
+		case w.hours&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, w.location)
+		case w.minutes&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
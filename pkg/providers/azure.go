@@ -0,0 +1,256 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// azureRetailPricesURL is the Azure Retail Prices API, which is public and needs no credentials
+const azureRetailPricesURL = "https://prices.azure.com/api/retail/prices"
+
+// azureVMSizePattern matches the vCPU count of a VM size, and the active vCPU count of
+// constrained sizes such as Standard_E8-4s_v5
+var azureVMSizePattern = regexp.MustCompile(`^Standard_[A-Za-z]+(\d+)(?:-(\d+))?`)
+
+// azureConfidentialBasePattern matches the AMD general purpose and memory optimized sizes that
+// have a confidential counterpart, such as Standard_D4as_v5 for Standard_DC4as_v5
+var azureConfidentialBasePattern = regexp.MustCompile(`^Standard_([DE])(\d+)(as|ads)_v5$`)
+
+// azureReferenceVMSize is a general purpose size offered in nearly every region, whose prices
+// tell which regions can be priced
+const azureReferenceVMSize = "Standard_D2s_v3"
+
+// AzureProvider prices Azure VM sizes from the Retail Prices API
+type AzureProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewAzureProvider creates a provider for the Retail Prices API, or for the endpoint in
+// AZURE_RETAIL_PRICES_URL when it is set, such as a mirror or a mock serving recorded responses
+func NewAzureProvider() *AzureProvider {
+	baseURL := azureRetailPricesURL
+	if endpoint := os.Getenv("AZURE_RETAIL_PRICES_URL"); endpoint != "" {
+		baseURL = endpoint
+	}
+	return &AzureProvider{
+		baseURL: baseURL,
+		client:  http.DefaultClient,
+	}
+}
+
+func (f *AzureProvider) Name() string {
+	return "azure"
+}
+
+// Configure only sets the HTTP client, since the Retail Prices API needs no credentials
+func (f *AzureProvider) Configure(ctx context.Context, cfg Config) error {
+	if cfg.HTTPClient != nil {
+		f.client = cfg.HTTPClient
+	}
+	return nil
+}
+
+// ListSupportedRegions returns the regions with a pay-as-you-go price for a general purpose
+// reference size
+func (f *AzureProvider) ListSupportedRegions(ctx context.Context) ([]string, error) {
+	filter := fmt.Sprintf("serviceName eq 'Virtual Machines' and priceType eq 'Consumption' and armSkuName eq '%s'", azureReferenceVMSize)
+	items, err := f.getPrices(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var regions []string
+	for _, item := range items {
+		if item.ArmRegionName != "" && !slices.Contains(regions, item.ArmRegionName) {
+			regions = append(regions, item.ArmRegionName)
+		}
+	}
+	slices.Sort(regions)
+	return regions, nil
+}
+
+// azureRetailPrice is an item of the Retail Prices API
+type azureRetailPrice struct {
+	RetailPrice          float64 `json:"retailPrice"`
+	UnitOfMeasure        string  `json:"unitOfMeasure"`
+	ArmSkuName           string  `json:"armSkuName"`
+	ArmRegionName        string  `json:"armRegionName"`
+	SkuName              string  `json:"skuName"`
+	ProductName          string  `json:"productName"`
+	Type                 string  `json:"type"`
+	IsPrimaryMeterRegion bool    `json:"isPrimaryMeterRegion"`
+	MeterID              string  `json:"meterId"`
+}
+
+// azureRetailPricesPage is a page of the Retail Prices API
+type azureRetailPricesPage struct {
+	Items        []azureRetailPrice `json:"Items"`
+	NextPageLink string             `json:"NextPageLink"`
+}
+
+// getPrices returns every item of the Retail Prices API matching an OData filter
+func (f *AzureProvider) getPrices(ctx context.Context, filter string) ([]azureRetailPrice, error) {
+	query := url.Values{}
+	query.Set("currencyCode", "USD")
+	query.Set("$filter", filter)
+	next := f.baseURL + "?" + query.Encode()
+
+	var items []azureRetailPrice
+	for next != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, next, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure pricing request: %w", err)
+		}
+
+		resp, err := f.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Azure pricing: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Azure pricing: %w", err)
+		}
+		switch resp.StatusCode {
+		case http.StatusOK:
+		case http.StatusTooManyRequests:
+			return nil, fmt.Errorf("failed to get Azure pricing: %w: %s", ErrThrottled, strings.TrimSpace(string(body)))
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return nil, fmt.Errorf("failed to get Azure pricing: %w: %s: %s", ErrAuth, resp.Status, strings.TrimSpace(string(body)))
+		default:
+			if resp.StatusCode >= http.StatusInternalServerError {
+				return nil, fmt.Errorf("failed to get Azure pricing: %w: %s: %s", ErrUnavailable, resp.Status, strings.TrimSpace(string(body)))
+			}
+			return nil, fmt.Errorf("failed to get Azure pricing: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+		}
+
+		var page azureRetailPricesPage
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse Azure pricing: %w: %w", ErrParse, err)
+		}
+		items = append(items, page.Items...)
+		next = page.NextPageLink
+	}
+	return items, nil
+}
+
+// FetchPricing returns the pay-as-you-go Linux price of a VM size in a region. The Retail
+// Prices API doesn't describe sizes, so the vCPU count is read from the size name and the
+// memory is left unknown.
+func (f *AzureProvider) FetchPricing(ctx context.Context, region, vmSize string) (*Pricing, error) {
+	slog.Debug("fetching Azure pricing",
+		"region", region,
+		"vm_size", vmSize,
+	)
+
+	filter := fmt.Sprintf("serviceName eq 'Virtual Machines' and priceType eq 'Consumption' and armRegionName eq '%s' and armSkuName eq '%s'", region, vmSize)
+	trace := MatchTraceFrom(ctx)
+	trace.Query("Retail Prices API filter: %s", filter)
+	items, err := f.getPrices(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var price *azureRetailPrice
+	var candidates []*azureRetailPrice
+	for i := range items {
+		item := &items[i]
+		if reason := azureRejection(item); reason != "" {
+			trace.Reject(item.MeterID, item.ProductName+" "+item.SkuName, reason)
+			continue
+		}
+		candidates = append(candidates, item)
+		if price == nil || (item.IsPrimaryMeterRegion && !price.IsPrimaryMeterRegion) {
+			price = item
+		}
+	}
+	for _, item := range candidates {
+		if item == price {
+			trace.Match(item.MeterID, item.ProductName+" "+item.SkuName, "hourly Linux pay-as-you-go meter")
+		} else {
+			trace.Reject(item.MeterID, item.ProductName+" "+item.SkuName, "another meter was taken, preferring the primary meter region")
+		}
+	}
+	if price == nil {
+		return nil, fmt.Errorf("%w for VM size %s in region %s", ErrNoPricingFound, vmSize, region)
+	}
+
+	totalCost := decimal.NewFromFloat(price.RetailPrice)
+	trace.Component(PriceComponent{
+		ID:          price.MeterID,
+		Description: price.ProductName + " " + price.SkuName,
+		Rate:        totalCost,
+		Quantity:    decimal.NewFromInt(1),
+		Unit:        "instance",
+	})
+
+	return &Pricing{
+		Provider:     "azure",
+		Region:       region,
+		InstanceType: vmSize,
+		TotalCost:    totalCost,
+		VCPUs:        azureVCPUs(vmSize),
+	}, nil
+}
+
+// azureRejection returns why a retail price isn't the pay-as-you-go Linux price of a VM size,
+// or nothing when it may be. Windows is listed as its own product, and spot and low priority
+// as their own SKUs.
+func azureRejection(item *azureRetailPrice) string {
+	switch {
+	case strings.Contains(item.ProductName, "Windows"):
+		return "Windows product"
+	case strings.HasSuffix(item.SkuName, " Spot"):
+		return "Spot SKU"
+	case strings.HasSuffix(item.SkuName, " Low Priority"):
+		return "Low Priority SKU"
+	case item.UnitOfMeasure != "1 Hour":
+		return fmt.Sprintf("priced per %s rather than per hour", item.UnitOfMeasure)
+	case item.RetailPrice <= 0:
+		return "no price"
+	default:
+		return ""
+	}
+}
+
+// azureVCPUs returns the vCPU count in a VM size's name, or 0 when it has none
+func azureVCPUs(vmSize string) int {
+	match := azureVMSizePattern.FindStringSubmatch(vmSize)
+	if match == nil {
+		return 0
+	}
+	count := match[1]
+	if match[2] != "" {
+		count = match[2]
+	}
+	vcpus, err := strconv.Atoi(count)
+	if err != nil {
+		return 0
+	}
+	return vcpus
+}
+
+// AzureConfidentialVMSize returns the confidential VM size of a VM size, such as
+// Standard_DC4as_v5 for Standard_D4as_v5, and false when it has none. Azure sells confidential
+// VMs as sizes of their own, the DCas, DCads, ECas, and ECads series, each the same shape as the
+// AMD size it is named after.
+func AzureConfidentialVMSize(vmSize string) (string, bool) {
+	match := azureConfidentialBasePattern.FindStringSubmatch(vmSize)
+	if match == nil {
+		return "", false
+	}
+	return "Standard_" + match[1] + "C" + match[2] + match[3] + "_v5", true
+}
@@ -0,0 +1,109 @@
+// Package providers defines the interface a cloud implements to have its VM prices monitored,
+// and the registry providers are looked up in by name
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/shopspring/decimal"
+)
+
+// The classes a failed fetch falls into. Providers wrap the cause of a failure in one of them,
+// so it can be told apart from the others with errors.Is.
+var (
+	// ErrNoPricingFound is returned when a provider catalog has no price for a region and instance type
+	ErrNoPricingFound = errors.New("no pricing found")
+
+	// ErrNotFound is the class of ErrNoPricingFound
+	ErrNotFound = ErrNoPricingFound
+
+	// ErrThrottled is returned when a provider's API rejects a request for exceeding its rate limit
+	ErrThrottled = errors.New("throttled")
+
+	// ErrAuth is returned when a provider's API rejects the credentials or their permissions
+	ErrAuth = errors.New("not authorized")
+
+	// ErrParse is returned when a provider's API responds with something that isn't pricing
+	ErrParse = errors.New("invalid pricing response")
+
+	// ErrUnavailable is returned when a provider's API fails with a server error. Like
+	// throttling, it is worth retrying, but it has no reason of its own and is reported as other.
+	ErrUnavailable = errors.New("service unavailable")
+)
+
+// Reasons name the class of an error in metric labels, logs, and the API
+const (
+	ReasonThrottled = "throttled"
+	ReasonNotFound  = "not_found"
+	ReasonAuth      = "auth"
+	ReasonParse     = "parse"
+	ReasonOther     = "other"
+)
+
+// ErrorReason returns the reason of the class an error falls into, or ReasonOther when it
+// wasn't classified. A nil error has no reason.
+func ErrorReason(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrThrottled):
+		return ReasonThrottled
+	case errors.Is(err, ErrNotFound):
+		return ReasonNotFound
+	case errors.Is(err, ErrAuth):
+		return ReasonAuth
+	case errors.Is(err, ErrParse):
+		return ReasonParse
+	default:
+		return ReasonOther
+	}
+}
+
+// Pricing is the on-demand price per hour in USD of an instance type in a region
+type Pricing struct {
+	Provider     string
+	Region       string
+	InstanceType string
+	TotalCost    decimal.Decimal
+	MemoryGB     float64
+	VCPUs        int
+	Confidential bool
+
+	// GPUType and GPUCount are the accelerators every instance of the type has, such as 8
+	// nvidia-a100, and empty and zero for instance types without any
+	GPUType  string
+	GPUCount int
+
+	// Overridden is set by the monitor when it replaced the price with a configured override.
+	// Providers leave it unset.
+	Overridden bool
+}
+
+// Config is what a provider is asked to price and how to reach its API
+type Config struct {
+	Regions       []string
+	InstanceTypes []string
+
+	// HTTPClient sends the requests of providers that call their API without an SDK, routed
+	// through the provider's egress. Nil uses http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// PricingProvider prices the instance types of a cloud
+type PricingProvider interface {
+	// Name is the provider label of the cloud's series, such as aws
+	Name() string
+
+	// Configure creates the clients the provider needs to price a configuration. It is called
+	// once, before any prices are fetched.
+	Configure(ctx context.Context, cfg Config) error
+
+	// FetchPricing returns the current on-demand price of an instance type in a region,
+	// wrapping ErrNoPricingFound when the catalog has none
+	FetchPricing(ctx context.Context, region, instanceType string) (*Pricing, error)
+
+	// ListSupportedRegions returns the regions the provider can price
+	ListSupportedRegions(ctx context.Context) ([]string, error)
+}
@@ -0,0 +1,55 @@
+package providers
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Factory returns a new, unconfigured provider
+type Factory func() PricingProvider
+
+// Registry holds the factories of the providers a monitor can price with, by name
+type Registry struct {
+	mu        sync.RWMutex
+	names     []string
+	factories map[string]Factory
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: make(map[string]Factory),
+	}
+}
+
+// Register adds a provider's factory under its name. Each name can only be registered once.
+func (r *Registry) Register(name string, factory Factory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.factories[name]; ok {
+		return fmt.Errorf("provider %s is already registered", name)
+	}
+	r.names = append(r.names, name)
+	r.factories[name] = factory
+	return nil
+}
+
+// New returns a new, unconfigured provider of a registered name
+func (r *Registry) New(name string) (PricingProvider, error) {
+	r.mu.RLock()
+	factory, ok := r.factories[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %s", name)
+	}
+	return factory(), nil
+}
+
+// Names returns the registered provider names in the order they were registered
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]string(nil), r.names...)
+}
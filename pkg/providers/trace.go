@@ -0,0 +1,99 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// MatchTrace records how a provider resolved a price: the queries it sent to its catalog,
+// every catalog entry it considered with whether it was matched and why, and the components
+// the price adds up from. Providers record into the trace of the context they fetch with, and
+// a nil trace records nothing, so fetches outside a trace pay nothing for it.
+type MatchTrace struct {
+	mu         sync.Mutex
+	Queries    []string         `json:"queries"`
+	Decisions  []MatchDecision  `json:"decisions"`
+	Components []PriceComponent `json:"components"`
+}
+
+// MatchDecision is a catalog entry considered for a price
+type MatchDecision struct {
+	// ID identifies the entry in the catalog, such as a GCP SKU ID or an AWS product SKU
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Matched     bool   `json:"matched"`
+	// Reason is why the entry was matched or rejected
+	Reason string `json:"reason"`
+}
+
+// Query records a query sent to the catalog, or a lookup in a catalog held in memory
+func (t *MatchTrace) Query(format string, args ...any) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Queries = append(t.Queries, fmt.Sprintf(format, args...))
+}
+
+// Match records an entry the price was taken from
+func (t *MatchTrace) Match(id, description, reason string) {
+	t.decide(MatchDecision{ID: id, Description: description, Matched: true, Reason: reason})
+}
+
+// Reject records an entry that was considered and left out
+func (t *MatchTrace) Reject(id, description, reason string) {
+	t.decide(MatchDecision{ID: id, Description: description, Reason: reason})
+}
+
+func (t *MatchTrace) decide(d MatchDecision) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Decisions = append(t.Decisions, d)
+}
+
+// PriceComponent is a part of a price: the rate of a catalog entry times the quantity of it an
+// instance is billed for, such as a vCPU SKU's rate times the vCPU count
+type PriceComponent struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	// Rate is the price in USD of a unit for an hour
+	Rate     decimal.Decimal `json:"rate"`
+	Quantity decimal.Decimal `json:"quantity"`
+	// Unit is what the quantity counts: vCPU, GiB, GPU, or instance
+	Unit string `json:"unit"`
+}
+
+// Cost is the price in USD per hour of the component
+func (c PriceComponent) Cost() decimal.Decimal {
+	return c.Rate.Mul(c.Quantity)
+}
+
+// Component records a part of the price, which adds up with the other parts to its total
+func (t *MatchTrace) Component(c PriceComponent) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Components = append(t.Components, c)
+}
+
+type matchTraceKey struct{}
+
+// WithMatchTrace returns a context that fetches record their matching decisions in a trace
+func WithMatchTrace(ctx context.Context, t *MatchTrace) context.Context {
+	return context.WithValue(ctx, matchTraceKey{}, t)
+}
+
+// MatchTraceFrom returns the trace of a context, or nil when the fetch isn't traced
+func MatchTraceFrom(ctx context.Context) *MatchTrace {
+	t, _ := ctx.Value(matchTraceKey{}).(*MatchTrace)
+	return t
+}